@@ -8,8 +8,14 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/kin-ark/GroAcademy/internal/database"
+	"github.com/kin-ark/GroAcademy/internal/jobs"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
 	"github.com/kin-ark/GroAcademy/internal/routes"
 	"github.com/kin-ark/GroAcademy/internal/seeds"
+	"github.com/kin-ark/GroAcademy/internal/services"
+	"github.com/kin-ark/GroAcademy/internal/storage"
+	"github.com/kin-ark/GroAcademy/internal/transcode"
+	"github.com/kin-ark/GroAcademy/internal/utils"
 )
 
 func main() {
@@ -54,10 +60,19 @@ func main() {
 	router.Use(cors.New(config))
 
 	router.Static("/static", "./static")
-	router.Static("/uploads", "./uploads")
 
 	database.ConnectDB()
 
+	worker := jobs.NewRunner(repositories.NewJobRepository())
+	videoService := services.NewVideoService(repositories.NewModuleRepository(), storage.NewFromEnv(), transcode.NewFromEnv())
+	worker.Register(services.JobTypeTranscodeVideo, videoService.HandleTranscodeJob)
+	publishService := services.NewPublishService(repositories.NewCourseRepository(), repositories.NewModuleRepository(), repositories.NewJobRepository())
+	worker.Register(services.JobTypePublishContent, publishService.HandlePublishJob)
+	certificateService := services.NewCertificateService(repositories.NewCertificateRepository(), repositories.NewCertificateTemplateRepository(), repositories.NewCourseRepository(), repositories.NewJobRepository(), storage.NewFromEnv(), utils.CertificateSignerFromEnv())
+	worker.Register(services.JobTypeRegenerateCertificates, certificateService.HandleRegenerateJob)
+	worker.Register(services.JobTypeRecomputeCertificates, certificateService.HandleRecomputeJob)
+	worker.Start()
+
 	routes.SetupHTMLRenderer(router)
 	routes.RegisterFEoutes(router)
 	routes.RegisterRoutes(router)
@@ -67,7 +82,7 @@ func main() {
 		port = "8080"
 	}
 
-	seeder := seeds.NewSeeder(database.DB)
+	seeder := seeds.NewSeeder(database.DB, storage.NewFromEnv())
 	err := seeder.SeedAll()
 	if err != nil {
 		log.Println(err.Error())
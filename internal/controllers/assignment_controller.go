@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
+	"github.com/kin-ark/GroAcademy/internal/services"
+	"gorm.io/gorm"
+)
+
+type AssignmentController struct {
+	service services.AssignmentService
+}
+
+func NewAssignmentController(s services.AssignmentService) AssignmentController {
+	return AssignmentController{service: s}
+}
+
+func (ac *AssignmentController) GetAssignment(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid module ID")
+	if !ok {
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	res, err := ac.service.GetAssignment(id, user)
+	if err != nil {
+		respondAssignmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "request success",
+		"data":    res,
+	})
+}
+
+func (ac *AssignmentController) PutAssignment(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid module ID")
+	if !ok {
+		return
+	}
+
+	var input models.AssignmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	assignment, err := ac.service.SaveAssignment(id, input, user)
+	if err != nil {
+		respondAssignmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "assignment saved",
+		"data":    assignment,
+	})
+}
+
+func (ac *AssignmentController) DeleteAssignment(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid module ID")
+	if !ok {
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	if err := ac.service.DeleteAssignment(id, user); err != nil {
+		respondAssignmentError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (ac *AssignmentController) PostSubmission(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid module ID")
+	if !ok {
+		return
+	}
+
+	var input models.SubmissionFormInput
+	if err := c.ShouldBind(&input); err != nil {
+		if respondUploadError(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Bad Request",
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	res, err := ac.service.Submit(id, input, user)
+	if err != nil {
+		respondAssignmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "submission received",
+		"data":    res,
+	})
+}
+
+func (ac *AssignmentController) GetSubmissions(c *gin.Context) {
+	var q models.SubmissionQueueQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "invalid query parameters",
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	submissions, pagination, err := ac.service.ListSubmissions(q, user)
+	if err != nil {
+		respondAssignmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"message":    "request success",
+		"data":       submissions,
+		"pagination": pagination,
+	})
+}
+
+func (ac *AssignmentController) GetSubmission(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid submission ID")
+	if !ok {
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	res, err := ac.service.GetSubmission(id, user)
+	if err != nil {
+		respondAssignmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "request success",
+		"data":    res,
+	})
+}
+
+func (ac *AssignmentController) GradeSubmission(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid submission ID")
+	if !ok {
+		return
+	}
+
+	var input models.GradeSubmissionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	res, err := ac.service.GradeSubmission(id, input, user)
+	if err != nil {
+		respondAssignmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "submission graded",
+		"data":    res,
+	})
+}
+
+func respondAssignmentError(c *gin.Context, err error) {
+	if respondUploadError(c, err) {
+		return
+	}
+
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrNoContentAccess), errors.Is(err, services.ErrNotCourseInstructor),
+		errors.Is(err, services.ErrModuleLocked), errors.Is(err, services.ErrPrerequisitesNotMet):
+		status = http.StatusForbidden
+	case errors.Is(err, services.ErrInvalidAssignment), errors.Is(err, services.ErrInvalidSubmission):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.ErrAssignmentPassed), errors.Is(err, repositories.ErrSubmissionGraded),
+		errors.Is(err, repositories.ErrSubmissionChanged):
+		status = http.StatusConflict
+	}
+
+	c.JSON(status, gin.H{
+		"status":  "error",
+		"message": err.Error(),
+		"data":    nil,
+	})
+}
@@ -2,10 +2,14 @@ package controllers
 
 import (
 	"errors"
+	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kin-ark/GroAcademy/internal/middlewares"
 	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
 	"github.com/kin-ark/GroAcademy/internal/services"
 )
 
@@ -62,7 +66,7 @@ func (authController *AuthController) Login(c *gin.Context) {
 		return
 	}
 
-	token, username, err := authController.service.LoginUser(input)
+	tokens, username, err := authController.service.LoginUser(input, sessionMeta(c))
 	if err != nil {
 		if errors.Is(err, services.ErrInvalidCredentials) {
 			c.JSON(http.StatusUnauthorized, gin.H{
@@ -80,19 +84,157 @@ func (authController *AuthController) Login(c *gin.Context) {
 		return
 	}
 
-	c.SetSameSite(http.SameSiteLaxMode)
-	c.SetCookie("Authorization", token, 3600, "", "", true, true)
+	setAuthCookies(c, tokens)
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
 		"message": "Login successful",
 		"data": gin.H{
-			"username": username,
-			"token":    token,
+			"username":           username,
+			"token":              tokens.AccessToken,
+			"expires_at":         tokens.AccessExpiresAt,
+			"refresh_token":      tokens.RefreshToken,
+			"refresh_expires_at": tokens.RefreshExpiresAt,
+		},
+	})
+}
+
+func (authController *AuthController) Refresh(c *gin.Context) {
+	var input models.RefreshTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "invalid request body",
+			"data":    nil,
+		})
+		return
+	}
+
+	if input.RefreshToken == "" {
+		input.RefreshToken, _ = c.Cookie("RefreshToken")
+	}
+
+	tokens, err := authController.service.RefreshSession(input.RefreshToken, sessionMeta(c))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+				"data":    nil,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "An internal error occured",
+			"data":    nil,
+		})
+		return
+	}
+
+	setAuthCookies(c, tokens)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Token refreshed",
+		"data": gin.H{
+			"token":              tokens.AccessToken,
+			"expires_at":         tokens.AccessExpiresAt,
+			"refresh_token":      tokens.RefreshToken,
+			"refresh_expires_at": tokens.RefreshExpiresAt,
 		},
 	})
 }
 
+func (authController *AuthController) Logout(c *gin.Context) {
+	u := c.MustGet("user").(models.User)
+	sessionID := c.GetUint("session_id")
+
+	if err := authController.service.Logout(sessionID, u.ID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	middlewares.ClearAuthCookies(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Logout successful",
+		"data":    nil,
+	})
+}
+
+func (authController *AuthController) GetSessions(c *gin.Context) {
+	u := c.MustGet("user").(models.User)
+
+	sessions, err := authController.service.GetSessions(u.ID, c.GetUint("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "An internal error occured",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Request success",
+		"data":    sessions,
+	})
+}
+
+func (authController *AuthController) DeleteSession(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid session ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	u := c.MustGet("user").(models.User)
+
+	if err := authController.service.RevokeSession(uint(id), u.ID); err != nil {
+		if errors.Is(err, repositories.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "Session not found",
+				"data":    nil,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "An internal error occured",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func sessionMeta(c *gin.Context) models.SessionMeta {
+	return models.SessionMeta{
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+}
+
+func setAuthCookies(c *gin.Context, tokens *models.AuthTokens) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie("Authorization", tokens.AccessToken, int(services.AccessTokenTTL.Seconds()), "", "", true, true)
+	c.SetCookie("RefreshToken", tokens.RefreshToken, int(services.RefreshTokenTTL.Seconds()), "/api/auth", "", true, true)
+}
+
 func (authController *AuthController) GetSelf(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
@@ -110,12 +252,111 @@ func (authController *AuthController) GetSelf(c *gin.Context) {
 		"status":  "success",
 		"message": "Request success",
 		"data": gin.H{
-			"id":         u.ID,
-			"username":   u.Username,
-			"first_name": u.FirstName,
-			"last_name":  u.LastName,
-			"email":      u.Email,
-			"balance":    u.Balance,
+			"id":             u.ID,
+			"username":       u.Username,
+			"first_name":     u.FirstName,
+			"last_name":      u.LastName,
+			"email":          u.Email,
+			"email_verified": u.EmailVerifiedAt != nil,
+			"role":           u.Role,
+			"balance":        u.Balance,
 		},
 	})
 }
+
+func (authController *AuthController) VerifyEmail(c *gin.Context) {
+	var input models.VerifyEmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "invalid request body",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := authController.service.VerifyEmail(input.Token); err != nil {
+		if errors.Is(err, repositories.ErrTokenNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+				"data":    nil,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "An internal error occured",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Email verified",
+		"data":    nil,
+	})
+}
+
+func (authController *AuthController) ForgotPassword(c *gin.Context) {
+	var input models.ForgotPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "invalid request body",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := authController.service.ForgotPassword(input.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "An internal error occured",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "If an account exists for that email, a reset link has been sent",
+		"data":    nil,
+	})
+}
+
+func (authController *AuthController) ResetPassword(c *gin.Context) {
+	var input models.ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "invalid request body",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := authController.service.ResetPassword(input); err != nil {
+		if errors.Is(err, repositories.ErrTokenNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+				"data":    nil,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "An internal error occured",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Password has been reset",
+		"data":    nil,
+	})
+}
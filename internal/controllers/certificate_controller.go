@@ -0,0 +1,383 @@
+package controllers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/services"
+	"gorm.io/gorm"
+)
+
+type CertificateController struct {
+	service services.CertificateService
+}
+
+func NewCertificateController(s services.CertificateService) CertificateController {
+	return CertificateController{service: s}
+}
+
+// VerifyCertificate is public: anyone holding a serial can check it.
+func (cc *CertificateController) VerifyCertificate(c *gin.Context) {
+	verification, err := cc.service.VerifyCertificate(c.Param("serial"))
+	if err != nil {
+		respondCertificateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "request success",
+		"data":    verification,
+	})
+}
+
+func (cc *CertificateController) RevokeCertificate(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid certificate ID")
+	if !ok {
+		return
+	}
+
+	var input models.CertificateRevocationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	verification, err := cc.service.RevokeCertificate(id, input, user)
+	if err != nil {
+		respondCertificateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "certificate revoked",
+		"data":    verification,
+	})
+}
+
+func (cc *CertificateController) ReinstateCertificate(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid certificate ID")
+	if !ok {
+		return
+	}
+
+	verification, err := cc.service.ReinstateCertificate(id)
+	if err != nil {
+		respondCertificateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "certificate reinstated",
+		"data":    verification,
+	})
+}
+
+func (cc *CertificateController) GetMyCertificates(c *gin.Context) {
+	var query models.PaginationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	certificates, pagination, err := cc.service.ListUserCertificates(user, query)
+	if err != nil {
+		respondCertificateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"message":    "request success",
+		"data":       certificates,
+		"pagination": pagination,
+	})
+}
+
+// DownloadCertificate streams the certificate as an attachment, as PDF
+// unless ?format=png is given.
+func (cc *CertificateController) DownloadCertificate(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid certificate ID")
+	if !ok {
+		return
+	}
+
+	var query models.CertificateFormatQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	obj, filename, err := cc.service.OpenCertificate(id, user, query.Format)
+	if err != nil {
+		respondCertificateError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	serveObject(c, obj, filename)
+}
+
+func (cc *CertificateController) RegenerateCertificate(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid certificate ID")
+	if !ok {
+		return
+	}
+
+	certificate, err := cc.service.RegenerateCertificate(id)
+	if err != nil {
+		respondCertificateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "certificate regenerated",
+		"data":    certificate,
+	})
+}
+
+func (cc *CertificateController) GetTemplates(c *gin.Context) {
+	templates, err := cc.service.ListTemplates()
+	if err != nil {
+		respondCertificateTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "request success",
+		"data":    templates,
+	})
+}
+
+func (cc *CertificateController) GetTemplate(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid template ID")
+	if !ok {
+		return
+	}
+
+	template, err := cc.service.GetTemplate(id)
+	if err != nil {
+		respondCertificateTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "request success",
+		"data":    template,
+	})
+}
+
+func (cc *CertificateController) PostTemplate(c *gin.Context) {
+	var input models.CertificateTemplateFormInput
+	if err := c.ShouldBind(&input); err != nil {
+		if respondUploadError(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	template, err := cc.service.CreateTemplate(input)
+	if err != nil {
+		respondCertificateTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "certificate template created",
+		"data":    template,
+	})
+}
+
+// PutTemplate replaces a template. Certificates using it are re-rendered in
+// the background.
+func (cc *CertificateController) PutTemplate(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid template ID")
+	if !ok {
+		return
+	}
+
+	var input models.CertificateTemplateFormInput
+	if err := c.ShouldBind(&input); err != nil {
+		if respondUploadError(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	template, err := cc.service.UpdateTemplate(id, input)
+	if err != nil {
+		respondCertificateTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "certificate template updated",
+		"data":    template,
+	})
+}
+
+func (cc *CertificateController) DeleteTemplate(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid template ID")
+	if !ok {
+		return
+	}
+
+	if err := cc.service.DeleteTemplate(id); err != nil {
+		respondCertificateTemplateError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// PreviewTemplate renders the template with sample data; ?format=pdf returns
+// the PDF instead of the PNG.
+func (cc *CertificateController) PreviewTemplate(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid template ID")
+	if !ok {
+		return
+	}
+
+	var query models.CertificateFormatQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	data, contentType, err := cc.service.PreviewTemplate(id, query.Format)
+	if err != nil {
+		respondCertificateTemplateError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, contentType, data)
+}
+
+func (cc *CertificateController) PutCourseTemplate(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid course ID")
+	if !ok {
+		return
+	}
+
+	var input models.CourseCertificateTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	course, err := cc.service.SetCourseTemplate(id, input, user)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "course not found",
+				"data":    nil,
+			})
+			return
+		}
+		respondCertificateTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "certificate template updated",
+		"data": gin.H{
+			"id":                      course.ID,
+			"certificate_template_id": course.CertificateTemplateID,
+		},
+	})
+}
+
+func respondCertificateTemplateError(c *gin.Context, err error) {
+	if respondUploadError(c, err) {
+		return
+	}
+
+	status := http.StatusInternalServerError
+	message := err.Error()
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status = http.StatusNotFound
+		message = "certificate template not found"
+	case errors.Is(err, services.ErrNotCourseInstructor):
+		status = http.StatusForbidden
+	case errors.Is(err, services.ErrInvalidCertificateTemplate):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.ErrCertificateTemplateExists):
+		status = http.StatusConflict
+	}
+
+	c.JSON(status, gin.H{
+		"status":  "error",
+		"message": message,
+		"data":    nil,
+	})
+}
+
+func respondCertificateError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	message := err.Error()
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status = http.StatusNotFound
+		message = "certificate not found"
+	case errors.Is(err, services.ErrCertificateRevoked):
+		status = http.StatusForbidden
+	}
+
+	c.JSON(status, gin.H{
+		"status":  "error",
+		"message": message,
+		"data":    nil,
+	})
+}
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/services"
+	"gorm.io/gorm"
+)
+
+type CouponController struct {
+	service services.CouponService
+}
+
+func NewCouponController(s services.CouponService) CouponController {
+	return CouponController{service: s}
+}
+
+func (cc *CouponController) GetCoupons(c *gin.Context) {
+	var q models.PaginationQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "invalid query parameters",
+			"data":    nil,
+		})
+		return
+	}
+
+	coupons, pagination, err := cc.service.ListCoupons(q)
+	if err != nil {
+		respondCouponError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"message":    "request success",
+		"data":       coupons,
+		"pagination": pagination,
+	})
+}
+
+func (cc *CouponController) GetCoupon(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid coupon ID")
+	if !ok {
+		return
+	}
+
+	coupon, err := cc.service.GetCoupon(id)
+	if err != nil {
+		respondCouponError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "request success",
+		"data":    coupon,
+	})
+}
+
+func (cc *CouponController) PostCoupon(c *gin.Context) {
+	var input models.CouponInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	coupon, err := cc.service.CreateCoupon(input)
+	if err != nil {
+		respondCouponError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "coupon created",
+		"data":    coupon,
+	})
+}
+
+func (cc *CouponController) PutCoupon(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid coupon ID")
+	if !ok {
+		return
+	}
+
+	var input models.CouponInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	coupon, err := cc.service.UpdateCoupon(id, input)
+	if err != nil {
+		respondCouponError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "coupon updated",
+		"data":    coupon,
+	})
+}
+
+func (cc *CouponController) DeleteCoupon(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid coupon ID")
+	if !ok {
+		return
+	}
+
+	if err := cc.service.DeleteCoupon(id); err != nil {
+		respondCouponError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondCouponError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	message := err.Error()
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status = http.StatusNotFound
+		message = "coupon not found"
+	case errors.Is(err, services.ErrInvalidCoupon):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.ErrCouponExists):
+		status = http.StatusConflict
+	}
+
+	c.JSON(status, gin.H{
+		"status":  "error",
+		"message": message,
+		"data":    nil,
+	})
+}
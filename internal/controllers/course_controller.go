@@ -1,12 +1,16 @@
 package controllers
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
 	"github.com/kin-ark/GroAcademy/internal/services"
+	"gorm.io/gorm"
 )
 
 type CourseController struct {
@@ -20,6 +24,9 @@ func NewCourseController(s services.CourseService) CourseController {
 func (cc *CourseController) PostCourse(c *gin.Context) {
 	var input models.CourseFormInput
 	if err := c.ShouldBind(&input); err != nil {
+		if respondUploadError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  "error",
 			"message": "Bad Request",
@@ -28,9 +35,14 @@ func (cc *CourseController) PostCourse(c *gin.Context) {
 		return
 	}
 
-	result, err := cc.service.CreateCourse(c, input)
+	user := c.MustGet("user").(models.User)
+
+	result, err := cc.service.CreateCourse(c, input, user)
 
 	if err != nil {
+		if respondUploadError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  "error",
 			"message": err.Error(),
@@ -41,15 +53,23 @@ func (cc *CourseController) PostCourse(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": gin.H{
-			"id":              result.ID,
-			"title":           result.Title,
-			"description":     result.Description,
-			"instructor":      result.Instructor,
-			"topics":          result.Topics,
-			"price":           result.Price,
-			"thumbnail_image": result.ThumbnailImage,
-			"created_at":      result.CreatedAt,
-			"updated_at":      result.UpdatedAt,
+			"id":                 result.ID,
+			"title":              result.Title,
+			"description":        result.Description,
+			"instructor":         result.Instructor,
+			"topics":             result.Topics,
+			"price":              result.Price,
+			"sale_price":         result.SalePrice,
+			"sale_starts_at":     result.SaleStartsAt,
+			"sale_ends_at":       result.SaleEndsAt,
+			"current_price":      result.CurrentPrice(),
+			"thumbnail_image":    result.ThumbnailImage,
+			"sequential_modules": result.SequentialModules,
+			"certificate_policy": result.CertificatePolicy,
+			"status":             result.Status,
+			"publish_at":         result.PublishAt,
+			"created_at":         result.CreatedAt,
+			"updated_at":         result.UpdatedAt,
 		},
 		"message": "Post course success",
 		"status":  "success",
@@ -57,7 +77,7 @@ func (cc *CourseController) PostCourse(c *gin.Context) {
 }
 
 func (cc *CourseController) GetAllCourses(c *gin.Context) {
-	var query models.SearchQuery
+	var query models.CourseSearchQuery
 	if err := c.ShouldBindQuery(&query); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  "error",
@@ -67,7 +87,9 @@ func (cc *CourseController) GetAllCourses(c *gin.Context) {
 		return
 	}
 
-	courses, pagination, err := cc.service.GetAllCourses(query)
+	user := c.MustGet("user").(models.User)
+
+	courses, pagination, facets, err := cc.service.GetAllCourses(query, user)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  "error",
@@ -84,6 +106,7 @@ func (cc *CourseController) GetAllCourses(c *gin.Context) {
 		"message":    "Successfully get all courses",
 		"data":       coursesResponse,
 		"pagination": pagination,
+		"facets":     facets,
 	})
 }
 
@@ -99,7 +122,9 @@ func (cc *CourseController) GetCourseByID(c *gin.Context) {
 		return
 	}
 
-	course, err := cc.service.GetCourseByID(uint(id))
+	user := c.MustGet("user").(models.User)
+
+	course, err := cc.service.GetCourseByID(uint(id), user)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"status":  "error",
@@ -118,6 +143,16 @@ func (cc *CourseController) GetCourseByID(c *gin.Context) {
 		return
 	}
 
+	instructors, err := cc.service.GetCourseInstructors(course.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to fetch instructors",
+			"data":    nil,
+		})
+		return
+	}
+
 	courseResponse := models.CourseResponse{
 		ID:             course.ID,
 		Title:          course.Title,
@@ -129,6 +164,18 @@ func (cc *CourseController) GetCourseByID(c *gin.Context) {
 		TotalModules:   int(moduleCount),
 		CreatedAt:      course.CreatedAt,
 		UpdatedAt:      course.UpdatedAt,
+
+		SequentialModules: course.SequentialModules,
+		SalePrice:         course.SalePrice,
+		SaleStartsAt:      course.SaleStartsAt,
+		SaleEndsAt:        course.SaleEndsAt,
+		CurrentPrice:      course.CurrentPrice(),
+		CertificatePolicy: course.CertificatePolicy,
+		Status:            course.Status,
+		PublishAt:         course.PublishAt,
+		RatingAverage:     course.RatingAverage,
+		RatingCount:       course.RatingCount,
+		Instructors:       instructors,
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -152,6 +199,9 @@ func (cc *CourseController) PutCourse(c *gin.Context) {
 
 	var input models.CourseFormInput
 	if err := c.ShouldBind(&input); err != nil {
+		if respondUploadError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  "error",
 			"message": "Bad Request",
@@ -160,9 +210,22 @@ func (cc *CourseController) PutCourse(c *gin.Context) {
 		return
 	}
 
-	result, err := cc.service.EditCourse(c, uint(id), input)
+	user := c.MustGet("user").(models.User)
+
+	result, err := cc.service.EditCourse(c, uint(id), input, user)
 
 	if err != nil {
+		if errors.Is(err, services.ErrNotCourseInstructor) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+				"data":    nil,
+			})
+			return
+		}
+		if respondUploadError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  "error",
 			"message": err.Error(),
@@ -173,15 +236,23 @@ func (cc *CourseController) PutCourse(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": gin.H{
-			"id":              result.ID,
-			"title":           result.Title,
-			"description":     result.Description,
-			"instructor":      result.Instructor,
-			"topics":          result.Topics,
-			"price":           result.Price,
-			"thumbnail_image": result.ThumbnailImage,
-			"created_at":      result.CreatedAt,
-			"updated_at":      result.UpdatedAt,
+			"id":                 result.ID,
+			"title":              result.Title,
+			"description":        result.Description,
+			"instructor":         result.Instructor,
+			"topics":             result.Topics,
+			"price":              result.Price,
+			"sale_price":         result.SalePrice,
+			"sale_starts_at":     result.SaleStartsAt,
+			"sale_ends_at":       result.SaleEndsAt,
+			"current_price":      result.CurrentPrice(),
+			"thumbnail_image":    result.ThumbnailImage,
+			"sequential_modules": result.SequentialModules,
+			"certificate_policy": result.CertificatePolicy,
+			"status":             result.Status,
+			"publish_at":         result.PublishAt,
+			"created_at":         result.CreatedAt,
+			"updated_at":         result.UpdatedAt,
 		},
 		"message": "Post course success",
 		"status":  "success",
@@ -237,8 +308,35 @@ func (cc *CourseController) BuyCourse(c *gin.Context) {
 	}
 	u := user.(models.User)
 
-	res, err := cc.service.BuyCourse(uint(id), &u)
+	// The body is optional; a purchase without a coupon may send none.
+	var input models.BuyCourseInput
+	if err := c.ShouldBind(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	res, err := cc.service.BuyCourse(uint(id), &u, input.CouponCode)
 	if err != nil {
+		if errors.Is(err, services.ErrPrerequisitesNotMet) || errors.Is(err, services.ErrCourseNotPublished) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+				"data":    nil,
+			})
+			return
+		}
+		if services.IsCouponError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+				"data":    nil,
+			})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  "error",
 			"message": "Bad Request",
@@ -254,6 +352,50 @@ func (cc *CourseController) BuyCourse(c *gin.Context) {
 	})
 }
 
+// GetCoursePrice quotes the course's current price, with the coupon given
+// in coupon_code applied.
+func (cc *CourseController) GetCoursePrice(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid course ID")
+	if !ok {
+		return
+	}
+
+	var input models.BuyCourseInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "invalid query parameters",
+			"data":    nil,
+		})
+		return
+	}
+
+	quote, err := cc.service.QuoteCoursePrice(id, input.CouponCode)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, services.ErrCourseNotPublished):
+			status = http.StatusForbidden
+		case services.IsCouponError(err):
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "request success",
+		"data":    quote,
+	})
+}
+
 func (cc *CourseController) GetMyCourses(c *gin.Context) {
 	var query models.SearchQuery
 	if err := c.ShouldBindQuery(&query); err != nil {
@@ -293,3 +435,449 @@ func (cc *CourseController) GetMyCourses(c *gin.Context) {
 		"pagination": pagination,
 	})
 }
+
+func (cc *CourseController) RefundCourse(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid course ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	var body models.RefundRequest
+	if err := c.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Bad Request",
+			"data":    nil,
+		})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Bad Request",
+			"data":    nil,
+		})
+		return
+	}
+	u := user.(models.User)
+
+	res, err := cc.service.RefundCourse(uint(id), &u, body.Reason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Refund course success",
+		"data":    res,
+	})
+}
+
+func (cc *CourseController) RefundPurchase(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid purchase ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	var body models.RefundRequest
+	if err := c.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Bad Request",
+			"data":    nil,
+		})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Bad Request",
+			"data":    nil,
+		})
+		return
+	}
+	u := user.(models.User)
+
+	res, err := cc.service.RefundPurchase(uint(id), &u, body.Reason)
+	if err != nil {
+		if errors.Is(err, repositories.ErrPurchaseNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "Purchase not found",
+				"data":    nil,
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Refund purchase success",
+		"data":    res,
+	})
+}
+
+func (cc *CourseController) GetInstructorDashboard(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	stats, err := cc.service.GetInstructorDashboard(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	var totalRevenue float64
+	var totalEnrolments int64
+	for _, course := range stats {
+		totalRevenue += course.Revenue
+		totalEnrolments += course.Enrolments
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Request success",
+		"data": gin.H{
+			"courses":          stats,
+			"total_revenue":    totalRevenue,
+			"total_enrolments": totalEnrolments,
+		},
+	})
+}
+
+func (cc *CourseController) GetPrerequisites(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid course ID")
+	if !ok {
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	prerequisites, err := cc.service.GetPrerequisites(id, user)
+	if err != nil {
+		respondPrerequisiteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "request success",
+		"data":    prerequisites,
+	})
+}
+
+func (cc *CourseController) PutPrerequisites(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid course ID")
+	if !ok {
+		return
+	}
+
+	var input models.PrerequisitesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "invalid request body",
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	prerequisites, err := cc.service.SetPrerequisites(id, input.CourseIDs, user)
+	if err != nil {
+		respondPrerequisiteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "prerequisites saved",
+		"data":    prerequisites,
+	})
+}
+
+func (cc *CourseController) GetAssistants(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid course ID")
+	if !ok {
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	assistants, err := cc.service.GetAssistants(id, user)
+	if err != nil {
+		respondPrerequisiteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "request success",
+		"data":    assistants,
+	})
+}
+
+func (cc *CourseController) PutAssistants(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid course ID")
+	if !ok {
+		return
+	}
+
+	var input models.AssistantsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "invalid request body",
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	assistants, err := cc.service.SetAssistants(id, input.UserIDs, user)
+	if err != nil {
+		respondPrerequisiteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "assistants saved",
+		"data":    assistants,
+	})
+}
+
+func respondPrerequisiteError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrNotCourseInstructor):
+		status = http.StatusForbidden
+	case errors.Is(err, services.ErrInvalidPrerequisite), errors.Is(err, services.ErrInvalidAssistant):
+		status = http.StatusBadRequest
+	}
+
+	c.JSON(status, gin.H{
+		"status":  "error",
+		"message": err.Error(),
+		"data":    nil,
+	})
+}
+
+func (cc *CourseController) PatchCourseStatus(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid course ID")
+	if !ok {
+		return
+	}
+
+	var input models.PublishStatusInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	course, err := cc.service.SetCourseStatus(id, input, user)
+	if err != nil {
+		respondPublishStatusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "course status updated",
+		"data": gin.H{
+			"id":         course.ID,
+			"status":     course.Status,
+			"publish_at": course.PublishAt,
+		},
+	})
+}
+
+func respondPublishStatusError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrNotCourseInstructor):
+		status = http.StatusForbidden
+	case errors.Is(err, services.ErrInvalidStatus):
+		status = http.StatusBadRequest
+	}
+
+	c.JSON(status, gin.H{
+		"status":  "error",
+		"message": err.Error(),
+		"data":    nil,
+	})
+}
+
+func (cc *CourseController) GetCourseRevisions(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid course ID")
+	if !ok {
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	revisions, err := cc.service.GetCourseRevisions(id, user)
+	if err != nil {
+		respondRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "request success",
+		"data":    revisions,
+	})
+}
+
+func (cc *CourseController) GetCourseRevision(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid course ID")
+	if !ok {
+		return
+	}
+	number, ok := parseRevisionParam(c)
+	if !ok {
+		return
+	}
+
+	var q models.RevisionDiffQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "invalid query parameters",
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	revision, err := cc.service.GetCourseRevision(id, number, q.Against, user)
+	if err != nil {
+		respondRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "request success",
+		"data":    revision,
+	})
+}
+
+func (cc *CourseController) RestoreCourseRevision(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid course ID")
+	if !ok {
+		return
+	}
+	number, ok := parseRevisionParam(c)
+	if !ok {
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	course, err := cc.service.RestoreCourseRevision(id, number, user)
+	if err != nil {
+		respondRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "revision restored",
+		"data": gin.H{
+			"id":                 course.ID,
+			"title":              course.Title,
+			"description":        course.Description,
+			"instructor":         course.Instructor,
+			"topics":             course.Topics,
+			"price":              course.Price,
+			"sale_price":         course.SalePrice,
+			"sale_starts_at":     course.SaleStartsAt,
+			"sale_ends_at":       course.SaleEndsAt,
+			"current_price":      course.CurrentPrice(),
+			"thumbnail_image":    course.ThumbnailImage,
+			"sequential_modules": course.SequentialModules,
+			"certificate_policy": course.CertificatePolicy,
+			"status":             course.Status,
+			"publish_at":         course.PublishAt,
+			"created_at":         course.CreatedAt,
+			"updated_at":         course.UpdatedAt,
+		},
+	})
+}
+
+func parseRevisionParam(c *gin.Context) (int, bool) {
+	number, err := strconv.Atoi(c.Param("rev"))
+	if err != nil || number < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid revision number",
+			"data":    nil,
+		})
+		return 0, false
+	}
+	return number, true
+}
+
+func respondRevisionError(c *gin.Context, err error) {
+	if respondUploadError(c, err) {
+		return
+	}
+
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrNotCourseInstructor):
+		status = http.StatusForbidden
+	}
+
+	c.JSON(status, gin.H{
+		"status":  "error",
+		"message": err.Error(),
+		"data":    nil,
+	})
+}
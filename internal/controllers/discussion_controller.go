@@ -0,0 +1,255 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/services"
+	"gorm.io/gorm"
+)
+
+type DiscussionController struct {
+	service services.DiscussionService
+}
+
+func NewDiscussionController(s services.DiscussionService) DiscussionController {
+	return DiscussionController{service: s}
+}
+
+func (dc *DiscussionController) GetThreads(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid module ID")
+	if !ok {
+		return
+	}
+
+	var q models.DiscussionQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "invalid query parameters",
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	threads, pagination, err := dc.service.GetThreads(id, q, user)
+	if err != nil {
+		respondDiscussionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"message":    "request success",
+		"data":       threads,
+		"pagination": pagination,
+	})
+}
+
+func (dc *DiscussionController) PostThread(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid module ID")
+	if !ok {
+		return
+	}
+
+	var input models.DiscussionThreadInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	thread, err := dc.service.CreateThread(id, input, user)
+	if err != nil {
+		respondDiscussionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "thread created",
+		"data":    thread,
+	})
+}
+
+func (dc *DiscussionController) GetThread(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	var q models.PaginationQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "invalid query parameters",
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	thread, pagination, err := dc.service.GetThread(id, q, user)
+	if err != nil {
+		respondDiscussionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"message":    "request success",
+		"data":       thread,
+		"pagination": pagination,
+	})
+}
+
+func (dc *DiscussionController) PostReply(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	var input models.DiscussionReplyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	reply, err := dc.service.Reply(id, input, user)
+	if err != nil {
+		respondDiscussionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "reply posted",
+		"data":    reply,
+	})
+}
+
+func (dc *DiscussionController) PutPost(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	var input models.DiscussionPostUpdateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	post, err := dc.service.UpdatePost(id, input, user)
+	if err != nil {
+		respondDiscussionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "post updated",
+		"data":    post,
+	})
+}
+
+func (dc *DiscussionController) DeletePost(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	if err := dc.service.DeletePost(id, user); err != nil {
+		respondDiscussionError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (dc *DiscussionController) PostUpvote(c *gin.Context) {
+	dc.respondPostAction(c, "post upvoted", dc.service.Upvote)
+}
+
+func (dc *DiscussionController) DeleteUpvote(c *gin.Context) {
+	dc.respondPostAction(c, "upvote removed", dc.service.RemoveUpvote)
+}
+
+func (dc *DiscussionController) PostAnswer(c *gin.Context) {
+	dc.respondPostAction(c, "reply marked as answer", func(id uint, user models.User) (*models.DiscussionPostResponse, error) {
+		return dc.service.SetAnswer(id, true, user)
+	})
+}
+
+func (dc *DiscussionController) DeleteAnswer(c *gin.Context) {
+	dc.respondPostAction(c, "answer mark removed", func(id uint, user models.User) (*models.DiscussionPostResponse, error) {
+		return dc.service.SetAnswer(id, false, user)
+	})
+}
+
+// respondPostAction runs a body-less action on the post in the path and
+// returns the updated post.
+func (dc *DiscussionController) respondPostAction(c *gin.Context, message string, action func(uint, models.User) (*models.DiscussionPostResponse, error)) {
+	id, ok := parseIDParam(c, "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	post, err := action(id, user)
+	if err != nil {
+		respondDiscussionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": message,
+		"data":    post,
+	})
+}
+
+func respondDiscussionError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrNoContentAccess), errors.Is(err, services.ErrNotCourseInstructor),
+		errors.Is(err, services.ErrModuleLocked), errors.Is(err, services.ErrPrerequisitesNotMet),
+		errors.Is(err, services.ErrNotPostAuthor):
+		status = http.StatusForbidden
+	case errors.Is(err, services.ErrInvalidDiscussionPost):
+		status = http.StatusBadRequest
+	}
+
+	c.JSON(status, gin.H{
+		"status":  "error",
+		"message": err.Error(),
+		"data":    nil,
+	})
+}
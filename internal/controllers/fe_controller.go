@@ -1,24 +1,39 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kin-ark/GroAcademy/internal/middlewares"
 	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/rbac"
 	"github.com/kin-ark/GroAcademy/internal/services"
+	"github.com/kin-ark/GroAcademy/internal/utils"
+	"gorm.io/gorm"
 )
 
 type FEController struct {
-	us services.UserService
-	cs services.CourseService
-	ms services.ModuleService
+	as  services.AuthService
+	us  services.UserService
+	cs  services.CourseService
+	ms  services.ModuleService
+	qs  services.QuizService
+	asg services.AssignmentService
+	ss  services.SectionService
+	rs  services.ReviewService
+	ds  services.DiscussionService
+	cts services.CertificateService
 }
 
-func NewFEController(us services.UserService, cs services.CourseService, ms services.ModuleService) *FEController {
-	return &FEController{us: us, cs: cs, ms: ms}
+func NewFEController(as services.AuthService, us services.UserService, cs services.CourseService, ms services.ModuleService, qs services.QuizService, asg services.AssignmentService, ss services.SectionService, rs services.ReviewService, ds services.DiscussionService, cts services.CertificateService) *FEController {
+	return &FEController{as: as, us: us, cs: cs, ms: ms, qs: qs, asg: asg, ss: ss, rs: rs, ds: ds, cts: cts}
 }
 
 func (fc *FEController) ShowLoginPage(c *gin.Context) {
@@ -29,6 +44,54 @@ func (fc *FEController) ShowRegisterPage(c *gin.Context) {
 	c.HTML(http.StatusOK, "register.html", nil)
 }
 
+func (fc *FEController) ShowForgotPasswordPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "forgot-password.html", nil)
+}
+
+func (fc *FEController) ShowResetPasswordPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "reset-password.html", gin.H{
+		"Token": c.Query("token"),
+	})
+}
+
+func (fc *FEController) VerifyEmailPage(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.HTML(http.StatusBadRequest, "verify-email.html", gin.H{
+			"Verified": false,
+			"Message":  "Verification link is missing its token.",
+		})
+		return
+	}
+
+	if err := fc.as.VerifyEmail(token); err != nil {
+		c.HTML(http.StatusBadRequest, "verify-email.html", gin.H{
+			"Verified": false,
+			"Message":  "This verification link is invalid or has expired.",
+		})
+		return
+	}
+
+	c.HTML(http.StatusOK, "verify-email.html", gin.H{
+		"Verified": true,
+		"Message":  "Your email address has been verified.",
+	})
+}
+
+func (fc *FEController) Logout(c *gin.Context) {
+	_, userID := getUserFromContext(c)
+
+	if err := fc.as.Logout(c.GetUint("session_id"), userID); err != nil {
+		log.Printf("ERROR: Failed to revoke session for user %d: %v", userID, err)
+	}
+
+	middlewares.ClearAuthCookies(c)
+
+	c.HTML(http.StatusOK, "login.html", gin.H{
+		"message": "Logged out",
+	})
+}
+
 func getUserFromContext(c *gin.Context) (*models.User, uint) {
 	userVal, exists := c.Get("user")
 	if !exists {
@@ -52,9 +115,22 @@ func (fc *FEController) GetCoursesPage(c *gin.Context) {
 	}
 	search := c.DefaultQuery("q", "")
 
-	query := models.SearchQuery{Q: search, PaginationQuery: models.PaginationQuery{Page: page, Limit: limit}}
+	var query models.CourseSearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		query = models.CourseSearchQuery{SearchQuery: models.SearchQuery{Q: search}}
+	}
+	query.Page = page
+	query.Limit = limit
+
+	user, userID := getUserFromContext(c)
 
-	courses, pagination, err := fc.cs.GetAllCourses(query)
+	courses, pagination, facets, err := fc.cs.GetAllCourses(query, *user)
+	if errors.Is(err, services.ErrInvalidPriceRange) {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "The maximum price must not be below the minimum price.",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
 	if err != nil {
 		log.Printf("Failed to get all courses: %v", err)
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
@@ -63,8 +139,6 @@ func (fc *FEController) GetCoursesPage(c *gin.Context) {
 		return
 	}
 
-	user, userID := getUserFromContext(c)
-
 	var courseIDs []uint
 	for _, course := range courses {
 		courseIDs = append(courseIDs, course.Course.ID)
@@ -86,6 +160,10 @@ func (fc *FEController) GetCoursesPage(c *gin.Context) {
 			Topics:         course.Topics,
 			ThumbnailImage: course.ThumbnailImage,
 			Price:          course.Price,
+			CurrentPrice:   course.CurrentPrice(),
+			Status:         course.Status,
+			RatingAverage:  course.RatingAverage,
+			RatingCount:    course.RatingCount,
 		})
 	}
 
@@ -102,6 +180,8 @@ func (fc *FEController) GetCoursesPage(c *gin.Context) {
 		Pages:      pages,
 		Limit:      limit,
 		Search:     search,
+		Filters:    query,
+		Facets:     facets,
 		User:       user,
 	})
 }
@@ -152,6 +232,9 @@ func (fc *FEController) GetMyCoursesPage(c *gin.Context) {
 			Topics:         course.Topics,
 			ThumbnailImage: course.ThumbnailImage,
 			Price:          course.Price,
+			CurrentPrice:   course.CurrentPrice(),
+			RatingAverage:  course.RatingAverage,
+			RatingCount:    course.RatingCount,
 		})
 	}
 
@@ -172,6 +255,112 @@ func (fc *FEController) GetMyCoursesPage(c *gin.Context) {
 	})
 }
 
+func (fc *FEController) GetMyCertificatesPage(c *gin.Context) {
+	user, _ := getUserFromContext(c)
+	if user == nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Cannot get User",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	certificates, pagination, err := fc.cts.ListUserCertificates(*user, models.PaginationQuery{Page: page})
+	if err != nil {
+		log.Printf("ERROR: Failed to list certificates for user %d: %v", user.ID, err)
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"Message":    "Could not retrieve your certificates.",
+			"StatusCode": http.StatusInternalServerError})
+		return
+	}
+
+	c.HTML(http.StatusOK, "my-certificates.html", models.MyCertificatesPageData{
+		User:         user,
+		Certificates: certificates,
+		Page:         pagination.CurrentPage,
+		TotalPages:   pagination.TotalPages,
+	})
+}
+
+func (fc *FEController) DownloadCertificateFE(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Invalid certificate ID.",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
+
+	user, _ := getUserFromContext(c)
+	if user == nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Cannot get User",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
+
+	obj, filename, err := fc.cts.OpenCertificate(uint(id), *user, c.Query("format"))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.HTML(http.StatusNotFound, "error.html", gin.H{
+				"Message":    "Certificate not found.",
+				"StatusCode": http.StatusNotFound})
+		case errors.Is(err, services.ErrCertificateRevoked):
+			c.HTML(http.StatusForbidden, "error.html", gin.H{
+				"Message":    "This certificate has been revoked.",
+				"StatusCode": http.StatusForbidden})
+		default:
+			log.Printf("ERROR: Failed to open certificate %d: %v", id, err)
+			c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+				"Message":    "Could not download the certificate.",
+				"StatusCode": http.StatusInternalServerError})
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	serveObject(c, obj, filename)
+}
+
+func (fc *FEController) GetInstructorDashboardPage(c *gin.Context) {
+	user, _ := getUserFromContext(c)
+	if user == nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Cannot get User",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
+
+	if !rbac.HasPermission(user.Role, rbac.PermCourseEdit) {
+		c.HTML(http.StatusForbidden, "error.html", gin.H{
+			"Message":    "Instructor access required.",
+			"StatusCode": http.StatusForbidden})
+		return
+	}
+
+	stats, err := fc.cs.GetInstructorDashboard(*user)
+	if err != nil {
+		log.Printf("ERROR: Failed to get instructor dashboard for user %d: %v", user.ID, err)
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"Message":    "Could not retrieve your courses.",
+			"StatusCode": http.StatusInternalServerError})
+		return
+	}
+
+	data := models.InstructorDashboardPageData{User: user, Courses: stats}
+	for _, course := range stats {
+		data.TotalRevenue += course.Revenue
+		data.TotalEnrolments += course.Enrolments
+	}
+
+	c.HTML(http.StatusOK, "instructor-dashboard.html", data)
+}
+
 func (fc *FEController) GetCourseDetailPage(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -182,7 +371,9 @@ func (fc *FEController) GetCourseDetailPage(c *gin.Context) {
 	}
 	courseID := uint(id)
 
-	course, err := fc.cs.GetCourseByID(courseID)
+	user, userID := getUserFromContext(c)
+
+	course, err := fc.cs.GetCourseByID(courseID, *user)
 	if err != nil {
 		log.Printf("ERROR: Course with ID %d not found: %v", courseID, err)
 		c.HTML(http.StatusNotFound, "error.html", gin.H{
@@ -191,8 +382,6 @@ func (fc *FEController) GetCourseDetailPage(c *gin.Context) {
 		return
 	}
 
-	user, userID := getUserFromContext(c)
-
 	purchased := false
 	if userID != 0 {
 		hasPurchased, err := fc.cs.HasPurchasedCourse(courseID, userID)
@@ -221,12 +410,53 @@ func (fc *FEController) GetCourseDetailPage(c *gin.Context) {
 		return
 	}
 
+	var refundEligibility *models.RefundEligibility
+	if purchased {
+		refundEligibility, err = fc.cs.GetRefundEligibility(courseID, user)
+		if err != nil {
+			log.Printf("ERROR: Could not check refund eligibility for course %d, user %d: %v", courseID, userID, err)
+		}
+	}
+
+	latestRefund, err := fc.cs.GetLatestRefund(courseID, userID)
+	if err != nil {
+		log.Printf("ERROR: Could not get refunds for course %d, user %d: %v", courseID, userID, err)
+	}
+
+	prerequisites, err := fc.cs.GetPrerequisites(courseID, *user)
+	if err != nil {
+		log.Printf("ERROR: Could not get prerequisites for course %d, user %d: %v", courseID, userID, err)
+	}
+	prerequisitesMet := true
+	for _, p := range prerequisites {
+		prerequisitesMet = prerequisitesMet && p.Completed
+	}
+
+	reviewQuery := models.ReviewQuery{PaginationQuery: models.PaginationQuery{Page: 1, Limit: 20}}
+	reviews, _, err := fc.rs.GetCourseReviews(courseID, reviewQuery, *user)
+	if err != nil {
+		log.Printf("ERROR: Could not get reviews for course %d: %v", courseID, err)
+	}
+
+	ownReview, err := fc.rs.GetOwnReview(courseID, *user)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("ERROR: Could not get review of course %d by user %d: %v", courseID, userID, err)
+	}
+
 	c.HTML(http.StatusOK, "course-detail.html", models.CourseDetailPageData{
-		Course:         course,
-		CourseProgress: courseProgress,
-		Purchased:      purchased,
-		User:           user,
-		CertificateURL: certificateUrl,
+		Course:            course,
+		CourseProgress:    courseProgress,
+		Purchased:         purchased,
+		User:              user,
+		CertificateURL:    certificateUrl,
+		RefundEligibility: refundEligibility,
+		LatestRefund:      latestRefund,
+		Prerequisites:     prerequisites,
+		PrerequisitesMet:  prerequisitesMet,
+		Reviews:           reviews,
+		OwnReview:         ownReview,
+		ReviewError:       c.Query("review_error"),
+		PurchaseError:     c.Query("purchase_error"),
 	})
 }
 
@@ -248,7 +478,11 @@ func (fc *FEController) BuyCourseFE(c *gin.Context) {
 		return
 	}
 
-	_, err = fc.cs.BuyCourse(courseID, user)
+	_, err = fc.cs.BuyCourse(courseID, user, c.PostForm("coupon_code"))
+	if services.IsCouponError(err) {
+		c.Redirect(http.StatusSeeOther, "/course/"+fmt.Sprint(courseID)+"?purchase_error="+url.QueryEscape(err.Error()))
+		return
+	}
 	if err != nil {
 		log.Println(err.Error())
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
@@ -261,7 +495,7 @@ func (fc *FEController) BuyCourseFE(c *gin.Context) {
 	c.Redirect(http.StatusFound, "/course/"+fmt.Sprint(courseID)+"/modules")
 }
 
-func (fc *FEController) GetCourseModulesPage(c *gin.Context) {
+func (fc *FEController) RefundCourseFE(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.HTML(http.StatusBadRequest, "error.html", gin.H{
@@ -279,57 +513,64 @@ func (fc *FEController) GetCourseModulesPage(c *gin.Context) {
 		return
 	}
 
-	course, err := fc.cs.GetCourseByID(courseID)
+	_, err = fc.cs.RefundCourse(courseID, user, c.PostForm("reason"))
 	if err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+		log.Println(err.Error())
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
 			"Message":    err.Error(),
-			"StatusCode": http.StatusInternalServerError})
+			"StatusCode": http.StatusBadRequest,
+		})
+		return
 	}
 
-	modules, _, err := fc.ms.GetModules(*user, courseID, models.PaginationQuery{})
+	c.Redirect(http.StatusFound, "/course/"+fmt.Sprint(courseID))
+}
+
+// SaveReviewFE posts the user's review of the course, or updates it if they
+// already wrote one.
+func (fc *FEController) SaveReviewFE(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.HTML(http.StatusBadRequest, "error.html", gin.H{
-			"Message":    err.Error(),
-			"StatusCode": http.StatusInternalServerError})
+			"Message":    "Invalid course ID.",
+			"StatusCode": http.StatusBadRequest})
+		return
 	}
+	courseID := uint(id)
 
-	courseProgress, err := fc.ms.GetCourseProgress(courseID, *user)
-	if err != nil {
+	user, _ := getUserFromContext(c)
+	if user == nil {
 		c.HTML(http.StatusBadRequest, "error.html", gin.H{
-			"Message":    err.Error(),
-			"StatusCode": http.StatusInternalServerError})
+			"Message":    "Cannot get User",
+			"StatusCode": http.StatusBadRequest})
+		return
 	}
 
-	var currentModule *models.ModuleWithIsCompleted
-	if moduleIDStr := c.Param("moduleId"); moduleIDStr != "" {
-		moduleID, err := strconv.ParseUint(moduleIDStr, 10, 32)
-		if err == nil {
-			for i := range modules {
-				if modules[i].ID == uint(moduleID) {
-					currentModule = &modules[i]
-					break
-				}
-			}
-		}
+	redirectURL := fmt.Sprintf("/course/%d", courseID)
+
+	var input models.ReviewInput
+	if err := c.ShouldBind(&input); err != nil {
+		c.Redirect(http.StatusSeeOther, redirectURL+"?review_error="+url.QueryEscape("choose a rating from 1 to 5 stars")+"#reviews")
+		return
 	}
 
-	contentType := c.Query("type")
-	if contentType == "" {
-		contentType = "pdf"
+	ownReview, err := fc.rs.GetOwnReview(courseID, *user)
+	switch {
+	case err == nil:
+		_, err = fc.rs.UpdateReview(ownReview.ID, input, *user)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		_, err = fc.rs.CreateReview(courseID, input, *user)
+	}
+	if err != nil {
+		c.Redirect(http.StatusSeeOther, redirectURL+"?review_error="+url.QueryEscape(err.Error())+"#reviews")
+		return
 	}
 
-	c.HTML(http.StatusOK, "course-modules.html", models.CourseModulesPageData{
-		Course:         course,
-		User:           user,
-		Modules:        modules,
-		CourseProgress: *courseProgress,
-		CurrentModule:  currentModule,
-		ContentType:    contentType,
-	})
+	c.Redirect(http.StatusSeeOther, redirectURL+"#reviews")
 }
 
-func (fc *FEController) ToggleModuleCompletion(c *gin.Context) {
-	courseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+func (fc *FEController) ModerateReviewFE(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.HTML(http.StatusBadRequest, "error.html", gin.H{
 			"Message":    "Invalid course ID.",
@@ -337,10 +578,10 @@ func (fc *FEController) ToggleModuleCompletion(c *gin.Context) {
 		return
 	}
 
-	moduleID, err := strconv.ParseUint(c.Param("moduleId"), 10, 32)
+	reviewID, err := strconv.ParseUint(c.Param("reviewId"), 10, 32)
 	if err != nil {
 		c.HTML(http.StatusBadRequest, "error.html", gin.H{
-			"Message":    "Invalid module ID.",
+			"Message":    "Invalid review ID.",
 			"StatusCode": http.StatusBadRequest})
 		return
 	}
@@ -353,20 +594,711 @@ func (fc *FEController) ToggleModuleCompletion(c *gin.Context) {
 		return
 	}
 
-	completedStr := c.PostForm("completed")
-	completed := completedStr == "true"
+	if !rbac.HasPermission(user.Role, rbac.PermReviewModerate) {
+		c.HTML(http.StatusForbidden, "error.html", gin.H{
+			"Message":    "Admin access required.",
+			"StatusCode": http.StatusForbidden})
+		return
+	}
 
-	err = fc.ms.ChangeModuleCompletion(uint(moduleID), *user, completed)
+	redirectURL := fmt.Sprintf("/course/%d", id)
+
+	var input models.ReviewModerationInput
+	if err := c.ShouldBind(&input); err != nil {
+		c.Redirect(http.StatusSeeOther, redirectURL+"?review_error="+url.QueryEscape("invalid moderation status")+"#reviews")
+		return
+	}
+
+	if _, err := fc.rs.ModerateReview(uint(reviewID), input, *user); err != nil {
+		c.Redirect(http.StatusSeeOther, redirectURL+"?review_error="+url.QueryEscape(err.Error())+"#reviews")
+		return
+	}
+
+	c.Redirect(http.StatusSeeOther, redirectURL+"#reviews")
+}
+
+// GetCertificateVerificationPage is the public page a certificate's QR code
+// links to.
+func (fc *FEController) GetCertificateVerificationPage(c *gin.Context) {
+	user, _ := getUserFromContext(c)
+	serial := c.Param("serial")
+
+	certificate, err := fc.cts.VerifyCertificate(serial)
 	if err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-			"Message":    "Failed to update completion",
-			"StatusCode": http.StatusInternalServerError})
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+				"Message":    err.Error(),
+				"StatusCode": http.StatusInternalServerError})
+			return
+		}
+		c.HTML(http.StatusNotFound, "verify-certificate.html", models.CertificateVerificationPageData{
+			User:   user,
+			Serial: serial,
+		})
 		return
 	}
 
-	redirectURL := fmt.Sprintf("/course/%d/modules/%d", courseID, moduleID)
-	if contentType := c.Query("type"); contentType != "" {
-		redirectURL += "?type=" + contentType
+	c.HTML(http.StatusOK, "verify-certificate.html", models.CertificateVerificationPageData{
+		User:        user,
+		Serial:      serial,
+		Certificate: certificate,
+		Error:       c.Query("error"),
+	})
+}
+
+func (fc *FEController) RevokeCertificateFE(c *gin.Context) {
+	certificate, user, ok := fc.findCertificateForRevocation(c)
+	if !ok {
+		return
+	}
+
+	redirectURL := utils.VerifyRoute + url.PathEscape(certificate.Serial)
+
+	var input models.CertificateRevocationInput
+	if err := c.ShouldBind(&input); err != nil {
+		c.Redirect(http.StatusSeeOther, redirectURL+"?error="+url.QueryEscape("give a reason for revoking the certificate"))
+		return
+	}
+
+	if _, err := fc.cts.RevokeCertificate(certificate.ID, input, *user); err != nil {
+		redirectURL += "?error=" + url.QueryEscape(err.Error())
+	}
+
+	c.Redirect(http.StatusSeeOther, redirectURL)
+}
+
+func (fc *FEController) ReinstateCertificateFE(c *gin.Context) {
+	certificate, _, ok := fc.findCertificateForRevocation(c)
+	if !ok {
+		return
+	}
+
+	redirectURL := utils.VerifyRoute + url.PathEscape(certificate.Serial)
+	if _, err := fc.cts.ReinstateCertificate(certificate.ID); err != nil {
+		redirectURL += "?error=" + url.QueryEscape(err.Error())
+	}
+
+	c.Redirect(http.StatusSeeOther, redirectURL)
+}
+
+// findCertificateForRevocation loads the certificate in the path for an admin
+// revoking or reinstating it, rendering an error page otherwise.
+func (fc *FEController) findCertificateForRevocation(c *gin.Context) (*models.CertificateVerificationResponse, *models.User, bool) {
+	user, _ := getUserFromContext(c)
+	if user == nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Cannot get User",
+			"StatusCode": http.StatusBadRequest})
+		return nil, nil, false
+	}
+
+	if !rbac.HasPermission(user.Role, rbac.PermCertificateRevoke) {
+		c.HTML(http.StatusForbidden, "error.html", gin.H{
+			"Message":    "Admin access required.",
+			"StatusCode": http.StatusForbidden})
+		return nil, nil, false
+	}
+
+	certificate, err := fc.cts.VerifyCertificate(c.Param("serial"))
+	if err != nil {
+		status, message := http.StatusInternalServerError, err.Error()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status, message = http.StatusNotFound, "Certificate not found."
+		}
+		c.HTML(status, "error.html", gin.H{
+			"Message":    message,
+			"StatusCode": status})
+		return nil, nil, false
+	}
+
+	return certificate, user, true
+}
+
+func (fc *FEController) GetCourseModulesPage(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Invalid course ID.",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
+	courseID := uint(id)
+
+	user, _ := getUserFromContext(c)
+	if user == nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Cannot get User",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
+
+	course, err := fc.cs.GetCourseByID(courseID, *user)
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{
+			"Message":    "Course not found.",
+			"StatusCode": http.StatusNotFound})
+		return
+	}
+
+	modules, _, err := fc.ms.GetModules(*user, courseID, models.PaginationQuery{})
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    err.Error(),
+			"StatusCode": http.StatusInternalServerError})
+	}
+
+	courseProgress, err := fc.ms.GetCourseProgress(courseID, *user)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    err.Error(),
+			"StatusCode": http.StatusInternalServerError})
+	}
+
+	var currentModule *models.ModuleWithIsCompleted
+	if moduleIDStr := c.Param("moduleId"); moduleIDStr != "" {
+		moduleID, err := strconv.ParseUint(moduleIDStr, 10, 32)
+		if err == nil {
+			for i := range modules {
+				if modules[i].ID == uint(moduleID) {
+					currentModule = &modules[i]
+					break
+				}
+			}
+		}
+	}
+
+	contentType := c.Query("type")
+	if contentType == "" {
+		contentType = "pdf"
+	}
+
+	var quiz *models.QuizResponse
+	if currentModule != nil && currentModule.HasQuiz {
+		quiz, err = fc.qs.GetQuiz(currentModule.ID, *user)
+		if err != nil {
+			log.Printf("Failed to get quiz for module %d: %v", currentModule.ID, err)
+		}
+	}
+
+	var assignment *models.AssignmentResponse
+	if currentModule != nil && currentModule.HasAssignment {
+		assignment, err = fc.asg.GetAssignment(currentModule.ID, *user)
+		if err != nil {
+			log.Printf("Failed to get assignment for module %d: %v", currentModule.ID, err)
+		}
+	}
+
+	sections, err := fc.ss.GetSections(courseID, *user)
+	if err != nil {
+		log.Printf("Failed to get sections for course %d: %v", courseID, err)
+	}
+
+	var discussions []models.DiscussionPostResponse
+	var thread *models.DiscussionThreadDetail
+	var discussionPagination models.PaginationResponse
+	var discussionQuery models.DiscussionQuery
+	if currentModule != nil && !currentModule.IsLocked {
+		_ = c.ShouldBindQuery(&discussionQuery)
+		if threadID, err := strconv.ParseUint(c.Query("thread"), 10, 32); err == nil {
+			thread, discussionPagination, err = fc.ds.GetThread(uint(threadID), discussionQuery.PaginationQuery, *user)
+			if err != nil {
+				log.Printf("Failed to get discussion thread %d: %v", threadID, err)
+			}
+		}
+		if thread == nil {
+			discussions, discussionPagination, err = fc.ds.GetThreads(currentModule.ID, discussionQuery, *user)
+			if err != nil {
+				log.Printf("Failed to get discussions for module %d: %v", currentModule.ID, err)
+			}
+		}
+	}
+
+	c.HTML(http.StatusOK, "course-modules.html", models.CourseModulesPageData{
+		Course:          course,
+		User:            user,
+		Modules:         modules,
+		ModuleGroups:    groupModulesBySection(sections, modules, currentModule),
+		CourseProgress:  *courseProgress,
+		CurrentModule:   currentModule,
+		ContentType:     contentType,
+		Quiz:            quiz,
+		QuizError:       c.Query("quiz_error"),
+		Assignment:      assignment,
+		AssignmentError: c.Query("assignment_error"),
+
+		Discussions:          discussions,
+		DiscussionThread:     thread,
+		DiscussionPagination: discussionPagination,
+		DiscussionSort:       discussionQuery.Sort,
+		DiscussionError:      c.Query("discussion_error"),
+	})
+}
+
+// groupModulesBySection splits the course modules into sidebar groups in
+// section order, with modules outside any section first. The group holding
+// the current module starts expanded; without one, all groups do.
+func groupModulesBySection(sections []models.SectionResponse, modules []models.ModuleWithIsCompleted, current *models.ModuleWithIsCompleted) []models.ModuleGroup {
+	groups := make([]models.ModuleGroup, 0, len(sections)+1)
+	index := map[uint]int{0: 0}
+	groups = append(groups, models.ModuleGroup{Open: true})
+	for _, section := range sections {
+		index[section.ID] = len(groups)
+		groups = append(groups, models.ModuleGroup{SectionID: section.ID, Title: section.Title})
+	}
+
+	for _, m := range modules {
+		var sectionID uint
+		if m.SectionID != nil {
+			sectionID = *m.SectionID
+		}
+		i, ok := index[sectionID]
+		if !ok {
+			i = 0
+		}
+		groups[i].Modules = append(groups[i].Modules, m)
+		if m.IsCompleted {
+			groups[i].CompletedModules++
+		}
+		if current == nil || current.ID == m.ID {
+			groups[i].Open = true
+		}
+	}
+
+	if len(groups[0].Modules) == 0 {
+		groups = groups[1:]
+	}
+	return groups
+}
+
+func (fc *FEController) ToggleModuleCompletion(c *gin.Context) {
+	courseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Invalid course ID.",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
+
+	moduleID, err := strconv.ParseUint(c.Param("moduleId"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Invalid module ID.",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
+
+	user, _ := getUserFromContext(c)
+	if user == nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Cannot get User",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
+
+	completedStr := c.PostForm("completed")
+	completed := completedStr == "true"
+
+	err = fc.ms.ChangeModuleCompletion(uint(moduleID), *user, completed)
+	if errors.Is(err, services.ErrQuizNotPassed) {
+		c.Redirect(http.StatusSeeOther, fmt.Sprintf("/course/%d/modules/%d?type=quiz", courseID, moduleID))
+		return
+	}
+	if errors.Is(err, services.ErrAssignmentNotPassed) {
+		c.Redirect(http.StatusSeeOther, fmt.Sprintf("/course/%d/modules/%d?type=assignment", courseID, moduleID))
+		return
+	}
+	if errors.Is(err, services.ErrModuleLocked) || errors.Is(err, services.ErrPrerequisitesNotMet) {
+		c.Redirect(http.StatusSeeOther, fmt.Sprintf("/course/%d/modules/%d", courseID, moduleID))
+		return
+	}
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"Message":    "Failed to update completion",
+			"StatusCode": http.StatusInternalServerError})
+		return
+	}
+
+	redirectURL := fmt.Sprintf("/course/%d/modules/%d", courseID, moduleID)
+	if contentType := c.Query("type"); contentType != "" {
+		redirectURL += "?type=" + contentType
+	}
+
+	c.Redirect(http.StatusSeeOther, redirectURL)
+}
+
+// UpdateModuleProgressFE receives playback heartbeats from the module page's
+// video player. It answers with JSON since it is called from script.
+func (fc *FEController) UpdateModuleProgressFE(c *gin.Context) {
+	moduleID, err := strconv.ParseUint(c.Param("moduleId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Invalid module ID.", "data": nil})
+		return
+	}
+
+	user, _ := getUserFromContext(c)
+	if user == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Cannot get User", "data": nil})
+		return
+	}
+
+	var input models.WatchProgressInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "invalid request body", "data": nil})
+		return
+	}
+
+	res, err := fc.ms.UpdateWatchProgress(uint(moduleID), *user, input)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, services.ErrContentNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, services.ErrNoContentAccess), errors.Is(err, services.ErrModuleLocked), errors.Is(err, services.ErrPrerequisitesNotMet):
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{"status": "error", "message": err.Error(), "data": nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "progress saved", "data": res})
+}
+
+func (fc *FEController) StartQuizFE(c *gin.Context) {
+	courseID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	moduleID, err := strconv.ParseUint(c.Param("moduleId"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Invalid module ID.",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
+
+	user, _ := getUserFromContext(c)
+	if user == nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Cannot get User",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
+
+	redirectURL := fmt.Sprintf("/course/%d/modules/%d?type=quiz", courseID, moduleID)
+	if _, err := fc.qs.StartAttempt(uint(moduleID), *user); err != nil {
+		redirectURL += "&quiz_error=" + url.QueryEscape(err.Error())
+	}
+
+	c.Redirect(http.StatusSeeOther, redirectURL)
+}
+
+// SubmitQuizFE grades a quiz form. Choice questions post option IDs as
+// "q_<question id>" and short answers post their text under the same name.
+func (fc *FEController) SubmitQuizFE(c *gin.Context) {
+	courseID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	moduleID, _ := strconv.ParseUint(c.Param("moduleId"), 10, 32)
+	attemptID, err := strconv.ParseUint(c.PostForm("attempt_id"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Invalid quiz attempt.",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
+
+	user, _ := getUserFromContext(c)
+	if user == nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Cannot get User",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
+
+	var submission models.QuizSubmission
+	for key, values := range c.Request.PostForm {
+		questionID, err := strconv.ParseUint(strings.TrimPrefix(key, "q_"), 10, 32)
+		if !strings.HasPrefix(key, "q_") || err != nil {
+			continue
+		}
+
+		answer := models.QuizAnswerInput{QuestionID: uint(questionID)}
+		if c.PostForm("type_"+strconv.FormatUint(questionID, 10)) == models.QuestionShortAnswer {
+			answer.Text = values[0]
+		} else {
+			for _, v := range values {
+				if optionID, err := strconv.ParseUint(v, 10, 32); err == nil {
+					answer.OptionIDs = append(answer.OptionIDs, uint(optionID))
+				}
+			}
+		}
+		submission.Answers = append(submission.Answers, answer)
+	}
+
+	redirectURL := fmt.Sprintf("/course/%d/modules/%d?type=quiz", courseID, moduleID)
+	if _, err := fc.qs.SubmitAttempt(uint(attemptID), submission, *user); err != nil {
+		redirectURL += "&quiz_error=" + url.QueryEscape(err.Error())
+	}
+
+	c.Redirect(http.StatusSeeOther, redirectURL)
+}
+
+func (fc *FEController) SubmitAssignmentFE(c *gin.Context) {
+	courseID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	moduleID, err := strconv.ParseUint(c.Param("moduleId"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Invalid module ID.",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
+
+	user, _ := getUserFromContext(c)
+	if user == nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Cannot get User",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
+
+	redirectURL := fmt.Sprintf("/course/%d/modules/%d?type=assignment", courseID, moduleID)
+
+	var input models.SubmissionFormInput
+	if err := c.ShouldBind(&input); err != nil {
+		c.Redirect(http.StatusSeeOther, redirectURL+"&assignment_error="+url.QueryEscape(err.Error()))
+		return
+	}
+
+	if _, err := fc.asg.Submit(uint(moduleID), input, *user); err != nil {
+		redirectURL += "&assignment_error=" + url.QueryEscape(err.Error())
+	}
+
+	c.Redirect(http.StatusSeeOther, redirectURL)
+}
+
+// discussionRedirectURL points back at the module's discussion panel, on the
+// given thread when threadID is set, carrying an error message if any.
+func discussionRedirectURL(courseID, moduleID uint64, threadID string, errMsg string) string {
+	v := url.Values{}
+	if threadID != "" {
+		v.Set("thread", threadID)
+	}
+	if errMsg != "" {
+		v.Set("discussion_error", errMsg)
+	}
+
+	redirectURL := fmt.Sprintf("/course/%d/modules/%d", courseID, moduleID)
+	if len(v) > 0 {
+		redirectURL += "?" + v.Encode()
+	}
+	return redirectURL + "#discussion"
+}
+
+func (fc *FEController) CreateDiscussionThreadFE(c *gin.Context) {
+	courseID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	moduleID, err := strconv.ParseUint(c.Param("moduleId"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Invalid module ID.",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
+
+	user, _ := getUserFromContext(c)
+	if user == nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Cannot get User",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
+
+	var input models.DiscussionThreadInput
+	if err := c.ShouldBind(&input); err != nil {
+		c.Redirect(http.StatusSeeOther, discussionRedirectURL(courseID, moduleID, "", "a thread needs a title and a question"))
+		return
+	}
+
+	thread, err := fc.ds.CreateThread(uint(moduleID), input, *user)
+	if err != nil {
+		c.Redirect(http.StatusSeeOther, discussionRedirectURL(courseID, moduleID, "", err.Error()))
+		return
+	}
+
+	c.Redirect(http.StatusSeeOther, discussionRedirectURL(courseID, moduleID, strconv.FormatUint(uint64(thread.ID), 10), ""))
+}
+
+func (fc *FEController) ReplyDiscussionFE(c *gin.Context) {
+	courseID, moduleID, postID, user, ok := parseDiscussionPostRequest(c)
+	if !ok {
+		return
+	}
+
+	threadID := c.PostForm("thread")
+
+	var input models.DiscussionReplyInput
+	if err := c.ShouldBind(&input); err != nil {
+		c.Redirect(http.StatusSeeOther, discussionRedirectURL(courseID, moduleID, threadID, "a reply cannot be empty"))
+		return
+	}
+
+	if _, err := fc.ds.Reply(uint(postID), input, *user); err != nil {
+		c.Redirect(http.StatusSeeOther, discussionRedirectURL(courseID, moduleID, threadID, err.Error()))
+		return
+	}
+
+	c.Redirect(http.StatusSeeOther, discussionRedirectURL(courseID, moduleID, threadID, ""))
+}
+
+// ToggleDiscussionUpvoteFE adds the user's upvote, or takes it back when the
+// form says the post is already upvoted.
+func (fc *FEController) ToggleDiscussionUpvoteFE(c *gin.Context) {
+	courseID, moduleID, postID, user, ok := parseDiscussionPostRequest(c)
+	if !ok {
+		return
+	}
+
+	var err error
+	if c.PostForm("upvoted") == "true" {
+		_, err = fc.ds.RemoveUpvote(uint(postID), *user)
+	} else {
+		_, err = fc.ds.Upvote(uint(postID), *user)
+	}
+
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+	c.Redirect(http.StatusSeeOther, discussionRedirectURL(courseID, moduleID, c.PostForm("thread"), errMsg))
+}
+
+func (fc *FEController) ToggleDiscussionAnswerFE(c *gin.Context) {
+	courseID, moduleID, postID, user, ok := parseDiscussionPostRequest(c)
+	if !ok {
+		return
+	}
+
+	if !rbac.HasPermission(user.Role, rbac.PermDiscussionModerate) {
+		c.HTML(http.StatusForbidden, "error.html", gin.H{
+			"Message":    "Only course staff can mark answers.",
+			"StatusCode": http.StatusForbidden})
+		return
+	}
+
+	errMsg := ""
+	if _, err := fc.ds.SetAnswer(uint(postID), c.PostForm("answer") == "true", *user); err != nil {
+		errMsg = err.Error()
+	}
+	c.Redirect(http.StatusSeeOther, discussionRedirectURL(courseID, moduleID, c.PostForm("thread"), errMsg))
+}
+
+func (fc *FEController) DeleteDiscussionPostFE(c *gin.Context) {
+	courseID, moduleID, postID, user, ok := parseDiscussionPostRequest(c)
+	if !ok {
+		return
+	}
+
+	errMsg := ""
+	if err := fc.ds.DeletePost(uint(postID), *user); err != nil {
+		errMsg = err.Error()
+	}
+	c.Redirect(http.StatusSeeOther, discussionRedirectURL(courseID, moduleID, c.PostForm("thread"), errMsg))
+}
+
+// parseDiscussionPostRequest reads the course, module and post IDs and the
+// user of a discussion form post, rendering an error page when one is off.
+func parseDiscussionPostRequest(c *gin.Context) (uint64, uint64, uint64, *models.User, bool) {
+	courseID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	moduleID, err := strconv.ParseUint(c.Param("moduleId"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Invalid module ID.",
+			"StatusCode": http.StatusBadRequest})
+		return 0, 0, 0, nil, false
+	}
+
+	postID, err := strconv.ParseUint(c.Param("postId"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Invalid post ID.",
+			"StatusCode": http.StatusBadRequest})
+		return 0, 0, 0, nil, false
+	}
+
+	user, _ := getUserFromContext(c)
+	if user == nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Cannot get User",
+			"StatusCode": http.StatusBadRequest})
+		return 0, 0, 0, nil, false
+	}
+
+	return courseID, moduleID, postID, user, true
+}
+
+func (fc *FEController) GetSubmissionQueuePage(c *gin.Context) {
+	user, _ := getUserFromContext(c)
+	if user == nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Cannot get User",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
+
+	if !rbac.HasPermission(user.Role, rbac.PermSubmissionGrade) {
+		c.HTML(http.StatusForbidden, "error.html", gin.H{
+			"Message":    "Instructor access required.",
+			"StatusCode": http.StatusForbidden})
+		return
+	}
+
+	var q models.SubmissionQueueQuery
+	_ = c.ShouldBindQuery(&q)
+	if q.Status == "" {
+		q.Status = models.SubmissionPending
+	}
+
+	submissions, pagination, err := fc.asg.ListSubmissions(q, *user)
+	if err != nil {
+		log.Printf("ERROR: Failed to list submissions for user %d: %v", user.ID, err)
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"Message":    "Could not retrieve submissions.",
+			"StatusCode": http.StatusInternalServerError})
+		return
+	}
+
+	c.HTML(http.StatusOK, "submissions.html", models.SubmissionQueuePageData{
+		User:        user,
+		Submissions: submissions,
+		Status:      q.Status,
+		Page:        pagination.CurrentPage,
+		TotalPages:  pagination.TotalPages,
+		Error:       c.Query("error"),
+	})
+}
+
+func (fc *FEController) GradeSubmissionFE(c *gin.Context) {
+	submissionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Invalid submission ID.",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
+
+	user, _ := getUserFromContext(c)
+	if user == nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"Message":    "Cannot get User",
+			"StatusCode": http.StatusBadRequest})
+		return
+	}
+
+	redirectURL := "/instructor/submissions?status=" + url.QueryEscape(c.DefaultQuery("status", models.SubmissionPending))
+
+	score, err := strconv.ParseFloat(c.PostForm("score"), 64)
+	if err != nil || score < 0 {
+		c.Redirect(http.StatusSeeOther, redirectURL+"&error="+url.QueryEscape("score must be a non-negative number"))
+		return
+	}
+
+	input := models.GradeSubmissionInput{Score: &score, Feedback: c.PostForm("feedback")}
+	if _, err := fc.asg.GradeSubmission(uint(submissionID), input, *user); err != nil {
+		redirectURL += "&error=" + url.QueryEscape(err.Error())
 	}
 
 	c.Redirect(http.StatusSeeOther, redirectURL)
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kin-ark/GroAcademy/internal/services"
+	"github.com/kin-ark/GroAcademy/internal/storage"
+)
+
+type MediaController struct {
+	service services.MediaService
+}
+
+func NewMediaController(s services.MediaService) MediaController {
+	return MediaController{service: s}
+}
+
+func (mc *MediaController) ServeSignedMedia(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+
+	obj, err := mc.service.OpenSigned(key, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, storage.ErrInvalidSignature), errors.Is(err, storage.ErrInvalidKey):
+			status = http.StatusForbidden
+		case errors.Is(err, storage.ErrNotFound):
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	serveObject(c, obj, path.Base(key))
+}
+
+func (mc *MediaController) ServeSignedPlaylist(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+
+	body, err := mc.service.SignedPlaylist(key, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, storage.ErrInvalidSignature), errors.Is(err, storage.ErrInvalidKey):
+			status = http.StatusForbidden
+		case errors.Is(err, storage.ErrNotFound):
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, "application/vnd.apple.mpegurl", body)
+}
@@ -1,12 +1,16 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kin-ark/GroAcademy/internal/models"
 	"github.com/kin-ark/GroAcademy/internal/services"
+	"github.com/kin-ark/GroAcademy/internal/storage"
+	"gorm.io/gorm"
 )
 
 type ModuleController struct {
@@ -31,6 +35,9 @@ func (mc *ModuleController) PostModule(c *gin.Context) {
 
 	var input models.ModuleFormInput
 	if err := c.ShouldBind(&input); err != nil {
+		if respondUploadError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  "error",
 			"message": "Bad Request",
@@ -39,9 +46,22 @@ func (mc *ModuleController) PostModule(c *gin.Context) {
 		return
 	}
 
-	result, err := mc.service.CreateModule(c, input, uint(id))
+	user := c.MustGet("user").(models.User)
+
+	result, err := mc.service.CreateModule(c, input, uint(id), user)
 
 	if err != nil {
+		if errors.Is(err, services.ErrNotCourseInstructor) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+				"data":    nil,
+			})
+			return
+		}
+		if respondUploadError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  "error",
 			"message": err.Error(),
@@ -52,15 +72,22 @@ func (mc *ModuleController) PostModule(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": gin.H{
-			"id":            result.ID,
-			"course_id":     result.CourseID,
-			"title":         result.Title,
-			"description":   result.Description,
-			"order":         result.Order,
-			"pdf_content":   result.PDFContent,
-			"video_content": result.VideoContent,
-			"created_at":    result.CreatedAt,
-			"updated_at":    result.UpdatedAt,
+			"id":             result.ID,
+			"course_id":      result.CourseID,
+			"title":          result.Title,
+			"description":    result.Description,
+			"order":          result.Order,
+			"section_id":     result.SectionID,
+			"status":         result.Status,
+			"publish_at":     result.PublishAt,
+			"pdf_content":    result.PDFContent,
+			"video_content":  result.VideoContent,
+			"video_status":   result.VideoStatus,
+			"video_hls":      result.VideoHLS,
+			"video_poster":   result.VideoPoster,
+			"video_duration": result.VideoDuration,
+			"created_at":     result.CreatedAt,
+			"updated_at":     result.UpdatedAt,
 		},
 		"message": "Post module success",
 		"status":  "success",
@@ -81,6 +108,9 @@ func (mc *ModuleController) PutModule(c *gin.Context) {
 
 	var input models.ModuleFormInput
 	if err := c.ShouldBind(&input); err != nil {
+		if respondUploadError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  "error",
 			"message": "Bad Request",
@@ -89,9 +119,22 @@ func (mc *ModuleController) PutModule(c *gin.Context) {
 		return
 	}
 
-	result, err := mc.service.EditModule(c, input, uint(id))
+	user := c.MustGet("user").(models.User)
+
+	result, err := mc.service.EditModule(c, input, uint(id), user)
 
 	if err != nil {
+		if errors.Is(err, services.ErrNotCourseInstructor) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+				"data":    nil,
+			})
+			return
+		}
+		if respondUploadError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  "error",
 			"message": err.Error(),
@@ -102,15 +145,22 @@ func (mc *ModuleController) PutModule(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": gin.H{
-			"id":            result.ID,
-			"course_id":     result.CourseID,
-			"title":         result.Title,
-			"description":   result.Description,
-			"order":         result.Order,
-			"pdf_content":   result.PDFContent,
-			"video_content": result.VideoContent,
-			"created_at":    result.CreatedAt,
-			"updated_at":    result.UpdatedAt,
+			"id":             result.ID,
+			"course_id":      result.CourseID,
+			"title":          result.Title,
+			"description":    result.Description,
+			"order":          result.Order,
+			"section_id":     result.SectionID,
+			"status":         result.Status,
+			"publish_at":     result.PublishAt,
+			"pdf_content":    result.PDFContent,
+			"video_content":  result.VideoContent,
+			"video_status":   result.VideoStatus,
+			"video_hls":      result.VideoHLS,
+			"video_poster":   result.VideoPoster,
+			"video_duration": result.VideoDuration,
+			"created_at":     result.CreatedAt,
+			"updated_at":     result.UpdatedAt,
 		},
 		"message": "Post module success",
 		"status":  "success",
@@ -129,9 +179,19 @@ func (mc *ModuleController) DeleteModuleByID(c *gin.Context) {
 		return
 	}
 
-	res := mc.service.DeleteModuleByID(uint(id))
+	user := c.MustGet("user").(models.User)
+
+	res := mc.service.DeleteModuleByID(uint(id), user)
 
 	if res != nil {
+		if errors.Is(res, services.ErrNotCourseInstructor) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"status":  "error",
+				"message": res.Error(),
+				"data":    nil,
+			})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  "error",
 			"message": res.Error(),
@@ -221,7 +281,11 @@ func (mc *ModuleController) GetModuleById(c *gin.Context) {
 
 	res, err := mc.service.GetModuleByID(uint(id), u)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrModuleLocked) || errors.Is(err, services.ErrPrerequisitesNotMet) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{
 			"status":  "error",
 			"message": err.Error(),
 			"data":    nil,
@@ -230,16 +294,25 @@ func (mc *ModuleController) GetModuleById(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"id":            res.ID,
-		"course_id":     res.CourseID,
-		"title":         res.Title,
-		"description":   res.Description,
-		"order":         res.Order,
-		"pdf_content":   res.PDFContent,
-		"video_content": res.VideoContent,
-		"is_completed":  res.IsCompleted,
-		"created_at":    res.CreatedAt,
-		"updated_at":    res.UpdatedAt,
+		"id":                 res.ID,
+		"course_id":          res.CourseID,
+		"title":              res.Title,
+		"description":        res.Description,
+		"order":              res.Order,
+		"section_id":         res.SectionID,
+		"status":             res.Status,
+		"publish_at":         res.PublishAt,
+		"pdf_content":        res.PDFContent,
+		"video_content":      res.VideoContent,
+		"video_status":       res.VideoStatus,
+		"video_hls":          res.VideoHLS,
+		"video_poster":       res.VideoPoster,
+		"video_duration":     res.VideoDuration,
+		"is_completed":       res.IsCompleted,
+		"position_seconds":   res.PositionSeconds,
+		"watched_percentage": res.WatchedPercentage,
+		"created_at":         res.CreatedAt,
+		"updated_at":         res.UpdatedAt,
 	})
 }
 
@@ -268,7 +341,11 @@ func (mc *ModuleController) MarkModuleAsComplete(c *gin.Context) {
 
 	res, err := mc.service.MarkModuleAsComplete(uint(id), u)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrModuleLocked) || errors.Is(err, services.ErrPrerequisitesNotMet) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{
 			"status":  "error",
 			"message": err.Error(),
 			"data":    nil,
@@ -283,6 +360,53 @@ func (mc *ModuleController) MarkModuleAsComplete(c *gin.Context) {
 	})
 }
 
+func (mc *ModuleController) UpdateWatchProgress(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid module ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	var input models.WatchProgressInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "invalid request body",
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	res, err := mc.service.UpdateWatchProgress(uint(id), user, input)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, services.ErrContentNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, services.ErrNoContentAccess), errors.Is(err, services.ErrModuleLocked), errors.Is(err, services.ErrPrerequisitesNotMet):
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "progress saved",
+		"data":    res,
+	})
+}
+
 func (mc *ModuleController) ReorderModules(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 64)
@@ -314,8 +438,26 @@ func (mc *ModuleController) ReorderModules(c *gin.Context) {
 		return
 	}
 
-	err = mc.service.ReorderModules(req, uint(id))
+	user := c.MustGet("user").(models.User)
+
+	err = mc.service.ReorderModules(req, uint(id), user)
 	if err != nil {
+		if errors.Is(err, services.ErrNotCourseInstructor) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+				"data":    nil,
+			})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidSection) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+				"data":    nil,
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
 			"message": "failed to reorder modules",
@@ -330,3 +472,221 @@ func (mc *ModuleController) ReorderModules(c *gin.Context) {
 		"data":    req.ModuleOrder,
 	})
 }
+
+func (mc *ModuleController) PatchModuleStatus(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid module ID")
+	if !ok {
+		return
+	}
+
+	var input models.PublishStatusInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	module, err := mc.service.SetModuleStatus(id, input, user)
+	if err != nil {
+		respondPublishStatusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "module status updated",
+		"data": gin.H{
+			"id":         module.ID,
+			"status":     module.Status,
+			"publish_at": module.PublishAt,
+		},
+	})
+}
+
+func (mc *ModuleController) GetModuleRevisions(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid module ID")
+	if !ok {
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	revisions, err := mc.service.GetModuleRevisions(id, user)
+	if err != nil {
+		respondRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "request success",
+		"data":    revisions,
+	})
+}
+
+func (mc *ModuleController) GetModuleRevision(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid module ID")
+	if !ok {
+		return
+	}
+	number, ok := parseRevisionParam(c)
+	if !ok {
+		return
+	}
+
+	var q models.RevisionDiffQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "invalid query parameters",
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	revision, err := mc.service.GetModuleRevision(id, number, q.Against, user)
+	if err != nil {
+		respondRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "request success",
+		"data":    revision,
+	})
+}
+
+func (mc *ModuleController) RestoreModuleRevision(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid module ID")
+	if !ok {
+		return
+	}
+	number, ok := parseRevisionParam(c)
+	if !ok {
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	module, err := mc.service.RestoreModuleRevision(id, number, user)
+	if err != nil {
+		respondRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "revision restored",
+		"data": gin.H{
+			"id":             module.ID,
+			"course_id":      module.CourseID,
+			"title":          module.Title,
+			"description":    module.Description,
+			"order":          module.Order,
+			"section_id":     module.SectionID,
+			"status":         module.Status,
+			"publish_at":     module.PublishAt,
+			"pdf_content":    module.PDFContent,
+			"video_content":  module.VideoContent,
+			"video_status":   module.VideoStatus,
+			"video_hls":      module.VideoHLS,
+			"video_poster":   module.VideoPoster,
+			"video_duration": module.VideoDuration,
+			"created_at":     module.CreatedAt,
+			"updated_at":     module.UpdatedAt,
+		},
+	})
+}
+
+func (mc *ModuleController) GetModuleContent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid module ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	contentType := c.Param("type")
+	if contentType != services.ContentTypePDF && contentType != services.ContentTypeVideo {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "Unknown content type",
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	obj, redirectURL, err := mc.service.OpenModuleContent(uint(id), user, contentType)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, services.ErrContentNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, services.ErrNoContentAccess), errors.Is(err, services.ErrModuleLocked), errors.Is(err, services.ErrPrerequisitesNotMet):
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	if redirectURL != "" {
+		c.Redirect(http.StatusFound, redirectURL)
+		return
+	}
+
+	serveObject(c, obj, fmt.Sprintf("module-%d-%s", id, contentType))
+}
+
+// serveObject streams obj with Range and conditional request support, so
+// video players can seek without downloading the whole file.
+func serveObject(c *gin.Context, obj storage.Object, name string) {
+	defer obj.Close()
+
+	c.Header("Cache-Control", "private, max-age=0")
+	c.Header("X-Content-Type-Options", "nosniff")
+	http.ServeContent(c.Writer, c.Request, name, obj.ModTime(), obj)
+}
+
+// respondUploadError answers with 413 or 415 when err comes from upload
+// validation or the request body limit, and reports whether it did.
+func respondUploadError(c *gin.Context, err error) bool {
+	var status int
+	message := err.Error()
+
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		status = http.StatusRequestEntityTooLarge
+		message = fmt.Sprintf("request body exceeds %d MB", maxBytesErr.Limit>>20)
+	case errors.Is(err, storage.ErrFileTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, storage.ErrUnsupportedType):
+		status = http.StatusUnsupportedMediaType
+	default:
+		return false
+	}
+
+	c.JSON(status, gin.H{
+		"status":  "error",
+		"message": message,
+		"data":    nil,
+	})
+	return true
+}
@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
+	"github.com/kin-ark/GroAcademy/internal/services"
+	"gorm.io/gorm"
+)
+
+type QuizController struct {
+	service services.QuizService
+}
+
+func NewQuizController(s services.QuizService) QuizController {
+	return QuizController{service: s}
+}
+
+func (qc *QuizController) GetQuiz(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid module ID")
+	if !ok {
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	res, err := qc.service.GetQuiz(id, user)
+	if err != nil {
+		respondQuizError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "request success",
+		"data":    res,
+	})
+}
+
+func (qc *QuizController) PutQuiz(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid module ID")
+	if !ok {
+		return
+	}
+
+	var input models.QuizInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	quiz, err := qc.service.SaveQuiz(id, input, user)
+	if err != nil {
+		respondQuizError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "quiz saved",
+		"data":    quiz,
+	})
+}
+
+func (qc *QuizController) DeleteQuiz(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid module ID")
+	if !ok {
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	if err := qc.service.DeleteQuiz(id, user); err != nil {
+		respondQuizError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (qc *QuizController) StartAttempt(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid module ID")
+	if !ok {
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	res, err := qc.service.StartAttempt(id, user)
+	if err != nil {
+		respondQuizError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "attempt started",
+		"data":    res,
+	})
+}
+
+func (qc *QuizController) SubmitAttempt(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid attempt ID")
+	if !ok {
+		return
+	}
+
+	var input models.QuizSubmission
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "invalid request body",
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	res, err := qc.service.SubmitAttempt(id, input, user)
+	if err != nil {
+		respondQuizError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "attempt submitted",
+		"data":    res,
+	})
+}
+
+func parseIDParam(c *gin.Context, message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": message,
+			"data":    nil,
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondQuizError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrNoContentAccess), errors.Is(err, services.ErrNotCourseInstructor),
+		errors.Is(err, services.ErrModuleLocked), errors.Is(err, services.ErrPrerequisitesNotMet):
+		status = http.StatusForbidden
+	case errors.Is(err, services.ErrInvalidQuiz), errors.Is(err, repositories.ErrUnknownQuizItem):
+		status = http.StatusBadRequest
+	case errors.Is(err, repositories.ErrNoAttemptsLeft), errors.Is(err, repositories.ErrAttemptSubmitted):
+		status = http.StatusConflict
+	}
+
+	c.JSON(status, gin.H{
+		"status":  "error",
+		"message": err.Error(),
+		"data":    nil,
+	})
+}
@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/services"
+	"gorm.io/gorm"
+)
+
+type ReviewController struct {
+	service services.ReviewService
+}
+
+func NewReviewController(s services.ReviewService) ReviewController {
+	return ReviewController{service: s}
+}
+
+func (rc *ReviewController) GetCourseReviews(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid course ID")
+	if !ok {
+		return
+	}
+
+	var q models.ReviewQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "invalid query parameters",
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	reviews, pagination, err := rc.service.GetCourseReviews(id, q, user)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"message":    "request success",
+		"data":       reviews,
+		"pagination": pagination,
+	})
+}
+
+func (rc *ReviewController) GetOwnReview(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid course ID")
+	if !ok {
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	review, err := rc.service.GetOwnReview(id, user)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "request success",
+		"data":    review,
+	})
+}
+
+func (rc *ReviewController) PostReview(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid course ID")
+	if !ok {
+		return
+	}
+
+	var input models.ReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	review, err := rc.service.CreateReview(id, input, user)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "review posted",
+		"data":    review,
+	})
+}
+
+func (rc *ReviewController) PutReview(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid review ID")
+	if !ok {
+		return
+	}
+
+	var input models.ReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	review, err := rc.service.UpdateReview(id, input, user)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "review updated",
+		"data":    review,
+	})
+}
+
+func (rc *ReviewController) DeleteReview(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid review ID")
+	if !ok {
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	if err := rc.service.DeleteReview(id, user); err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (rc *ReviewController) PatchReviewModeration(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid review ID")
+	if !ok {
+		return
+	}
+
+	var input models.ReviewModerationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	review, err := rc.service.ModerateReview(id, input, user)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "review moderated",
+		"data":    review,
+	})
+}
+
+func (rc *ReviewController) GetReviews(c *gin.Context) {
+	var q models.ReviewQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "invalid query parameters",
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	reviews, pagination, err := rc.service.ListReviews(q, user)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"message":    "request success",
+		"data":       reviews,
+		"pagination": pagination,
+	})
+}
+
+func respondReviewError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrReviewNotAllowed), errors.Is(err, services.ErrNotReviewAuthor):
+		status = http.StatusForbidden
+	case errors.Is(err, services.ErrReviewExists):
+		status = http.StatusConflict
+	}
+
+	c.JSON(status, gin.H{
+		"status":  "error",
+		"message": err.Error(),
+		"data":    nil,
+	})
+}
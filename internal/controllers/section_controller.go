@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/services"
+	"gorm.io/gorm"
+)
+
+type SectionController struct {
+	service services.SectionService
+}
+
+func NewSectionController(s services.SectionService) SectionController {
+	return SectionController{service: s}
+}
+
+func (sc *SectionController) GetSections(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid course ID")
+	if !ok {
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	sections, err := sc.service.GetSections(id, user)
+	if err != nil {
+		respondSectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "request success",
+		"data":    sections,
+	})
+}
+
+func (sc *SectionController) PostSection(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid course ID")
+	if !ok {
+		return
+	}
+
+	var input models.SectionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	section, err := sc.service.CreateSection(id, input, user)
+	if err != nil {
+		respondSectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "section created",
+		"data":    section,
+	})
+}
+
+func (sc *SectionController) PutSection(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid section ID")
+	if !ok {
+		return
+	}
+
+	var input models.SectionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	section, err := sc.service.UpdateSection(id, input, user)
+	if err != nil {
+		respondSectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "section updated",
+		"data":    section,
+	})
+}
+
+func (sc *SectionController) DeleteSection(c *gin.Context) {
+	id, ok := parseIDParam(c, "Invalid section ID")
+	if !ok {
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	if err := sc.service.DeleteSection(id, user); err != nil {
+		respondSectionError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondSectionError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrNotCourseInstructor):
+		status = http.StatusForbidden
+	}
+
+	c.JSON(status, gin.H{
+		"status":  "error",
+		"message": err.Error(),
+		"data":    nil,
+	})
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -185,7 +186,11 @@ func (cc *UserController) DeleteUserByID(c *gin.Context) {
 	res := cc.service.DeleteUser(uint(id))
 
 	if res != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
+		status := http.StatusBadRequest
+		if errors.Is(res, services.ErrUserHasTransactions) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
 			"status":  "error",
 			"message": res.Error(),
 			"data":    nil,
@@ -195,3 +200,115 @@ func (cc *UserController) DeleteUserByID(c *gin.Context) {
 
 	c.Status(http.StatusNoContent)
 }
+
+func (uc *UserController) GetUserTransactions(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid user ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	uc.respondWithTransactions(c, uint(id))
+}
+
+func (uc *UserController) GetMyTransactions(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": "Error: Unauthorized",
+			"data":    nil,
+		})
+		return
+	}
+	u := user.(models.User)
+
+	uc.respondWithTransactions(c, u.ID)
+}
+
+func (uc *UserController) respondWithTransactions(c *gin.Context, userID uint) {
+	var query models.PaginationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Bad Request",
+			"data":    nil,
+		})
+		return
+	}
+
+	transactions, pagination, err := uc.service.GetBalanceTransactions(userID, query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"message":    "Request success",
+		"data":       uc.service.BuildBalanceTransactionsResponse(transactions),
+		"pagination": pagination,
+	})
+}
+
+func (uc *UserController) PutUserRole(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid user ID",
+			"data":    nil,
+		})
+		return
+	}
+
+	var input models.PutUserRoleRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Bad Request",
+			"data":    nil,
+		})
+		return
+	}
+
+	actor := c.MustGet("user").(models.User)
+
+	result, err := uc.service.AssignRole(uint(id), input.Role, actor)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"id":       idParam,
+			"username": result.Username,
+			"role":     result.Role,
+		},
+		"message": "Assign role success",
+		"status":  "success",
+	})
+}
+
+func (uc *UserController) GetRoles(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Request success",
+		"data":    uc.service.GetRoles(),
+	})
+}
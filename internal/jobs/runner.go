@@ -0,0 +1,158 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
+)
+
+// Handler processes the JSON payload of one job. Returning an error schedules
+// a retry until the job runs out of attempts.
+type Handler func(payload []byte) error
+
+type Runner struct {
+	repo         repositories.JobRepository
+	handlers     map[string]Handler
+	workers      int
+	pollInterval time.Duration
+	lease        time.Duration
+}
+
+// NewRunner builds a runner with JOB_WORKERS workers (default 1). A running
+// job is leased for JOB_LEASE_MINUTES (default 5) at a time and requeued if
+// its worker stops renewing the lease.
+func NewRunner(repo repositories.JobRepository) *Runner {
+	workers := 1
+	if v, err := strconv.Atoi(os.Getenv("JOB_WORKERS")); err == nil && v > 0 {
+		workers = v
+	}
+
+	leaseMinutes := 5
+	if v, err := strconv.Atoi(os.Getenv("JOB_LEASE_MINUTES")); err == nil && v > 0 {
+		leaseMinutes = v
+	}
+
+	return &Runner{
+		repo:         repo,
+		handlers:     make(map[string]Handler),
+		workers:      workers,
+		pollInterval: 2 * time.Second,
+		lease:        time.Duration(leaseMinutes) * time.Minute,
+	}
+}
+
+func (r *Runner) Register(jobType string, h Handler) {
+	r.handlers[jobType] = h
+}
+
+// Start launches the workers in the background, along with a loop that
+// requeues jobs whose worker stopped without finishing them. Jobs still
+// leased by a live worker, on this instance or another, are left alone.
+func (r *Runner) Start() {
+	types := make([]string, 0, len(r.handlers))
+	for t := range r.handlers {
+		types = append(types, t)
+	}
+
+	go r.reclaim()
+	for i := 0; i < r.workers; i++ {
+		go r.work(types)
+	}
+}
+
+func (r *Runner) reclaim() {
+	for {
+		requeued, failed, err := r.repo.ReclaimExpired(r.lease)
+		if err != nil {
+			log.Printf("ERROR: Failed to requeue interrupted jobs: %v", err)
+		}
+		if requeued > 0 {
+			log.Printf("Requeued %d interrupted jobs", requeued)
+		}
+		if failed > 0 {
+			log.Printf("Failed %d interrupted jobs that ran out of attempts", failed)
+		}
+		time.Sleep(r.lease / 2)
+	}
+}
+
+func (r *Runner) work(types []string) {
+	for {
+		job, err := r.repo.ClaimNext(types, r.lease)
+		if err != nil {
+			log.Printf("ERROR: Failed to claim job: %v", err)
+		}
+		if job == nil {
+			time.Sleep(r.pollInterval)
+			continue
+		}
+
+		r.run(job)
+	}
+}
+
+func (r *Runner) run(job *models.Job) {
+	done := make(chan struct{})
+	go r.renewLease(job, done)
+	err := r.safeHandle(job)
+	close(done)
+
+	if err == nil {
+		if err := r.repo.Complete(job); err != nil {
+			log.Printf("ERROR: Failed to complete job %d: %v", job.ID, err)
+		}
+		return
+	}
+
+	log.Printf("ERROR: Job %d (%s) attempt %d failed: %v", job.ID, job.Type, job.Attempts, err)
+
+	if job.Attempts >= job.MaxAttempts {
+		err = r.repo.Fail(job, err.Error())
+	} else {
+		backoff := time.Duration(job.Attempts*job.Attempts) * time.Minute
+		err = r.repo.Retry(job, err.Error(), time.Now().Add(backoff))
+	}
+	if err != nil {
+		log.Printf("ERROR: Failed to update job %d: %v", job.ID, err)
+	}
+}
+
+// renewLease extends the job's lease until done is closed, so long jobs such
+// as transcodes are not mistaken for abandoned ones. It stops once the job
+// has been taken from this worker.
+func (r *Runner) renewLease(job *models.Job, done <-chan struct{}) {
+	ticker := time.NewTicker(r.lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			err := r.repo.ExtendLease(job, time.Now().Add(r.lease))
+			if errors.Is(err, repositories.ErrJobLost) {
+				log.Printf("ERROR: Job %d was reclaimed while still running", job.ID)
+				return
+			}
+			if err != nil {
+				log.Printf("ERROR: Failed to extend lease of job %d: %v", job.ID, err)
+			}
+		}
+	}
+}
+
+func (r *Runner) safeHandle(job *models.Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	return r.handlers[job.Type]([]byte(job.Payload))
+}
@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"os"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// NewFromEnv builds the mailer selected by MAIL_DRIVER. "smtp" sends real
+// mail; anything else falls back to the log mailer used for local development.
+func NewFromEnv() Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "GroAcademy <no-reply@groacademy.local>"
+	}
+
+	if os.Getenv("MAIL_DRIVER") == "smtp" {
+		return NewSMTPMailer(
+			os.Getenv("SMTP_HOST"),
+			os.Getenv("SMTP_PORT"),
+			os.Getenv("SMTP_USERNAME"),
+			os.Getenv("SMTP_PASSWORD"),
+			from,
+		)
+	}
+
+	return NewLogMailer(os.Getenv("MAIL_LOG_FILE"), from)
+}
@@ -1,6 +1,7 @@
 package middlewares
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"os"
@@ -11,6 +12,7 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/kin-ark/GroAcademy/internal/database"
 	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/rbac"
 )
 
 func RequireAuth(c *gin.Context) {
@@ -45,13 +47,14 @@ func RequireAuth(c *gin.Context) {
 			return
 		}
 
-		var user models.User
-		if err := database.DB.Where("username = ?", claims["sub"]).First(&user).Error; err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		user, sessionID, err := loadSessionUser(claims)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.Set("user", user)
+		c.Set("user", *user)
+		c.Set("session_id", sessionID)
 
 		c.Next()
 	} else {
@@ -59,21 +62,23 @@ func RequireAuth(c *gin.Context) {
 	}
 }
 
-func RequireAdmin(c *gin.Context) {
-	userI, exists := c.Get("user")
-	if !exists {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
-	}
+func RequirePermission(permission rbac.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userI, exists := c.Get("user")
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
 
-	user := userI.(models.User)
+		user := userI.(models.User)
 
-	if user.Role != "admin" {
-		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
-		return
-	}
+		if !rbac.HasPermission(user.Role, permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Missing permission: " + string(permission)})
+			return
+		}
 
-	c.Next()
+		c.Next()
+	}
 }
 
 func FERequireAuth(c *gin.Context) {
@@ -108,21 +113,22 @@ func FERequireAuth(c *gin.Context) {
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 		if float64(time.Now().Unix()) > claims["exp"].(float64) {
-			c.SetCookie("Authorization", "", -1, "/", "", false, true)
+			ClearAuthCookies(c)
 			c.Redirect(http.StatusFound, "/login")
 			c.Abort()
 			return
 		}
 
-		var user models.User
-		if err := database.DB.Where("username = ?", claims["sub"]).First(&user).Error; err != nil {
-			c.SetCookie("Authorization", "", -1, "/", "", false, true)
+		user, sessionID, err := loadSessionUser(claims)
+		if err != nil {
+			ClearAuthCookies(c)
 			c.Redirect(http.StatusFound, "/login")
 			c.Abort()
 			return
 		}
 
-		c.Set("user", user)
+		c.Set("user", *user)
+		c.Set("session_id", sessionID)
 		c.Next()
 	} else {
 		c.Redirect(http.StatusFound, "/login")
@@ -130,6 +136,15 @@ func FERequireAuth(c *gin.Context) {
 	}
 }
 
+// ClearAuthCookies expires the cookies set at login with the same
+// attributes; browsers ignore a non-Secure cookie that would replace a
+// Secure one.
+func ClearAuthCookies(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie("Authorization", "", -1, "", "", true, true)
+	c.SetCookie("RefreshToken", "", -1, "/api/auth", "", true, true)
+}
+
 func RedirectIfAuthenticated(c *gin.Context) {
 	cookie, err := c.Cookie("Authorization")
 	if err != nil {
@@ -146,8 +161,7 @@ func RedirectIfAuthenticated(c *gin.Context) {
 
 	if err == nil && token.Valid {
 		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			if float64(time.Now().Unix()) < claims["exp"].(float64) {
-
+			if _, _, err := loadSessionUser(claims); err == nil {
 				c.Redirect(http.StatusFound, "/courses")
 				c.Abort()
 				return
@@ -157,3 +171,67 @@ func RedirectIfAuthenticated(c *gin.Context) {
 
 	c.Next()
 }
+
+// FEOptionalAuth loads the signed-in user on public pages when there is one,
+// and lets anonymous visitors through.
+func FEOptionalAuth(c *gin.Context) {
+	cookie, err := c.Cookie("Authorization")
+	if err != nil {
+		c.Next()
+		return
+	}
+
+	token, err := jwt.Parse(cookie, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+		return []byte(os.Getenv("SECRET")), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+
+	if err == nil && token.Valid {
+		if claims, ok := token.Claims.(jwt.MapClaims); ok {
+			if user, sessionID, err := loadSessionUser(claims); err == nil {
+				c.Set("user", *user)
+				c.Set("session_id", sessionID)
+			}
+		}
+	}
+
+	c.Next()
+}
+
+// loadSessionUser resolves the user behind a token's claims, rejecting tokens
+// whose session has been revoked or has expired.
+func loadSessionUser(claims jwt.MapClaims) (*models.User, uint, error) {
+	sid, ok := claims["sid"].(float64)
+	if !ok {
+		return nil, 0, errors.New("Session not found")
+	}
+
+	var session models.Session
+	if err := database.DB.
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ?", uint(sid), time.Now()).
+		First(&session).Error; err != nil {
+		return nil, 0, errors.New("Session expired or revoked")
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, session.UserID).Error; err != nil {
+		return nil, 0, errors.New("User not found")
+	}
+
+	if user.Username != claims["sub"] {
+		return nil, 0, errors.New("User not found")
+	}
+
+	return &user, session.ID, nil
+}
+
+// LimitBodySize caps the request body so oversized uploads are rejected while
+// they stream in rather than after being spooled to disk.
+func LimitBodySize(n int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, n)
+		c.Next()
+	}
+}
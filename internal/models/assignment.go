@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+const (
+	SubmissionPending = "pending"
+	SubmissionGraded  = "graded"
+)
+
+// Assignment is hand-in work attached to a module. A submission graded at or
+// above PassingScore completes the module.
+type Assignment struct {
+	ID           uint `gorm:"primaryKey"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	ModuleID     uint    `json:"module_id" gorm:"not null;uniqueIndex"`
+	Instructions string  `json:"instructions" gorm:"type:text;not null"`
+	MaxScore     float64 `json:"max_score" gorm:"not null;default:100"`
+	PassingScore float64 `json:"passing_score" gorm:"not null;default:60"`
+	AllowText    bool    `json:"allow_text" gorm:"not null;default:true"`
+	AllowFile    bool    `json:"allow_file" gorm:"not null;default:true"`
+
+	Module Module `json:"-" gorm:"foreignKey:ModuleID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+type AssignmentSubmission struct {
+	ID           uint `gorm:"primaryKey"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	AssignmentID uint       `json:"assignment_id" gorm:"not null;index"`
+	UserID       uint       `json:"user_id" gorm:"not null;index"`
+	Text         string     `json:"text" gorm:"type:text"`
+	FileKey      string     `json:"file_key" gorm:"size:255"`
+	Status       string     `json:"status" gorm:"size:20;not null;default:'pending';index"`
+	Score        *float64   `json:"score"`
+	Feedback     string     `json:"feedback" gorm:"type:text"`
+	Passed       bool       `json:"passed" gorm:"not null;default:false"`
+	GradedBy     *uint      `json:"graded_by"`
+	GradedAt     *time.Time `json:"graded_at"`
+
+	Assignment Assignment `json:"-" gorm:"foreignKey:AssignmentID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	User       User       `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+const (
+	TransactionTypePurchase = "purchase"
+	TransactionTypeTopUp    = "top_up"
+	TransactionTypeRefund   = "refund"
+)
+
+// BalanceTransaction is an append-only ledger entry recording a single change
+// to a user's balance. Amount is signed: debits are negative, credits positive.
+type BalanceTransaction struct {
+	ID           uint `gorm:"primaryKey"`
+	CreatedAt    time.Time
+	UserID       uint    `json:"user_id" gorm:"not null;index"`
+	Type         string  `json:"type" gorm:"size:20;not null"`
+	Amount       float64 `json:"amount" gorm:"type:numeric(10,2);not null"`
+	BalanceAfter float64 `json:"balance_after" gorm:"type:numeric(10,2);not null"`
+	PurchaseID   *uint   `json:"purchase_id" gorm:"index"`
+	Description  string  `json:"description" gorm:"size:255"`
+
+	// RESTRICT rather than CASCADE: a cascading delete would bypass the
+	// append-only hooks, so users with ledger entries cannot be deleted.
+	User User `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+}
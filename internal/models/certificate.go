@@ -2,13 +2,43 @@ package models
 
 import "time"
 
+// CertificateRefundedReason is the revocation reason of certificates whose
+// course purchase was refunded.
+const CertificateRefundedReason = "refunded"
+
 type Certificate struct {
 	ID        uint `gorm:"primaryKey"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	UserID    uint   `json:"user_id" gorm:"not null;index"`
-	CourseID  uint   `json:"course_id" gorm:"not null;index"`
-	FileURL   string `json:"file_url" gorm:"size:255;not null"`
-	User      User   `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	Course    Course `gorm:"foreignKey:CourseID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID    uint   `json:"user_id" gorm:"not null;index;uniqueIndex:idx_certificate_user_course"`
+	CourseID  uint   `json:"course_id" gorm:"not null;index;uniqueIndex:idx_certificate_user_course"`
+	FileKey   string `json:"file_key" gorm:"size:255;not null"`
+	// PDFKey is the vector PDF rendered alongside the PNG in FileKey. It is
+	// empty for certificates issued before PDFs were produced.
+	PDFKey string `json:"pdf_key" gorm:"size:255;not null;default:''"`
+
+	// Serial identifies the certificate on its public verification page.
+	// Signature is an HMAC over the serial, holder, course and IssuedAt, so
+	// edited rows no longer verify.
+	Serial    string    `json:"serial" gorm:"size:32;uniqueIndex"`
+	Signature string    `json:"-" gorm:"size:64"`
+	IssuedAt  time.Time `json:"issued_at" gorm:"not null;default:now()"`
+
+	RevokedAt        *time.Time `json:"revoked_at"`
+	RevokedBy        *uint      `json:"revoked_by"`
+	RevocationReason string     `json:"revocation_reason" gorm:"size:500;not null;default:''"`
+
+	// OutdatedAt is set while course changes leave the holder's progress
+	// incomplete under the revoke or needs_update policy. Revocations made
+	// by that policy have no RevokedBy and are lifted with OutdatedAt.
+	OutdatedAt *time.Time `json:"outdated_at"`
+
+	User   User   `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Course Course `gorm:"foreignKey:CourseID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+// RevokedByRefund reports whether the certificate was revoked because its
+// course purchase was refunded.
+func (c *Certificate) RevokedByRefund() bool {
+	return c.RevokedAt != nil && c.RevocationReason == CertificateRefundedReason
 }
@@ -0,0 +1,99 @@
+package models
+
+import "time"
+
+// Certificate fields a template can place. Text fields are drawn in the
+// order listed; heading, holder and course use the title font and accent
+// color, the rest the body font and text color.
+const (
+	CertFieldHeading    = "heading"
+	CertFieldIntro      = "intro"
+	CertFieldHolder     = "holder"
+	CertFieldCompletion = "completion"
+	CertFieldCourse     = "course"
+	CertFieldInstructor = "instructor"
+	CertFieldDate       = "date"
+	CertFieldSerial     = "serial"
+	CertFieldVerifyURL  = "verify_url"
+
+	CertFieldLogo      = "logo"
+	CertFieldSignature = "signature"
+	CertFieldQR        = "qr"
+)
+
+// CertificateTextFields and CertificateImageFields list the valid keys of
+// CertificateTemplate.Fields.
+var (
+	CertificateTextFields = []string{
+		CertFieldHeading, CertFieldIntro, CertFieldHolder, CertFieldCompletion, CertFieldCourse,
+		CertFieldInstructor, CertFieldDate, CertFieldSerial, CertFieldVerifyURL,
+	}
+	CertificateImageFields = []string{CertFieldLogo, CertFieldSignature, CertFieldQR}
+)
+
+// CertificateField positions one field, in pixels of the template. Text is
+// anchored at (X, Y) per Align and centered vertically; images and the QR
+// code have their top-left corner at (X, Y) and are scaled to Width x Height.
+type CertificateField struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Size   float64 `json:"size,omitempty"`
+	Align  string  `json:"align,omitempty"`
+	Width  float64 `json:"width,omitempty"`
+	Height float64 `json:"height,omitempty"`
+	Hidden bool    `json:"hidden,omitempty"`
+}
+
+// CertificateTemplate is an admin-managed certificate design. Courses pick
+// one with Course.CertificateTemplateID; those without use the template
+// marked IsDefault, or the built-in design when there is none. Asset keys
+// point at files in storage and are optional.
+type CertificateTemplate struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Name      string `json:"name" gorm:"size:100;not null;uniqueIndex"`
+	IsDefault bool   `json:"is_default" gorm:"not null;default:false"`
+	Width     int    `json:"width" gorm:"not null;default:1200"`
+	Height    int    `json:"height" gorm:"not null;default:800"`
+
+	BackgroundKey string `json:"background_key" gorm:"size:255;not null;default:''"`
+	LogoKey       string `json:"logo_key" gorm:"size:255;not null;default:''"`
+	SignatureKey  string `json:"signature_key" gorm:"size:255;not null;default:''"`
+	TitleFontKey  string `json:"title_font_key" gorm:"size:255;not null;default:''"`
+	BodyFontKey   string `json:"body_font_key" gorm:"size:255;not null;default:''"`
+
+	TextColor   string                      `json:"text_color" gorm:"size:7;not null;default:'#000000'"`
+	AccentColor string                      `json:"accent_color" gorm:"size:7;not null;default:'#000000'"`
+	Fields      map[string]CertificateField `json:"fields" gorm:"serializer:json;type:text;not null"`
+}
+
+// DefaultCertificateTemplate is the built-in design, used when no template
+// applies and as the starting point for new templates.
+func DefaultCertificateTemplate() CertificateTemplate {
+	return CertificateTemplate{
+		Name:        "Default",
+		Width:       1200,
+		Height:      800,
+		TextColor:   "#000000",
+		AccentColor: "#000000",
+		Fields:      DefaultCertificateFields(),
+	}
+}
+
+func DefaultCertificateFields() map[string]CertificateField {
+	return map[string]CertificateField{
+		CertFieldHeading:    {X: 600, Y: 150, Size: 40, Align: "center"},
+		CertFieldIntro:      {X: 600, Y: 250, Size: 20, Align: "center"},
+		CertFieldHolder:     {X: 600, Y: 320, Size: 36, Align: "center"},
+		CertFieldCompletion: {X: 600, Y: 400, Size: 20, Align: "center"},
+		CertFieldCourse:     {X: 600, Y: 460, Size: 30, Align: "center"},
+		CertFieldInstructor: {X: 300, Y: 600, Size: 18, Align: "center"},
+		CertFieldDate:       {X: 900, Y: 600, Size: 18, Align: "center"},
+		CertFieldSerial:     {X: 60, Y: 710, Size: 14, Align: "left"},
+		CertFieldVerifyURL:  {X: 60, Y: 735, Size: 14, Align: "left"},
+		CertFieldLogo:       {X: 60, Y: 60, Width: 160, Height: 80},
+		CertFieldSignature:  {X: 210, Y: 510, Width: 180, Height: 70},
+		CertFieldQR:         {X: 1030, Y: 630, Width: 120, Height: 120},
+	}
+}
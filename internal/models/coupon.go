@@ -0,0 +1,65 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	CouponTypePercent = "percent"
+	CouponTypeFixed   = "fixed"
+)
+
+// Coupon is a discount code for one course, or for every course when
+// CourseID is nil. A zero MaxUses or MaxUsesPerUser means no limit; uses are
+// counted from purchases, refunded ones included.
+type Coupon struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// Code is stored in upper case and matched regardless of case.
+	Code  string  `json:"code" gorm:"size:50;not null;uniqueIndex"`
+	Type  string  `json:"type" gorm:"size:10;not null"`
+	Value float64 `json:"value" gorm:"type:numeric(10,2);not null"`
+
+	CourseID       *uint      `json:"course_id" gorm:"index"`
+	MaxUses        int        `json:"max_uses" gorm:"not null;default:0"`
+	MaxUsesPerUser int        `json:"max_uses_per_user" gorm:"not null;default:0"`
+	StartsAt       *time.Time `json:"starts_at"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	Active         bool       `json:"active" gorm:"not null;default:true"`
+
+	Course *Course `json:"-" gorm:"foreignKey:CourseID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+// CouponWithUses is a coupon with the number of purchases that used it.
+type CouponWithUses struct {
+	Coupon
+	Uses int64 `json:"uses"`
+}
+
+// ValidAt reports whether the coupon is active and within its validity window.
+func (c Coupon) ValidAt(now time.Time) bool {
+	if !c.Active {
+		return false
+	}
+	if c.StartsAt != nil && now.Before(*c.StartsAt) {
+		return false
+	}
+	return c.ExpiresAt == nil || now.Before(*c.ExpiresAt)
+}
+
+// AppliesTo reports whether the coupon can be used on the course.
+func (c Coupon) AppliesTo(courseID uint) bool {
+	return c.CourseID == nil || *c.CourseID == courseID
+}
+
+// DiscountFor returns how much the coupon takes off price, rounded to cents
+// and never more than price.
+func (c Coupon) DiscountFor(price float64) float64 {
+	discount := c.Value
+	if c.Type == CouponTypePercent {
+		discount = math.Round(price*c.Value) / 100
+	}
+	return math.Min(discount, price)
+}
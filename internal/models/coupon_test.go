@@ -0,0 +1,83 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCouponValidAt(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+
+	tests := []struct {
+		name   string
+		coupon Coupon
+		want   bool
+	}{
+		{name: "active without window", coupon: Coupon{Active: true}, want: true},
+		{name: "inactive", coupon: Coupon{}},
+		{name: "inactive within window", coupon: Coupon{StartsAt: &earlier, ExpiresAt: &later}},
+		{name: "within window", coupon: Coupon{Active: true, StartsAt: &earlier, ExpiresAt: &later}, want: true},
+		{name: "not started", coupon: Coupon{Active: true, StartsAt: &later}},
+		{name: "starts now", coupon: Coupon{Active: true, StartsAt: &now}, want: true},
+		{name: "expired", coupon: Coupon{Active: true, ExpiresAt: &earlier}},
+		{name: "expires now", coupon: Coupon{Active: true, ExpiresAt: &now}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.coupon.ValidAt(now); got != tt.want {
+				t.Errorf("ValidAt = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCouponAppliesTo(t *testing.T) {
+	course := uint(3)
+
+	tests := []struct {
+		name     string
+		coupon   Coupon
+		courseID uint
+		want     bool
+	}{
+		{name: "every course", coupon: Coupon{}, courseID: 9, want: true},
+		{name: "its course", coupon: Coupon{CourseID: &course}, courseID: 3, want: true},
+		{name: "another course", coupon: Coupon{CourseID: &course}, courseID: 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.coupon.AppliesTo(tt.courseID); got != tt.want {
+				t.Errorf("AppliesTo(%d) = %v, want %v", tt.courseID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCouponDiscountFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		coupon Coupon
+		price  float64
+		want   float64
+	}{
+		{name: "percent", coupon: Coupon{Type: CouponTypePercent, Value: 25}, price: 200, want: 50},
+		{name: "percent rounds to cents", coupon: Coupon{Type: CouponTypePercent, Value: 15}, price: 19.99, want: 3},
+		{name: "percent rounds half up", coupon: Coupon{Type: CouponTypePercent, Value: 10}, price: 0.05, want: 0.01},
+		{name: "full percent", coupon: Coupon{Type: CouponTypePercent, Value: 100}, price: 49.5, want: 49.5},
+		{name: "fixed", coupon: Coupon{Type: CouponTypeFixed, Value: 20}, price: 100, want: 20},
+		{name: "fixed is capped at the price", coupon: Coupon{Type: CouponTypeFixed, Value: 150}, price: 100, want: 100},
+		{name: "free course", coupon: Coupon{Type: CouponTypeFixed, Value: 10}, price: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.coupon.DiscountFor(tt.price); got != tt.want {
+				t.Errorf("DiscountFor(%v) = %v, want %v", tt.price, got, tt.want)
+			}
+		})
+	}
+}
@@ -6,6 +6,24 @@ import (
 	"github.com/lib/pq"
 )
 
+// Publication states shared by courses and modules. Only published content
+// is shown to students; archived courses stay open to their buyers.
+const (
+	StatusDraft     = "draft"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// Certificate policies decide what happens to issued certificates when new
+// modules or a progress reset leave their holder's progress incomplete.
+// Grandfathered certificates stay valid; revoked and needs-update ones are
+// restored once the holder completes the course again.
+const (
+	CertificatePolicyGrandfather = "grandfather"
+	CertificatePolicyRevoke      = "revoke"
+	CertificatePolicyNeedsUpdate = "needs_update"
+)
+
 type Course struct {
 	ID             uint `gorm:"primaryKey"`
 	CreatedAt      time.Time
@@ -16,6 +34,66 @@ type Course struct {
 	Topics         pq.StringArray `json:"topics" gorm:"type:text[];not null;default:'{}'"`
 	Price          float64        `json:"price" gorm:"type:numeric(10,2);not null"`
 	ThumbnailImage string         `json:"thumbnail_image" gorm:"size:255"`
+
+	// SalePrice replaces Price from SaleStartsAt until SaleEndsAt; a missing
+	// bound leaves that side of the sale open.
+	SalePrice    *float64   `json:"sale_price" gorm:"type:numeric(10,2)"`
+	SaleStartsAt *time.Time `json:"sale_starts_at"`
+	SaleEndsAt   *time.Time `json:"sale_ends_at"`
+
+	// SequentialModules keeps each module locked until the one before it
+	// is completed.
+	SequentialModules bool `json:"sequential_modules" gorm:"not null;default:false"`
+
+	// Status is draft, published or archived. A draft with PublishAt set is
+	// published automatically at that time.
+	Status    string     `json:"status" gorm:"size:20;not null;default:'published';index"`
+	PublishAt *time.Time `json:"publish_at"`
+
+	// RatingAverage and RatingCount summarise the course's reviews.
+	RatingAverage float64 `json:"rating_average" gorm:"type:numeric(3,2);not null;default:0"`
+	RatingCount   int64   `json:"rating_count" gorm:"not null;default:0"`
+
+	// CertificateTemplateID selects the certificate design; nil uses the
+	// default template.
+	CertificateTemplateID *uint                `json:"certificate_template_id"`
+	CertificateTemplate   *CertificateTemplate `json:"-" gorm:"foreignKey:CertificateTemplateID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+
+	// CertificatePolicy is grandfather, revoke or needs_update.
+	CertificatePolicy string `json:"certificate_policy" gorm:"size:20;not null;default:'grandfather'"`
+
+	Instructors []User `json:"-" gorm:"many2many:course_instructors;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+
+	// Assistants are teaching assistants who grade submissions and moderate
+	// discussions for this course.
+	Assistants []User `json:"-" gorm:"many2many:course_assistants;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+
+	// Prerequisites are courses whose certificate is required before this
+	// course can be bought or studied.
+	Prerequisites []Course `json:"-" gorm:"many2many:course_prerequisites;joinForeignKey:CourseID;joinReferences:PrerequisiteID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+// PriceAt returns the price charged at now: the sale price while the sale
+// runs, otherwise the list price.
+func (c Course) PriceAt(now time.Time) float64 {
+	if c.SalePrice == nil {
+		return c.Price
+	}
+	if c.SaleStartsAt != nil && now.Before(*c.SaleStartsAt) {
+		return c.Price
+	}
+	if c.SaleEndsAt != nil && !now.Before(*c.SaleEndsAt) {
+		return c.Price
+	}
+	return *c.SalePrice
+}
+
+func (c Course) CurrentPrice() float64 {
+	return c.PriceAt(time.Now())
+}
+
+func (c Course) OnSale() bool {
+	return c.CurrentPrice() < c.Price
 }
 
 type CourseWithModulesCount struct {
@@ -23,8 +101,49 @@ type CourseWithModulesCount struct {
 	ModulesCount int64 `json:"modules_count"`
 }
 
+// Sort orders accepted by the course catalog.
+const (
+	SortRelevance = "relevance"
+	SortNewest    = "newest"
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+	SortRating    = "rating"
+)
+
+// FacetCount is how many catalog courses match a facet value.
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// CourseFacets are counted over the courses matching the search. Each facet
+// ignores its own filter, so the other values stay selectable.
+type CourseFacets struct {
+	Topics      []FacetCount `json:"topics"`
+	Instructors []FacetCount `json:"instructors"`
+	Price       []FacetCount `json:"price"`
+	Rating      []FacetCount `json:"rating"`
+}
+
 type CourseProgress struct {
+	TotalModules     int               `json:"total_modules"`
+	CompletedModules int               `json:"completed_modules"`
+	Percentage       float64           `json:"percentage"`
+	Sections         []SectionProgress `json:"sections,omitempty"`
+}
+
+type SectionProgress struct {
+	SectionID        uint    `json:"section_id"`
+	Title            string  `json:"title"`
 	TotalModules     int     `json:"total_modules"`
 	CompletedModules int     `json:"completed_modules"`
 	Percentage       float64 `json:"percentage"`
 }
+
+type InstructorCourseStats struct {
+	CourseID   uint    `json:"course_id"`
+	Title      string  `json:"title"`
+	Price      float64 `json:"price"`
+	Enrolments int64   `json:"enrolments"`
+	Revenue    float64 `json:"revenue"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// DiscussionPost is a question asked under a module or a reply to one. A
+// thread is its opening post (no ParentID) and the replies pointing at it;
+// replies are not nested further.
+type DiscussionPost struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ModuleID  uint       `json:"module_id" gorm:"not null;index"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	ParentID  *uint      `json:"parent_id" gorm:"index"`
+	Title     string     `json:"title" gorm:"size:200;not null;default:''"`
+	Body      string     `json:"body" gorm:"type:text;not null"`
+	EditedAt  *time.Time `json:"edited_at"`
+
+	// UpvoteCount and ReplyCount are kept with the votes and replies they
+	// count, so threads can be sorted without aggregating.
+	UpvoteCount int64 `json:"upvote_count" gorm:"not null;default:0"`
+	ReplyCount  int64 `json:"reply_count" gorm:"not null;default:0"`
+
+	// IsAnswer marks a reply picked by an instructor as the answer; the
+	// thread's AnsweredAt is set while it has one.
+	IsAnswer       bool       `json:"is_answer" gorm:"not null;default:false"`
+	AnsweredAt     *time.Time `json:"answered_at"`
+	LastActivityAt time.Time  `json:"last_activity_at" gorm:"not null;index"`
+
+	Module Module          `json:"-" gorm:"foreignKey:ModuleID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	User   User            `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Parent *DiscussionPost `json:"-" gorm:"foreignKey:ParentID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+type DiscussionVote struct {
+	PostID    uint `gorm:"primaryKey"`
+	UserID    uint `gorm:"primaryKey;index"`
+	CreatedAt time.Time
+
+	Post DiscussionPost `json:"-" gorm:"foreignKey:PostID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	User User           `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
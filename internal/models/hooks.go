@@ -1,6 +1,8 @@
 package models
 
 import (
+	"errors"
+
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -9,9 +11,19 @@ func (p *Purchase) AfterCreate(tx *gorm.DB) error {
 	return createModuleProgressesForUser(tx, p.UserID, p.CourseID)
 }
 
+var ErrLedgerImmutable = errors.New("balance transactions are append-only")
+
+func (t *BalanceTransaction) BeforeUpdate(tx *gorm.DB) error {
+	return ErrLedgerImmutable
+}
+
+func (t *BalanceTransaction) BeforeDelete(tx *gorm.DB) error {
+	return ErrLedgerImmutable
+}
+
 func (m *Module) AfterCreate(tx *gorm.DB) error {
 	var purchases []Purchase
-	if err := tx.Where("course_id = ?", m.CourseID).Find(&purchases).Error; err != nil {
+	if err := tx.Where("course_id = ? AND refunded_at IS NULL", m.CourseID).Find(&purchases).Error; err != nil {
 		return err
 	}
 
@@ -1,7 +1,11 @@
 package models
 
 import (
+	"math"
 	"mime/multipart"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/lib/pq"
 )
@@ -20,20 +24,114 @@ type LoginInput struct {
 	Password   string `json:"password" binding:"required"`
 }
 
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type ForgotPasswordInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordInput struct {
+	Token           string `json:"token" binding:"required"`
+	Password        string `json:"password" binding:"required,min=8"`
+	ConfirmPassword string `json:"confirm_password" binding:"required,min=8,eqfield=Password"`
+}
+
+type VerifyEmailInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
 type CourseFormInput struct {
 	Title          string                `form:"title" binding:"required"`
 	Description    string                `form:"description" binding:"required"`
-	Instructor     string                `form:"instructor" binding:"required"`
+	Instructor     string                `form:"instructor"`
+	InstructorIDs  []uint                `form:"instructor_ids"`
 	Topics         pq.StringArray        `form:"topics" binding:"required"`
 	Price          float64               `form:"price" binding:"required"`
 	ThumbnailImage *multipart.FileHeader `form:"thumbnail_image"`
+
+	SequentialModules bool `form:"sequential_modules"`
+
+	// CertificatePolicy defaults to grandfather on create and is left
+	// unchanged on edit when empty.
+	CertificatePolicy string `form:"certificate_policy" binding:"omitempty,oneof=grandfather revoke needs_update"`
+
+	// The sale fields replace the course's sale; leave SalePrice empty to
+	// end it. SalePrice must be below Price.
+	SalePrice    *float64   `form:"sale_price" binding:"omitempty,min=0"`
+	SaleStartsAt *time.Time `form:"sale_starts_at"`
+	SaleEndsAt   *time.Time `form:"sale_ends_at"`
+
+	// Status and PublishAt only apply when creating; new courses start as
+	// drafts. Use the status endpoint to change them later.
+	Status    string     `form:"status" binding:"omitempty,oneof=draft published archived"`
+	PublishAt *time.Time `form:"publish_at"`
+}
+
+// PublishStatusInput moves a course or module through its lifecycle.
+// PublishAt schedules a draft to be published and must be in the future.
+type PublishStatusInput struct {
+	Status    string     `json:"status" binding:"required,oneof=draft published archived"`
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// PrerequisitesInput replaces a course's prerequisites; an empty list clears
+// them.
+type PrerequisitesInput struct {
+	CourseIDs []uint `json:"course_ids"`
+}
+
+// AssistantsInput replaces a course's teaching assistants; an empty list
+// clears them.
+type AssistantsInput struct {
+	UserIDs []uint `json:"user_ids"`
+}
+
+// BuyCourseInput is the optional body of a purchase. The same field is read
+// from the query string when quoting a price.
+type BuyCourseInput struct {
+	CouponCode string `json:"coupon_code" form:"coupon_code" binding:"max=50"`
+}
+
+// CouponInput creates or replaces a coupon. Percentage values are capped at
+// 100; a nil CourseID makes the coupon valid for every course.
+type CouponInput struct {
+	Code           string     `json:"code" binding:"required,max=50"`
+	Type           string     `json:"type" binding:"required,oneof=percent fixed"`
+	Value          float64    `json:"value" binding:"required,gt=0"`
+	CourseID       *uint      `json:"course_id"`
+	MaxUses        int        `json:"max_uses" binding:"min=0"`
+	MaxUsesPerUser int        `json:"max_uses_per_user" binding:"min=0"`
+	StartsAt       *time.Time `json:"starts_at"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	Active         *bool      `json:"active"`
 }
 
 type SearchQuery struct {
 	Q string `form:"q"`
+	// Status filters the catalog. Only users who can manage every course see
+	// all unpublished courses; see CourseSearchQuery.TaughtBy.
+	Status string `form:"status" binding:"omitempty,oneof=draft published archived"`
 	PaginationQuery
 }
 
+// CourseSearchQuery filters and sorts the course catalog. Zero values leave
+// a filter off; sort defaults to relevance when Q is set and newest otherwise.
+type CourseSearchQuery struct {
+	SearchQuery
+	Topic      string  `form:"topic"`
+	Instructor string  `form:"instructor"`
+	Price      string  `form:"price" binding:"omitempty,oneof=free paid"`
+	MinPrice   float64 `form:"min_price" binding:"min=0"`
+	MaxPrice   float64 `form:"max_price" binding:"min=0"`
+	MinRating  float64 `form:"min_rating" binding:"min=0,max=5"`
+	Sort       string  `form:"sort" binding:"omitempty,oneof=relevance newest price_asc price_desc rating"`
+	// TaughtBy, set by the service rather than the request, hides unpublished
+	// courses that user does not teach.
+	TaughtBy *uint `form:"-"`
+}
+
 type PaginationQuery struct {
 	Page  int `form:"page"`
 	Limit int `form:"limit"`
@@ -51,24 +149,224 @@ func (q *PaginationQuery) Normalize() {
 	}
 }
 
+// Paginate describes the page q asks for out of totalItems, clamping it
+// between the first and the last page. There is always at least one page,
+// even if it is empty.
+func (q PaginationQuery) Paginate(totalItems int64) PaginationResponse {
+	totalPages := int(math.Ceil(float64(totalItems) / float64(q.Limit)))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	return PaginationResponse{
+		CurrentPage: max(min(q.Page, totalPages), 1),
+		TotalPages:  totalPages,
+		TotalItems:  int(totalItems),
+	}
+}
+
 type ModuleFormInput struct {
 	Title        string                `form:"title" binding:"required"`
 	Description  string                `form:"description" binding:"required"`
 	PDFContent   *multipart.FileHeader `form:"pdf_content"`
 	VideoContent *multipart.FileHeader `form:"video_content"`
+	// SectionID places the module in a section; 0 takes it out of its
+	// section. Edits keep the current section when it is omitted.
+	SectionID *uint `form:"section_id"`
+
+	// Status and PublishAt only apply when creating; new modules are
+	// published unless stated otherwise.
+	Status    string     `form:"status" binding:"omitempty,oneof=draft published archived"`
+	PublishAt *time.Time `form:"publish_at"`
 }
 
+// ReorderModulesRequest sets the course-wide module order. A module's
+// section_id moves it to that section (0 for none) and section_order
+// optionally reorders the sections in the same request.
 type ReorderModulesRequest struct {
 	ModuleOrder []struct {
+		ID        string `json:"id"`
+		Order     int    `json:"order"`
+		SectionID *uint  `json:"section_id"`
+	} `json:"module_order"`
+	SectionOrder []struct {
 		ID    string `json:"id"`
 		Order int    `json:"order"`
-	} `json:"module_order"`
+	} `json:"section_order"`
+}
+
+// RevisionDiffQuery picks the revision to diff against; 0 means the one
+// right before.
+type RevisionDiffQuery struct {
+	Against int `form:"against" binding:"min=0"`
+}
+
+type SectionInput struct {
+	Title string `json:"title" binding:"required,max=200"`
+}
+
+// WatchProgressInput is a playback heartbeat. WatchedSeconds is the amount of
+// video actually played since the previous heartbeat; seeking does not count.
+type WatchProgressInput struct {
+	PositionSeconds float64 `json:"position_seconds" form:"position_seconds" binding:"min=0"`
+	WatchedSeconds  float64 `json:"watched_seconds" form:"watched_seconds" binding:"min=0"`
+	DurationSeconds float64 `json:"duration_seconds" form:"duration_seconds" binding:"min=0"`
+}
+
+// QuizInput replaces a module's quiz. True/false questions set Answer and
+// short answer questions list their AcceptedAnswers instead of Options.
+// Questions and options keep their IDs when edited: send the ID to change
+// one in place, and leave it out only for new ones.
+type QuizInput struct {
+	Title               string              `json:"title" binding:"required,max=200"`
+	PassPercentage      *float64            `json:"pass_percentage" binding:"omitempty,min=0,max=100"`
+	MaxAttempts         int                 `json:"max_attempts" binding:"min=0"`
+	QuestionsPerAttempt int                 `json:"questions_per_attempt" binding:"min=0"`
+	Questions           []QuizQuestionInput `json:"questions" binding:"required,min=1,dive"`
+}
+
+type QuizQuestionInput struct {
+	ID              uint              `json:"id"`
+	Type            string            `json:"type" binding:"required,oneof=single_choice multiple_choice true_false short_answer"`
+	Prompt          string            `json:"prompt" binding:"required"`
+	Points          float64           `json:"points" binding:"min=0"`
+	Options         []QuizOptionInput `json:"options" binding:"dive"`
+	Answer          *bool             `json:"answer"`
+	AcceptedAnswers []string          `json:"accepted_answers"`
+}
+
+type QuizOptionInput struct {
+	ID        uint   `json:"id"`
+	Text      string `json:"text" binding:"required"`
+	IsCorrect bool   `json:"is_correct"`
+}
+
+type QuizSubmission struct {
+	Answers []QuizAnswerInput `json:"answers" binding:"dive"`
+}
+
+type QuizAnswerInput struct {
+	QuestionID uint   `json:"question_id" binding:"required"`
+	OptionIDs  []uint `json:"option_ids"`
+	Text       string `json:"text"`
+}
+
+type AssignmentInput struct {
+	Instructions string   `json:"instructions" binding:"required"`
+	MaxScore     *float64 `json:"max_score" binding:"omitempty,gt=0"`
+	PassingScore *float64 `json:"passing_score" binding:"omitempty,min=0"`
+	AllowText    *bool    `json:"allow_text"`
+	AllowFile    *bool    `json:"allow_file"`
+}
+
+type SubmissionFormInput struct {
+	Text string                `form:"text"`
+	File *multipart.FileHeader `form:"file"`
+}
+
+type GradeSubmissionInput struct {
+	Score    *float64 `json:"score" form:"score" binding:"required,min=0"`
+	Feedback string   `json:"feedback" form:"feedback"`
+}
+
+// SubmissionQueueQuery filters the grading queue. Status defaults to pending;
+// "all" lists every submission.
+type SubmissionQueueQuery struct {
+	PaginationQuery
+	Status   string `form:"status"`
+	CourseID uint   `form:"course_id"`
+}
+
+type ReviewInput struct {
+	Rating int    `json:"rating" form:"rating" binding:"required,min=1,max=5"`
+	Body   string `json:"body" form:"body" binding:"max=5000"`
+}
+
+type ReviewModerationInput struct {
+	Status string `json:"status" form:"status" binding:"required,oneof=visible flagged hidden"`
+	Note   string `json:"note" form:"note" binding:"max=1000"`
+}
+
+type CertificateRevocationInput struct {
+	Reason string `json:"reason" form:"reason" binding:"required,max=500"`
+}
+
+// CertificateTemplateFormInput creates or replaces a certificate template.
+// Empty size and color values keep the current ones. Fields is a JSON object
+// of CertificateField keyed by field name, merged over the current layout.
+// Uploaded files replace the current ones; Remove names assets to drop:
+// background, logo, signature, title_font or body_font.
+type CertificateTemplateFormInput struct {
+	Name        string `form:"name" binding:"required,max=100"`
+	IsDefault   bool   `form:"is_default"`
+	Width       int    `form:"width" binding:"omitempty,min=300,max=4000"`
+	Height      int    `form:"height" binding:"omitempty,min=300,max=4000"`
+	TextColor   string `form:"text_color"`
+	AccentColor string `form:"accent_color"`
+	Fields      string `form:"fields"`
+
+	Background *multipart.FileHeader `form:"background"`
+	Logo       *multipart.FileHeader `form:"logo"`
+	Signature  *multipart.FileHeader `form:"signature"`
+	TitleFont  *multipart.FileHeader `form:"title_font"`
+	BodyFont   *multipart.FileHeader `form:"body_font"`
+	Remove     []string              `form:"remove"`
+}
+
+// CourseCertificateTemplateInput picks a course's certificate template; a
+// null template_id goes back to the default.
+type CourseCertificateTemplateInput struct {
+	TemplateID *uint `json:"template_id"`
+}
+
+// CertificateFormatQuery picks the file format of a certificate download or
+// template preview. Downloads default to PDF and previews to PNG.
+type CertificateFormatQuery struct {
+	Format string `form:"format" binding:"omitempty,oneof=png pdf"`
+}
+
+// ReviewQuery filters review listings. Only moderators can ask for hidden
+// reviews; the moderation queue defaults to flagged ones.
+type ReviewQuery struct {
+	PaginationQuery
+	Status   string `form:"status" binding:"omitempty,oneof=visible flagged hidden"`
+	CourseID uint   `form:"course_id"`
+}
+
+type DiscussionThreadInput struct {
+	Title string `json:"title" form:"title" binding:"required,max=200"`
+	Body  string `json:"body" form:"body" binding:"required,max=10000"`
+}
+
+type DiscussionReplyInput struct {
+	Body string `json:"body" form:"body" binding:"required,max=10000"`
+}
+
+// DiscussionPostUpdateInput edits a post; Title only applies to threads.
+type DiscussionPostUpdateInput struct {
+	Title string `json:"title" binding:"max=200"`
+	Body  string `json:"body" binding:"required,max=10000"`
+}
+
+// DiscussionQuery lists a module's threads by latest activity (recent), by
+// upvotes (top), or only those without an answer yet (unanswered).
+type DiscussionQuery struct {
+	PaginationQuery
+	Sort string `form:"sort" binding:"omitempty,oneof=recent top unanswered"`
+}
+
+type RefundRequest struct {
+	Reason string `json:"reason" form:"reason" binding:"max=255"`
 }
 
 type PostBalance struct {
 	Increment float64 `json:"increment"`
 }
 
+type PutUserRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
 type PostUserRequest struct {
 	FirstName string `json:"first_name" binding:"required"`
 	LastName  string `json:"last_name" binding:"required"`
@@ -85,9 +383,42 @@ type CoursesPageData struct {
 	Pages      []int
 	Limit      int
 	Search     string
+	Filters    CourseSearchQuery
+	Facets     *CourseFacets
 	User       *User
 }
 
+// PageURL links to another page of the catalog, keeping the search and
+// filters.
+func (d CoursesPageData) PageURL(page int) string {
+	v := url.Values{}
+	v.Set("page", strconv.Itoa(page))
+	v.Set("limit", strconv.Itoa(d.Limit))
+	f := d.Filters
+	for key, value := range map[string]string{
+		"q":          f.Q,
+		"status":     f.Status,
+		"topic":      f.Topic,
+		"instructor": f.Instructor,
+		"price":      f.Price,
+		"sort":       f.Sort,
+	} {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	for key, value := range map[string]float64{
+		"min_price":  f.MinPrice,
+		"max_price":  f.MaxPrice,
+		"min_rating": f.MinRating,
+	} {
+		if value > 0 {
+			v.Set(key, strconv.FormatFloat(value, 'f', -1, 64))
+		}
+	}
+	return "?" + v.Encode()
+}
+
 type CourseCardData struct {
 	ID             uint
 	Title          string
@@ -95,22 +426,108 @@ type CourseCardData struct {
 	Topics         pq.StringArray
 	ThumbnailImage string
 	Price          float64
+	CurrentPrice   float64
 	Purchased      bool
+	Status         string
+	RatingAverage  float64
+	RatingCount    int64
 }
 
 type CourseDetailPageData struct {
-	Course         *Course
-	Purchased      bool
-	User           *User
-	CourseProgress *CourseProgress
-	CertificateURL *string
+	Course            *Course
+	Purchased         bool
+	User              *User
+	CourseProgress    *CourseProgress
+	CertificateURL    *string
+	RefundEligibility *RefundEligibility
+	LatestRefund      *Refund
+	Prerequisites     []PrerequisiteResponse
+	PrerequisitesMet  bool
+	Reviews           []ReviewResponse
+	OwnReview         *ReviewResponse
+	ReviewError       string
+	PurchaseError     string
+}
+
+type InstructorDashboardPageData struct {
+	User            *User
+	Courses         []InstructorCourseStats
+	TotalRevenue    float64
+	TotalEnrolments int64
+}
+
+// ModuleGroup is a run of modules shown together in the course sidebar.
+// Modules without a section form a group with SectionID 0 and no title.
+type ModuleGroup struct {
+	SectionID        uint
+	Title            string
+	Modules          []ModuleWithIsCompleted
+	CompletedModules int
+	Open             bool
 }
 
 type CourseModulesPageData struct {
-	Course         *Course
-	User           *User
-	Modules        []ModuleWithIsCompleted
-	CourseProgress CourseProgress
-	CurrentModule  *ModuleWithIsCompleted
-	ContentType    string
+	Course          *Course
+	User            *User
+	Modules         []ModuleWithIsCompleted
+	ModuleGroups    []ModuleGroup
+	CourseProgress  CourseProgress
+	CurrentModule   *ModuleWithIsCompleted
+	ContentType     string
+	Quiz            *QuizResponse
+	QuizError       string
+	Assignment      *AssignmentResponse
+	AssignmentError string
+
+	Discussions          []DiscussionPostResponse
+	DiscussionThread     *DiscussionThreadDetail
+	DiscussionPagination PaginationResponse
+	DiscussionSort       string
+	DiscussionError      string
+}
+
+// DiscussionURL links to a page of the module's discussion: the thread list,
+// or the open thread's replies when one is shown.
+func (d CourseModulesPageData) DiscussionURL(page int) string {
+	v := url.Values{}
+	if page > 1 {
+		v.Set("page", strconv.Itoa(page))
+	}
+	if d.DiscussionThread != nil {
+		v.Set("thread", strconv.FormatUint(uint64(d.DiscussionThread.ID), 10))
+	} else if d.DiscussionSort != "" {
+		v.Set("sort", d.DiscussionSort)
+	}
+
+	u := "/course/" + strconv.FormatUint(uint64(d.Course.ID), 10) +
+		"/modules/" + strconv.FormatUint(uint64(d.CurrentModule.ID), 10)
+	if len(v) > 0 {
+		u += "?" + v.Encode()
+	}
+	return u + "#discussion"
+}
+
+// CertificateVerificationPageData backs the public verification page; User
+// is nil for visitors who are not signed in.
+type CertificateVerificationPageData struct {
+	User        *User
+	Serial      string
+	Certificate *CertificateVerificationResponse
+	Error       string
+}
+
+type MyCertificatesPageData struct {
+	User         *User
+	Certificates []CertificateResponse
+	Page         int
+	TotalPages   int
+}
+
+type SubmissionQueuePageData struct {
+	User        *User
+	Submissions []SubmissionResponse
+	Status      string
+	Page        int
+	TotalPages  int
+	Error       string
 }
@@ -0,0 +1,26 @@
+package models
+
+import "testing"
+
+func TestPaginationQueryPaginate(t *testing.T) {
+	tests := []struct {
+		name       string
+		q          PaginationQuery
+		totalItems int64
+		want       PaginationResponse
+	}{
+		{name: "no items", q: PaginationQuery{Page: 1, Limit: 15}, want: PaginationResponse{CurrentPage: 1, TotalPages: 1}},
+		{name: "partial last page", q: PaginationQuery{Page: 2, Limit: 15}, totalItems: 16, want: PaginationResponse{CurrentPage: 2, TotalPages: 2, TotalItems: 16}},
+		{name: "exact pages", q: PaginationQuery{Page: 1, Limit: 5}, totalItems: 10, want: PaginationResponse{CurrentPage: 1, TotalPages: 2, TotalItems: 10}},
+		{name: "before the first page", q: PaginationQuery{Page: 0, Limit: 5}, totalItems: 10, want: PaginationResponse{CurrentPage: 1, TotalPages: 2, TotalItems: 10}},
+		{name: "past the last page", q: PaginationQuery{Page: 9, Limit: 5}, totalItems: 10, want: PaginationResponse{CurrentPage: 2, TotalPages: 2, TotalItems: 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.q.Paginate(tt.totalItems); got != tt.want {
+				t.Errorf("Paginate(%d) = %+v, want %+v", tt.totalItems, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+const (
+	JobStatusPending = "pending"
+	JobStatusRunning = "running"
+	JobStatusDone    = "done"
+	JobStatusFailed  = "failed"
+)
+
+// Job is a unit of background work. Jobs live in the database so they
+// survive restarts and can be picked up by any app instance.
+type Job struct {
+	ID          uint `gorm:"primaryKey"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Type        string    `json:"type" gorm:"size:100;not null;index"`
+	Payload     string    `json:"payload" gorm:"type:text;not null"`
+	Status      string    `json:"status" gorm:"size:20;not null;default:'pending';index"`
+	Attempts    int       `json:"attempts" gorm:"not null;default:0"`
+	MaxAttempts int       `json:"max_attempts" gorm:"not null;default:3"`
+	RunAt       time.Time `json:"run_at" gorm:"not null;index"`
+	LastError   string    `json:"last_error" gorm:"type:text"`
+	// LeasedUntil is when a running job is considered abandoned. The worker
+	// running it keeps extending the lease until the job finishes.
+	LeasedUntil *time.Time `json:"leased_until"`
+}
@@ -5,25 +5,58 @@ import (
 )
 
 type Module struct {
-	ID           uint   `gorm:"primaryKey"`
-	CourseID     uint   `json:"course_id" gorm:"not null;index;uniqueIndex:idx_course_order"`
-	Title        string `json:"title" gorm:"size:200;not null"`
-	Description  string `json:"description" gorm:"type:text;not null"`
-	PDFContent   string `json:"pdf_content" gorm:"size:255"`
-	VideoContent string `json:"video_content" gorm:"size:255"`
-	Order        int    `json:"order" gorm:"not null;uniqueIndex:idx_course_order"`
+	ID           uint       `gorm:"primaryKey"`
+	CourseID     uint       `json:"course_id" gorm:"not null;index;uniqueIndex:idx_course_order"`
+	Title        string     `json:"title" gorm:"size:200;not null"`
+	Description  string     `json:"description" gorm:"type:text;not null"`
+	PDFContent   string     `json:"pdf_content" gorm:"size:255"`
+	VideoContent string     `json:"video_content" gorm:"size:255"`
+	Order        int        `json:"order" gorm:"not null;uniqueIndex:idx_course_order"`
+	SectionID    *uint      `json:"section_id" gorm:"index"`
+	Status       string     `json:"status" gorm:"size:20;not null;default:'published';index"`
+	PublishAt    *time.Time `json:"publish_at"`
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 
-	Course Course `gorm:"foreignKey:CourseID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	// Transcoding output for VideoContent. VideoHLS and VideoPoster are
+	// storage keys; VideoStatus moves from pending to ready or failed.
+	VideoStatus   string  `json:"video_status" gorm:"size:20;not null;default:'none'"`
+	VideoHLS      string  `json:"video_hls" gorm:"size:255"`
+	VideoPoster   string  `json:"video_poster" gorm:"size:255"`
+	VideoDuration float64 `json:"video_duration" gorm:"not null;default:0"`
+	VideoError    string  `json:"-" gorm:"size:500"`
+
+	Course  Course   `gorm:"foreignKey:CourseID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Section *Section `json:"-" gorm:"foreignKey:SectionID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
 }
 
+const (
+	VideoStatusNone       = "none"
+	VideoStatusPending    = "pending"
+	VideoStatusProcessing = "processing"
+	VideoStatusReady      = "ready"
+	VideoStatusFailed     = "failed"
+)
+
 type ModuleWithIsCompleted struct {
 	Module
-	IsCompleted bool `json:"is_completed"`
+	IsCompleted       bool    `json:"is_completed"`
+	HasQuiz           bool    `json:"has_quiz"`
+	HasAssignment     bool    `json:"has_assignment"`
+	PositionSeconds   float64 `json:"position_seconds"`
+	WatchedPercentage float64 `json:"watched_percentage"`
+	IsLocked          bool    `json:"is_locked"`
+	LockReason        string  `json:"lock_reason,omitempty"`
 }
 
 type ModuleOrder struct {
-	ID    uint
-	Order int
+	ID        uint
+	Order     int
+	SectionID *uint
+}
+
+type ModuleCompletion struct {
+	ID          uint
+	Order       int
+	IsCompleted bool
 }
@@ -3,14 +3,21 @@ package models
 import "time"
 
 type ModuleProgress struct {
-	ID          uint `gorm:"primaryKey"`
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	UserID      uint   `json:"user_id" gorm:"not null;index:idx_user_module,unique"`
-	ModuleID    uint   `json:"module_id" gorm:"not null;index:idx_user_module,unique"`
-	IsCompleted bool   `json:"is_completed" gorm:"default:false"`
-	User        User   `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	Module      Module `gorm:"foreignKey:ModuleID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	ID              uint `gorm:"primaryKey"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	UserID          uint    `json:"user_id" gorm:"not null;index:idx_user_module,unique"`
+	ModuleID        uint    `json:"module_id" gorm:"not null;index:idx_user_module,unique"`
+	IsCompleted     bool    `json:"is_completed" gorm:"default:false"`
+	PositionSeconds float64 `json:"position_seconds" gorm:"not null;default:0"`
+	WatchedSeconds  float64 `json:"watched_seconds" gorm:"not null;default:0"`
+	// WatchedRanges are the sorted, disjoint [start, end] spans of the video
+	// played so far; WatchedSeconds is their total length.
+	WatchedRanges     [][2]float64 `json:"-" gorm:"serializer:json;type:text"`
+	WatchedPercentage float64      `json:"watched_percentage" gorm:"not null;default:0"`
+	LastWatchedAt     *time.Time   `json:"last_watched_at"`
+	User              User         `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Module            Module       `gorm:"foreignKey:ModuleID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 }
 
 type ReorderModulesResponse struct {
@@ -25,24 +25,141 @@ type CourseResponse struct {
 	TotalModules   int       `json:"total_modules"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
+
+	SalePrice         *float64             `json:"sale_price"`
+	SaleStartsAt      *time.Time           `json:"sale_starts_at"`
+	SaleEndsAt        *time.Time           `json:"sale_ends_at"`
+	CurrentPrice      float64              `json:"current_price"`
+	SequentialModules bool                 `json:"sequential_modules"`
+	CertificatePolicy string               `json:"certificate_policy"`
+	Status            string               `json:"status"`
+	PublishAt         *time.Time           `json:"publish_at"`
+	RatingAverage     float64              `json:"rating_average"`
+	RatingCount       int64                `json:"rating_count"`
+	Instructors       []InstructorResponse `json:"instructors,omitempty"`
+}
+
+// PrerequisiteResponse is a required course and whether the requesting user
+// already holds its certificate.
+type PrerequisiteResponse struct {
+	CourseID  uint   `json:"course_id"`
+	Title     string `json:"title"`
+	Completed bool   `json:"completed"`
+}
+
+type InstructorResponse struct {
+	ID        uint   `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
 }
 
 type ModuleResponse struct {
-	ID           uint      `json:"id"`
-	Title        string    `json:"title"`
-	Description  string    `json:"description"`
-	PDFContent   string    `json:"pdf_content"`
-	VideoContent string    `json:"video_content"`
-	Order        int       `json:"order"`
-	IsCompleted  bool      `json:"is_completed"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID                uint       `json:"id"`
+	Title             string     `json:"title"`
+	Description       string     `json:"description"`
+	PDFContent        string     `json:"pdf_content"`
+	VideoContent      string     `json:"video_content"`
+	VideoStatus       string     `json:"video_status"`
+	VideoHLS          string     `json:"video_hls"`
+	VideoPoster       string     `json:"video_poster"`
+	VideoDuration     float64    `json:"video_duration"`
+	Order             int        `json:"order"`
+	SectionID         *uint      `json:"section_id"`
+	Status            string     `json:"status"`
+	PublishAt         *time.Time `json:"publish_at"`
+	IsCompleted       bool       `json:"is_completed"`
+	HasQuiz           bool       `json:"has_quiz"`
+	HasAssignment     bool       `json:"has_assignment"`
+	PositionSeconds   float64    `json:"position_seconds"`
+	WatchedPercentage float64    `json:"watched_percentage"`
+	IsLocked          bool       `json:"is_locked"`
+	LockReason        string     `json:"lock_reason,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// RevisionAuthor identifies who made a revision; it is nil once the
+// account is deleted.
+type RevisionAuthor struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
+
+type ModuleRevisionResponse struct {
+	Number        int             `json:"number"`
+	Author        *RevisionAuthor `json:"author"`
+	Note          string          `json:"note,omitempty"`
+	Title         string          `json:"title"`
+	Description   string          `json:"description"`
+	PDFContent    string          `json:"pdf_content"`
+	VideoContent  string          `json:"video_content"`
+	ChangedFields []string        `json:"changed_fields"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+type CourseRevisionResponse struct {
+	Number         int             `json:"number"`
+	Author         *RevisionAuthor `json:"author"`
+	Note           string          `json:"note,omitempty"`
+	Title          string          `json:"title"`
+	Description    string          `json:"description"`
+	Instructor     string          `json:"instructor"`
+	Topics         []string        `json:"topics"`
+	Price          float64         `json:"price"`
+	ThumbnailImage string          `json:"thumbnail_image"`
+	ChangedFields  []string        `json:"changed_fields"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// ModuleRevisionDetail adds a diff of the revision's text fields against an
+// earlier revision; Against is 0 when there is nothing to compare with.
+type ModuleRevisionDetail struct {
+	ModuleRevisionResponse
+	Against int                   `json:"against"`
+	Diff    map[string][]DiffLine `json:"diff"`
+}
+
+type CourseRevisionDetail struct {
+	CourseRevisionResponse
+	Against int                   `json:"against"`
+	Diff    map[string][]DiffLine `json:"diff"`
+}
+
+type SectionResponse struct {
+	ID        uint      `json:"id"`
+	CourseID  uint      `json:"course_id"`
+	Title     string    `json:"title"`
+	Order     int       `json:"order"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PriceQuote breaks down what a course costs the user: the list price, the
+// running sale and the coupon, if any, apply in that order.
+type PriceQuote struct {
+	CourseID       uint    `json:"course_id"`
+	ListPrice      float64 `json:"list_price"`
+	SaleDiscount   float64 `json:"sale_discount"`
+	CouponCode     string  `json:"coupon_code,omitempty"`
+	CouponDiscount float64 `json:"coupon_discount"`
+	Amount         float64 `json:"amount"`
+	Coupon         *Coupon `json:"-"`
 }
 
 type BuyCourseResponse struct {
-	CourseID      uint    `json:"course_id"`
-	UserBalance   float64 `json:"user_balance"`
-	TransactionID uint    `json:"transaction_id"`
+	CourseID      uint       `json:"course_id"`
+	UserBalance   float64    `json:"user_balance"`
+	TransactionID uint       `json:"transaction_id"`
+	Price         PriceQuote `json:"price"`
+}
+
+type RefundResponse struct {
+	RefundID    uint    `json:"refund_id"`
+	PurchaseID  uint    `json:"purchase_id"`
+	CourseID    uint    `json:"course_id"`
+	Amount      float64 `json:"amount"`
+	UserBalance float64 `json:"user_balance"`
 }
 
 type MyCoursesResponse struct {
@@ -58,17 +175,224 @@ type MarkModuleResponse struct {
 	CertificateURL *string        `json:"certificate_url"`
 }
 
+// QuizResponse describes a module's quiz to the current user. Questions
+// include the answer key and are only filled for course managers.
+type QuizResponse struct {
+	ID                  uint                  `json:"id"`
+	ModuleID            uint                  `json:"module_id"`
+	Title               string                `json:"title"`
+	PassPercentage      float64               `json:"pass_percentage"`
+	MaxAttempts         int                   `json:"max_attempts"`
+	QuestionsPerAttempt int                   `json:"questions_per_attempt"`
+	TotalQuestions      int                   `json:"total_questions"`
+	Passed              bool                  `json:"passed"`
+	AttemptsRemaining   *int                  `json:"attempts_remaining"`
+	Attempts            []QuizAttemptResponse `json:"attempts"`
+	ActiveAttempt       *QuizAttemptResponse  `json:"active_attempt"`
+	Questions           []QuizQuestion        `json:"questions,omitempty"`
+}
+
+type QuizAttemptResponse struct {
+	ID          uint                   `json:"id"`
+	QuizID      uint                   `json:"quiz_id"`
+	Status      string                 `json:"status"`
+	Score       float64                `json:"score"`
+	MaxScore    float64                `json:"max_score"`
+	Percentage  float64                `json:"percentage"`
+	Passed      bool                   `json:"passed"`
+	StartedAt   time.Time              `json:"started_at"`
+	SubmittedAt *time.Time             `json:"submitted_at"`
+	Questions   []QuizQuestionResponse `json:"questions,omitempty"`
+	Answers     []QuizAnswer           `json:"answers,omitempty"`
+	Completion  *MarkModuleResponse    `json:"completion,omitempty"`
+}
+
+// QuizQuestionResponse is a question as shown to a student taking the quiz,
+// without the answer key.
+type QuizQuestionResponse struct {
+	ID      uint                 `json:"id"`
+	Type    string               `json:"type"`
+	Prompt  string               `json:"prompt"`
+	Points  float64              `json:"points"`
+	Options []QuizOptionResponse `json:"options"`
+}
+
+type QuizOptionResponse struct {
+	ID   uint   `json:"id"`
+	Text string `json:"text"`
+}
+
+type AssignmentResponse struct {
+	ID           uint                 `json:"id"`
+	ModuleID     uint                 `json:"module_id"`
+	Instructions string               `json:"instructions"`
+	MaxScore     float64              `json:"max_score"`
+	PassingScore float64              `json:"passing_score"`
+	AllowText    bool                 `json:"allow_text"`
+	AllowFile    bool                 `json:"allow_file"`
+	Passed       bool                 `json:"passed"`
+	Submissions  []SubmissionResponse `json:"submissions"`
+}
+
+type SubmissionResponse struct {
+	ID           uint       `json:"id"`
+	AssignmentID uint       `json:"assignment_id"`
+	ModuleID     uint       `json:"module_id"`
+	ModuleTitle  string     `json:"module_title,omitempty"`
+	CourseID     uint       `json:"course_id"`
+	CourseTitle  string     `json:"course_title,omitempty"`
+	UserID       uint       `json:"user_id"`
+	Username     string     `json:"username,omitempty"`
+	Text         string     `json:"text"`
+	FileURL      string     `json:"file_url"`
+	Status       string     `json:"status"`
+	Score        *float64   `json:"score"`
+	MaxScore     float64    `json:"max_score"`
+	Feedback     string     `json:"feedback"`
+	Passed       bool       `json:"passed"`
+	GradedAt     *time.Time `json:"graded_at"`
+	SubmittedAt  time.Time  `json:"submitted_at"`
+}
+
+// ReviewResponse carries moderation details only to moderators and the
+// review's author.
+type ReviewResponse struct {
+	ID             uint       `json:"id"`
+	CourseID       uint       `json:"course_id"`
+	CourseTitle    string     `json:"course_title,omitempty"`
+	UserID         uint       `json:"user_id"`
+	Username       string     `json:"username"`
+	Rating         int        `json:"rating"`
+	Body           string     `json:"body"`
+	Status         string     `json:"status"`
+	ModerationNote string     `json:"moderation_note,omitempty"`
+	ModeratedAt    *time.Time `json:"moderated_at,omitempty"`
+	EditedAt       *time.Time `json:"edited_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// Certificate verification statuses: a valid certificate matches its
+// signature and is not revoked; an invalid one no longer matches. A
+// certificate that needs an update is genuine and still valid, but its
+// course gained content the holder has not completed yet.
+const (
+	CertificateValid       = "valid"
+	CertificateRevoked     = "revoked"
+	CertificateInvalid     = "invalid"
+	CertificateNeedsUpdate = "needs_update"
+)
+
+// CertificateResponse is one of a user's certificates with links to its
+// files, which are left empty once it is revoked, and to its public
+// verification page.
+type CertificateResponse struct {
+	CertificateVerificationResponse
+	VerifyURL   string `json:"verify_url"`
+	DownloadURL string `json:"download_url"`
+	ImageURL    string `json:"image_url"`
+	PDFURL      string `json:"pdf_url"`
+}
+
+// CertificateTemplateResponse is a certificate template with links to its
+// image assets.
+type CertificateTemplateResponse struct {
+	CertificateTemplate
+	BackgroundURL string `json:"background_url"`
+	LogoURL       string `json:"logo_url"`
+	SignatureURL  string `json:"signature_url"`
+}
+
+// CertificateVerificationResponse is what the public verification page shows
+// about a certificate.
+type CertificateVerificationResponse struct {
+	ID               uint       `json:"id"`
+	Serial           string     `json:"serial"`
+	Status           string     `json:"status"`
+	Valid            bool       `json:"valid"`
+	HolderName       string     `json:"holder_name"`
+	CourseID         uint       `json:"course_id"`
+	CourseTitle      string     `json:"course_title"`
+	Instructor       string     `json:"instructor"`
+	IssuedAt         time.Time  `json:"issued_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	RevocationReason string     `json:"revocation_reason,omitempty"`
+	OutdatedAt       *time.Time `json:"outdated_at,omitempty"`
+}
+
+type DiscussionPostResponse struct {
+	ID                 uint       `json:"id"`
+	ModuleID           uint       `json:"module_id"`
+	ParentID           *uint      `json:"parent_id"`
+	UserID             uint       `json:"user_id"`
+	Username           string     `json:"username"`
+	AuthorIsInstructor bool       `json:"author_is_instructor"`
+	Title              string     `json:"title,omitempty"`
+	Body               string     `json:"body"`
+	UpvoteCount        int64      `json:"upvote_count"`
+	Upvoted            bool       `json:"upvoted"`
+	ReplyCount         int64      `json:"reply_count"`
+	IsAnswer           bool       `json:"is_answer"`
+	AnsweredAt         *time.Time `json:"answered_at"`
+	EditedAt           *time.Time `json:"edited_at"`
+	LastActivityAt     time.Time  `json:"last_activity_at"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// DiscussionThreadDetail is a thread's opening post with a page of its
+// replies, answers first.
+type DiscussionThreadDetail struct {
+	DiscussionPostResponse
+	Replies []DiscussionPostResponse `json:"replies"`
+}
+
+type WatchProgressResponse struct {
+	ModuleID          uint            `json:"module_id"`
+	PositionSeconds   float64         `json:"position_seconds"`
+	WatchedSeconds    float64         `json:"watched_seconds"`
+	WatchedPercentage float64         `json:"watched_percentage"`
+	IsCompleted       bool            `json:"is_completed"`
+	AutoCompleted     bool            `json:"auto_completed"`
+	CourseProgress    *CourseProgress `json:"course_progress,omitempty"`
+	CertificateURL    *string         `json:"certificate_url,omitempty"`
+}
+
 type UsersResponse struct {
 	ID        string  `json:"id"`
 	Username  string  `json:"username"`
 	Email     string  `json:"email"`
 	FirstName string  `json:"first_name"`
 	LastName  string  `json:"last_name"`
+	Role      string  `json:"role"`
 	Balance   float64 `json:"balance"`
 }
 
+type RoleResponse struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
+
 type PostUserBalanceResponse struct {
 	ID       string  `json:"id"`
 	Username string  `json:"username"`
 	Balance  float64 `json:"balance"`
 }
+
+type BalanceTransactionResponse struct {
+	ID           uint      `json:"id"`
+	Type         string    `json:"type"`
+	Amount       float64   `json:"amount"`
+	BalanceAfter float64   `json:"balance_after"`
+	PurchaseID   *uint     `json:"purchase_id"`
+	Description  string    `json:"description"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
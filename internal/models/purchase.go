@@ -10,6 +10,19 @@ type Purchase struct {
 	CourseID  uint    `json:"course_id" gorm:"not null;index"`
 	Amount    float64 `json:"amount" gorm:"type:numeric(10,2);not null"`
 
-	User   User   `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	Course Course `gorm:"foreignKey:CourseID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	// ListPrice is the course price at the time of purchase. Amount is what
+	// was paid after the sale and coupon discounts.
+	ListPrice      float64 `json:"list_price" gorm:"type:numeric(10,2);not null;default:0"`
+	SaleDiscount   float64 `json:"sale_discount" gorm:"type:numeric(10,2);not null;default:0"`
+	CouponDiscount float64 `json:"coupon_discount" gorm:"type:numeric(10,2);not null;default:0"`
+	CouponID       *uint   `json:"coupon_id" gorm:"index"`
+	// CouponCode is kept for reporting after the coupon is deleted.
+	CouponCode string `json:"coupon_code" gorm:"size:50;not null;default:''"`
+	// RefundedAt is set when the purchase is refunded. The row is kept for
+	// the payment history, but no longer grants access to the course.
+	RefundedAt *time.Time `json:"refunded_at" gorm:"index"`
+
+	User   User    `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Course Course  `gorm:"foreignKey:CourseID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Coupon *Coupon `json:"-" gorm:"foreignKey:CouponID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
 }
@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+const (
+	QuestionSingleChoice   = "single_choice"
+	QuestionMultipleChoice = "multiple_choice"
+	QuestionTrueFalse      = "true_false"
+	QuestionShortAnswer    = "short_answer"
+)
+
+const (
+	AttemptInProgress = "in_progress"
+	AttemptSubmitted  = "submitted"
+)
+
+// Quiz is the graded assessment attached to a module. Each attempt draws
+// QuestionsPerAttempt questions from the bank, or all of them when zero.
+type Quiz struct {
+	ID                  uint `gorm:"primaryKey"`
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	ModuleID            uint           `json:"module_id" gorm:"not null;uniqueIndex"`
+	Title               string         `json:"title" gorm:"size:200;not null"`
+	PassPercentage      float64        `json:"pass_percentage" gorm:"not null;default:70"`
+	MaxAttempts         int            `json:"max_attempts" gorm:"not null;default:0"`
+	QuestionsPerAttempt int            `json:"questions_per_attempt" gorm:"not null;default:0"`
+	Questions           []QuizQuestion `json:"questions" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+
+	Module Module `json:"-" gorm:"foreignKey:ModuleID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+type QuizQuestion struct {
+	ID      uint         `json:"id" gorm:"primaryKey"`
+	QuizID  uint         `json:"quiz_id" gorm:"not null;index"`
+	Type    string       `json:"type" gorm:"size:20;not null"`
+	Prompt  string       `json:"prompt" gorm:"type:text;not null"`
+	Points  float64      `json:"points" gorm:"not null;default:1"`
+	Order   int          `json:"order" gorm:"not null"`
+	Options []QuizOption `json:"options" gorm:"foreignKey:QuestionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+// QuizOption is a choice of a choice question. For short answer questions the
+// options hold the accepted answers and are never shown to students.
+type QuizOption struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	QuestionID uint   `json:"question_id" gorm:"not null;index"`
+	Text       string `json:"text" gorm:"type:text;not null"`
+	IsCorrect  bool   `json:"is_correct" gorm:"not null;default:false"`
+	Order      int    `json:"order" gorm:"not null"`
+}
+
+type QuizAttempt struct {
+	ID          uint `gorm:"primaryKey"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	QuizID      uint       `json:"quiz_id" gorm:"not null;index:idx_quiz_attempt_user"`
+	UserID      uint       `json:"user_id" gorm:"not null;index:idx_quiz_attempt_user"`
+	Status      string     `json:"status" gorm:"size:20;not null;default:'in_progress'"`
+	QuestionIDs []uint     `json:"question_ids" gorm:"serializer:json;type:text;not null"`
+	Score       float64    `json:"score" gorm:"not null;default:0"`
+	MaxScore    float64    `json:"max_score" gorm:"not null;default:0"`
+	Percentage  float64    `json:"percentage" gorm:"not null;default:0"`
+	Passed      bool       `json:"passed" gorm:"not null;default:false"`
+	SubmittedAt *time.Time `json:"submitted_at"`
+
+	Answers []QuizAnswer `json:"answers,omitempty" gorm:"foreignKey:AttemptID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Quiz    Quiz         `json:"-" gorm:"foreignKey:QuizID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	User    User         `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+// QuizAnswer stores a graded answer. QuestionID is kept without a foreign
+// key so attempt history survives edits to the question bank.
+type QuizAnswer struct {
+	ID         uint    `json:"id" gorm:"primaryKey"`
+	AttemptID  uint    `json:"attempt_id" gorm:"not null;index"`
+	QuestionID uint    `json:"question_id" gorm:"not null"`
+	OptionIDs  []uint  `json:"option_ids" gorm:"serializer:json;type:text"`
+	Text       string  `json:"text" gorm:"type:text"`
+	IsCorrect  bool    `json:"is_correct" gorm:"not null;default:false"`
+	Points     float64 `json:"points" gorm:"not null;default:0"`
+}
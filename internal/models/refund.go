@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+type Refund struct {
+	ID         uint `gorm:"primaryKey"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uint    `json:"user_id" gorm:"not null;index"`
+	CourseID   uint    `json:"course_id" gorm:"not null;index"`
+	PurchaseID uint    `json:"purchase_id" gorm:"not null;uniqueIndex"`
+	Amount     float64 `json:"amount" gorm:"type:numeric(10,2);not null"`
+	Reason     string  `json:"reason" gorm:"size:255"`
+	RefundedBy uint    `json:"refunded_by" gorm:"not null"`
+
+	User   User   `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Course Course `gorm:"foreignKey:CourseID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+type RefundEligibility struct {
+	Eligible bool      `json:"eligible"`
+	Reason   string    `json:"reason,omitempty"`
+	Deadline time.Time `json:"deadline"`
+}
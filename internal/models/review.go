@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Moderation states of a review. Flagged reviews stay public while an admin
+// looks into them; hidden reviews are left out of listings and the rating.
+const (
+	ReviewVisible = "visible"
+	ReviewFlagged = "flagged"
+	ReviewHidden  = "hidden"
+)
+
+// Review is a purchaser's rating of a course, one per user per course.
+type Review struct {
+	ID             uint `gorm:"primaryKey"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	CourseID       uint       `json:"course_id" gorm:"not null;uniqueIndex:idx_review_course_user"`
+	UserID         uint       `json:"user_id" gorm:"not null;uniqueIndex:idx_review_course_user;index"`
+	Rating         int        `json:"rating" gorm:"not null;check:chk_reviews_rating,rating BETWEEN 1 AND 5"`
+	Body           string     `json:"body" gorm:"type:text;not null;default:''"`
+	Status         string     `json:"status" gorm:"size:20;not null;default:'visible';index"`
+	EditedAt       *time.Time `json:"edited_at"`
+	ModerationNote string     `json:"moderation_note" gorm:"type:text"`
+	ModeratedBy    *uint      `json:"moderated_by"`
+	ModeratedAt    *time.Time `json:"moderated_at"`
+
+	Course Course `json:"-" gorm:"foreignKey:CourseID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	User   User   `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ModuleRevision is a snapshot of a module's content, taken whenever the
+// module is created, edited or restored. File fields hold storage keys; the
+// files are kept as long as the module exists so any revision can be
+// restored.
+type ModuleRevision struct {
+	ID           uint `gorm:"primaryKey"`
+	CreatedAt    time.Time
+	ModuleID     uint   `json:"module_id" gorm:"not null;uniqueIndex:idx_module_revision"`
+	Number       int    `json:"number" gorm:"not null;uniqueIndex:idx_module_revision"`
+	AuthorID     *uint  `json:"author_id" gorm:"index"`
+	Note         string `json:"note" gorm:"size:200"`
+	Title        string `json:"title" gorm:"size:200;not null"`
+	Description  string `json:"description" gorm:"type:text;not null"`
+	PDFContent   string `json:"pdf_content" gorm:"size:255"`
+	VideoContent string `json:"video_content" gorm:"size:255"`
+
+	Module Module `json:"-" gorm:"foreignKey:ModuleID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Author *User  `json:"-" gorm:"foreignKey:AuthorID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+}
+
+// CourseRevision is a snapshot of a course's catalog details, taken whenever
+// the course is created, edited or restored.
+type CourseRevision struct {
+	ID             uint `gorm:"primaryKey"`
+	CreatedAt      time.Time
+	CourseID       uint           `json:"course_id" gorm:"not null;uniqueIndex:idx_course_revision"`
+	Number         int            `json:"number" gorm:"not null;uniqueIndex:idx_course_revision"`
+	AuthorID       *uint          `json:"author_id" gorm:"index"`
+	Note           string         `json:"note" gorm:"size:200"`
+	Title          string         `json:"title" gorm:"size:200;not null"`
+	Description    string         `json:"description" gorm:"type:text;not null"`
+	Instructor     string         `json:"instructor" gorm:"size:100;not null"`
+	Topics         pq.StringArray `json:"topics" gorm:"type:text[];not null;default:'{}'"`
+	Price          float64        `json:"price" gorm:"type:numeric(10,2);not null"`
+	ThumbnailImage string         `json:"thumbnail_image" gorm:"size:255"`
+
+	Course Course `json:"-" gorm:"foreignKey:CourseID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Author *User  `json:"-" gorm:"foreignKey:AuthorID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+}
+
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// DiffLine is one line of a line-based diff between two revisions.
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
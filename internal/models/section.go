@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Section groups consecutive modules of a course into a chapter.
+type Section struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	CourseID  uint   `json:"course_id" gorm:"not null;index;uniqueIndex:idx_section_course_order"`
+	Title     string `json:"title" gorm:"size:200;not null"`
+	Order     int    `json:"order" gorm:"not null;uniqueIndex:idx_section_course_order"`
+
+	Course Course `json:"-" gorm:"foreignKey:CourseID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+type SectionOrder struct {
+	ID    uint
+	Order int
+}
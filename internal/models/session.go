@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Session is a logged-in device. Access tokens carry the session ID in their
+// "sid" claim and are rejected once the session is revoked or expires.
+type Session struct {
+	ID               uint `gorm:"primaryKey"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	UserID           uint       `json:"user_id" gorm:"not null;index"`
+	RefreshTokenHash string     `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	UserAgent        string     `json:"user_agent" gorm:"size:255"`
+	IPAddress        string     `json:"ip_address" gorm:"size:64"`
+	ExpiresAt        time.Time  `json:"expires_at" gorm:"not null"`
+	LastUsedAt       time.Time  `json:"last_used_at" gorm:"not null"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+type SessionMeta struct {
+	UserAgent string
+	IPAddress string
+}
+
+type AuthTokens struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+	SessionID        uint
+}
@@ -13,4 +13,6 @@ type User struct {
 	Password  string  `json:"-" gorm:"not null"`
 	Role      string  `json:"role" gorm:"size:20;not null;default:'student'"`
 	Balance   float64 `json:"balance" gorm:"type:decimal(10,2);default:0"`
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
 }
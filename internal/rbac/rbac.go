@@ -0,0 +1,87 @@
+package rbac
+
+import "slices"
+
+const (
+	RoleStudent           = "student"
+	RoleTeachingAssistant = "teaching_assistant"
+	RoleInstructor        = "instructor"
+	RoleAdmin             = "admin"
+)
+
+type Permission string
+
+const (
+	PermCourseCreate       Permission = "course:create"
+	PermCourseEdit         Permission = "course:edit"
+	PermCourseDelete       Permission = "course:delete"
+	PermCourseManageAny    Permission = "course:manage:any"
+	PermModuleEdit         Permission = "module:edit"
+	PermSubmissionGrade    Permission = "submission:grade"
+	PermDiscussionModerate Permission = "discussion:moderate"
+	PermPurchaseRefund     Permission = "purchase:refund"
+	PermReviewModerate     Permission = "review:moderate"
+	PermCertificateRevoke  Permission = "certificate:revoke"
+	PermCertificateDesign  Permission = "certificate:design"
+	PermCertificateManage  Permission = "certificate:manage"
+	PermCouponManage       Permission = "coupon:manage"
+	PermUserRead           Permission = "user:read"
+	PermUserEdit           Permission = "user:edit"
+	PermUserDelete         Permission = "user:delete"
+	PermUserBalanceWrite   Permission = "user:balance:write"
+	PermUserRoleWrite      Permission = "user:role:write"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleStudent: {},
+	// Teaching assistants only act on the courses they are assigned to.
+	RoleTeachingAssistant: {
+		PermSubmissionGrade,
+		PermDiscussionModerate,
+	},
+	RoleInstructor: {
+		PermCourseCreate,
+		PermCourseEdit,
+		PermModuleEdit,
+		PermSubmissionGrade,
+		PermDiscussionModerate,
+	},
+	RoleAdmin: {
+		PermCourseCreate,
+		PermCourseEdit,
+		PermCourseDelete,
+		PermCourseManageAny,
+		PermModuleEdit,
+		PermSubmissionGrade,
+		PermDiscussionModerate,
+		PermPurchaseRefund,
+		PermReviewModerate,
+		PermCertificateRevoke,
+		PermCertificateDesign,
+		PermCertificateManage,
+		PermCouponManage,
+		PermUserRead,
+		PermUserEdit,
+		PermUserDelete,
+		PermUserBalanceWrite,
+		PermUserRoleWrite,
+	},
+}
+
+// Roles lists every assignable role, from least to most privileged.
+func Roles() []string {
+	return []string{RoleStudent, RoleTeachingAssistant, RoleInstructor, RoleAdmin}
+}
+
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func PermissionsFor(role string) []Permission {
+	return rolePermissions[role]
+}
+
+func HasPermission(role string, p Permission) bool {
+	return slices.Contains(rolePermissions[role], p)
+}
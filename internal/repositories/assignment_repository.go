@@ -0,0 +1,262 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/kin-ark/GroAcademy/internal/database"
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSubmissionGraded  = errors.New("submission has already been graded")
+	ErrSubmissionChanged = errors.New("submission was changed while it was being graded; review it again")
+)
+
+type AssignmentRepository interface {
+	FindByModule(moduleID uint) (*models.Assignment, error)
+	Save(assignment *models.Assignment) error
+	DeleteByModule(moduleID uint) error
+	ModulesWithAssignment(moduleIDs []uint) (map[uint]bool, error)
+	PassedModuleAssignment(moduleID uint, userID uint) (bool, error)
+	FindSubmissions(assignmentID uint, userID uint) ([]models.AssignmentSubmission, error)
+	FindPendingSubmission(assignmentID uint, userID uint) (*models.AssignmentSubmission, error)
+	CreateSubmission(submission *models.AssignmentSubmission) error
+	UpdateSubmissionContent(submission *models.AssignmentSubmission) error
+	FindSubmission(id uint) (*models.AssignmentSubmission, error)
+	GradeSubmission(submission *models.AssignmentSubmission) error
+	ListSubmissions(q models.SubmissionQueueQuery, graderID uint, manageAny bool) ([]models.SubmissionResponse, int64, error)
+}
+
+type assignmentRepository struct {
+	db *gorm.DB
+}
+
+func NewAssignmentRepository() AssignmentRepository {
+	return &assignmentRepository{db: database.DB}
+}
+
+func (r *assignmentRepository) FindByModule(moduleID uint) (*models.Assignment, error) {
+	var assignment models.Assignment
+	if err := r.db.Where("module_id = ?", moduleID).First(&assignment).Error; err != nil {
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+// Save creates the module's assignment or updates the existing one.
+func (r *assignmentRepository) Save(assignment *models.Assignment) error {
+	var existing models.Assignment
+	err := r.db.Where("module_id = ?", assignment.ModuleID).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return r.db.Create(assignment).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	assignment.ID = existing.ID
+	assignment.CreatedAt = existing.CreatedAt
+	return r.db.Model(&existing).Updates(map[string]any{
+		"instructions":  assignment.Instructions,
+		"max_score":     assignment.MaxScore,
+		"passing_score": assignment.PassingScore,
+		"allow_text":    assignment.AllowText,
+		"allow_file":    assignment.AllowFile,
+	}).Error
+}
+
+func (r *assignmentRepository) DeleteByModule(moduleID uint) error {
+	res := r.db.Where("module_id = ?", moduleID).Delete(&models.Assignment{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *assignmentRepository) ModulesWithAssignment(moduleIDs []uint) (map[uint]bool, error) {
+	res := make(map[uint]bool)
+	if len(moduleIDs) == 0 {
+		return res, nil
+	}
+
+	var ids []uint
+	if err := r.db.Model(&models.Assignment{}).
+		Where("module_id IN ?", moduleIDs).
+		Pluck("module_id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		res[id] = true
+	}
+	return res, nil
+}
+
+// PassedModuleAssignment reports whether the user has a passing submission
+// for the module's assignment. Modules without an assignment count as passed.
+func (r *assignmentRepository) PassedModuleAssignment(moduleID uint, userID uint) (bool, error) {
+	var assignment models.Assignment
+	err := r.db.Select("id").Where("module_id = ?", moduleID).First(&assignment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var count int64
+	err = r.db.Model(&models.AssignmentSubmission{}).
+		Where("assignment_id = ? AND user_id = ? AND passed = TRUE", assignment.ID, userID).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *assignmentRepository) FindSubmissions(assignmentID uint, userID uint) ([]models.AssignmentSubmission, error) {
+	var submissions []models.AssignmentSubmission
+	err := r.db.
+		Where("assignment_id = ? AND user_id = ?", assignmentID, userID).
+		Order("created_at DESC").
+		Find(&submissions).Error
+	return submissions, err
+}
+
+// FindPendingSubmission returns the user's submission that still awaits
+// grading, or nil if there is none.
+func (r *assignmentRepository) FindPendingSubmission(assignmentID uint, userID uint) (*models.AssignmentSubmission, error) {
+	var submission models.AssignmentSubmission
+	err := r.db.
+		Where("assignment_id = ? AND user_id = ? AND status = ?", assignmentID, userID, models.SubmissionPending).
+		First(&submission).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+func (r *assignmentRepository) CreateSubmission(submission *models.AssignmentSubmission) error {
+	submission.Status = models.SubmissionPending
+	return r.db.Create(submission).Error
+}
+
+// UpdateSubmissionContent replaces the text and file of a submission that
+// has not been graded yet.
+func (r *assignmentRepository) UpdateSubmissionContent(submission *models.AssignmentSubmission) error {
+	res := r.db.Model(&models.AssignmentSubmission{}).
+		Where("id = ? AND status = ?", submission.ID, models.SubmissionPending).
+		Updates(map[string]any{
+			"text":     submission.Text,
+			"file_key": submission.FileKey,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrSubmissionGraded
+	}
+	return nil
+}
+
+func (r *assignmentRepository) FindSubmission(id uint) (*models.AssignmentSubmission, error) {
+	var submission models.AssignmentSubmission
+	if err := r.db.Preload("Assignment").Preload("Assignment.Module").Preload("User").
+		First(&submission, id).Error; err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+// GradeSubmission stores the grade of a pending submission, as long as its
+// text and file are still the ones the grader loaded. A submission that was
+// graded meanwhile fails with ErrSubmissionGraded, one that was replaced with
+// ErrSubmissionChanged.
+func (r *assignmentRepository) GradeSubmission(submission *models.AssignmentSubmission) error {
+	res := r.db.Model(&models.AssignmentSubmission{}).
+		Where("id = ? AND status = ? AND text = ? AND file_key = ?",
+			submission.ID, models.SubmissionPending, submission.Text, submission.FileKey).
+		Updates(map[string]any{
+			"status":    submission.Status,
+			"score":     submission.Score,
+			"feedback":  submission.Feedback,
+			"passed":    submission.Passed,
+			"graded_by": submission.GradedBy,
+			"graded_at": submission.GradedAt,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		return nil
+	}
+
+	var current models.AssignmentSubmission
+	if err := r.db.Select("status").First(&current, submission.ID).Error; err != nil {
+		return err
+	}
+	if current.Status != models.SubmissionPending {
+		return ErrSubmissionGraded
+	}
+	return ErrSubmissionChanged
+}
+
+// ListSubmissions returns the grading queue, oldest first. Graders without
+// manageAny only see submissions for courses they teach.
+func (r *assignmentRepository) ListSubmissions(q models.SubmissionQueueQuery, graderID uint, manageAny bool) ([]models.SubmissionResponse, int64, error) {
+	var submissions []models.SubmissionResponse
+	var totalItems int64
+
+	base := r.db.Table("assignment_submissions").
+		Joins("JOIN assignments ON assignments.id = assignment_submissions.assignment_id").
+		Joins("JOIN modules ON modules.id = assignments.module_id").
+		Joins("JOIN courses ON courses.id = modules.course_id").
+		Joins("JOIN users ON users.id = assignment_submissions.user_id")
+
+	if !manageAny {
+		base = base.Where("(courses.id IN (?) OR courses.id IN (?))",
+			r.db.Table("course_instructors").Select("course_id").Where("user_id = ?", graderID),
+			r.db.Table("course_assistants").Select("course_id").Where("user_id = ?", graderID))
+	}
+	if q.CourseID != 0 {
+		base = base.Where("courses.id = ?", q.CourseID)
+	}
+	switch q.Status {
+	case "all":
+	case models.SubmissionGraded:
+		base = base.Where("assignment_submissions.status = ?", models.SubmissionGraded)
+	default:
+		base = base.Where("assignment_submissions.status = ?", models.SubmissionPending)
+	}
+
+	if err := base.Count(&totalItems).Error; err != nil {
+		return nil, 0, err
+	}
+
+	q.Page = q.Paginate(totalItems).CurrentPage
+
+	err := base.Select(`assignment_submissions.id, assignment_submissions.assignment_id,
+			assignments.module_id, modules.title AS module_title,
+			courses.id AS course_id, courses.title AS course_title,
+			assignment_submissions.user_id, users.username,
+			assignment_submissions.text, assignment_submissions.file_key AS file_url,
+			assignment_submissions.status, assignment_submissions.score, assignments.max_score,
+			assignment_submissions.feedback, assignment_submissions.passed,
+			assignment_submissions.graded_at, assignment_submissions.created_at AS submitted_at`).
+		Order("assignment_submissions.created_at ASC").
+		Limit(q.Limit).
+		Offset((q.Page - 1) * q.Limit).
+		Scan(&submissions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return submissions, totalItems, nil
+}
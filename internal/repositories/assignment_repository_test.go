@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+)
+
+func TestGradeSubmissionOnlyGradesWhatWasReviewed(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		want    error
+	}{
+		{name: "graded meanwhile", current: models.SubmissionGraded, want: ErrSubmissionGraded},
+		{name: "file replaced meanwhile", current: models.SubmissionPending, want: ErrSubmissionChanged},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, fake := newFakeDB(t)
+			fake.onExec(`UPDATE "assignment_submissions"`, 0)
+			fake.onQuery(`FROM "assignment_submissions"`, []string{"status"}, []driver.Value{tt.current})
+			repo := &assignmentRepository{db: db}
+
+			submission := &models.AssignmentSubmission{ID: 3, Status: models.SubmissionGraded, FileKey: "submissions/a.pdf"}
+			if err := repo.GradeSubmission(submission); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+
+			update := fake.find(`UPDATE "assignment_submissions"`)[0]
+			if !strings.Contains(update.SQL, "status = $") || !strings.Contains(update.SQL, "file_key = $") {
+				t.Errorf("update = %s, want it guarded by status and file", update.SQL)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"github.com/kin-ark/GroAcademy/internal/database"
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"gorm.io/gorm"
+)
+
+type CertificateRepository interface {
+	FindByID(id uint) (*models.Certificate, error)
+	FindBySerial(serial string) (*models.Certificate, error)
+	UpdateRevocation(cert *models.Certificate) error
+	FindByCourse(courseID uint) ([]models.Certificate, error)
+	FindByUser(userID uint, q models.PaginationQuery) ([]models.Certificate, int64, error)
+	UpdateFiles(cert *models.Certificate) error
+}
+
+type certificateRepository struct {
+	db *gorm.DB
+}
+
+func NewCertificateRepository() CertificateRepository {
+	return &certificateRepository{db: database.DB}
+}
+
+func (r *certificateRepository) FindByID(id uint) (*models.Certificate, error) {
+	var cert models.Certificate
+	if err := r.db.Preload("User").Preload("Course").First(&cert, id).Error; err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+func (r *certificateRepository) FindBySerial(serial string) (*models.Certificate, error) {
+	var cert models.Certificate
+	err := r.db.Preload("User").Preload("Course").
+		Where("serial = ?", serial).
+		First(&cert).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// UpdateRevocation saves whether the certificate is revoked, by whom and why,
+// and whether it is outdated.
+func (r *certificateRepository) UpdateRevocation(cert *models.Certificate) error {
+	return r.db.Model(&models.Certificate{}).Where("id = ?", cert.ID).
+		Updates(map[string]any{
+			"revoked_at":        cert.RevokedAt,
+			"revoked_by":        cert.RevokedBy,
+			"revocation_reason": cert.RevocationReason,
+			"outdated_at":       cert.OutdatedAt,
+		}).Error
+}
+
+// FindByCourse returns every certificate issued for the course, with the
+// holder and course loaded for rendering.
+func (r *certificateRepository) FindByCourse(courseID uint) ([]models.Certificate, error) {
+	var certs []models.Certificate
+	err := r.db.Preload("User").Preload("Course").
+		Where("course_id = ?", courseID).
+		Order("id").
+		Find(&certs).Error
+	return certs, err
+}
+
+// FindByUser returns a page of the user's certificates, newest first.
+func (r *certificateRepository) FindByUser(userID uint, q models.PaginationQuery) ([]models.Certificate, int64, error) {
+	var certs []models.Certificate
+	var totalItems int64
+
+	base := r.db.Model(&models.Certificate{}).Where("user_id = ?", userID)
+	if err := base.Count(&totalItems).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := q.Paginate(totalItems).CurrentPage
+	err := base.Preload("User").Preload("Course").
+		Order("issued_at DESC, id DESC").
+		Offset((page - 1) * q.Limit).
+		Limit(q.Limit).
+		Find(&certs).Error
+	return certs, totalItems, err
+}
+
+// UpdateFiles saves the storage keys of a re-rendered certificate.
+func (r *certificateRepository) UpdateFiles(cert *models.Certificate) error {
+	return r.db.Model(&models.Certificate{}).Where("id = ?", cert.ID).
+		Updates(map[string]any{"file_key": cert.FileKey, "pdf_key": cert.PDFKey}).Error
+}
@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/kin-ark/GroAcademy/internal/database"
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"gorm.io/gorm"
+)
+
+type CertificateTemplateRepository interface {
+	FindAll() ([]models.CertificateTemplate, error)
+	FindByID(id uint) (*models.CertificateTemplate, error)
+	FindDefault() (*models.CertificateTemplate, error)
+	Create(tpl *models.CertificateTemplate) error
+	Update(tpl *models.CertificateTemplate) error
+	Delete(id uint) error
+	FindCourseIDsWithCertificates(templateID *uint) ([]uint, error)
+}
+
+type certificateTemplateRepository struct {
+	db *gorm.DB
+}
+
+func NewCertificateTemplateRepository() CertificateTemplateRepository {
+	return &certificateTemplateRepository{db: database.DB}
+}
+
+func (r *certificateTemplateRepository) FindAll() ([]models.CertificateTemplate, error) {
+	var templates []models.CertificateTemplate
+	err := r.db.Order("is_default DESC, name ASC").Find(&templates).Error
+	return templates, err
+}
+
+func (r *certificateTemplateRepository) FindByID(id uint) (*models.CertificateTemplate, error) {
+	var tpl models.CertificateTemplate
+	if err := r.db.First(&tpl, id).Error; err != nil {
+		return nil, err
+	}
+	return &tpl, nil
+}
+
+// FindDefault returns the template marked as default, or nil if there is none.
+func (r *certificateTemplateRepository) FindDefault() (*models.CertificateTemplate, error) {
+	var tpl models.CertificateTemplate
+	err := r.db.Where("is_default = ?", true).First(&tpl).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &tpl, nil
+}
+
+// Create saves a new template. Only one template is the default, so making
+// this one the default clears the flag on the others.
+func (r *certificateTemplateRepository) Create(tpl *models.CertificateTemplate) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := clearDefaultTemplate(tx, tpl); err != nil {
+			return err
+		}
+		return tx.Create(tpl).Error
+	})
+}
+
+func (r *certificateTemplateRepository) Update(tpl *models.CertificateTemplate) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := clearDefaultTemplate(tx, tpl); err != nil {
+			return err
+		}
+		return tx.Model(&models.CertificateTemplate{}).
+			Where("id = ?", tpl.ID).
+			Select("*").
+			Omit("id", "created_at").
+			Updates(tpl).Error
+	})
+}
+
+func clearDefaultTemplate(tx *gorm.DB, tpl *models.CertificateTemplate) error {
+	if !tpl.IsDefault {
+		return nil
+	}
+	return tx.Model(&models.CertificateTemplate{}).
+		Where("is_default = ? AND id <> ?", true, tpl.ID).
+		Update("is_default", false).Error
+}
+
+// Delete removes a template; courses using it fall back to the default.
+func (r *certificateTemplateRepository) Delete(id uint) error {
+	res := r.db.Delete(&models.CertificateTemplate{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FindCourseIDsWithCertificates lists the courses that use the template and
+// have issued certificates. A nil templateID matches courses without a
+// template of their own, which use the default.
+func (r *certificateTemplateRepository) FindCourseIDsWithCertificates(templateID *uint) ([]uint, error) {
+	query := r.db.Model(&models.Course{}).
+		Where("EXISTS (SELECT 1 FROM certificates WHERE certificates.course_id = courses.id)")
+	if templateID == nil {
+		query = query.Where("certificate_template_id IS NULL")
+	} else {
+		query = query.Where("certificate_template_id = ?", *templateID)
+	}
+
+	var ids []uint
+	err := query.Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/kin-ark/GroAcademy/internal/database"
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"gorm.io/gorm"
+)
+
+// Errors reported when a coupon cannot be used for a purchase.
+var (
+	ErrUnknownCoupon       = errors.New("coupon code is not valid")
+	ErrCouponInactive      = errors.New("coupon is not active or has expired")
+	ErrCouponNotApplicable = errors.New("coupon does not apply to this course")
+	ErrCouponUsedUp        = errors.New("coupon has reached its usage limit")
+	ErrCouponUserLimit     = errors.New("you have already used this coupon")
+)
+
+type CouponRepository interface {
+	FindAll(q models.PaginationQuery) ([]models.CouponWithUses, int64, error)
+	FindByID(id uint) (*models.CouponWithUses, error)
+	FindByCode(code string) (*models.Coupon, error)
+	Create(coupon *models.Coupon) error
+	Update(coupon *models.Coupon) error
+	Delete(id uint) error
+}
+
+type couponRepository struct {
+	db *gorm.DB
+}
+
+func NewCouponRepository() CouponRepository {
+	return &couponRepository{db: database.DB}
+}
+
+// couponUsesSQL counts the purchases made with a coupon.
+const couponUsesSQL = "(SELECT COUNT(*) FROM purchases WHERE purchases.coupon_id = coupons.id) AS uses"
+
+func (r *couponRepository) FindAll(q models.PaginationQuery) ([]models.CouponWithUses, int64, error) {
+	var coupons []models.CouponWithUses
+	var totalItems int64
+
+	base := r.db.Model(&models.Coupon{})
+	if err := base.Count(&totalItems).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := q.Paginate(totalItems).CurrentPage
+	err := base.Select("coupons.*, " + couponUsesSQL).
+		Order("coupons.created_at DESC, coupons.id DESC").
+		Offset((page - 1) * q.Limit).
+		Limit(q.Limit).
+		Scan(&coupons).Error
+	return coupons, totalItems, err
+}
+
+func (r *couponRepository) FindByID(id uint) (*models.CouponWithUses, error) {
+	var coupon models.CouponWithUses
+	res := r.db.Model(&models.Coupon{}).
+		Select("coupons.*, "+couponUsesSQL).
+		Where("coupons.id = ?", id).
+		Limit(1).
+		Scan(&coupon)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &coupon, nil
+}
+
+// FindByCode returns the coupon with the code, or nil if there is none.
+// Codes are stored in upper case.
+func (r *couponRepository) FindByCode(code string) (*models.Coupon, error) {
+	var coupon models.Coupon
+	err := r.db.Where("code = ?", code).First(&coupon).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (r *couponRepository) Create(coupon *models.Coupon) error {
+	return r.db.Create(coupon).Error
+}
+
+func (r *couponRepository) Update(coupon *models.Coupon) error {
+	return r.db.Model(&models.Coupon{}).
+		Where("id = ?", coupon.ID).
+		Select("*").
+		Omit("id", "created_at").
+		Updates(coupon).Error
+}
+
+// Delete removes a coupon. Purchases made with it keep its code.
+func (r *couponRepository) Delete(id uint) error {
+	res := r.db.Delete(&models.Coupon{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -1,29 +1,63 @@
 package repositories
 
 import (
+	"errors"
 	"math"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/kin-ark/GroAcademy/internal/database"
 	"github.com/kin-ark/GroAcademy/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrAlreadyPurchased    = errors.New("course already purchased")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrPurchaseNotFound    = errors.New("purchase not found")
+	ErrPurchaseRefunded    = errors.New("purchase has already been refunded")
 )
 
 type CourseRepository interface {
-	Create(course *models.Course) error
+	Create(course *models.Course, instructors []models.User) error
 	Update(course *models.Course) error
 	Delete(course *models.Course) error
 	FindById(id uint) (*models.Course, error)
-	GetAllCourses(query models.SearchQuery) ([]models.CourseWithModulesCount, int64, error)
+	GetAllCourses(query models.CourseSearchQuery) ([]models.CourseWithModulesCount, int64, error)
+	GetCourseFacets(query models.CourseSearchQuery) (*models.CourseFacets, error)
 	FindModulesByCourseID(id uint) ([]models.Module, int64, error)
 	FindModulesByCourseIDPaginated(id uint, q models.PaginationQuery) ([]models.Module, int64, error)
 	HasPurchasedCourse(courseId uint, userId uint) (bool, error)
-	FindModulesWithProgressPaginated(courseID, userID uint, q models.PaginationQuery) ([]models.ModuleWithIsCompleted, int64, error)
-	BuyCourse(user *models.User, course *models.Course) (*models.Purchase, error)
+	FindModulesWithProgressPaginated(courseID, userID uint, q models.PaginationQuery, publishedOnly bool) ([]models.ModuleWithIsCompleted, int64, error)
+	BuyCourse(user *models.User, course *models.Course, quote models.PriceQuote) (*models.Purchase, error)
 	GetCoursesByUser(user models.User, query models.SearchQuery) ([]models.MyCoursesResponse, int64, error)
 	GetCourseProgress(id uint, user models.User) (*models.CourseProgress, error)
+	FindCompletedUserIDs(courseID uint) ([]uint, error)
 	FindPurchasedCourseIDs(userID uint, courseIDs []uint) ([]uint, error)
 	CreateCourseCertificate(cert *models.Certificate) error
 	FindCourseCertificate(userID uint, courseID uint) (*models.Certificate, error)
+	FindCertificatesByCourse(courseID uint) ([]models.Certificate, error)
+	FindPurchase(userID uint, courseID uint) (*models.Purchase, error)
+	FindPurchaseByID(id uint) (*models.Purchase, error)
+	FindLatestRefund(userID uint, courseID uint) (*models.Refund, error)
+	RefundPurchase(purchase *models.Purchase, refund *models.Refund, check func(CourseRepository, *models.Purchase) error) (float64, error)
+	SetInstructors(course *models.Course, instructors []models.User) error
+	FindInstructors(courseID uint) ([]models.User, error)
+	FindUsersByIDs(ids []uint) ([]models.User, error)
+	IsCourseInstructor(courseID uint, userID uint) (bool, error)
+	SetAssistants(course *models.Course, assistants []models.User) error
+	FindAssistants(courseID uint) ([]models.User, error)
+	IsCourseAssistant(courseID uint, userID uint) (bool, error)
+	GetInstructorCourseStats(userID uint) ([]models.InstructorCourseStats, error)
+	FindCoursesByIDs(ids []uint) ([]models.Course, error)
+	SetPrerequisites(course *models.Course, prerequisites []models.Course) error
+	FindPrerequisites(courseID uint, userID uint) ([]models.PrerequisiteResponse, error)
+	FindPrerequisiteIDs(courseIDs []uint) ([]uint, error)
+	SetStatus(id uint, status string, publishAt *time.Time) error
+	SetCertificateTemplate(id uint, templateID *uint) error
+	PublishScheduled(id uint, publishAt time.Time) (bool, error)
 }
 
 type courseRepository struct {
@@ -34,17 +68,44 @@ func NewCourseRepository() CourseRepository {
 	return &courseRepository{db: database.DB}
 }
 
-func (r *courseRepository) Create(course *models.Course) error {
-	return r.db.Create(course).Error
+// Create saves the course and links its instructors in one transaction, so a
+// course is never left without them.
+func (r *courseRepository) Create(course *models.Course, instructors []models.User) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(course).Error; err != nil {
+			return err
+		}
+		return tx.Model(course).Association("Instructors").Replace(instructors)
+	})
 }
 
 func (r *courseRepository) Update(course *models.Course) error {
 	return r.db.Model(&models.Course{}).
 		Where("id = ?", course.ID).
 		Select("*").
+		Omit(clause.Associations, "rating_average", "rating_count", "certificate_template_id").
 		Updates(course).Error
 }
 
+func (r *courseRepository) SetCertificateTemplate(id uint, templateID *uint) error {
+	return r.db.Model(&models.Course{}).Where("id = ?", id).
+		Update("certificate_template_id", templateID).Error
+}
+
+func (r *courseRepository) SetStatus(id uint, status string, publishAt *time.Time) error {
+	return r.db.Model(&models.Course{}).Where("id = ?", id).
+		Updates(map[string]any{"status": status, "publish_at": publishAt}).Error
+}
+
+// PublishScheduled publishes the course if it is still a draft scheduled for
+// publishAt, and reports whether it did.
+func (r *courseRepository) PublishScheduled(id uint, publishAt time.Time) (bool, error) {
+	res := r.db.Model(&models.Course{}).
+		Where("id = ? AND status = ? AND publish_at = ?", id, models.StatusDraft, publishAt).
+		Update("status", models.StatusPublished)
+	return res.RowsAffected > 0, res.Error
+}
+
 func (r *courseRepository) Delete(course *models.Course) error {
 	return r.db.Delete(course).Error
 }
@@ -59,37 +120,45 @@ func (r *courseRepository) FindById(id uint) (*models.Course, error) {
 	return &course, nil
 }
 
-func (r *courseRepository) GetAllCourses(query models.SearchQuery) ([]models.CourseWithModulesCount, int64, error) {
+func (r *courseRepository) GetAllCourses(query models.CourseSearchQuery) ([]models.CourseWithModulesCount, int64, error) {
 	var results []models.CourseWithModulesCount
 	var totalItems int64
 
-	base := r.db.Model(&models.Course{})
-
-	if query.Q != "" {
-		search := "%" + query.Q + "%"
-		base = base.Where("courses.title ILIKE ? OR courses.instructor ILIKE ? OR EXISTS (SELECT 1 FROM unnest(courses.topics) t WHERE t ILIKE ?)",
-			search, search, search)
-	}
+	tsQuery := prefixTSQuery(query.Q)
+	base := applyCourseFilters(r.db.Model(&models.Course{}), query, tsQuery, "")
 
 	if err := base.Count(&totalItems).Error; err != nil {
 		return nil, 0, err
 	}
 
-	totalPages := int(math.Ceil(float64(totalItems) / float64(query.Limit)))
-	if totalPages == 0 {
-		totalPages = 1
-	}
-	if query.Page > totalPages {
-		query.Page = totalPages
-	}
-	if query.Page < 1 {
-		query.Page = 1
-	}
+	query.Page = query.Paginate(totalItems).CurrentPage
 
 	db := base.Select("courses.*, COUNT(modules.id) as modules_count").
-		Joins("LEFT JOIN modules ON modules.course_id = courses.id").
+		Joins("LEFT JOIN modules ON modules.course_id = courses.id AND modules.status = ?", models.StatusPublished).
 		Group("courses.id")
 
+	sort := query.Sort
+	if sort == "" || (sort == models.SortRelevance && tsQuery == "") {
+		sort = models.SortNewest
+		if tsQuery != "" {
+			sort = models.SortRelevance
+		}
+	}
+	switch sort {
+	case models.SortRelevance:
+		db = db.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "ts_rank(courses.search_vector, to_tsquery('simple', ?)) DESC",
+			Vars: []any{tsQuery},
+		}})
+	case models.SortPriceAsc:
+		db = db.Order(currentPriceSQL + " ASC")
+	case models.SortPriceDesc:
+		db = db.Order(currentPriceSQL + " DESC")
+	case models.SortRating:
+		db = db.Order("courses.rating_average DESC").Order("courses.rating_count DESC")
+	}
+	db = db.Order("courses.created_at DESC").Order("courses.id DESC")
+
 	offset := (query.Page - 1) * query.Limit
 	if err := db.Limit(query.Limit).Offset(offset).Scan(&results).Error; err != nil {
 		return nil, 0, err
@@ -98,6 +167,138 @@ func (r *courseRepository) GetAllCourses(query models.SearchQuery) ([]models.Cou
 	return results, totalItems, nil
 }
 
+// currentPriceSQL is the price a course sells for now, matching
+// models.Course.PriceAt.
+const currentPriceSQL = `(CASE WHEN courses.sale_price IS NOT NULL
+	AND (courses.sale_starts_at IS NULL OR courses.sale_starts_at <= NOW())
+	AND (courses.sale_ends_at IS NULL OR courses.sale_ends_at > NOW())
+	THEN courses.sale_price ELSE courses.price END)`
+
+// facetLimit caps how many topics and instructors are reported.
+const facetLimit = 20
+
+// GetCourseFacets counts the topics, instructors, free and paid courses and
+// minimum ratings (4, 3, 2 and 1 stars) among the courses matching query.
+func (r *courseRepository) GetCourseFacets(query models.CourseSearchQuery) (*models.CourseFacets, error) {
+	tsQuery := prefixTSQuery(query.Q)
+	facets := &models.CourseFacets{}
+
+	if err := applyCourseFilters(r.db.Model(&models.Course{}), query, tsQuery, "topic").
+		Select("t.topic AS value, COUNT(*) AS count").
+		Joins("CROSS JOIN LATERAL unnest(courses.topics) AS t(topic)").
+		Group("t.topic").
+		Order("count DESC, value ASC").
+		Limit(facetLimit).
+		Scan(&facets.Topics).Error; err != nil {
+		return nil, err
+	}
+
+	if err := applyCourseFilters(r.db.Model(&models.Course{}), query, tsQuery, "instructor").
+		Select("courses.instructor AS value, COUNT(*) AS count").
+		Group("courses.instructor").
+		Order("count DESC, value ASC").
+		Limit(facetLimit).
+		Scan(&facets.Instructors).Error; err != nil {
+		return nil, err
+	}
+
+	var price struct {
+		Free int64
+		Paid int64
+	}
+	if err := applyCourseFilters(r.db.Model(&models.Course{}), query, tsQuery, "price").
+		Select("COUNT(*) FILTER (WHERE " + currentPriceSQL + " = 0) AS free, COUNT(*) FILTER (WHERE " + currentPriceSQL + " > 0) AS paid").
+		Scan(&price).Error; err != nil {
+		return nil, err
+	}
+	facets.Price = []models.FacetCount{
+		{Value: "free", Count: price.Free},
+		{Value: "paid", Count: price.Paid},
+	}
+
+	var rating struct {
+		Four  int64
+		Three int64
+		Two   int64
+		One   int64
+	}
+	if err := applyCourseFilters(r.db.Model(&models.Course{}), query, tsQuery, "rating").
+		Select(`COUNT(*) FILTER (WHERE courses.rating_average >= 4) AS four,
+			COUNT(*) FILTER (WHERE courses.rating_average >= 3) AS three,
+			COUNT(*) FILTER (WHERE courses.rating_average >= 2) AS two,
+			COUNT(*) FILTER (WHERE courses.rating_average >= 1) AS one`).
+		Scan(&rating).Error; err != nil {
+		return nil, err
+	}
+	facets.Rating = []models.FacetCount{
+		{Value: "4", Count: rating.Four},
+		{Value: "3", Count: rating.Three},
+		{Value: "2", Count: rating.Two},
+		{Value: "1", Count: rating.One},
+	}
+
+	return facets, nil
+}
+
+// applyCourseFilters narrows db to the courses matching query. The filter
+// named by skip is left out, so a facet can count its other values.
+func applyCourseFilters(db *gorm.DB, query models.CourseSearchQuery, tsQuery string, skip string) *gorm.DB {
+	if tsQuery != "" {
+		db = db.Where("courses.search_vector @@ to_tsquery('simple', ?)", tsQuery)
+	}
+
+	if query.Status != "" {
+		db = db.Where("courses.status = ?", query.Status)
+	}
+	if query.TaughtBy != nil {
+		db = db.Where("courses.status = ? OR EXISTS (SELECT 1 FROM course_instructors WHERE course_instructors.course_id = courses.id AND course_instructors.user_id = ?)",
+			models.StatusPublished, *query.TaughtBy)
+	}
+
+	if query.Topic != "" && skip != "topic" {
+		db = db.Where("? = ANY(courses.topics)", query.Topic)
+	}
+
+	if query.Instructor != "" && skip != "instructor" {
+		db = db.Where("courses.instructor = ?", query.Instructor)
+	}
+
+	if skip != "price" {
+		switch query.Price {
+		case "free":
+			db = db.Where(currentPriceSQL + " = 0")
+		case "paid":
+			db = db.Where(currentPriceSQL + " > 0")
+		}
+		if query.MinPrice > 0 {
+			db = db.Where(currentPriceSQL+" >= ?", query.MinPrice)
+		}
+		if query.MaxPrice > 0 {
+			db = db.Where(currentPriceSQL+" <= ?", query.MaxPrice)
+		}
+	}
+
+	if query.MinRating > 0 && skip != "rating" {
+		db = db.Where("courses.rating_average >= ?", query.MinRating)
+	}
+
+	return db
+}
+
+// prefixTSQuery turns free text into a tsquery that matches courses
+// containing every word, each as a prefix so partly typed words still match.
+func prefixTSQuery(q string) string {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		terms = append(terms, w+":*")
+	}
+	return strings.Join(terms, " & ")
+}
+
 func (r *courseRepository) FindModulesByCourseID(id uint) ([]models.Module, int64, error) {
 	var modules []models.Module
 	var count int64
@@ -129,13 +330,7 @@ func (r *courseRepository) FindModulesByCourseIDPaginated(id uint, q models.Pagi
 		return nil, 0, err
 	}
 
-	totalPages := int(math.Ceil(float64(totalItems) / float64(q.Limit)))
-	if totalPages == 0 {
-		totalPages = 1
-	}
-	if q.Page > totalPages {
-		q.Page = totalPages
-	}
+	q.Page = q.Paginate(totalItems).CurrentPage
 
 	offset := (q.Page - 1) * q.Limit
 
@@ -155,7 +350,7 @@ func (r *courseRepository) HasPurchasedCourse(courseId uint, userId uint) (bool,
 	var count int64
 
 	err := r.db.Model(&models.Purchase{}).
-		Where("user_id = ? AND course_id = ?", userId, courseId).
+		Where("user_id = ? AND course_id = ? AND refunded_at IS NULL", userId, courseId).
 		Count(&count).Error
 	if err != nil {
 		return false, err
@@ -164,30 +359,28 @@ func (r *courseRepository) HasPurchasedCourse(courseId uint, userId uint) (bool,
 	return count > 0, nil
 }
 
-func (r *courseRepository) FindModulesWithProgressPaginated(courseID, userID uint, q models.PaginationQuery) ([]models.ModuleWithIsCompleted, int64, error) {
+// FindModulesWithProgressPaginated lists the course's modules with the user's
+// progress. With publishedOnly, drafts and archived modules are left out.
+func (r *courseRepository) FindModulesWithProgressPaginated(courseID, userID uint, q models.PaginationQuery, publishedOnly bool) ([]models.ModuleWithIsCompleted, int64, error) {
 	var modules []models.ModuleWithIsCompleted
 	var totalItems int64
 
 	base := r.db.Model(&models.Module{}).
-		Select("modules.*, module_progresses.is_completed").
+		Select(`modules.*, module_progresses.is_completed,
+			COALESCE(module_progresses.position_seconds, 0) AS position_seconds,
+			COALESCE(module_progresses.watched_percentage, 0) AS watched_percentage`).
 		Joins("LEFT JOIN module_progresses ON module_progresses.module_id = modules.id AND module_progresses.user_id = ?", userID).
 		Where("modules.course_id = ?", courseID).
 		Order("modules.order ASC")
+	if publishedOnly {
+		base = base.Where("modules.status = ?", models.StatusPublished)
+	}
 
 	if err := base.Count(&totalItems).Error; err != nil {
 		return nil, 0, err
 	}
 
-	totalPages := int(math.Ceil(float64(totalItems) / float64(q.Limit)))
-	if totalPages == 0 {
-		totalPages = 1
-	}
-	if q.Page > totalPages {
-		q.Page = totalPages
-	}
-	if q.Page < 1 {
-		q.Page = 1
-	}
+	q.Page = q.Paginate(totalItems).CurrentPage
 
 	offset := (q.Page - 1) * q.Limit
 	if err := base.Limit(q.Limit).Offset(offset).Scan(&modules).Error; err != nil {
@@ -197,22 +390,125 @@ func (r *courseRepository) FindModulesWithProgressPaginated(courseID, userID uin
 	return modules, totalItems, nil
 }
 
-func (r *courseRepository) BuyCourse(user *models.User, course *models.Course) (*models.Purchase, error) {
-	user.Balance -= course.Price
-	if err := r.db.Save(user).Error; err != nil {
+// BuyCourse charges the quoted amount. The coupon is checked again with its
+// row locked, so a concurrent edit or purchase cannot slip past its validity
+// or usage limits.
+func (r *courseRepository) BuyCourse(user *models.User, course *models.Course, quote models.PriceQuote) (*models.Purchase, error) {
+	var purchase models.Purchase
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var locked models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&locked, user.ID).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.Purchase{}).
+			Where("user_id = ? AND course_id = ? AND refunded_at IS NULL", user.ID, course.ID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAlreadyPurchased
+		}
+
+		if quote.Coupon != nil {
+			coupon, err := checkCoupon(tx, quote.Coupon.ID, course.ID, user.ID)
+			if err != nil {
+				return err
+			}
+
+			// The coupon may have been edited since the price was quoted, so
+			// its discount is taken from the locked row.
+			price := math.Round((quote.ListPrice-quote.SaleDiscount)*100) / 100
+			quote.CouponCode = coupon.Code
+			quote.CouponDiscount = coupon.DiscountFor(price)
+			quote.Amount = math.Round((price-quote.CouponDiscount)*100) / 100
+		}
+
+		if quote.Amount > locked.Balance {
+			return ErrInsufficientBalance
+		}
+
+		locked.Balance -= quote.Amount
+		if err := tx.Model(&models.User{}).
+			Where("id = ?", locked.ID).
+			UpdateColumn("balance", locked.Balance).Error; err != nil {
+			return err
+		}
+
+		purchase = models.Purchase{
+			UserID:         user.ID,
+			CourseID:       course.ID,
+			Amount:         quote.Amount,
+			ListPrice:      quote.ListPrice,
+			SaleDiscount:   quote.SaleDiscount,
+			CouponDiscount: quote.CouponDiscount,
+			CouponCode:     quote.CouponCode,
+		}
+		if quote.Coupon != nil {
+			purchase.CouponID = &quote.Coupon.ID
+		}
+		if err := tx.Create(&purchase).Error; err != nil {
+			return err
+		}
+
+		if err := recordBalanceTransaction(tx, &models.BalanceTransaction{
+			UserID:       user.ID,
+			Type:         models.TransactionTypePurchase,
+			Amount:       -quote.Amount,
+			BalanceAfter: locked.Balance,
+			PurchaseID:   &purchase.ID,
+			Description:  "Purchase of course: " + course.Title,
+		}); err != nil {
+			return err
+		}
+
+		user.Balance = locked.Balance
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	purchase := models.Purchase{
-		UserID:   user.ID,
-		CourseID: course.ID,
-		Amount:   course.Price,
+	return &purchase, nil
+}
+
+// checkCoupon locks the coupon, then checks that it is still valid for the
+// course and counts its uses. It returns the locked coupon.
+func checkCoupon(tx *gorm.DB, couponID uint, courseID uint, userID uint) (*models.Coupon, error) {
+	var coupon models.Coupon
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&coupon, couponID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUnknownCoupon
+		}
+		return nil, err
 	}
-	if err := r.db.Create(&purchase).Error; err != nil {
+
+	if !coupon.ValidAt(time.Now()) {
+		return nil, ErrCouponInactive
+	}
+	if !coupon.AppliesTo(courseID) {
+		return nil, ErrCouponNotApplicable
+	}
+
+	var uses, userUses int64
+	if err := tx.Model(&models.Purchase{}).
+		Select("COUNT(*), COUNT(*) FILTER (WHERE user_id = ?)", userID).
+		Where("coupon_id = ?", couponID).
+		Row().Scan(&uses, &userUses); err != nil {
 		return nil, err
 	}
 
-	return &purchase, nil
+	if coupon.MaxUses > 0 && uses >= int64(coupon.MaxUses) {
+		return nil, ErrCouponUsedUp
+	}
+	if coupon.MaxUsesPerUser > 0 && userUses >= int64(coupon.MaxUsesPerUser) {
+		return nil, ErrCouponUserLimit
+	}
+	return &coupon, nil
 }
 
 func (r *courseRepository) GetCoursesByUser(user models.User, query models.SearchQuery) ([]models.MyCoursesResponse, int64, error) {
@@ -220,23 +516,14 @@ func (r *courseRepository) GetCoursesByUser(user models.User, query models.Searc
 	var totalItems int64
 
 	base := r.db.Model(&models.Course{}).
-		Joins("JOIN purchases ON purchases.course_id = courses.id").
+		Joins("JOIN purchases ON purchases.course_id = courses.id AND purchases.refunded_at IS NULL").
 		Where("purchases.user_id = ?", user.ID)
 
 	if err := base.Count(&totalItems).Error; err != nil {
 		return nil, 0, err
 	}
 
-	totalPages := int(math.Ceil(float64(totalItems) / float64(query.Limit)))
-	if totalPages == 0 {
-		totalPages = 1
-	}
-	if query.Page > totalPages {
-		query.Page = totalPages
-	}
-	if query.Page < 1 {
-		query.Page = 1
-	}
+	query.Page = query.Paginate(totalItems).CurrentPage
 
 	db := base.Select(
 		"courses.*",
@@ -245,7 +532,7 @@ func (r *courseRepository) GetCoursesByUser(user models.User, query models.Searc
 			WHEN COUNT(modules.id) = 0 THEN 0
 			ELSE (SUM(CASE WHEN module_progresses.is_completed THEN 1 ELSE 0 END) * 100.0 / COUNT(modules.id))
 		END AS progress_percentage`).
-		Joins("LEFT JOIN modules ON modules.course_id = courses.id").
+		Joins("LEFT JOIN modules ON modules.course_id = courses.id AND modules.status = ?", models.StatusPublished).
 		Joins("LEFT JOIN module_progresses ON module_progresses.module_id = modules.id AND module_progresses.user_id = ?", user.ID).
 		Group("courses.id, purchases.created_at")
 
@@ -260,7 +547,7 @@ func (r *courseRepository) GetCoursesByUser(user models.User, query models.Searc
 func (r *courseRepository) GetCourseProgress(id uint, user models.User) (*models.CourseProgress, error) {
 	var totalModules int64
 	err := r.db.Model(&models.Module{}).
-		Where("course_id = ?", id).
+		Where("course_id = ? AND status = ?", id, models.StatusPublished).
 		Count(&totalModules).Error
 	if err != nil {
 		return nil, err
@@ -269,7 +556,7 @@ func (r *courseRepository) GetCourseProgress(id uint, user models.User) (*models
 	var completedModules int64
 	err = r.db.Model(&models.ModuleProgress{}).
 		Joins("JOIN modules ON modules.id = module_progresses.module_id").
-		Where("modules.course_id = ? AND module_progresses.user_id = ? AND module_progresses.is_completed = TRUE", id, user.ID).
+		Where("modules.course_id = ? AND modules.status = ? AND module_progresses.user_id = ? AND module_progresses.is_completed = TRUE", id, models.StatusPublished, user.ID).
 		Count(&completedModules).Error
 	if err != nil {
 		return nil, err
@@ -280,19 +567,55 @@ func (r *courseRepository) GetCourseProgress(id uint, user models.User) (*models
 		percentage = float64(completedModules) * 100.0 / float64(totalModules)
 	}
 
+	var sections []models.SectionProgress
+	err = r.db.Model(&models.Section{}).
+		Select(`sections.id AS section_id, sections.title,
+			COUNT(modules.id) AS total_modules,
+			COUNT(module_progresses.module_id) AS completed_modules`).
+		Joins("LEFT JOIN modules ON modules.section_id = sections.id AND modules.status = ?", models.StatusPublished).
+		Joins("LEFT JOIN module_progresses ON module_progresses.module_id = modules.id AND module_progresses.user_id = ? AND module_progresses.is_completed = TRUE", user.ID).
+		Where("sections.course_id = ?", id).
+		Group(`sections.id, sections.title, sections."order"`).
+		Order(`sections."order"`).
+		Scan(&sections).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range sections {
+		if sections[i].TotalModules > 0 {
+			sections[i].Percentage = float64(sections[i].CompletedModules) * 100.0 / float64(sections[i].TotalModules)
+		}
+	}
+
 	res := models.CourseProgress{
 		TotalModules:     int(totalModules),
 		CompletedModules: int(completedModules),
 		Percentage:       percentage,
+		Sections:         sections,
 	}
 	return &res, nil
 }
 
+// FindCompletedUserIDs returns the buyers who completed every published
+// module of the course. A course without published modules has none.
+func (r *courseRepository) FindCompletedUserIDs(courseID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.Purchase{}).
+		Joins("JOIN modules ON modules.course_id = purchases.course_id AND modules.status = ?", models.StatusPublished).
+		Joins("LEFT JOIN module_progresses ON module_progresses.module_id = modules.id AND module_progresses.user_id = purchases.user_id AND module_progresses.is_completed = TRUE").
+		Where("purchases.course_id = ? AND purchases.refunded_at IS NULL", courseID).
+		Group("purchases.user_id").
+		Having("COUNT(modules.id) = COUNT(module_progresses.id)").
+		Order("purchases.user_id").
+		Pluck("purchases.user_id", &ids).Error
+	return ids, err
+}
+
 func (r *courseRepository) FindPurchasedCourseIDs(userID uint, courseIDs []uint) ([]uint, error) {
 	var purchasedIDs []uint
 
 	err := r.db.Table("purchases").
-		Where("user_id = ? AND course_id IN (?)", userID, courseIDs).
+		Where("user_id = ? AND course_id IN (?) AND refunded_at IS NULL", userID, courseIDs).
 		Pluck("course_id", &purchasedIDs).Error
 
 	if err != nil {
@@ -317,3 +640,283 @@ func (r *courseRepository) FindCourseCertificate(userID uint, courseID uint) (*m
 	}
 	return &cert, nil
 }
+
+func (r *courseRepository) FindCertificatesByCourse(courseID uint) ([]models.Certificate, error) {
+	var certs []models.Certificate
+	err := r.db.Where("course_id = ?", courseID).Find(&certs).Error
+	return certs, err
+}
+
+// FindPurchase returns the user's current, unrefunded purchase of the course.
+func (r *courseRepository) FindPurchase(userID uint, courseID uint) (*models.Purchase, error) {
+	var purchase models.Purchase
+	err := r.db.Where("user_id = ? AND course_id = ? AND refunded_at IS NULL", userID, courseID).First(&purchase).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPurchaseNotFound
+		}
+		return nil, err
+	}
+	return &purchase, nil
+}
+
+func (r *courseRepository) FindPurchaseByID(id uint) (*models.Purchase, error) {
+	var purchase models.Purchase
+	err := r.db.First(&purchase, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPurchaseNotFound
+		}
+		return nil, err
+	}
+	return &purchase, nil
+}
+
+func (r *courseRepository) FindLatestRefund(userID uint, courseID uint) (*models.Refund, error) {
+	var refund models.Refund
+	err := r.db.Where("user_id = ? AND course_id = ?", userID, courseID).
+		Order("created_at DESC").
+		First(&refund).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// RefundPurchase credits the purchase amount back to the buyer, marks the
+// purchase refunded, removes the progress it granted and revokes its
+// certificate. A non-nil check is run on the locked purchase, with a
+// repository bound to the refund's transaction, and aborts the refund with
+// its error. It returns the buyer's balance after the refund.
+func (r *courseRepository) RefundPurchase(purchase *models.Purchase, refund *models.Refund, check func(CourseRepository, *models.Purchase) error) (float64, error) {
+	var balance float64
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&user, purchase.UserID).Error; err != nil {
+			return err
+		}
+
+		var locked models.Purchase
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&locked, purchase.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPurchaseNotFound
+			}
+			return err
+		}
+		if locked.RefundedAt != nil {
+			return ErrPurchaseRefunded
+		}
+		if check != nil {
+			if err := check(&courseRepository{db: tx}, &locked); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("user_id = ? AND module_id IN (?)", locked.UserID,
+			tx.Model(&models.Module{}).Select("id").Where("course_id = ?", locked.CourseID)).
+			Delete(&models.ModuleProgress{}).Error; err != nil {
+			return err
+		}
+
+		// The certificate row is kept so its serial still verifies, as
+		// revoked. It is restored if the course is bought and completed again,
+		// so a certificate an admin revoked already keeps that revocation.
+		if err := tx.Model(&models.Certificate{}).
+			Where("user_id = ? AND course_id = ? AND revoked_at IS NULL", locked.UserID, locked.CourseID).
+			Updates(map[string]any{
+				"revoked_at":        time.Now(),
+				"revoked_by":        refund.RefundedBy,
+				"revocation_reason": models.CertificateRefundedReason,
+			}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ? AND course_id = ?", locked.UserID, locked.CourseID).
+			Delete(&models.Review{}).Error; err != nil {
+			return err
+		}
+		if err := refreshCourseRating(tx, locked.CourseID); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&locked).UpdateColumn("refunded_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		user.Balance += locked.Amount
+		if err := tx.Model(&models.User{}).
+			Where("id = ?", user.ID).
+			UpdateColumn("balance", user.Balance).Error; err != nil {
+			return err
+		}
+
+		refund.UserID = locked.UserID
+		refund.CourseID = locked.CourseID
+		refund.PurchaseID = locked.ID
+		refund.Amount = locked.Amount
+		if err := tx.Create(refund).Error; err != nil {
+			return err
+		}
+
+		if err := recordBalanceTransaction(tx, &models.BalanceTransaction{
+			UserID:       locked.UserID,
+			Type:         models.TransactionTypeRefund,
+			Amount:       locked.Amount,
+			BalanceAfter: user.Balance,
+			PurchaseID:   &locked.ID,
+			Description:  "Refund of course purchase",
+		}); err != nil {
+			return err
+		}
+
+		balance = user.Balance
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return balance, nil
+}
+
+func (r *courseRepository) SetInstructors(course *models.Course, instructors []models.User) error {
+	return r.db.Model(course).Association("Instructors").Replace(instructors)
+}
+
+func (r *courseRepository) FindInstructors(courseID uint) ([]models.User, error) {
+	var users []models.User
+	err := r.db.Model(&models.User{}).
+		Joins("JOIN course_instructors ON course_instructors.user_id = users.id").
+		Where("course_instructors.course_id = ?", courseID).
+		Order("users.id ASC").
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *courseRepository) FindUsersByIDs(ids []uint) ([]models.User, error) {
+	var users []models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	if err := r.db.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *courseRepository) IsCourseInstructor(courseID uint, userID uint) (bool, error) {
+	var count int64
+	err := r.db.Table("course_instructors").
+		Where("course_id = ? AND user_id = ?", courseID, userID).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *courseRepository) SetAssistants(course *models.Course, assistants []models.User) error {
+	return r.db.Model(course).Association("Assistants").Replace(assistants)
+}
+
+func (r *courseRepository) FindAssistants(courseID uint) ([]models.User, error) {
+	var users []models.User
+	err := r.db.Model(&models.User{}).
+		Joins("JOIN course_assistants ON course_assistants.user_id = users.id").
+		Where("course_assistants.course_id = ?", courseID).
+		Order("users.id ASC").
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *courseRepository) IsCourseAssistant(courseID uint, userID uint) (bool, error) {
+	var count int64
+	err := r.db.Table("course_assistants").
+		Where("course_id = ? AND user_id = ?", courseID, userID).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *courseRepository) GetInstructorCourseStats(userID uint) ([]models.InstructorCourseStats, error) {
+	var stats []models.InstructorCourseStats
+	err := r.db.Model(&models.Course{}).
+		Select(`courses.id AS course_id, courses.title, courses.price,
+			COUNT(purchases.id) AS enrolments,
+			COALESCE(SUM(purchases.amount), 0) AS revenue`).
+		Joins("JOIN course_instructors ON course_instructors.course_id = courses.id AND course_instructors.user_id = ?", userID).
+		Joins("LEFT JOIN purchases ON purchases.course_id = courses.id AND purchases.refunded_at IS NULL").
+		Group("courses.id").
+		Order("courses.created_at DESC").
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (r *courseRepository) FindCoursesByIDs(ids []uint) ([]models.Course, error) {
+	var courses []models.Course
+	if len(ids) == 0 {
+		return courses, nil
+	}
+	if err := r.db.Where("id IN ?", ids).Find(&courses).Error; err != nil {
+		return nil, err
+	}
+	return courses, nil
+}
+
+func (r *courseRepository) SetPrerequisites(course *models.Course, prerequisites []models.Course) error {
+	return r.db.Model(course).Association("Prerequisites").Replace(prerequisites)
+}
+
+// FindPrerequisites lists the courses required by courseID and whether the
+// user holds a certificate for each one that has not been revoked. An
+// outdated certificate (needs_update) still counts: its holder finished the
+// course, and it stays valid until the new modules are done.
+func (r *courseRepository) FindPrerequisites(courseID uint, userID uint) ([]models.PrerequisiteResponse, error) {
+	var prerequisites []models.PrerequisiteResponse
+	err := r.db.Model(&models.Course{}).
+		Select(`courses.id AS course_id, courses.title,
+			EXISTS (SELECT 1 FROM certificates WHERE certificates.course_id = courses.id AND certificates.user_id = ?
+				AND certificates.revoked_at IS NULL) AS completed`, userID).
+		Joins("JOIN course_prerequisites ON course_prerequisites.prerequisite_id = courses.id").
+		Where("course_prerequisites.course_id = ?", courseID).
+		Order("courses.title ASC").
+		Scan(&prerequisites).Error
+	if err != nil {
+		return nil, err
+	}
+	return prerequisites, nil
+}
+
+// FindPrerequisiteIDs returns the courses directly required by any of
+// courseIDs.
+func (r *courseRepository) FindPrerequisiteIDs(courseIDs []uint) ([]uint, error) {
+	var ids []uint
+	if len(courseIDs) == 0 {
+		return ids, nil
+	}
+	err := r.db.Table("course_prerequisites").
+		Where("course_id IN ?", courseIDs).
+		Distinct().
+		Pluck("prerequisite_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,227 @@
+package repositories
+
+import (
+	"database/sql/driver"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+)
+
+func TestPrefixTSQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		q    string
+		want string
+	}{
+		{name: "single word", q: "golang", want: "golang:*"},
+		{name: "words are combined", q: "web dev", want: "web:* & dev:*"},
+		{name: "lower cased", q: "Go Basics", want: "go:* & basics:*"},
+		{name: "extra spacing", q: "  data   science ", want: "data:* & science:*"},
+		{name: "digits are kept", q: "python 3", want: "python:* & 3:*"},
+		{name: "non-ASCII letters are kept", q: "Pemrograman dasar café", want: "pemrograman:* & dasar:* & café:*"},
+		{name: "tsquery operators are dropped", q: "go & !rust | (c:*)", want: "go:* & rust:* & c:*"},
+		{name: "punctuation splits words", q: "c++/node.js", want: "c:* & node:* & js:*"},
+		{name: "quotes are dropped", q: `it's "go"`, want: "it:* & s:* & go:*"},
+		{name: "empty", q: "", want: ""},
+		{name: "only symbols", q: "&|!():*'", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := prefixTSQuery(tt.q); got != tt.want {
+				t.Errorf("prefixTSQuery(%q) = %q, want %q", tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+// lockedPurchaseRows answers the refund's locking reads with a buyer and an
+// unrefunded purchase of course 3.
+func lockedPurchaseRows(fake *fakeDB) {
+	fake.onQuery(`FROM "users"`, []string{"id", "balance"}, []driver.Value{int64(1), 20.0})
+	fake.onQuery(`FROM "purchases"`, []string{"id", "user_id", "course_id", "amount", "created_at"},
+		[]driver.Value{int64(9), int64(1), int64(3), 80.0, time.Now()})
+}
+
+func TestRefundPurchaseRevokesCertificate(t *testing.T) {
+	db, fake := newFakeDB(t)
+	lockedPurchaseRows(fake)
+	repo := &courseRepository{db: db}
+
+	balance, err := repo.RefundPurchase(&models.Purchase{ID: 9, UserID: 1}, &models.Refund{RefundedBy: 4}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance != 100 {
+		t.Errorf("balance = %v, want 100", balance)
+	}
+
+	if deletes := fake.find(`DELETE FROM "certificates"`); len(deletes) != 0 {
+		t.Errorf("certificate deleted: %q", deletes[0].SQL)
+	}
+	updates := fake.find(`UPDATE "certificates"`)
+	if len(updates) != 1 {
+		t.Fatalf("got %d certificate updates, want 1", len(updates))
+	}
+	args := updates[0].Args
+	if !slices.Contains(args, any(models.CertificateRefundedReason)) || !slices.Contains(args, any(uint(4))) {
+		t.Errorf("certificate update args = %v, want reason %q and revoker 4", args, models.CertificateRefundedReason)
+	}
+	if !strings.Contains(updates[0].SQL, "revoked_at IS NULL") {
+		t.Errorf("certificate update %q overwrites an earlier revocation", updates[0].SQL)
+	}
+}
+
+func TestRefundPurchaseChecksInsideTransaction(t *testing.T) {
+	db, fake := newFakeDB(t)
+	lockedPurchaseRows(fake)
+	repo := &courseRepository{db: db}
+
+	checked := false
+	_, err := repo.RefundPurchase(&models.Purchase{ID: 9, UserID: 1}, &models.Refund{RefundedBy: 1}, func(tx CourseRepository, locked *models.Purchase) error {
+		checked = true
+		if locked.CourseID != 3 {
+			t.Errorf("check got course %d, want the locked purchase's course 3", locked.CourseID)
+		}
+		_, err := tx.FindLatestRefund(locked.UserID, locked.CourseID)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !checked {
+		t.Fatal("check was not run")
+	}
+	// A read outside the transaction would need a second connection while
+	// the transaction holds the first.
+	if fake.connections != 1 {
+		t.Errorf("opened %d connections, want the refund's check to use the transaction's", fake.connections)
+	}
+}
+
+func TestFindPrerequisitesIgnoresRevokedCertificates(t *testing.T) {
+	db, fake := newFakeDB(t)
+	repo := &courseRepository{db: db}
+
+	if _, err := repo.FindPrerequisites(2, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	query := fake.find("course_prerequisites")
+	if len(query) != 1 {
+		t.Fatalf("queries = %+v, want one", query)
+	}
+	if !strings.Contains(query[0].SQL, "certificates.revoked_at IS NULL") {
+		t.Errorf("query = %s, want revoked certificates excluded", query[0].SQL)
+	}
+	if strings.Contains(query[0].SQL, "outdated_at") {
+		t.Errorf("query = %s, want outdated certificates to still count", query[0].SQL)
+	}
+}
+
+func TestBuyCourseChargesTheLockedCouponsDiscount(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.onQuery(`FROM "users"`, []string{"id", "balance"}, []driver.Value{int64(1), 100.0})
+	fake.onQuery(`FROM "coupons"`, []string{"id", "code", "type", "value", "active"},
+		[]driver.Value{int64(4), "SPRING", models.CouponTypePercent, 50.0, true})
+	fake.onQuery("FILTER", []string{"count", "count"}, []driver.Value{int64(0), int64(0)})
+	repo := &courseRepository{db: db}
+
+	// The coupon was raised from 10% to 50% after the price was quoted.
+	quote := models.PriceQuote{
+		ListPrice:      80,
+		CouponCode:     "SPRING",
+		CouponDiscount: 8,
+		Amount:         72,
+		Coupon:         &models.Coupon{ID: 4, Code: "SPRING", Type: models.CouponTypePercent, Value: 10, Active: true},
+	}
+	user := &models.User{ID: 1}
+	purchase, err := repo.BuyCourse(user, &models.Course{ID: 3, Title: "Go"}, quote)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if purchase.Amount != 40 || purchase.CouponDiscount != 40 || purchase.CouponCode != "SPRING" {
+		t.Errorf("purchase = %+v, want 40 off and 40 charged", purchase)
+	}
+	if user.Balance != 60 {
+		t.Errorf("balance = %v, want 60", user.Balance)
+	}
+}
+
+// lockedReads fails the test unless every read of the given tables took a
+// row lock.
+func lockedReads(t *testing.T, fake *fakeDB, tables ...string) {
+	t.Helper()
+	for _, table := range tables {
+		reads := fake.find(`SELECT * FROM "` + table + `"`)
+		if len(reads) == 0 {
+			t.Errorf("%s was not read", table)
+		}
+		for _, r := range reads {
+			if !strings.HasSuffix(r.SQL, "FOR UPDATE") {
+				t.Errorf("read of %s without a row lock: %s", table, r.SQL)
+			}
+		}
+	}
+}
+
+func TestBalanceChangesLockTheirRows(t *testing.T) {
+	t.Run("purchase with coupon", func(t *testing.T) {
+		db, fake := newFakeDB(t)
+		fake.onQuery(`FROM "users"`, []string{"id", "balance"}, []driver.Value{int64(1), 100.0})
+		fake.onQuery(`FROM "coupons"`, []string{"id", "code", "type", "value", "active"},
+			[]driver.Value{int64(4), "SPRING", models.CouponTypeFixed, 10.0, true})
+		fake.onQuery("FILTER", []string{"count", "count"}, []driver.Value{int64(0), int64(0)})
+		repo := &courseRepository{db: db}
+
+		quote := models.PriceQuote{ListPrice: 80, Amount: 70, Coupon: &models.Coupon{ID: 4}}
+		if _, err := repo.BuyCourse(&models.User{ID: 1}, &models.Course{ID: 3}, quote); err != nil {
+			t.Fatal(err)
+		}
+		lockedReads(t, fake, "users", "coupons")
+	})
+
+	t.Run("refund", func(t *testing.T) {
+		db, fake := newFakeDB(t)
+		lockedPurchaseRows(fake)
+		repo := &courseRepository{db: db}
+
+		if _, err := repo.RefundPurchase(&models.Purchase{ID: 9, UserID: 1}, &models.Refund{RefundedBy: 1}, nil); err != nil {
+			t.Fatal(err)
+		}
+		lockedReads(t, fake, "users", "purchases")
+	})
+
+	t.Run("top-up", func(t *testing.T) {
+		db, fake := newFakeDB(t)
+		fake.onQuery(`FROM "users"`, []string{"id", "balance"}, []driver.Value{int64(1), 20.0})
+		repo := &userRepository{db: db}
+
+		if err := repo.AddUserBalance(1, 30); err != nil {
+			t.Fatal(err)
+		}
+		lockedReads(t, fake, "users")
+	})
+}
+
+func TestGetCoursesByUserCountsPublishedModules(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.onQuery(`SELECT count(*)`, []string{"count"}, []driver.Value{int64(1)})
+	repo := &courseRepository{db: db}
+
+	if _, _, err := repo.GetCoursesByUser(models.User{ID: 1}, models.SearchQuery{PaginationQuery: models.PaginationQuery{Page: 1, Limit: 10}}); err != nil {
+		t.Fatal(err)
+	}
+
+	pages := fake.find(`progress_percentage`)
+	if len(pages) != 1 {
+		t.Fatalf("got %d page queries, want 1", len(pages))
+	}
+	if !strings.Contains(pages[0].SQL, "modules.status = $") || !slices.Contains(pages[0].Args, any(models.StatusPublished)) {
+		t.Errorf("progress query %q with args %v counts draft modules", pages[0].SQL, pages[0].Args)
+	}
+}
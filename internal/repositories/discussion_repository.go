@@ -0,0 +1,235 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/database"
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DiscussionRepository interface {
+	FindPost(id uint) (*models.DiscussionPost, error)
+	FindPostResponse(id uint, viewerID uint) (*models.DiscussionPostResponse, error)
+	ListThreads(moduleID uint, q models.DiscussionQuery, viewerID uint) ([]models.DiscussionPostResponse, int64, error)
+	ListReplies(threadID uint, q models.PaginationQuery, viewerID uint) ([]models.DiscussionPostResponse, int64, error)
+	CreateThread(post *models.DiscussionPost) error
+	CreateReply(reply *models.DiscussionPost) error
+	UpdatePost(post *models.DiscussionPost) error
+	DeletePost(post *models.DiscussionPost) error
+	Upvote(postID uint, userID uint) error
+	RemoveUpvote(postID uint, userID uint) error
+	SetAnswer(reply *models.DiscussionPost, isAnswer bool) error
+}
+
+type discussionRepository struct {
+	db *gorm.DB
+}
+
+func NewDiscussionRepository() DiscussionRepository {
+	return &discussionRepository{db: database.DB}
+}
+
+func (r *discussionRepository) FindPost(id uint) (*models.DiscussionPost, error) {
+	var post models.DiscussionPost
+	if err := r.db.First(&post, id).Error; err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// postResponses selects posts as responses for viewerID, with the author's
+// name, whether they teach the course and whether the viewer upvoted.
+func (r *discussionRepository) postResponses(viewerID uint) *gorm.DB {
+	return r.db.Table("discussion_posts").
+		Joins("JOIN users ON users.id = discussion_posts.user_id").
+		Joins("JOIN modules ON modules.id = discussion_posts.module_id").
+		Select(`discussion_posts.id, discussion_posts.module_id, discussion_posts.parent_id,
+			discussion_posts.user_id, users.username,
+			EXISTS (SELECT 1 FROM course_instructors ci
+				WHERE ci.course_id = modules.course_id AND ci.user_id = discussion_posts.user_id) AS author_is_instructor,
+			discussion_posts.title, discussion_posts.body,
+			discussion_posts.upvote_count,
+			EXISTS (SELECT 1 FROM discussion_votes v
+				WHERE v.post_id = discussion_posts.id AND v.user_id = ?) AS upvoted,
+			discussion_posts.reply_count, discussion_posts.is_answer, discussion_posts.answered_at,
+			discussion_posts.edited_at, discussion_posts.last_activity_at, discussion_posts.created_at`, viewerID)
+}
+
+func (r *discussionRepository) FindPostResponse(id uint, viewerID uint) (*models.DiscussionPostResponse, error) {
+	var post models.DiscussionPostResponse
+	res := r.postResponses(viewerID).Where("discussion_posts.id = ?", id).Limit(1).Scan(&post)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &post, nil
+}
+
+func (r *discussionRepository) ListThreads(moduleID uint, q models.DiscussionQuery, viewerID uint) ([]models.DiscussionPostResponse, int64, error) {
+	var threads []models.DiscussionPostResponse
+	var totalItems int64
+
+	base := r.db.Model(&models.DiscussionPost{}).
+		Where("discussion_posts.module_id = ? AND discussion_posts.parent_id IS NULL", moduleID)
+	if q.Sort == "unanswered" {
+		base = base.Where("discussion_posts.answered_at IS NULL")
+	}
+
+	if err := base.Count(&totalItems).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := q.Paginate(totalItems).CurrentPage
+
+	db := r.postResponses(viewerID).
+		Where("discussion_posts.module_id = ? AND discussion_posts.parent_id IS NULL", moduleID)
+	if q.Sort == "unanswered" {
+		db = db.Where("discussion_posts.answered_at IS NULL")
+	}
+	if q.Sort == "top" {
+		db = db.Order("discussion_posts.upvote_count DESC")
+	}
+
+	err := db.Order("discussion_posts.last_activity_at DESC").
+		Order("discussion_posts.id DESC").
+		Limit(q.Limit).
+		Offset((page - 1) * q.Limit).
+		Scan(&threads).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return threads, totalItems, nil
+}
+
+// ListReplies returns a page of the thread's replies, answers first and then
+// oldest first.
+func (r *discussionRepository) ListReplies(threadID uint, q models.PaginationQuery, viewerID uint) ([]models.DiscussionPostResponse, int64, error) {
+	var replies []models.DiscussionPostResponse
+	var totalItems int64
+
+	if err := r.db.Model(&models.DiscussionPost{}).
+		Where("parent_id = ?", threadID).
+		Count(&totalItems).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := q.Paginate(totalItems).CurrentPage
+
+	err := r.postResponses(viewerID).
+		Where("discussion_posts.parent_id = ?", threadID).
+		Order("discussion_posts.is_answer DESC").
+		Order("discussion_posts.created_at ASC").
+		Order("discussion_posts.id ASC").
+		Limit(q.Limit).
+		Offset((page - 1) * q.Limit).
+		Scan(&replies).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return replies, totalItems, nil
+}
+
+func (r *discussionRepository) CreateThread(post *models.DiscussionPost) error {
+	post.LastActivityAt = time.Now()
+	return r.db.Create(post).Error
+}
+
+// CreateReply adds the reply and bumps the thread's reply count and latest
+// activity.
+func (r *discussionRepository) CreateReply(reply *models.DiscussionPost) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		reply.LastActivityAt = time.Now()
+		if err := tx.Create(reply).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.DiscussionPost{}).
+			Where("id = ?", *reply.ParentID).
+			UpdateColumns(map[string]any{
+				"reply_count":      gorm.Expr("reply_count + 1"),
+				"last_activity_at": reply.LastActivityAt,
+			}).Error
+	})
+}
+
+func (r *discussionRepository) UpdatePost(post *models.DiscussionPost) error {
+	return r.db.Model(&models.DiscussionPost{}).Where("id = ?", post.ID).
+		Updates(map[string]any{
+			"title":     post.Title,
+			"body":      post.Body,
+			"edited_at": post.EditedAt,
+		}).Error
+}
+
+// DeletePost removes a thread with its replies, or a single reply while
+// keeping the thread's counters and answered state in step.
+func (r *discussionRepository) DeletePost(post *models.DiscussionPost) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(post).Error; err != nil {
+			return err
+		}
+		if post.ParentID == nil {
+			return nil
+		}
+
+		if err := tx.Model(&models.DiscussionPost{}).
+			Where("id = ?", *post.ParentID).
+			UpdateColumn("reply_count", gorm.Expr("reply_count - 1")).Error; err != nil {
+			return err
+		}
+		return refreshThreadAnswered(tx, *post.ParentID)
+	})
+}
+
+// Upvote records the user's vote once; voting again changes nothing.
+func (r *discussionRepository) Upvote(postID uint, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.DiscussionVote{PostID: postID, UserID: userID})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+
+		return tx.Model(&models.DiscussionPost{}).Where("id = ?", postID).
+			UpdateColumn("upvote_count", gorm.Expr("upvote_count + 1")).Error
+	})
+}
+
+func (r *discussionRepository) RemoveUpvote(postID uint, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("post_id = ? AND user_id = ?", postID, userID).
+			Delete(&models.DiscussionVote{})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+
+		return tx.Model(&models.DiscussionPost{}).Where("id = ?", postID).
+			UpdateColumn("upvote_count", gorm.Expr("upvote_count - 1")).Error
+	})
+}
+
+func (r *discussionRepository) SetAnswer(reply *models.DiscussionPost, isAnswer bool) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.DiscussionPost{}).Where("id = ?", reply.ID).
+			UpdateColumn("is_answer", isAnswer).Error; err != nil {
+			return err
+		}
+		return refreshThreadAnswered(tx, *reply.ParentID)
+	})
+}
+
+// refreshThreadAnswered keeps the thread's answered time while any reply is
+// marked as the answer and clears it once none is.
+func refreshThreadAnswered(tx *gorm.DB, threadID uint) error {
+	return tx.Exec(`UPDATE discussion_posts SET answered_at = CASE
+			WHEN EXISTS (SELECT 1 FROM discussion_posts r WHERE r.parent_id = discussion_posts.id AND r.is_answer)
+			THEN COALESCE(answered_at, ?)
+			ELSE NULL END
+		WHERE id = ?`, time.Now(), threadID).Error
+}
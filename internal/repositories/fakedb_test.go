@@ -0,0 +1,160 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeDB stands in for Postgres in repository tests. It records every
+// statement it receives and answers those containing a registered fragment
+// with the given rows, rows affected or error. Other queries return no rows
+// and other statements affect one row.
+type fakeDB struct {
+	mu          sync.Mutex
+	statements  []fakeStatement
+	answers     []fakeAnswer
+	connections int
+}
+
+type fakeStatement struct {
+	SQL  string
+	Args []any
+}
+
+type fakeAnswer struct {
+	match    string
+	columns  []string
+	rows     [][]driver.Value
+	affected int64
+	err      error
+}
+
+// newFakeDB returns a gorm handle backed by a new fakeDB.
+func newFakeDB(t *testing.T) (*gorm.DB, *fakeDB) {
+	t.Helper()
+
+	fake := &fakeDB{}
+	sqlDB := sql.OpenDB(fake)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger:               logger.Discard,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, fake
+}
+
+// onQuery answers queries containing match with the rows.
+func (f *fakeDB) onQuery(match string, columns []string, rows ...[]driver.Value) {
+	f.answers = append(f.answers, fakeAnswer{match: match, columns: columns, rows: rows})
+}
+
+// onExec reports affected rows for statements containing match.
+func (f *fakeDB) onExec(match string, affected int64) {
+	f.answers = append(f.answers, fakeAnswer{match: match, affected: affected})
+}
+
+// onError fails statements containing match with err.
+func (f *fakeDB) onError(match string, err error) {
+	f.answers = append(f.answers, fakeAnswer{match: match, err: err})
+}
+
+// find returns the recorded statements containing match.
+func (f *fakeDB) find(match string) []fakeStatement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var found []fakeStatement
+	for _, s := range f.statements {
+		if strings.Contains(s.SQL, match) {
+			found = append(found, s)
+		}
+	}
+	return found
+}
+
+func (f *fakeDB) answer(query string, args []driver.NamedValue) fakeAnswer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stmt := fakeStatement{SQL: query}
+	for _, a := range args {
+		stmt.Args = append(stmt.Args, a.Value)
+	}
+	f.statements = append(f.statements, stmt)
+
+	for _, a := range f.answers {
+		if strings.Contains(query, a.match) {
+			return a
+		}
+	}
+	return fakeAnswer{affected: 1}
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) {
+	f.mu.Lock()
+	f.connections++
+	f.mu.Unlock()
+	return &fakeConn{db: f}, nil
+}
+
+func (f *fakeDB) Driver() driver.Driver { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return nil, driver.ErrSkip }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return c, nil }
+func (c *fakeConn) Commit() error                       { return nil }
+func (c *fakeConn) Rollback() error                     { return nil }
+
+func (c *fakeConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	a := c.db.answer(query, args)
+	if a.err != nil {
+		return nil, a.err
+	}
+	return driver.RowsAffected(a.affected), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	a := c.db.answer(query, args)
+	if a.err != nil {
+		return nil, a.err
+	}
+	return &fakeRows{columns: a.columns, rows: a.rows}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
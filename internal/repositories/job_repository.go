@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/database"
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrJobLost means the worker no longer holds the job: its lease ran out and
+// the job was requeued, and possibly claimed again, in the meantime.
+var ErrJobLost = errors.New("job is no longer held by this worker")
+
+type JobRepository interface {
+	Enqueue(job *models.Job) error
+	ClaimNext(types []string, lease time.Duration) (*models.Job, error)
+	ExtendLease(job *models.Job, until time.Time) error
+	Complete(job *models.Job) error
+	Retry(job *models.Job, lastError string, runAt time.Time) error
+	Fail(job *models.Job, lastError string) error
+	ReclaimExpired(lease time.Duration) (requeued int64, failed int64, err error)
+}
+
+type jobRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRepository() JobRepository {
+	return &jobRepository{db: database.DB}
+}
+
+func (r *jobRepository) Enqueue(job *models.Job) error {
+	if job.Status == "" {
+		job.Status = models.JobStatusPending
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = 3
+	}
+	return r.db.Create(job).Error
+}
+
+// ClaimNext marks the oldest due job of one of the given types as running,
+// leased for the given duration, and returns it, or nil if there is nothing
+// to do. SKIP LOCKED lets several workers poll concurrently without picking
+// the same job.
+func (r *jobRepository) ClaimNext(types []string, lease time.Duration) (*models.Job, error) {
+	var job models.Job
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND run_at <= ? AND type IN ?", models.JobStatusPending, time.Now(), types).
+			Order("run_at").
+			First(&job).Error
+		if err != nil {
+			return err
+		}
+
+		leasedUntil := time.Now().Add(lease)
+		job.Status = models.JobStatusRunning
+		job.Attempts++
+		job.LeasedUntil = &leasedUntil
+		return tx.Model(&job).Updates(map[string]any{
+			"status":       job.Status,
+			"attempts":     job.Attempts,
+			"leased_until": leasedUntil,
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// ExtendLease pushes back when a running job is considered abandoned.
+func (r *jobRepository) ExtendLease(job *models.Job, until time.Time) error {
+	return r.updateClaimed(job, map[string]any{"leased_until": until})
+}
+
+func (r *jobRepository) Complete(job *models.Job) error {
+	return r.updateClaimed(job, map[string]any{"status": models.JobStatusDone, "last_error": ""})
+}
+
+func (r *jobRepository) Retry(job *models.Job, lastError string, runAt time.Time) error {
+	return r.updateClaimed(job, map[string]any{
+		"status":       models.JobStatusPending,
+		"last_error":   lastError,
+		"run_at":       runAt,
+		"leased_until": nil,
+	})
+}
+
+func (r *jobRepository) Fail(job *models.Job, lastError string) error {
+	return r.updateClaimed(job, map[string]any{"status": models.JobStatusFailed, "last_error": lastError})
+}
+
+// updateClaimed updates a job only while it is still running under the claim
+// the worker got from ClaimNext. Every claim bumps Attempts, so a worker whose
+// job was reclaimed and claimed again cannot overwrite the new run's outcome.
+func (r *jobRepository) updateClaimed(job *models.Job, values map[string]any) error {
+	res := r.db.Model(&models.Job{}).
+		Where("id = ? AND status = ? AND attempts = ?", job.ID, models.JobStatusRunning, job.Attempts).
+		Updates(values)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrJobLost
+	}
+	return nil
+}
+
+// ReclaimExpired puts running jobs whose lease has run out, because the
+// worker running them stopped, back in the queue. Jobs that have used all
+// their attempts are failed instead, so a job that keeps killing its worker
+// does not run forever. Jobs claimed before leases existed are reclaimed once
+// they have not been touched for a lease.
+func (r *jobRepository) ReclaimExpired(lease time.Duration) (int64, int64, error) {
+	var requeued, failed int64
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		expired := func() *gorm.DB {
+			return tx.Model(&models.Job{}).
+				Where("status = ?", models.JobStatusRunning).
+				Where("leased_until < ? OR (leased_until IS NULL AND updated_at < ?)", now, now.Add(-lease))
+		}
+
+		res := expired().Where("attempts >= max_attempts").
+			Updates(map[string]any{
+				"status":       models.JobStatusFailed,
+				"leased_until": nil,
+				"last_error":   "worker stopped before the job finished",
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		failed = res.RowsAffected
+
+		res = expired().Updates(map[string]any{"status": models.JobStatusPending, "leased_until": nil})
+		if res.Error != nil {
+			return res.Error
+		}
+		requeued = res.RowsAffected
+		return nil
+	})
+	return requeued, failed, err
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+)
+
+func TestJobUpdatesRequireTheClaim(t *testing.T) {
+	db, fake := newFakeDB(t)
+	repo := &jobRepository{db: db}
+	job := &models.Job{ID: 7, Status: models.JobStatusRunning, Attempts: 2}
+
+	if err := repo.Complete(job); err != nil {
+		t.Fatal(err)
+	}
+	updates := fake.find(`UPDATE "jobs"`)
+	if len(updates) != 1 || !strings.Contains(updates[0].SQL, "status = $") || !strings.Contains(updates[0].SQL, "attempts = $") {
+		t.Fatalf("update = %+v, want it guarded by status and attempts", updates)
+	}
+
+	// The lease ran out and another worker claimed the job again.
+	fake.onExec(`UPDATE "jobs"`, 0)
+	for name, update := range map[string]func() error{
+		"complete": func() error { return repo.Complete(job) },
+		"retry":    func() error { return repo.Retry(job, "boom", time.Now()) },
+		"fail":     func() error { return repo.Fail(job, "boom") },
+		"extend":   func() error { return repo.ExtendLease(job, time.Now()) },
+	} {
+		if err := update(); !errors.Is(err, ErrJobLost) {
+			t.Errorf("%s: err = %v, want ErrJobLost", name, err)
+		}
+	}
+}
+
+func TestReclaimExpiredFailsExhaustedJobs(t *testing.T) {
+	db, fake := newFakeDB(t)
+	fake.onExec("attempts >= max_attempts", 1)
+	fake.onExec(`UPDATE "jobs"`, 2)
+	repo := &jobRepository{db: db}
+
+	requeued, failed, err := repo.ReclaimExpired(time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requeued != 2 || failed != 1 {
+		t.Errorf("requeued = %d, failed = %d; want 2 and 1", requeued, failed)
+	}
+
+	updates := fake.find(`UPDATE "jobs"`)
+	if len(updates) != 2 || !strings.Contains(updates[0].SQL, "attempts >= max_attempts") || !slices.Contains(updates[0].Args, any(models.JobStatusFailed)) {
+		t.Fatalf("updates = %+v, want exhausted jobs failed first", updates)
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/kin-ark/GroAcademy/internal/database"
 	"github.com/kin-ark/GroAcademy/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ModuleRepository interface {
@@ -17,10 +19,21 @@ type ModuleRepository interface {
 	FindById(uint) (*models.Module, error)
 	IsModuleCompleted(id uint, userId uint) (bool, error)
 	ChangeModuleCompletion(moduleID uint, userID uint, completed bool) error
-	ReorderModules(courseID uint, orders []models.ModuleOrder) error
+	FindProgress(moduleID uint, userID uint) (*models.ModuleProgress, error)
+	FindCompletionsByCourse(courseID uint, userID uint) ([]models.ModuleCompletion, error)
+	RecordWatchProgress(moduleID uint, userID uint, update func(*models.ModuleProgress) error) (*models.ModuleProgress, error)
+	ReorderModules(courseID uint, orders []models.ModuleOrder, sectionOrders []models.SectionOrder) error
+	RenumberModules(courseID uint) error
 	GetModuleIDsByCourse(courseID uint, ids *[]uint) error
+	SetVideoState(id uint, videoContent string, fields map[string]any) (bool, error)
+	SetStatus(id uint, status string, publishAt *time.Time) error
+	PublishScheduled(id uint, publishAt time.Time) (bool, error)
 }
 
+// videoStateColumns are owned by the transcoding pipeline and only written
+// through SetVideoState.
+var videoStateColumns = []string{"video_status", "video_hls", "video_poster", "video_duration", "video_error"}
+
 type moduleRepository struct {
 	db *gorm.DB
 }
@@ -47,9 +60,34 @@ func (r *moduleRepository) Update(module *models.Module) error {
 	return r.db.Model(&models.Module{}).
 		Where("id = ?", module.ID).
 		Select("*").
+		Omit(videoStateColumns...).
 		Updates(module).Error
 }
 
+// SetVideoState updates transcoding columns only while the module still
+// holds the given video, so results for a replaced upload are discarded. It
+// reports whether the module was updated.
+func (r *moduleRepository) SetVideoState(id uint, videoContent string, fields map[string]any) (bool, error) {
+	res := r.db.Model(&models.Module{}).
+		Where("id = ? AND video_content = ?", id, videoContent).
+		Updates(fields)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *moduleRepository) SetStatus(id uint, status string, publishAt *time.Time) error {
+	return r.db.Model(&models.Module{}).Where("id = ?", id).
+		Updates(map[string]any{"status": status, "publish_at": publishAt}).Error
+}
+
+// PublishScheduled publishes the module if it is still a draft scheduled for
+// publishAt, and reports whether it did.
+func (r *moduleRepository) PublishScheduled(id uint, publishAt time.Time) (bool, error) {
+	res := r.db.Model(&models.Module{}).
+		Where("id = ? AND status = ? AND publish_at = ?", id, models.StatusDraft, publishAt).
+		Update("status", models.StatusPublished)
+	return res.RowsAffected > 0, res.Error
+}
+
 func (r *moduleRepository) Delete(module *models.Module) error {
 	courseID := module.CourseID
 	deletedOrder := module.Order
@@ -107,8 +145,75 @@ func (r *moduleRepository) ChangeModuleCompletion(moduleID uint, userID uint, co
 	return nil
 }
 
-func (r *moduleRepository) ReorderModules(courseID uint, orders []models.ModuleOrder) error {
+func (r *moduleRepository) FindProgress(moduleID uint, userID uint) (*models.ModuleProgress, error) {
+	var progress models.ModuleProgress
+	err := r.db.Where("module_id = ? AND user_id = ?", moduleID, userID).First(&progress).Error
+	if err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// FindCompletionsByCourse returns every published module of the course in
+// order with the user's completion state.
+func (r *moduleRepository) FindCompletionsByCourse(courseID uint, userID uint) ([]models.ModuleCompletion, error) {
+	var completions []models.ModuleCompletion
+	err := r.db.Model(&models.Module{}).
+		Select(`modules.id, modules."order", COALESCE(module_progresses.is_completed, FALSE) AS is_completed`).
+		Joins("LEFT JOIN module_progresses ON module_progresses.module_id = modules.id AND module_progresses.user_id = ?", userID).
+		Where("modules.course_id = ? AND modules.status = ?", courseID, models.StatusPublished).
+		Order(`modules."order" ASC`).
+		Scan(&completions).Error
+	if err != nil {
+		return nil, err
+	}
+	return completions, nil
+}
+
+// RecordWatchProgress locks the user's progress row for the module, lets
+// update modify it and saves the watch fields, so concurrent heartbeats from
+// several tabs cannot double count watched time.
+func (r *moduleRepository) RecordWatchProgress(moduleID uint, userID uint, update func(*models.ModuleProgress) error) (*models.ModuleProgress, error) {
+	var progress models.ModuleProgress
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("module_id = ? AND user_id = ?", moduleID, userID).
+			First(&progress).Error; err != nil {
+			return err
+		}
+
+		if err := update(&progress); err != nil {
+			return err
+		}
+
+		return tx.Model(&progress).
+			Select("position_seconds", "watched_seconds", "watched_ranges", "watched_percentage", "last_watched_at", "is_completed").
+			Updates(&progress).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+func (r *moduleRepository) ReorderModules(courseID uint, orders []models.ModuleOrder, sectionOrders []models.SectionOrder) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, s := range sectionOrders {
+			if err := tx.Model(&models.Section{}).
+				Where("id = ? AND course_id = ?", s.ID, courseID).
+				Update("order", -int(s.ID)).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, s := range sectionOrders {
+			if err := tx.Model(&models.Section{}).
+				Where("id = ? AND course_id = ?", s.ID, courseID).
+				Update("order", s.Order).Error; err != nil {
+				return err
+			}
+		}
+
 		for _, m := range orders {
 			if err := tx.Model(&models.Module{}).
 				Where("id = ? AND course_id = ?", m.ID, courseID).
@@ -118,17 +223,66 @@ func (r *moduleRepository) ReorderModules(courseID uint, orders []models.ModuleO
 		}
 
 		for _, m := range orders {
+			fields := map[string]any{"order": m.Order}
+			if m.SectionID != nil {
+				fields["section_id"] = nullableID(*m.SectionID)
+			}
 			if err := tx.Model(&models.Module{}).
 				Where("id = ? AND course_id = ?", m.ID, courseID).
-				Update("order", m.Order).Error; err != nil {
+				Updates(fields).Error; err != nil {
 				return err
 			}
 		}
 
-		return nil
+		return renumberModules(tx, courseID)
 	})
 }
 
+func (r *moduleRepository) RenumberModules(courseID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return renumberModules(tx, courseID)
+	})
+}
+
+// renumberModules rewrites module orders as 1..n so that they follow section
+// order, keeping the relative order of modules within each section. Modules
+// without a section come first.
+func renumberModules(tx *gorm.DB, courseID uint) error {
+	var ids []uint
+	err := tx.Model(&models.Module{}).
+		Joins("LEFT JOIN sections ON sections.id = modules.section_id").
+		Where("modules.course_id = ?", courseID).
+		Order(`COALESCE(sections."order", 0), modules."order"`).
+		Pluck("modules.id", &ids).Error
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := tx.Model(&models.Module{}).Where("id = ?", id).
+			Update("order", -int(id)).Error; err != nil {
+			return err
+		}
+	}
+
+	for i, id := range ids {
+		if err := tx.Model(&models.Module{}).Where("id = ?", id).
+			Update("order", i+1).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nullableID maps a zero ID to NULL for optional foreign keys.
+func nullableID(id uint) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
 func (r *moduleRepository) GetModuleIDsByCourse(courseID uint, ids *[]uint) error {
 	return r.db.Model(&models.Module{}).
 		Where("course_id = ?", courseID).
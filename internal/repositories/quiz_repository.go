@@ -0,0 +1,405 @@
+package repositories
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/database"
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNoAttemptsLeft   = errors.New("no quiz attempts left")
+	ErrAttemptSubmitted = errors.New("quiz attempt has already been submitted")
+	ErrUnknownQuizItem  = errors.New("question or option does not belong to this quiz")
+)
+
+type QuizRepository interface {
+	FindByID(id uint) (*models.Quiz, error)
+	FindByModule(moduleID uint) (*models.Quiz, error)
+	Replace(quiz *models.Quiz) error
+	DeleteByModule(moduleID uint) error
+	ModulesWithQuiz(moduleIDs []uint) (map[uint]bool, error)
+	PassedModuleQuiz(moduleID uint, userID uint) (bool, error)
+	FindActiveAttempt(quizID uint, userID uint) (*models.QuizAttempt, error)
+	FindAttempts(quizID uint, userID uint) ([]models.QuizAttempt, error)
+	FindAttempt(id uint) (*models.QuizAttempt, error)
+	StartAttempt(attempt *models.QuizAttempt, maxAttempts int) error
+	SubmitAttempt(attempt *models.QuizAttempt) error
+}
+
+type quizRepository struct {
+	db *gorm.DB
+}
+
+func NewQuizRepository() QuizRepository {
+	return &quizRepository{db: database.DB}
+}
+
+// withQuestions preloads the question bank in display order.
+func (r *quizRepository) withQuestions() *gorm.DB {
+	return r.db.
+		Preload("Questions", func(db *gorm.DB) *gorm.DB { return db.Order(`"order"`) }).
+		Preload("Questions.Options", func(db *gorm.DB) *gorm.DB { return db.Order(`"order"`) })
+}
+
+func (r *quizRepository) FindByID(id uint) (*models.Quiz, error) {
+	var quiz models.Quiz
+	if err := r.withQuestions().First(&quiz, id).Error; err != nil {
+		return nil, err
+	}
+	return &quiz, nil
+}
+
+func (r *quizRepository) FindByModule(moduleID uint) (*models.Quiz, error) {
+	var quiz models.Quiz
+	if err := r.withQuestions().Where("module_id = ?", moduleID).First(&quiz).Error; err != nil {
+		return nil, err
+	}
+	return &quiz, nil
+}
+
+// Replace creates the module's quiz or overwrites its settings and question
+// bank. Questions and options are updated in place, so attempts keep pointing
+// at them: one sent with an ID updates that row, one without reuses an
+// unclaimed row with the same content, and rows left unclaimed are deleted.
+// In-progress attempts that drew a deleted question are discarded, which
+// gives the learner the attempt back.
+func (r *quizRepository) Replace(quiz *models.Quiz) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.Quiz
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("module_id = ?", quiz.ModuleID).
+			First(&existing).Error
+
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return tx.Create(quiz).Error
+		case err != nil:
+			return err
+		}
+
+		quiz.ID = existing.ID
+		quiz.CreatedAt = existing.CreatedAt
+		if err := tx.Model(&existing).Updates(map[string]any{
+			"title":                 quiz.Title,
+			"pass_percentage":       quiz.PassPercentage,
+			"max_attempts":          quiz.MaxAttempts,
+			"questions_per_attempt": quiz.QuestionsPerAttempt,
+		}).Error; err != nil {
+			return err
+		}
+
+		var current []models.QuizQuestion
+		if err := tx.Preload("Options").Where("quiz_id = ?", quiz.ID).Find(&current).Error; err != nil {
+			return err
+		}
+
+		previous := make(map[uint]models.QuizQuestion, len(current))
+		for _, q := range current {
+			previous[q.ID] = q
+		}
+		claimed, err := claimQuizItems(quiz.Questions, current,
+			func(q models.QuizQuestion) uint { return q.ID },
+			func(q *models.QuizQuestion, id uint) { q.ID = id },
+			func(a, b models.QuizQuestion) bool { return a.Type == b.Type && a.Prompt == b.Prompt })
+		if err != nil {
+			return err
+		}
+
+		for i := range quiz.Questions {
+			q := &quiz.Questions[i]
+			q.QuizID = quiz.ID
+			if q.ID == 0 {
+				if err := tx.Create(q).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err := replaceQuestion(tx, q, previous[q.ID].Options); err != nil {
+				return err
+			}
+		}
+
+		removed := make(map[uint]bool)
+		for id := range previous {
+			if !claimed[id] {
+				removed[id] = true
+			}
+		}
+		if len(removed) == 0 {
+			return nil
+		}
+		if err := tx.Delete(&models.QuizQuestion{}, mapKeys(removed)).Error; err != nil {
+			return err
+		}
+		return discardStaleAttempts(tx, quiz.ID, removed)
+	})
+}
+
+// replaceQuestion updates an existing question and its options in place.
+func replaceQuestion(tx *gorm.DB, q *models.QuizQuestion, current []models.QuizOption) error {
+	if err := tx.Model(&models.QuizQuestion{ID: q.ID}).Updates(map[string]any{
+		"type":   q.Type,
+		"prompt": q.Prompt,
+		"points": q.Points,
+		"order":  q.Order,
+	}).Error; err != nil {
+		return err
+	}
+
+	claimed, err := claimQuizItems(q.Options, current,
+		func(o models.QuizOption) uint { return o.ID },
+		func(o *models.QuizOption, id uint) { o.ID = id },
+		func(a, b models.QuizOption) bool { return a.Text == b.Text })
+	if err != nil {
+		return err
+	}
+
+	for i := range q.Options {
+		o := &q.Options[i]
+		o.QuestionID = q.ID
+		if o.ID == 0 {
+			err = tx.Create(o).Error
+		} else {
+			err = tx.Model(&models.QuizOption{ID: o.ID}).Updates(map[string]any{
+				"text":       o.Text,
+				"is_correct": o.IsCorrect,
+				"order":      o.Order,
+			}).Error
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	removed := make(map[uint]bool)
+	for _, o := range current {
+		if !claimed[o.ID] {
+			removed[o.ID] = true
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	return tx.Delete(&models.QuizOption{}, mapKeys(removed)).Error
+}
+
+// claimQuizItems matches incoming items to the current rows and returns the
+// IDs of the rows kept. Explicit IDs are matched first and must belong to
+// current; items without one then take an unclaimed row that is the same.
+func claimQuizItems[T any](items []T, current []T, idOf func(T) uint, setID func(*T, uint), same func(a, b T) bool) (map[uint]bool, error) {
+	known := make(map[uint]bool, len(current))
+	for _, c := range current {
+		known[idOf(c)] = true
+	}
+
+	claimed := make(map[uint]bool, len(items))
+	for _, item := range items {
+		id := idOf(item)
+		if id == 0 {
+			continue
+		}
+		if !known[id] || claimed[id] {
+			return nil, ErrUnknownQuizItem
+		}
+		claimed[id] = true
+	}
+
+	for i := range items {
+		if idOf(items[i]) != 0 {
+			continue
+		}
+		for _, c := range current {
+			if !claimed[idOf(c)] && same(items[i], c) {
+				setID(&items[i], idOf(c))
+				claimed[idOf(c)] = true
+				break
+			}
+		}
+	}
+	return claimed, nil
+}
+
+// discardStaleAttempts deletes the quiz's in-progress attempts that drew one
+// of the removed questions. Deleted attempts do not count towards the limit.
+func discardStaleAttempts(tx *gorm.DB, quizID uint, removed map[uint]bool) error {
+	var attempts []models.QuizAttempt
+	if err := tx.Where("quiz_id = ? AND status = ?", quizID, models.AttemptInProgress).
+		Find(&attempts).Error; err != nil {
+		return err
+	}
+
+	var stale []uint
+	for _, a := range attempts {
+		if slices.ContainsFunc(a.QuestionIDs, func(id uint) bool { return removed[id] }) {
+			stale = append(stale, a.ID)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	return tx.Delete(&models.QuizAttempt{}, stale).Error
+}
+
+func mapKeys(m map[uint]bool) []uint {
+	keys := make([]uint, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (r *quizRepository) DeleteByModule(moduleID uint) error {
+	res := r.db.Where("module_id = ?", moduleID).Delete(&models.Quiz{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *quizRepository) ModulesWithQuiz(moduleIDs []uint) (map[uint]bool, error) {
+	res := make(map[uint]bool)
+	if len(moduleIDs) == 0 {
+		return res, nil
+	}
+
+	var ids []uint
+	if err := r.db.Model(&models.Quiz{}).
+		Where("module_id IN ?", moduleIDs).
+		Pluck("module_id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		res[id] = true
+	}
+	return res, nil
+}
+
+// PassedModuleQuiz reports whether the user passed the module's quiz. Modules
+// without a quiz count as passed.
+func (r *quizRepository) PassedModuleQuiz(moduleID uint, userID uint) (bool, error) {
+	var quiz models.Quiz
+	err := r.db.Select("id").Where("module_id = ?", moduleID).First(&quiz).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var count int64
+	err = r.db.Model(&models.QuizAttempt{}).
+		Where("quiz_id = ? AND user_id = ? AND passed = TRUE", quiz.ID, userID).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// FindActiveAttempt returns the user's unsubmitted attempt, or nil if there is
+// none.
+func (r *quizRepository) FindActiveAttempt(quizID uint, userID uint) (*models.QuizAttempt, error) {
+	var attempt models.QuizAttempt
+	err := r.db.
+		Where("quiz_id = ? AND user_id = ? AND status = ?", quizID, userID, models.AttemptInProgress).
+		First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (r *quizRepository) FindAttempts(quizID uint, userID uint) ([]models.QuizAttempt, error) {
+	var attempts []models.QuizAttempt
+	err := r.db.
+		Where("quiz_id = ? AND user_id = ?", quizID, userID).
+		Order("created_at DESC").
+		Find(&attempts).Error
+	return attempts, err
+}
+
+func (r *quizRepository) FindAttempt(id uint) (*models.QuizAttempt, error) {
+	var attempt models.QuizAttempt
+	if err := r.db.Preload("Answers").First(&attempt, id).Error; err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// StartAttempt creates the attempt unless the user already used maxAttempts
+// attempts (0 means unlimited). The quiz row is locked so concurrent starts
+// cannot exceed the limit.
+func (r *quizRepository) StartAttempt(attempt *models.QuizAttempt, maxAttempts int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var quiz models.Quiz
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			First(&quiz, attempt.QuizID).Error; err != nil {
+			return err
+		}
+
+		if maxAttempts > 0 {
+			var count int64
+			if err := tx.Model(&models.QuizAttempt{}).
+				Where("quiz_id = ? AND user_id = ?", attempt.QuizID, attempt.UserID).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count >= int64(maxAttempts) {
+				return ErrNoAttemptsLeft
+			}
+		}
+
+		attempt.Status = models.AttemptInProgress
+		return tx.Create(attempt).Error
+	})
+}
+
+// SubmitAttempt stores the graded attempt and its answers. It fails with
+// ErrAttemptSubmitted if the attempt was submitted in the meantime.
+func (r *quizRepository) SubmitAttempt(attempt *models.QuizAttempt) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var current models.QuizAttempt
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&current, attempt.ID).Error; err != nil {
+			return err
+		}
+		if current.Status != models.AttemptInProgress {
+			return ErrAttemptSubmitted
+		}
+
+		now := time.Now()
+		attempt.Status = models.AttemptSubmitted
+		attempt.SubmittedAt = &now
+		if err := tx.Model(&current).Updates(map[string]any{
+			"status":       attempt.Status,
+			"score":        attempt.Score,
+			"max_score":    attempt.MaxScore,
+			"percentage":   attempt.Percentage,
+			"passed":       attempt.Passed,
+			"submitted_at": attempt.SubmittedAt,
+		}).Error; err != nil {
+			return err
+		}
+
+		if len(attempt.Answers) == 0 {
+			return nil
+		}
+		for i := range attempt.Answers {
+			attempt.Answers[i].AttemptID = attempt.ID
+		}
+		return tx.Create(&attempt.Answers).Error
+	})
+}
@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"github.com/kin-ark/GroAcademy/internal/database"
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"gorm.io/gorm"
+)
+
+type ReviewRepository interface {
+	FindByID(id uint) (*models.Review, error)
+	FindByCourseAndUser(courseID uint, userID uint) (*models.Review, error)
+	List(q models.ReviewQuery, statuses []string) ([]models.ReviewResponse, int64, error)
+	Create(review *models.Review) error
+	UpdateContent(review *models.Review) error
+	Moderate(review *models.Review) error
+	Delete(review *models.Review) error
+}
+
+type reviewRepository struct {
+	db *gorm.DB
+}
+
+func NewReviewRepository() ReviewRepository {
+	return &reviewRepository{db: database.DB}
+}
+
+func (r *reviewRepository) FindByID(id uint) (*models.Review, error) {
+	var review models.Review
+	if err := r.db.Preload("User").First(&review, id).Error; err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r *reviewRepository) FindByCourseAndUser(courseID uint, userID uint) (*models.Review, error) {
+	var review models.Review
+	if err := r.db.Preload("User").
+		Where("course_id = ? AND user_id = ?", courseID, userID).
+		First(&review).Error; err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// List returns reviews in the given statuses, newest first, optionally for a
+// single course.
+func (r *reviewRepository) List(q models.ReviewQuery, statuses []string) ([]models.ReviewResponse, int64, error) {
+	var reviews []models.ReviewResponse
+	var totalItems int64
+
+	base := r.db.Table("reviews").
+		Joins("JOIN courses ON courses.id = reviews.course_id").
+		Joins("JOIN users ON users.id = reviews.user_id").
+		Where("reviews.status IN ?", statuses)
+
+	if q.CourseID != 0 {
+		base = base.Where("reviews.course_id = ?", q.CourseID)
+	}
+
+	if err := base.Count(&totalItems).Error; err != nil {
+		return nil, 0, err
+	}
+
+	q.Page = q.Paginate(totalItems).CurrentPage
+
+	err := base.Select(`reviews.id, reviews.course_id, courses.title AS course_title,
+			reviews.user_id, users.username, reviews.rating, reviews.body, reviews.status,
+			reviews.moderation_note, reviews.moderated_at, reviews.edited_at, reviews.created_at`).
+		Order("reviews.created_at DESC").
+		Order("reviews.id DESC").
+		Limit(q.Limit).
+		Offset((q.Page - 1) * q.Limit).
+		Scan(&reviews).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return reviews, totalItems, nil
+}
+
+func (r *reviewRepository) Create(review *models.Review) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(review).Error; err != nil {
+			return err
+		}
+		return refreshCourseRating(tx, review.CourseID)
+	})
+}
+
+func (r *reviewRepository) UpdateContent(review *models.Review) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Review{}).Where("id = ?", review.ID).
+			Updates(map[string]any{
+				"rating":    review.Rating,
+				"body":      review.Body,
+				"edited_at": review.EditedAt,
+			}).Error; err != nil {
+			return err
+		}
+		return refreshCourseRating(tx, review.CourseID)
+	})
+}
+
+func (r *reviewRepository) Moderate(review *models.Review) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Review{}).Where("id = ?", review.ID).
+			Updates(map[string]any{
+				"status":          review.Status,
+				"moderation_note": review.ModerationNote,
+				"moderated_by":    review.ModeratedBy,
+				"moderated_at":    review.ModeratedAt,
+			}).Error; err != nil {
+			return err
+		}
+		return refreshCourseRating(tx, review.CourseID)
+	})
+}
+
+func (r *reviewRepository) Delete(review *models.Review) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(review).Error; err != nil {
+			return err
+		}
+		return refreshCourseRating(tx, review.CourseID)
+	})
+}
+
+// refreshCourseRating recomputes the course's rating from its reviews that
+// are not hidden. It runs in the transaction that changed the reviews so the
+// summary never drifts from them.
+func refreshCourseRating(tx *gorm.DB, courseID uint) error {
+	return tx.Exec(`UPDATE courses SET
+			rating_average = COALESCE((SELECT ROUND(AVG(rating), 2) FROM reviews WHERE course_id = courses.id AND status <> ?), 0),
+			rating_count = (SELECT COUNT(*) FROM reviews WHERE course_id = courses.id AND status <> ?)
+		WHERE id = ?`, models.ReviewHidden, models.ReviewHidden, courseID).Error
+}
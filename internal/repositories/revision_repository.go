@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"github.com/kin-ark/GroAcademy/internal/database"
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RevisionRepository interface {
+	CreateModuleRevision(rev *models.ModuleRevision) error
+	FindModuleRevisions(moduleID uint) ([]models.ModuleRevision, error)
+	FindModuleRevision(moduleID uint, number int) (*models.ModuleRevision, error)
+	FindModuleRevisionsByCourse(courseID uint) ([]models.ModuleRevision, error)
+	CountModuleRevisions(moduleID uint) (int64, error)
+	CreateCourseRevision(rev *models.CourseRevision) error
+	FindCourseRevisions(courseID uint) ([]models.CourseRevision, error)
+	FindCourseRevision(courseID uint, number int) (*models.CourseRevision, error)
+	CountCourseRevisions(courseID uint) (int64, error)
+}
+
+type revisionRepository struct {
+	db *gorm.DB
+}
+
+func NewRevisionRepository() RevisionRepository {
+	return &revisionRepository{db: database.DB}
+}
+
+// CreateModuleRevision numbers the revision after the module's latest one.
+// The module row is locked so concurrent edits get distinct numbers.
+func (r *revisionRepository) CreateModuleRevision(rev *models.ModuleRevision) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").First(&models.Module{}, rev.ModuleID).Error; err != nil {
+			return err
+		}
+
+		var latest int
+		if err := tx.Model(&models.ModuleRevision{}).
+			Where("module_id = ?", rev.ModuleID).
+			Select("COALESCE(MAX(number), 0)").
+			Scan(&latest).Error; err != nil {
+			return err
+		}
+
+		rev.Number = latest + 1
+		return tx.Create(rev).Error
+	})
+}
+
+// FindModuleRevisions returns the module's revisions, newest first.
+func (r *revisionRepository) FindModuleRevisions(moduleID uint) ([]models.ModuleRevision, error) {
+	var revisions []models.ModuleRevision
+	err := r.db.Preload("Author").
+		Where("module_id = ?", moduleID).
+		Order("number DESC").
+		Find(&revisions).Error
+	return revisions, err
+}
+
+func (r *revisionRepository) FindModuleRevision(moduleID uint, number int) (*models.ModuleRevision, error) {
+	var rev models.ModuleRevision
+	if err := r.db.Preload("Author").
+		Where("module_id = ? AND number = ?", moduleID, number).
+		First(&rev).Error; err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// FindModuleRevisionsByCourse returns the revisions of every module in the
+// course, so their files can be cleaned up with it.
+func (r *revisionRepository) FindModuleRevisionsByCourse(courseID uint) ([]models.ModuleRevision, error) {
+	var revisions []models.ModuleRevision
+	err := r.db.Joins("JOIN modules ON modules.id = module_revisions.module_id").
+		Where("modules.course_id = ?", courseID).
+		Find(&revisions).Error
+	return revisions, err
+}
+
+func (r *revisionRepository) CountModuleRevisions(moduleID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.ModuleRevision{}).Where("module_id = ?", moduleID).Count(&count).Error
+	return count, err
+}
+
+// CreateCourseRevision numbers the revision after the course's latest one.
+func (r *revisionRepository) CreateCourseRevision(rev *models.CourseRevision) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").First(&models.Course{}, rev.CourseID).Error; err != nil {
+			return err
+		}
+
+		var latest int
+		if err := tx.Model(&models.CourseRevision{}).
+			Where("course_id = ?", rev.CourseID).
+			Select("COALESCE(MAX(number), 0)").
+			Scan(&latest).Error; err != nil {
+			return err
+		}
+
+		rev.Number = latest + 1
+		return tx.Create(rev).Error
+	})
+}
+
+// FindCourseRevisions returns the course's revisions, newest first.
+func (r *revisionRepository) FindCourseRevisions(courseID uint) ([]models.CourseRevision, error) {
+	var revisions []models.CourseRevision
+	err := r.db.Preload("Author").
+		Where("course_id = ?", courseID).
+		Order("number DESC").
+		Find(&revisions).Error
+	return revisions, err
+}
+
+func (r *revisionRepository) FindCourseRevision(courseID uint, number int) (*models.CourseRevision, error) {
+	var rev models.CourseRevision
+	if err := r.db.Preload("Author").
+		Where("course_id = ? AND number = ?", courseID, number).
+		First(&rev).Error; err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+func (r *revisionRepository) CountCourseRevisions(courseID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.CourseRevision{}).Where("course_id = ?", courseID).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"github.com/kin-ark/GroAcademy/internal/database"
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"gorm.io/gorm"
+)
+
+type SectionRepository interface {
+	FindByCourse(courseID uint) ([]models.Section, error)
+	FindByID(id uint) (*models.Section, error)
+	Create(section *models.Section) error
+	UpdateTitle(section *models.Section) error
+	Delete(section *models.Section) error
+}
+
+type sectionRepository struct {
+	db *gorm.DB
+}
+
+func NewSectionRepository() SectionRepository {
+	return &sectionRepository{db: database.DB}
+}
+
+func (r *sectionRepository) FindByCourse(courseID uint) ([]models.Section, error) {
+	var sections []models.Section
+	err := r.db.Where("course_id = ?", courseID).
+		Order(`"order" ASC`).
+		Find(&sections).Error
+	return sections, err
+}
+
+func (r *sectionRepository) FindByID(id uint) (*models.Section, error) {
+	var section models.Section
+	if err := r.db.First(&section, id).Error; err != nil {
+		return nil, err
+	}
+	return &section, nil
+}
+
+// Create appends the section after the course's existing sections.
+func (r *sectionRepository) Create(section *models.Section) error {
+	var maxOrder int
+	err := r.db.Model(&models.Section{}).
+		Where("course_id = ?", section.CourseID).
+		Select(`COALESCE(MAX("order"), 0)`).
+		Scan(&maxOrder).Error
+	if err != nil {
+		return err
+	}
+	section.Order = maxOrder + 1
+	return r.db.Create(section).Error
+}
+
+func (r *sectionRepository) UpdateTitle(section *models.Section) error {
+	return r.db.Model(section).Update("title", section.Title).Error
+}
+
+// Delete removes the section and closes the gap in section orders. Its
+// modules are kept without a section and move ahead of the remaining
+// sections.
+func (r *sectionRepository) Delete(section *models.Section) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Module{}).
+			Where("section_id = ?", section.ID).
+			Update("section_id", nil).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Delete(section).Error; err != nil {
+			return err
+		}
+
+		// Shift through negative orders so the unique index never sees two
+		// sections sharing an order mid-update.
+		if err := tx.Model(&models.Section{}).
+			Where(`course_id = ? AND "order" > ?`, section.CourseID, section.Order).
+			Update("order", gorm.Expr(`-"order"`)).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Section{}).
+			Where(`course_id = ? AND "order" < 0`, section.CourseID).
+			Update("order", gorm.Expr(`-"order" - 1`)).Error; err != nil {
+			return err
+		}
+
+		return renumberModules(tx, section.CourseID)
+	})
+}
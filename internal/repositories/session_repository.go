@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/database"
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type SessionRepository interface {
+	Create(session *models.Session) error
+	FindActiveByRefreshTokenHash(hash string) (*models.Session, error)
+	FindActiveByUser(userID uint) ([]models.Session, error)
+	RotateRefreshToken(session *models.Session, hash string, expiresAt time.Time) error
+	Revoke(id uint, userID uint) error
+	RevokeAllForUser(userID uint) error
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository() SessionRepository {
+	return &sessionRepository{db: database.DB}
+}
+
+func (r *sessionRepository) Create(session *models.Session) error {
+	return r.db.Create(session).Error
+}
+
+func (r *sessionRepository) FindActiveByRefreshTokenHash(hash string) (*models.Session, error) {
+	var session models.Session
+	err := r.db.Where("refresh_token_hash = ? AND revoked_at IS NULL AND expires_at > ?", hash, time.Now()).
+		First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) FindActiveByUser(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RotateRefreshToken swaps the session's refresh token only if it still holds
+// the one being presented, so a token can never be redeemed twice.
+func (r *sessionRepository) RotateRefreshToken(session *models.Session, hash string, expiresAt time.Time) error {
+	now := time.Now()
+	result := r.db.Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", session.ID, session.RefreshTokenHash).
+		Updates(map[string]any{
+			"refresh_token_hash": hash,
+			"expires_at":         expiresAt,
+			"last_used_at":       now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+
+	session.RefreshTokenHash = hash
+	session.ExpiresAt = expiresAt
+	session.LastUsedAt = now
+	return nil
+}
+
+func (r *sessionRepository) Revoke(id uint, userID uint) error {
+	result := r.db.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *sessionRepository) RevokeAllForUser(userID uint) error {
+	return r.db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -3,11 +3,12 @@ package repositories
 import (
 	"errors"
 	"fmt"
-	"math"
+	"time"
 
 	"github.com/kin-ark/GroAcademy/internal/database"
 	"github.com/kin-ark/GroAcademy/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository interface {
@@ -19,6 +20,12 @@ type UserRepository interface {
 	FindById(id uint) (*models.User, error)
 	GetNumberOfCoursePurchased(id uint) (int, error)
 	AddUserBalance(id uint, increment float64) error
+	GetBalanceTransactions(id uint, query models.PaginationQuery) ([]models.BalanceTransaction, int64, error)
+	HasBalanceTransactions(id uint) (bool, error)
+	FindByEmail(email string) (*models.User, error)
+	MarkEmailVerified(id uint) error
+	UpdatePassword(id uint, hash string) error
+	UpdateRole(id uint, role string) error
 }
 
 type userRepository struct {
@@ -42,6 +49,33 @@ func (r *userRepository) FindByIdentifier(identifier string) (*models.User, erro
 	return &user, nil
 }
 
+func (r *userRepository) FindByEmail(email string) (*models.User, error) {
+	var user models.User
+	err := r.db.Where("LOWER(email) = LOWER(?)", email).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) MarkEmailVerified(id uint) error {
+	return r.db.Model(&models.User{}).
+		Where("id = ? AND email_verified_at IS NULL", id).
+		UpdateColumn("email_verified_at", time.Now()).Error
+}
+
+func (r *userRepository) UpdatePassword(id uint, hash string) error {
+	return r.db.Model(&models.User{}).
+		Where("id = ?", id).
+		Update("password", hash).Error
+}
+
+func (r *userRepository) UpdateRole(id uint, role string) error {
+	return r.db.Model(&models.User{}).
+		Where("id = ?", id).
+		Update("role", role).Error
+}
+
 func (r *userRepository) GetAllUsers(query models.SearchQuery) ([]models.User, int64, error) {
 	var results []models.User
 	var totalItems int64
@@ -58,16 +92,7 @@ func (r *userRepository) GetAllUsers(query models.SearchQuery) ([]models.User, i
 		return nil, 0, err
 	}
 
-	totalPages := int(math.Ceil(float64(totalItems) / float64(query.Limit)))
-	if totalPages == 0 {
-		totalPages = 1
-	}
-	if query.Page > totalPages {
-		query.Page = totalPages
-	}
-	if query.Page < 1 {
-		query.Page = 1
-	}
+	query.Page = query.Paginate(totalItems).CurrentPage
 
 	offset := (query.Page - 1) * query.Limit
 	if err := base.Limit(query.Limit).Offset(offset).Scan(&results).Error; err != nil {
@@ -90,7 +115,7 @@ func (r *userRepository) FindById(id uint) (*models.User, error) {
 func (r *userRepository) GetNumberOfCoursePurchased(id uint) (int, error) {
 	var coursesPurchased int64
 	err := r.db.Model(&models.Purchase{}).
-		Where("purchases.user_id = ?", id).
+		Where("purchases.user_id = ? AND purchases.refunded_at IS NULL", id).
 		Count(&coursesPurchased).Error
 	if err != nil {
 		return -1, err
@@ -101,24 +126,72 @@ func (r *userRepository) GetNumberOfCoursePurchased(id uint) (int, error) {
 }
 
 func (r *userRepository) AddUserBalance(id uint, increment float64) error {
-	result := r.db.Debug().
-		Model(&models.User{}).
-		Where("id = ?", id).
-		UpdateColumn("balance", gorm.Expr("balance + ?", increment))
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("no user record found for user " + fmt.Sprint(id))
+			}
+			return err
+		}
+
+		user.Balance += increment
+		if err := tx.Model(&models.User{}).
+			Where("id = ?", id).
+			UpdateColumn("balance", user.Balance).Error; err != nil {
+			return err
+		}
+
+		return recordBalanceTransaction(tx, &models.BalanceTransaction{
+			UserID:       id,
+			Type:         models.TransactionTypeTopUp,
+			Amount:       increment,
+			BalanceAfter: user.Balance,
+			Description:  "Balance top-up",
+		})
+	})
+}
+
+func (r *userRepository) HasBalanceTransactions(id uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.BalanceTransaction{}).Where("user_id = ?", id).Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *userRepository) GetBalanceTransactions(id uint, query models.PaginationQuery) ([]models.BalanceTransaction, int64, error) {
+	var results []models.BalanceTransaction
+	var totalItems int64
 
-	if result.Error != nil {
-		return result.Error
+	base := r.db.Model(&models.BalanceTransaction{}).Where("user_id = ?", id)
+
+	if err := base.Count(&totalItems).Error; err != nil {
+		return nil, 0, err
 	}
-	if result.RowsAffected == 0 {
-		return errors.New("no user record found for user " + fmt.Sprint(id))
+
+	query.Page = query.Paginate(totalItems).CurrentPage
+
+	offset := (query.Page - 1) * query.Limit
+	if err := base.Order("created_at DESC, id DESC").Limit(query.Limit).Offset(offset).Find(&results).Error; err != nil {
+		return nil, 0, err
 	}
-	return nil
+
+	return results, totalItems, nil
+}
+
+// recordBalanceTransaction appends a ledger entry using the caller's
+// transaction so it commits or rolls back together with the balance change.
+func recordBalanceTransaction(tx *gorm.DB, entry *models.BalanceTransaction) error {
+	return tx.Create(entry).Error
 }
 
+// Update saves the user's profile and password. Balance and role are left
+// out: they only change through AddUserBalance, purchases, refunds and
+// UpdateRole, so a stale copy of the user cannot overwrite them.
 func (r *userRepository) Update(user *models.User) error {
 	return r.db.Model(&models.User{}).
 		Where("id = ?", user.ID).
-		Select("*").
+		Select("first_name", "last_name", "username", "email", "password", "updated_at").
 		Updates(user).Error
 }
 
@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+)
+
+func TestUserUpdateLeavesBalanceAndRole(t *testing.T) {
+	db, fake := newFakeDB(t)
+	repo := &userRepository{db: db}
+
+	user := &models.User{ID: 7, FirstName: "Ada", LastName: "Lovelace", Username: "ada", Email: "ada@example.com", Password: "hash", Role: "admin", Balance: 50}
+	if err := repo.Update(user); err != nil {
+		t.Fatal(err)
+	}
+
+	updates := fake.find(`UPDATE "users"`)
+	if len(updates) != 1 {
+		t.Fatalf("got %d user updates, want 1", len(updates))
+	}
+	sql := updates[0].SQL
+	for _, column := range []string{`"first_name"`, `"last_name"`, `"username"`, `"email"`, `"password"`, `"updated_at"`} {
+		if !strings.Contains(sql, column) {
+			t.Errorf("update %q does not set %s", sql, column)
+		}
+	}
+	for _, column := range []string{`"balance"`, `"role"`} {
+		if strings.Contains(sql, column) {
+			t.Errorf("update %q sets %s", sql, column)
+		}
+	}
+}
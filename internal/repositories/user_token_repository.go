@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/database"
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrTokenNotFound = errors.New("token is invalid or has expired")
+
+type UserTokenRepository interface {
+	Create(token *models.UserToken) error
+	Consume(hash string, purpose string) (*models.UserToken, error)
+	InvalidateForUser(userID uint, purpose string) error
+}
+
+type userTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewUserTokenRepository() UserTokenRepository {
+	return &userTokenRepository{db: database.DB}
+}
+
+func (r *userTokenRepository) Create(token *models.UserToken) error {
+	return r.db.Create(token).Error
+}
+
+// Consume marks a valid token as used and returns it. The conditional update
+// guarantees that concurrent requests cannot redeem the same token twice.
+func (r *userTokenRepository) Consume(hash string, purpose string) (*models.UserToken, error) {
+	var token models.UserToken
+	err := r.db.Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", hash, purpose, time.Now()).
+		First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTokenNotFound
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	result := r.db.Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL", token.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrTokenNotFound
+	}
+
+	token.UsedAt = &now
+	return &token, nil
+}
+
+func (r *userTokenRepository) InvalidateForUser(userID uint, purpose string) error {
+	return r.db.Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
@@ -5,27 +5,44 @@ import (
 	"html/template"
 	"log"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kin-ark/GroAcademy/internal/controllers"
+	"github.com/kin-ark/GroAcademy/internal/mailer"
 	"github.com/kin-ark/GroAcademy/internal/middlewares"
 	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/rbac"
 	"github.com/kin-ark/GroAcademy/internal/repositories"
 	"github.com/kin-ark/GroAcademy/internal/services"
+	"github.com/kin-ark/GroAcademy/internal/storage"
+	"github.com/kin-ark/GroAcademy/internal/utils"
 )
 
 func SetupHTMLRenderer(router *gin.Engine) {
 	funcMap := template.FuncMap{
-		"add":                 func(a, b int) int { return a + b },
-		"sub":                 func(a, b int) int { return a - b },
-		"mul":                 func(a, b int) int { return a * b },
-		"moduleURL":           moduleURL,
-		"moduleURLWithType":   moduleURLWithType,
-		"moduleCompletionURL": moduleCompletionURL,
-		"moduleTypeLabel":     moduleTypeLabel,
-		"hasMultipleContent":  hasMultipleContent,
-		"shouldShowPDF":       shouldShowPDF,
-		"shouldShowVideo":     shouldShowVideo,
+		"add":                  func(a, b int) int { return a + b },
+		"sub":                  func(a, b int) int { return a - b },
+		"mul":                  func(a, b int) int { return a * b },
+		"deref":                func(p *int) int { return *p },
+		"derefFloat":           func(p *float64) float64 { return *p },
+		"moduleURL":            moduleURL,
+		"moduleURLWithType":    moduleURLWithType,
+		"moduleCompletionURL":  moduleCompletionURL,
+		"moduleProgressURL":    moduleProgressURL,
+		"moduleQuizURL":        moduleQuizURL,
+		"moduleAssignmentURL":  moduleAssignmentURL,
+		"moduleDiscussionURL":  moduleDiscussionURL,
+		"moduleTypeLabel":      moduleTypeLabel,
+		"hasMultipleContent":   hasMultipleContent,
+		"shouldShowPDF":        shouldShowPDF,
+		"shouldShowVideo":      shouldShowVideo,
+		"shouldShowQuiz":       shouldShowQuiz,
+		"shouldShowAssignment": shouldShowAssignment,
+		"activeContent":        activeContent,
+		"can":                  can,
+		"stars":                stars,
 	}
 
 	tmpl := template.New("").Funcs(funcMap)
@@ -45,12 +62,36 @@ func RegisterFEoutes(r *gin.Engine) {
 	userRepo := repositories.NewUserRepository()
 	courseRepo := repositories.NewCourseRepository()
 	moduleRepo := repositories.NewModuleRepository()
-
+	sessionRepo := repositories.NewSessionRepository()
+	userTokenRepo := repositories.NewUserTokenRepository()
+	quizRepo := repositories.NewQuizRepository()
+	assignmentRepo := repositories.NewAssignmentRepository()
+	sectionRepo := repositories.NewSectionRepository()
+	revisionRepo := repositories.NewRevisionRepository()
+	reviewRepo := repositories.NewReviewRepository()
+	discussionRepo := repositories.NewDiscussionRepository()
+	certificateRepo := repositories.NewCertificateRepository()
+	certificateTemplateRepo := repositories.NewCertificateTemplateRepository()
+	couponRepo := repositories.NewCouponRepository()
+
+	jobRepo := repositories.NewJobRepository()
+	store := storage.NewFromEnv()
+	signer := storage.SignerFromEnv()
+	certSigner := utils.CertificateSignerFromEnv()
+
+	authService := services.NewAuthService(userRepo, sessionRepo, userTokenRepo, mailer.NewFromEnv())
 	userService := services.NewUserService(userRepo)
-	courseService := services.NewCourseService(courseRepo)
-	moduleService := services.NewModuleService(moduleRepo, courseRepo)
+	courseService := services.NewCourseService(courseRepo, jobRepo, revisionRepo, couponRepo, services.LoadRefundPolicy(), store)
+	certificateService := services.NewCertificateService(certificateRepo, certificateTemplateRepo, courseRepo, jobRepo, store, certSigner)
+	moduleService := services.NewModuleService(moduleRepo, courseRepo, jobRepo, quizRepo, assignmentRepo, sectionRepo, revisionRepo, store, signer, certificateService, services.LoadWatchPolicy())
+
+	quizService := services.NewQuizService(quizRepo, moduleRepo, courseRepo, moduleService)
+	assignmentService := services.NewAssignmentService(assignmentRepo, moduleRepo, courseRepo, moduleService, store)
+	sectionService := services.NewSectionService(sectionRepo, courseRepo)
+	reviewService := services.NewReviewService(reviewRepo, courseRepo)
+	discussionService := services.NewDiscussionService(discussionRepo, moduleRepo, courseRepo, moduleService)
 
-	fc := controllers.NewFEController(userService, courseService, moduleService)
+	fc := controllers.NewFEController(authService, userService, courseService, moduleService, quizService, assignmentService, sectionService, reviewService, discussionService, certificateService)
 
 	r.GET("/", func(c *gin.Context) {
 		c.Redirect(http.StatusMovedPermanently, "/login")
@@ -60,25 +101,47 @@ func RegisterFEoutes(r *gin.Engine) {
 
 	r.GET("/register", middlewares.RedirectIfAuthenticated, fc.ShowRegisterPage)
 
-	r.GET("/logout", func(c *gin.Context) {
-		c.SetCookie("Authorization", "", -1, "", "", false, true)
+	r.GET("/forgot-password", middlewares.RedirectIfAuthenticated, fc.ShowForgotPasswordPage)
 
-		c.HTML(http.StatusOK, "login.html", gin.H{
-			"message": "Logged out",
-		})
-	})
+	r.GET("/reset-password", fc.ShowResetPasswordPage)
+
+	r.GET("/verify-email", fc.VerifyEmailPage)
+
+	r.GET("/logout", middlewares.FERequireAuth, fc.Logout)
 
 	r.GET("/courses", middlewares.FERequireAuth, fc.GetCoursesPage)
 
 	r.GET("/my-courses", middlewares.FERequireAuth, fc.GetMyCoursesPage)
+	r.GET("/my-certificates", middlewares.FERequireAuth, fc.GetMyCertificatesPage)
+	r.GET("/my-certificates/:id/download", middlewares.FERequireAuth, fc.DownloadCertificateFE)
+
+	r.GET("/instructor", middlewares.FERequireAuth, fc.GetInstructorDashboardPage)
+	r.GET("/instructor/submissions", middlewares.FERequireAuth, fc.GetSubmissionQueuePage)
+	r.POST("/instructor/submissions/:id/grade", middlewares.FERequireAuth, fc.GradeSubmissionFE)
+
+	r.GET("/verify/:serial", middlewares.FEOptionalAuth, fc.GetCertificateVerificationPage)
+	r.POST("/verify/:serial/revoke", middlewares.FERequireAuth, fc.RevokeCertificateFE)
+	r.POST("/verify/:serial/reinstate", middlewares.FERequireAuth, fc.ReinstateCertificateFE)
 
 	r.GET("/course/:id", middlewares.FERequireAuth, fc.GetCourseDetailPage)
 
 	r.POST("/course/:id/purchase", middlewares.FERequireAuth, fc.BuyCourseFE)
+	r.POST("/course/:id/refund", middlewares.FERequireAuth, fc.RefundCourseFE)
+	r.POST("/course/:id/review", middlewares.FERequireAuth, fc.SaveReviewFE)
+	r.POST("/course/:id/reviews/:reviewId/moderate", middlewares.FERequireAuth, fc.ModerateReviewFE)
 
 	r.GET("/course/:id/modules", middlewares.FERequireAuth, fc.GetCourseModulesPage)
 	r.GET("/course/:id/modules/:moduleId", middlewares.FERequireAuth, fc.GetCourseModulesPage)
 	r.POST("/course/:id/modules/:moduleId/completion", middlewares.FERequireAuth, fc.ToggleModuleCompletion)
+	r.POST("/course/:id/modules/:moduleId/progress", middlewares.FERequireAuth, fc.UpdateModuleProgressFE)
+	r.POST("/course/:id/modules/:moduleId/quiz/start", middlewares.FERequireAuth, fc.StartQuizFE)
+	r.POST("/course/:id/modules/:moduleId/quiz/submit", middlewares.FERequireAuth, fc.SubmitQuizFE)
+	r.POST("/course/:id/modules/:moduleId/assignment/submit", middlewares.FERequireAuth, middlewares.LimitBodySize(storage.MaxRequestSize()), fc.SubmitAssignmentFE)
+	r.POST("/course/:id/modules/:moduleId/discussions", middlewares.FERequireAuth, fc.CreateDiscussionThreadFE)
+	r.POST("/course/:id/modules/:moduleId/discussions/:postId/replies", middlewares.FERequireAuth, fc.ReplyDiscussionFE)
+	r.POST("/course/:id/modules/:moduleId/discussions/:postId/upvote", middlewares.FERequireAuth, fc.ToggleDiscussionUpvoteFE)
+	r.POST("/course/:id/modules/:moduleId/discussions/:postId/answer", middlewares.FERequireAuth, fc.ToggleDiscussionAnswerFE)
+	r.POST("/course/:id/modules/:moduleId/discussions/:postId/delete", middlewares.FERequireAuth, fc.DeleteDiscussionPostFE)
 
 	r.NoRoute(func(c *gin.Context) {
 		c.HTML(http.StatusNotFound, "404.html", gin.H{
@@ -87,6 +150,16 @@ func RegisterFEoutes(r *gin.Engine) {
 	})
 }
 
+// stars draws a 1-5 rating as filled and empty stars.
+func stars(rating int) string {
+	rating = max(0, min(rating, 5))
+	return strings.Repeat("\u2605", rating) + strings.Repeat("\u2606", 5-rating)
+}
+
+func can(role string, permission string) bool {
+	return rbac.HasPermission(role, rbac.Permission(permission))
+}
+
 func moduleURL(courseID, moduleID uint) string {
 	return fmt.Sprintf("/course/%d/modules/%d", courseID, moduleID)
 }
@@ -99,44 +172,94 @@ func moduleCompletionURL(courseID, moduleID uint) string {
 	return fmt.Sprintf("/course/%d/modules/%d/completion", courseID, moduleID)
 }
 
+func moduleProgressURL(courseID, moduleID uint) string {
+	return fmt.Sprintf("/course/%d/modules/%d/progress", courseID, moduleID)
+}
+
+func moduleQuizURL(courseID, moduleID uint, action string) string {
+	return fmt.Sprintf("/course/%d/modules/%d/quiz/%s", courseID, moduleID, action)
+}
+
+func moduleAssignmentURL(courseID, moduleID uint) string {
+	return fmt.Sprintf("/course/%d/modules/%d/assignment/submit", courseID, moduleID)
+}
+
+// moduleDiscussionURL is where the module's discussion forms post: new
+// threads without a post, or an action on the given post.
+func moduleDiscussionURL(courseID, moduleID, postID uint, action string) string {
+	if postID == 0 {
+		return fmt.Sprintf("/course/%d/modules/%d/discussions", courseID, moduleID)
+	}
+	return fmt.Sprintf("/course/%d/modules/%d/discussions/%d/%s", courseID, moduleID, postID, action)
+}
+
 func moduleTypeLabel(module models.ModuleWithIsCompleted) string {
-	hasPDF := module.PDFContent != ""
-	hasVideo := module.VideoContent != ""
+	kinds := contentKinds(module)
 
-	if hasPDF && hasVideo {
-		return "PDF + Video"
-	} else if hasPDF {
+	switch {
+	case len(kinds) == 0:
+		return "No Content"
+	case len(kinds) == 1 && kinds[0] == "pdf":
 		return "PDF Content"
-	} else if hasVideo {
+	case len(kinds) == 1 && kinds[0] == "video":
 		return "Video Content"
 	}
-	return "No Content"
+
+	labels := map[string]string{"pdf": "PDF", "video": "Video", "quiz": "Quiz", "assignment": "Assignment"}
+	parts := make([]string, 0, len(kinds))
+	for _, k := range kinds {
+		parts = append(parts, labels[k])
+	}
+	return strings.Join(parts, " + ")
+}
+
+// contentKinds lists the content types a module offers, in tab order.
+func contentKinds(module models.ModuleWithIsCompleted) []string {
+	var kinds []string
+	if module.PDFContent != "" {
+		kinds = append(kinds, "pdf")
+	}
+	if module.VideoContent != "" {
+		kinds = append(kinds, "video")
+	}
+	if module.HasQuiz {
+		kinds = append(kinds, "quiz")
+	}
+	if module.HasAssignment {
+		kinds = append(kinds, "assignment")
+	}
+	return kinds
 }
 
 func hasMultipleContent(module models.ModuleWithIsCompleted) bool {
-	return module.PDFContent != "" && module.VideoContent != ""
+	return len(contentKinds(module)) > 1
 }
 
-func shouldShowPDF(module models.ModuleWithIsCompleted, contentType string) bool {
-	if module.PDFContent == "" {
-		return false
+// activeContent resolves the requested content type to one the module has,
+// falling back to its first one.
+func activeContent(module models.ModuleWithIsCompleted, contentType string) string {
+	kinds := contentKinds(module)
+	if slices.Contains(kinds, contentType) {
+		return contentType
 	}
-
-	if hasMultipleContent(module) {
-		return contentType != "video"
+	if len(kinds) > 0 {
+		return kinds[0]
 	}
+	return ""
+}
 
-	return true
+func shouldShowPDF(module models.ModuleWithIsCompleted, contentType string) bool {
+	return activeContent(module, contentType) == "pdf"
 }
 
 func shouldShowVideo(module models.ModuleWithIsCompleted, contentType string) bool {
-	if module.VideoContent == "" {
-		return false
-	}
+	return activeContent(module, contentType) == "video"
+}
 
-	if hasMultipleContent(module) {
-		return contentType == "video"
-	}
+func shouldShowQuiz(module models.ModuleWithIsCompleted, contentType string) bool {
+	return activeContent(module, contentType) == "quiz"
+}
 
-	return true
+func shouldShowAssignment(module models.ModuleWithIsCompleted, contentType string) bool {
+	return activeContent(module, contentType) == "assignment"
 }
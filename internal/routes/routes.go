@@ -3,9 +3,13 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/kin-ark/GroAcademy/internal/controllers"
+	"github.com/kin-ark/GroAcademy/internal/mailer"
 	"github.com/kin-ark/GroAcademy/internal/middlewares"
+	"github.com/kin-ark/GroAcademy/internal/rbac"
 	"github.com/kin-ark/GroAcademy/internal/repositories"
 	"github.com/kin-ark/GroAcademy/internal/services"
+	"github.com/kin-ark/GroAcademy/internal/storage"
+	"github.com/kin-ark/GroAcademy/internal/utils"
 )
 
 func RegisterRoutes(r *gin.Engine) {
@@ -13,23 +17,69 @@ func RegisterRoutes(r *gin.Engine) {
 	userRepo := repositories.NewUserRepository()
 	courseRepo := repositories.NewCourseRepository()
 	moduleRepo := repositories.NewModuleRepository()
+	sessionRepo := repositories.NewSessionRepository()
+	userTokenRepo := repositories.NewUserTokenRepository()
+	quizRepo := repositories.NewQuizRepository()
+	assignmentRepo := repositories.NewAssignmentRepository()
+	sectionRepo := repositories.NewSectionRepository()
+	revisionRepo := repositories.NewRevisionRepository()
+	reviewRepo := repositories.NewReviewRepository()
+	discussionRepo := repositories.NewDiscussionRepository()
+	certificateRepo := repositories.NewCertificateRepository()
+	certificateTemplateRepo := repositories.NewCertificateTemplateRepository()
+	couponRepo := repositories.NewCouponRepository()
 
-	authService := services.NewAuthService(userRepo)
+	jobRepo := repositories.NewJobRepository()
+	store := storage.NewFromEnv()
+	signer := storage.SignerFromEnv()
+	certSigner := utils.CertificateSignerFromEnv()
+
+	authService := services.NewAuthService(userRepo, sessionRepo, userTokenRepo, mailer.NewFromEnv())
 	userService := services.NewUserService(userRepo)
-	courseService := services.NewCourseService(courseRepo)
-	moduleService := services.NewModuleService(moduleRepo, courseRepo)
+	courseService := services.NewCourseService(courseRepo, jobRepo, revisionRepo, couponRepo, services.LoadRefundPolicy(), store)
+	certificateService := services.NewCertificateService(certificateRepo, certificateTemplateRepo, courseRepo, jobRepo, store, certSigner)
+	moduleService := services.NewModuleService(moduleRepo, courseRepo, jobRepo, quizRepo, assignmentRepo, sectionRepo, revisionRepo, store, signer, certificateService, services.LoadWatchPolicy())
+	mediaService := services.NewMediaService(store, signer)
+	quizService := services.NewQuizService(quizRepo, moduleRepo, courseRepo, moduleService)
+	assignmentService := services.NewAssignmentService(assignmentRepo, moduleRepo, courseRepo, moduleService, store)
+	sectionService := services.NewSectionService(sectionRepo, courseRepo)
+	reviewService := services.NewReviewService(reviewRepo, courseRepo)
+	discussionService := services.NewDiscussionService(discussionRepo, moduleRepo, courseRepo, moduleService)
+	couponService := services.NewCouponService(couponRepo, courseRepo)
 
 	authController := controllers.NewAuthController(authService)
 	userController := controllers.NewUserController(userService)
 	courseController := controllers.NewCourseController(courseService)
 	moduleController := controllers.NewModuleController(moduleService)
+	mediaController := controllers.NewMediaController(mediaService)
+	quizController := controllers.NewQuizController(quizService)
+	assignmentController := controllers.NewAssignmentController(assignmentService)
+	sectionController := controllers.NewSectionController(sectionService)
+	reviewController := controllers.NewReviewController(reviewService)
+	discussionController := controllers.NewDiscussionController(discussionService)
+	certificateController := controllers.NewCertificateController(certificateService)
+	couponController := controllers.NewCouponController(couponService)
+
+	registerMediaRoutes(r, &mediaController)
 
 	api := r.Group("/api")
 	{
 		registerAuthRoutes(api, &authController)
-		registerCourseRoutes(api, &courseController, &moduleController)
-		registerModuleRoutes(api, &moduleController)
+		registerCourseRoutes(api, &courseController, &moduleController, &sectionController, &reviewController, &certificateController)
+		registerModuleRoutes(api, &moduleController, &quizController, &assignmentController, &discussionController)
+		registerSectionRoutes(api, &sectionController)
+		registerQuizAttemptRoutes(api, &quizController)
+		registerSubmissionRoutes(api, &assignmentController)
+		registerReviewRoutes(api, &reviewController)
+		registerDiscussionRoutes(api, &discussionController)
+		registerCertificateRoutes(api, &certificateController)
+		registerCertificateTemplateRoutes(api, &certificateController)
+		registerPurchaseRoutes(api, &courseController)
+		registerCouponRoutes(api, &couponController)
 		registerUserRoutes(api, &userController)
+		registerMeRoutes(api, &userController, &certificateController)
+		registerRoleRoutes(api, &userController)
+		registerInstructorRoutes(api, &courseController)
 	}
 }
 
@@ -38,48 +88,236 @@ func registerAuthRoutes(api *gin.RouterGroup, authController *controllers.AuthCo
 	{
 		auth.POST("/login", authController.Login)
 		auth.POST("/register", authController.Register)
+		auth.POST("/refresh", authController.Refresh)
+		auth.POST("/verify-email", authController.VerifyEmail)
+		auth.POST("/forgot-password", authController.ForgotPassword)
+		auth.POST("/reset-password", authController.ResetPassword)
 		auth.GET("/self", middlewares.RequireAuth, authController.GetSelf)
+		auth.POST("/logout", middlewares.RequireAuth, authController.Logout)
+		auth.GET("/sessions", middlewares.RequireAuth, authController.GetSessions)
+		auth.DELETE("/sessions/:id", middlewares.RequireAuth, authController.DeleteSession)
 	}
 }
 
-func registerCourseRoutes(api *gin.RouterGroup, courseController *controllers.CourseController, moduleController *controllers.ModuleController) {
+// registerMediaRoutes serves files behind signed URLs issued by the local
+// store, and HLS playlists with signed segment URLs. They are public because
+// the signature itself grants access.
+func registerMediaRoutes(r *gin.Engine, mediaController *controllers.MediaController) {
+	r.GET("/media/*key", mediaController.ServeSignedMedia)
+	r.HEAD("/media/*key", mediaController.ServeSignedMedia)
+	r.GET("/hls/*key", mediaController.ServeSignedPlaylist)
+}
+
+func registerCourseRoutes(api *gin.RouterGroup, courseController *controllers.CourseController, moduleController *controllers.ModuleController, sectionController *controllers.SectionController, reviewController *controllers.ReviewController, certificateController *controllers.CertificateController) {
+	uploadLimit := middlewares.LimitBodySize(storage.MaxRequestSize())
+
 	courses := api.Group("/courses")
 	courses.Use(middlewares.RequireAuth)
 	{
-		courses.POST("", middlewares.RequireAdmin, courseController.PostCourse)
+		courses.POST("", middlewares.RequirePermission(rbac.PermCourseCreate), uploadLimit, courseController.PostCourse)
 		courses.GET("", courseController.GetAllCourses)
 		courses.GET("/:id", courseController.GetCourseByID)
-		courses.PUT("/:id", middlewares.RequireAdmin, courseController.PutCourse)
-		courses.DELETE("/:id", middlewares.RequireAdmin, courseController.DeleteCourseByID)
+		courses.PUT("/:id", middlewares.RequirePermission(rbac.PermCourseEdit), uploadLimit, courseController.PutCourse)
+		courses.DELETE("/:id", middlewares.RequirePermission(rbac.PermCourseDelete), courseController.DeleteCourseByID)
+		courses.PATCH("/:id/status", middlewares.RequirePermission(rbac.PermCourseEdit), courseController.PatchCourseStatus)
+		courses.GET("/:id/revisions", middlewares.RequirePermission(rbac.PermCourseEdit), courseController.GetCourseRevisions)
+		courses.GET("/:id/revisions/:rev", middlewares.RequirePermission(rbac.PermCourseEdit), courseController.GetCourseRevision)
+		courses.POST("/:id/revisions/:rev/restore", middlewares.RequirePermission(rbac.PermCourseEdit), courseController.RestoreCourseRevision)
+
+		courses.GET("/:id/prerequisites", courseController.GetPrerequisites)
+		courses.PUT("/:id/prerequisites", middlewares.RequirePermission(rbac.PermCourseEdit), courseController.PutPrerequisites)
+		courses.GET("/:id/assistants", middlewares.RequirePermission(rbac.PermCourseEdit), courseController.GetAssistants)
+		courses.PUT("/:id/assistants", middlewares.RequirePermission(rbac.PermCourseEdit), courseController.PutAssistants)
+		courses.PUT("/:id/certificate-template", middlewares.RequirePermission(rbac.PermCourseEdit), certificateController.PutCourseTemplate)
 
+		courses.GET("/:id/price", courseController.GetCoursePrice)
 		courses.POST("/:id/buy", courseController.BuyCourse)
+		courses.POST("/:id/refund", courseController.RefundCourse)
 		courses.GET("/my-courses", courseController.GetMyCourses)
 
-		courses.POST("/:id/modules", middlewares.RequireAdmin, moduleController.PostModule)
+		courses.POST("/:id/modules", middlewares.RequirePermission(rbac.PermModuleEdit), uploadLimit, moduleController.PostModule)
 		courses.GET("/:id/modules", moduleController.GetModules)
-		courses.PATCH("/:id/modules/reorder", middlewares.RequireAdmin, moduleController.ReorderModules)
+		courses.PATCH("/:id/modules/reorder", middlewares.RequirePermission(rbac.PermModuleEdit), moduleController.ReorderModules)
+
+		courses.GET("/:id/sections", sectionController.GetSections)
+		courses.POST("/:id/sections", middlewares.RequirePermission(rbac.PermModuleEdit), sectionController.PostSection)
+
+		courses.GET("/:id/reviews", reviewController.GetCourseReviews)
+		courses.GET("/:id/reviews/me", reviewController.GetOwnReview)
+		courses.POST("/:id/reviews", reviewController.PostReview)
 	}
 }
 
-func registerModuleRoutes(api *gin.RouterGroup, moduleController *controllers.ModuleController) {
+func registerSectionRoutes(api *gin.RouterGroup, sectionController *controllers.SectionController) {
+	sections := api.Group("/sections")
+	sections.Use(middlewares.RequireAuth, middlewares.RequirePermission(rbac.PermModuleEdit))
+	{
+		sections.PUT("/:id", sectionController.PutSection)
+		sections.DELETE("/:id", sectionController.DeleteSection)
+	}
+}
+
+func registerModuleRoutes(api *gin.RouterGroup, moduleController *controllers.ModuleController, quizController *controllers.QuizController, assignmentController *controllers.AssignmentController, discussionController *controllers.DiscussionController) {
+	uploadLimit := middlewares.LimitBodySize(storage.MaxRequestSize())
+
 	modules := api.Group("/modules")
 	modules.Use(middlewares.RequireAuth)
 	{
 		modules.GET("/:id", moduleController.GetModuleById)
-		modules.PUT("/:id", middlewares.RequireAdmin, moduleController.PutModule)
-		modules.DELETE("/:id", middlewares.RequireAdmin, moduleController.DeleteModuleByID)
+		modules.GET("/:id/content/:type", moduleController.GetModuleContent)
+		modules.PUT("/:id", middlewares.RequirePermission(rbac.PermModuleEdit), uploadLimit, moduleController.PutModule)
+		modules.DELETE("/:id", middlewares.RequirePermission(rbac.PermModuleEdit), moduleController.DeleteModuleByID)
+		modules.PATCH("/:id/status", middlewares.RequirePermission(rbac.PermModuleEdit), moduleController.PatchModuleStatus)
+		modules.GET("/:id/revisions", middlewares.RequirePermission(rbac.PermModuleEdit), moduleController.GetModuleRevisions)
+		modules.GET("/:id/revisions/:rev", middlewares.RequirePermission(rbac.PermModuleEdit), moduleController.GetModuleRevision)
+		modules.POST("/:id/revisions/:rev/restore", middlewares.RequirePermission(rbac.PermModuleEdit), moduleController.RestoreModuleRevision)
 		modules.PATCH("/:id/complete", moduleController.MarkModuleAsComplete)
+		modules.PUT("/:id/progress", moduleController.UpdateWatchProgress)
+
+		modules.GET("/:id/quiz", quizController.GetQuiz)
+		modules.PUT("/:id/quiz", middlewares.RequirePermission(rbac.PermModuleEdit), quizController.PutQuiz)
+		modules.DELETE("/:id/quiz", middlewares.RequirePermission(rbac.PermModuleEdit), quizController.DeleteQuiz)
+		modules.POST("/:id/quiz/attempts", quizController.StartAttempt)
+
+		modules.GET("/:id/assignment", assignmentController.GetAssignment)
+		modules.PUT("/:id/assignment", middlewares.RequirePermission(rbac.PermModuleEdit), assignmentController.PutAssignment)
+		modules.DELETE("/:id/assignment", middlewares.RequirePermission(rbac.PermModuleEdit), assignmentController.DeleteAssignment)
+		modules.POST("/:id/assignment/submissions", uploadLimit, assignmentController.PostSubmission)
+
+		modules.GET("/:id/discussions", discussionController.GetThreads)
+		modules.POST("/:id/discussions", discussionController.PostThread)
+	}
+}
+
+func registerQuizAttemptRoutes(api *gin.RouterGroup, quizController *controllers.QuizController) {
+	attempts := api.Group("/quiz-attempts")
+	attempts.Use(middlewares.RequireAuth)
+	{
+		attempts.POST("/:id/submit", quizController.SubmitAttempt)
+	}
+}
+
+func registerSubmissionRoutes(api *gin.RouterGroup, assignmentController *controllers.AssignmentController) {
+	submissions := api.Group("/submissions")
+	submissions.Use(middlewares.RequireAuth)
+	{
+		submissions.GET("", middlewares.RequirePermission(rbac.PermSubmissionGrade), assignmentController.GetSubmissions)
+		submissions.GET("/:id", assignmentController.GetSubmission)
+		submissions.PUT("/:id/grade", middlewares.RequirePermission(rbac.PermSubmissionGrade), assignmentController.GradeSubmission)
+	}
+}
+
+func registerReviewRoutes(api *gin.RouterGroup, reviewController *controllers.ReviewController) {
+	reviews := api.Group("/reviews")
+	reviews.Use(middlewares.RequireAuth)
+	{
+		reviews.GET("", middlewares.RequirePermission(rbac.PermReviewModerate), reviewController.GetReviews)
+		reviews.PUT("/:id", reviewController.PutReview)
+		reviews.DELETE("/:id", reviewController.DeleteReview)
+		reviews.PATCH("/:id/moderation", middlewares.RequirePermission(rbac.PermReviewModerate), reviewController.PatchReviewModeration)
+	}
+}
+
+func registerDiscussionRoutes(api *gin.RouterGroup, discussionController *controllers.DiscussionController) {
+	discussions := api.Group("/discussions")
+	discussions.Use(middlewares.RequireAuth)
+	{
+		discussions.GET("/:id", discussionController.GetThread)
+		discussions.PUT("/:id", discussionController.PutPost)
+		discussions.DELETE("/:id", discussionController.DeletePost)
+		discussions.POST("/:id/replies", discussionController.PostReply)
+		discussions.POST("/:id/upvote", discussionController.PostUpvote)
+		discussions.DELETE("/:id/upvote", discussionController.DeleteUpvote)
+		discussions.POST("/:id/answer", middlewares.RequirePermission(rbac.PermDiscussionModerate), discussionController.PostAnswer)
+		discussions.DELETE("/:id/answer", middlewares.RequirePermission(rbac.PermDiscussionModerate), discussionController.DeleteAnswer)
+	}
+}
+
+// registerCertificateRoutes exposes verification publicly so employers can
+// check a certificate without an account.
+func registerCertificateRoutes(api *gin.RouterGroup, certificateController *controllers.CertificateController) {
+	certificates := api.Group("/certificates")
+	{
+		certificates.GET("/verify/:serial", certificateController.VerifyCertificate)
+		certificates.POST("/:id/revocation", middlewares.RequireAuth, middlewares.RequirePermission(rbac.PermCertificateRevoke), certificateController.RevokeCertificate)
+		certificates.DELETE("/:id/revocation", middlewares.RequireAuth, middlewares.RequirePermission(rbac.PermCertificateRevoke), certificateController.ReinstateCertificate)
+		certificates.GET("/:id/download", middlewares.RequireAuth, certificateController.DownloadCertificate)
+		certificates.POST("/:id/regenerate", middlewares.RequireAuth, middlewares.RequirePermission(rbac.PermCertificateManage), certificateController.RegenerateCertificate)
+	}
+}
+
+// registerCertificateTemplateRoutes lets instructors browse and preview the
+// designs they can pick for their courses; only admins edit them.
+func registerCertificateTemplateRoutes(api *gin.RouterGroup, certificateController *controllers.CertificateController) {
+	uploadLimit := middlewares.LimitBodySize(storage.MaxRequestSize())
+
+	templates := api.Group("/certificate-templates")
+	templates.Use(middlewares.RequireAuth)
+	{
+		templates.GET("", middlewares.RequirePermission(rbac.PermCourseEdit), certificateController.GetTemplates)
+		templates.GET("/:id", middlewares.RequirePermission(rbac.PermCourseEdit), certificateController.GetTemplate)
+		templates.GET("/:id/preview", middlewares.RequirePermission(rbac.PermCourseEdit), certificateController.PreviewTemplate)
+		templates.POST("", middlewares.RequirePermission(rbac.PermCertificateDesign), uploadLimit, certificateController.PostTemplate)
+		templates.PUT("/:id", middlewares.RequirePermission(rbac.PermCertificateDesign), uploadLimit, certificateController.PutTemplate)
+		templates.DELETE("/:id", middlewares.RequirePermission(rbac.PermCertificateDesign), certificateController.DeleteTemplate)
+	}
+}
+
+func registerPurchaseRoutes(api *gin.RouterGroup, courseController *controllers.CourseController) {
+	purchases := api.Group("/purchases")
+	purchases.Use(middlewares.RequireAuth)
+	{
+		purchases.POST("/:id/refund", middlewares.RequirePermission(rbac.PermPurchaseRefund), courseController.RefundPurchase)
+	}
+}
+
+func registerCouponRoutes(api *gin.RouterGroup, couponController *controllers.CouponController) {
+	coupons := api.Group("/coupons")
+	coupons.Use(middlewares.RequireAuth, middlewares.RequirePermission(rbac.PermCouponManage))
+	{
+		coupons.GET("", couponController.GetCoupons)
+		coupons.GET("/:id", couponController.GetCoupon)
+		coupons.POST("", couponController.PostCoupon)
+		coupons.PUT("/:id", couponController.PutCoupon)
+		coupons.DELETE("/:id", couponController.DeleteCoupon)
 	}
 }
 
 func registerUserRoutes(api *gin.RouterGroup, userController *controllers.UserController) {
 	users := api.Group("/users")
-	users.Use(middlewares.RequireAuth, middlewares.RequireAdmin)
+	users.Use(middlewares.RequireAuth)
+	{
+		users.GET("", middlewares.RequirePermission(rbac.PermUserRead), userController.GetUsers)
+		users.GET("/:id", middlewares.RequirePermission(rbac.PermUserRead), userController.GetUserById)
+		users.POST("/:id/balance", middlewares.RequirePermission(rbac.PermUserBalanceWrite), userController.AddUserBalance)
+		users.GET("/:id/transactions", middlewares.RequirePermission(rbac.PermUserRead), userController.GetUserTransactions)
+		users.PUT("/:id", middlewares.RequirePermission(rbac.PermUserEdit), userController.PutUser)
+		users.PUT("/:id/role", middlewares.RequirePermission(rbac.PermUserRoleWrite), userController.PutUserRole)
+		users.DELETE("/:id", middlewares.RequirePermission(rbac.PermUserDelete), userController.DeleteUserByID)
+	}
+}
+
+func registerMeRoutes(api *gin.RouterGroup, userController *controllers.UserController, certificateController *controllers.CertificateController) {
+	me := api.Group("/me")
+	me.Use(middlewares.RequireAuth)
+	{
+		me.GET("/transactions", userController.GetMyTransactions)
+		me.GET("/certificates", certificateController.GetMyCertificates)
+	}
+}
+
+func registerRoleRoutes(api *gin.RouterGroup, userController *controllers.UserController) {
+	roles := api.Group("/roles")
+	roles.Use(middlewares.RequireAuth, middlewares.RequirePermission(rbac.PermUserRoleWrite))
+	{
+		roles.GET("", userController.GetRoles)
+	}
+}
+
+func registerInstructorRoutes(api *gin.RouterGroup, courseController *controllers.CourseController) {
+	instructor := api.Group("/instructor")
+	instructor.Use(middlewares.RequireAuth, middlewares.RequirePermission(rbac.PermCourseEdit))
 	{
-		users.GET("", userController.GetUsers)
-		users.GET("/:id", userController.GetUserById)
-		users.POST("/:id/balance", userController.AddUserBalance)
-		users.PUT("/:id", userController.PutUser)
-		users.DELETE("/:id", userController.DeleteUserByID)
+		instructor.GET("/courses", courseController.GetInstructorDashboard)
 	}
 }
@@ -0,0 +1,353 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/rbac"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
+	"github.com/kin-ark/GroAcademy/internal/storage"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidAssignment = errors.New("invalid assignment")
+	ErrInvalidSubmission = errors.New("invalid submission")
+	ErrAssignmentPassed  = errors.New("assignment has already been passed")
+)
+
+type AssignmentService interface {
+	SaveAssignment(moduleID uint, input models.AssignmentInput, user models.User) (*models.Assignment, error)
+	DeleteAssignment(moduleID uint, user models.User) error
+	GetAssignment(moduleID uint, user models.User) (*models.AssignmentResponse, error)
+	Submit(moduleID uint, input models.SubmissionFormInput, user models.User) (*models.SubmissionResponse, error)
+	ListSubmissions(q models.SubmissionQueueQuery, user models.User) ([]models.SubmissionResponse, models.PaginationResponse, error)
+	GetSubmission(id uint, user models.User) (*models.SubmissionResponse, error)
+	GradeSubmission(id uint, input models.GradeSubmissionInput, user models.User) (*models.SubmissionResponse, error)
+}
+
+type assignmentService struct {
+	assignmentRepo repositories.AssignmentRepository
+	moduleRepo     repositories.ModuleRepository
+	courseRepo     repositories.CourseRepository
+	moduleService  ModuleService
+	store          storage.Store
+}
+
+func NewAssignmentService(ar repositories.AssignmentRepository, mr repositories.ModuleRepository, cr repositories.CourseRepository, ms ModuleService, st storage.Store) AssignmentService {
+	return &assignmentService{assignmentRepo: ar, moduleRepo: mr, courseRepo: cr, moduleService: ms, store: st}
+}
+
+// submissionPrefix is where files handed in for a module's assignment are
+// stored, so they can be removed together with the module.
+func submissionPrefix(moduleID uint) string {
+	return fmt.Sprintf("submissions/module_%d/", moduleID)
+}
+
+func (s *assignmentService) SaveAssignment(moduleID uint, input models.AssignmentInput, user models.User) (*models.Assignment, error) {
+	module, err := s.moduleRepo.FindById(moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeCourseManagement(s.courseRepo, user, module.CourseID); err != nil {
+		return nil, err
+	}
+
+	assignment := models.Assignment{
+		ModuleID:     moduleID,
+		Instructions: strings.TrimSpace(input.Instructions),
+		MaxScore:     100,
+		PassingScore: 60,
+		AllowText:    true,
+		AllowFile:    true,
+	}
+	if input.MaxScore != nil {
+		assignment.MaxScore = *input.MaxScore
+	}
+	if input.PassingScore != nil {
+		assignment.PassingScore = *input.PassingScore
+	}
+	if input.AllowText != nil {
+		assignment.AllowText = *input.AllowText
+	}
+	if input.AllowFile != nil {
+		assignment.AllowFile = *input.AllowFile
+	}
+
+	if assignment.PassingScore > assignment.MaxScore {
+		return nil, fmt.Errorf("%w: passing_score exceeds max_score", ErrInvalidAssignment)
+	}
+	if !assignment.AllowText && !assignment.AllowFile {
+		return nil, fmt.Errorf("%w: allow text or file submissions", ErrInvalidAssignment)
+	}
+
+	if err := s.assignmentRepo.Save(&assignment); err != nil {
+		return nil, err
+	}
+
+	return s.assignmentRepo.FindByModule(moduleID)
+}
+
+func (s *assignmentService) DeleteAssignment(moduleID uint, user models.User) error {
+	module, err := s.moduleRepo.FindById(moduleID)
+	if err != nil {
+		return err
+	}
+
+	if err := authorizeCourseManagement(s.courseRepo, user, module.CourseID); err != nil {
+		return err
+	}
+
+	if err := s.assignmentRepo.DeleteByModule(moduleID); err != nil {
+		return err
+	}
+
+	storage.RemovePrefix(s.store, submissionPrefix(moduleID))
+	return nil
+}
+
+func (s *assignmentService) GetAssignment(moduleID uint, user models.User) (*models.AssignmentResponse, error) {
+	module, err := s.moduleRepo.FindById(moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment, err := s.assignmentRepo.FindByModule(moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	hasPurchased, err := s.courseRepo.HasPurchasedCourse(module.CourseID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !hasPurchased {
+		canPreview, err := canPreviewCourse(s.courseRepo, user, module.CourseID)
+		if err != nil {
+			return nil, err
+		}
+		if !canPreview {
+			return nil, ErrNoContentAccess
+		}
+	} else if err := s.moduleService.CheckModuleUnlocked(module, user); err != nil {
+		return nil, err
+	}
+
+	submissions, err := s.assignmentRepo.FindSubmissions(assignment.ID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := models.AssignmentResponse{
+		ID:           assignment.ID,
+		ModuleID:     assignment.ModuleID,
+		Instructions: assignment.Instructions,
+		MaxScore:     assignment.MaxScore,
+		PassingScore: assignment.PassingScore,
+		AllowText:    assignment.AllowText,
+		AllowFile:    assignment.AllowFile,
+		Submissions:  []models.SubmissionResponse{},
+	}
+	for i := range submissions {
+		res.Passed = res.Passed || submissions[i].Passed
+		res.Submissions = append(res.Submissions, *s.buildSubmissionResponse(&submissions[i], assignment, module))
+	}
+
+	return &res, nil
+}
+
+// Submit hands in work for a module's assignment. While the previous
+// submission is still waiting for a grade it is replaced instead of queueing
+// another one.
+func (s *assignmentService) Submit(moduleID uint, input models.SubmissionFormInput, user models.User) (*models.SubmissionResponse, error) {
+	module, err := s.moduleRepo.FindById(moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment, err := s.assignmentRepo.FindByModule(moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	hasPurchased, err := s.courseRepo.HasPurchasedCourse(module.CourseID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !hasPurchased {
+		return nil, ErrNoContentAccess
+	}
+
+	if err := s.moduleService.CheckModuleUnlocked(module, user); err != nil {
+		return nil, err
+	}
+
+	passed, err := s.assignmentRepo.PassedModuleAssignment(moduleID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if passed {
+		return nil, ErrAssignmentPassed
+	}
+
+	text := strings.TrimSpace(input.Text)
+	switch {
+	case text != "" && !assignment.AllowText:
+		return nil, fmt.Errorf("%w: text submissions are not accepted", ErrInvalidSubmission)
+	case input.File != nil && !assignment.AllowFile:
+		return nil, fmt.Errorf("%w: file submissions are not accepted", ErrInvalidSubmission)
+	case text == "" && input.File == nil:
+		return nil, fmt.Errorf("%w: text or file is required", ErrInvalidSubmission)
+	}
+
+	var fileKey string
+	if input.File != nil {
+		fileKey, err = storage.SaveUpload(s.store, "file", submissionPrefix(moduleID), storage.KindSubmission, input.File)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pending, err := s.assignmentRepo.FindPendingSubmission(assignment.ID, user.ID)
+	if err != nil {
+		storage.Remove(s.store, fileKey)
+		return nil, err
+	}
+
+	if pending != nil {
+		oldFile := pending.FileKey
+		pending.Text = text
+		pending.FileKey = fileKey
+		if err := s.assignmentRepo.UpdateSubmissionContent(pending); err != nil {
+			storage.Remove(s.store, fileKey)
+			return nil, err
+		}
+		if oldFile != fileKey {
+			storage.Remove(s.store, oldFile)
+		}
+		return s.buildSubmissionResponse(pending, assignment, module), nil
+	}
+
+	submission := models.AssignmentSubmission{
+		AssignmentID: assignment.ID,
+		UserID:       user.ID,
+		Text:         text,
+		FileKey:      fileKey,
+	}
+	if err := s.assignmentRepo.CreateSubmission(&submission); err != nil {
+		storage.Remove(s.store, fileKey)
+		return nil, err
+	}
+
+	return s.buildSubmissionResponse(&submission, assignment, module), nil
+}
+
+func (s *assignmentService) ListSubmissions(q models.SubmissionQueueQuery, user models.User) ([]models.SubmissionResponse, models.PaginationResponse, error) {
+	q.Normalize()
+
+	submissions, totalItems, err := s.assignmentRepo.ListSubmissions(q, user.ID, rbac.HasPermission(user.Role, rbac.PermCourseManageAny))
+	if err != nil {
+		return nil, models.PaginationResponse{}, err
+	}
+
+	for i := range submissions {
+		submissions[i].FileURL = storage.URLWithExpiry(s.store, submissions[i].FileURL, storage.ContentURLExpiry)
+	}
+
+	return submissions, q.Paginate(totalItems), nil
+}
+
+// GetSubmission returns a submission to its author or to someone who manages
+// or grades the course.
+func (s *assignmentService) GetSubmission(id uint, user models.User) (*models.SubmissionResponse, error) {
+	submission, err := s.assignmentRepo.FindSubmission(id)
+	if err != nil {
+		return nil, err
+	}
+
+	module := &submission.Assignment.Module
+	if submission.UserID != user.ID {
+		if err := authorizeCourseStaff(s.courseRepo, user, module.CourseID, rbac.PermSubmissionGrade); err != nil {
+			if errors.Is(err, ErrNotCourseInstructor) {
+				return nil, gorm.ErrRecordNotFound
+			}
+			return nil, err
+		}
+	}
+
+	return s.buildSubmissionResponse(submission, &submission.Assignment, module), nil
+}
+
+// GradeSubmission scores a pending submission and leaves feedback. A passing
+// grade completes the module for the student, which may issue their
+// certificate. Grades are final, so a graded submission cannot be regraded.
+func (s *assignmentService) GradeSubmission(id uint, input models.GradeSubmissionInput, user models.User) (*models.SubmissionResponse, error) {
+	submission, err := s.assignmentRepo.FindSubmission(id)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment := &submission.Assignment
+	module := &assignment.Module
+	if err := authorizeCourseStaff(s.courseRepo, user, module.CourseID, rbac.PermSubmissionGrade); err != nil {
+		return nil, err
+	}
+
+	if submission.Status != models.SubmissionPending {
+		return nil, repositories.ErrSubmissionGraded
+	}
+
+	score := *input.Score
+	if score > assignment.MaxScore {
+		return nil, fmt.Errorf("%w: score exceeds the maximum of %.0f", ErrInvalidSubmission, assignment.MaxScore)
+	}
+
+	now := time.Now()
+	submission.Status = models.SubmissionGraded
+	submission.Score = &score
+	submission.Feedback = strings.TrimSpace(input.Feedback)
+	submission.Passed = score >= assignment.PassingScore
+	submission.GradedBy = &user.ID
+	submission.GradedAt = &now
+
+	if err := s.assignmentRepo.GradeSubmission(submission); err != nil {
+		return nil, err
+	}
+
+	if submission.Passed {
+		// The grade is already stored, so a failure here only delays
+		// completion until the student marks the module again.
+		if _, err := s.moduleService.MarkModuleAsComplete(module.ID, submission.User); err != nil {
+			log.Printf("ERROR: Failed to complete module %d after grading submission %d: %v", module.ID, submission.ID, err)
+		}
+	}
+
+	res := s.buildSubmissionResponse(submission, assignment, module)
+	res.Username = submission.User.Username
+	return res, nil
+}
+
+func (s *assignmentService) buildSubmissionResponse(submission *models.AssignmentSubmission, assignment *models.Assignment, module *models.Module) *models.SubmissionResponse {
+	return &models.SubmissionResponse{
+		ID:           submission.ID,
+		AssignmentID: submission.AssignmentID,
+		ModuleID:     module.ID,
+		ModuleTitle:  module.Title,
+		CourseID:     module.CourseID,
+		UserID:       submission.UserID,
+		Text:         submission.Text,
+		FileURL:      storage.URLWithExpiry(s.store, submission.FileKey, storage.ContentURLExpiry),
+		Status:       submission.Status,
+		Score:        submission.Score,
+		MaxScore:     assignment.MaxScore,
+		Feedback:     submission.Feedback,
+		Passed:       submission.Passed,
+		GradedAt:     submission.GradedAt,
+		SubmittedAt:  submission.CreatedAt,
+	}
+}
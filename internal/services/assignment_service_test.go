@@ -0,0 +1,47 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// assignmentDesk serves one assignment on module 2 with no submissions yet.
+type assignmentDesk struct {
+	repositories.AssignmentRepository
+}
+
+func (assignmentDesk) FindByModule(moduleID uint) (*models.Assignment, error) {
+	return &models.Assignment{ID: 1, ModuleID: moduleID, MaxScore: 100, AllowText: true}, nil
+}
+
+func (assignmentDesk) FindSubmissions(assignmentID, userID uint) ([]models.AssignmentSubmission, error) {
+	return nil, nil
+}
+
+func TestAssignmentRequiresPurchaseAndPublishedModule(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		refunded bool
+		want     error
+	}{
+		{name: "bought and published", status: models.StatusPublished},
+		{name: "refunded", status: models.StatusPublished, refunded: true, want: ErrNoContentAccess},
+		{name: "module back in draft", status: models.StatusDraft, want: gorm.ErrRecordNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := &quizModule{module: models.Module{ID: 2, CourseID: 1, Status: tt.status}}
+			s := &assignmentService{assignmentRepo: assignmentDesk{}, moduleRepo: module, courseRepo: &quizBuyer{refunded: tt.refunded}, moduleService: quizLocks{}}
+
+			if _, err := s.GetAssignment(2, models.User{ID: 1}); !errors.Is(err, tt.want) {
+				t.Errorf("GetAssignment err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -1,31 +1,60 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"log"
 	"os"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/kin-ark/GroAcademy/internal/mailer"
 	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/rbac"
 	"github.com/kin-ark/GroAcademy/internal/repositories"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+const (
+	AccessTokenTTL       = time.Hour
+	RefreshTokenTTL      = 30 * 24 * time.Hour
+	EmailVerificationTTL = 24 * time.Hour
+	PasswordResetTTL     = time.Hour
+)
+
 type AuthService interface {
 	RegisterUser(input models.RegisterInput) (*models.User, error)
-	LoginUser(input models.LoginInput) (string, string, error)
+	LoginUser(input models.LoginInput, meta models.SessionMeta) (*models.AuthTokens, string, error)
+	RefreshSession(refreshToken string, meta models.SessionMeta) (*models.AuthTokens, error)
+	Logout(sessionID uint, userID uint) error
+	GetSessions(userID uint, currentSessionID uint) ([]models.SessionResponse, error)
+	RevokeSession(sessionID uint, userID uint) error
+	VerifyEmail(token string) error
+	ForgotPassword(email string) error
+	ResetPassword(input models.ResetPasswordInput) error
 }
 
 type authService struct {
-	userRepo repositories.UserRepository
+	userRepo    repositories.UserRepository
+	sessionRepo repositories.SessionRepository
+	tokenRepo   repositories.UserTokenRepository
+	mailer      mailer.Mailer
 }
 
-func NewAuthService(r repositories.UserRepository) AuthService {
-	return &authService{userRepo: r}
+func NewAuthService(r repositories.UserRepository, sr repositories.SessionRepository, tr repositories.UserTokenRepository, m mailer.Mailer) AuthService {
+	return &authService{userRepo: r, sessionRepo: sr, tokenRepo: tr, mailer: m}
 }
 
-var ErrInvalidCredentials = errors.New("invalid identifier or password")
+var (
+	ErrInvalidCredentials  = errors.New("invalid identifier or password")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+)
 
 func (s *authService) RegisterUser(body models.RegisterInput) (*models.User, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(body.Password), 10)
@@ -34,37 +63,266 @@ func (s *authService) RegisterUser(body models.RegisterInput) (*models.User, err
 		return nil, err
 	}
 
-	user := models.User{FirstName: body.FirstName, LastName: body.LastName, Username: body.Username, Email: body.Email, Password: string(hash), Role: "user", Balance: 0}
+	user := models.User{FirstName: body.FirstName, LastName: body.LastName, Username: body.Username, Email: body.Email, Password: string(hash), Role: rbac.RoleStudent, Balance: 0}
 	if err := s.userRepo.Create(&user); err != nil {
 		return nil, err
 	}
+
+	if err := s.sendEmailVerification(&user); err != nil {
+		log.Printf("ERROR: Failed to send verification email to user %d: %v", user.ID, err)
+	}
+
 	return &user, nil
 }
 
-func (s *authService) LoginUser(input models.LoginInput) (string, string, error) {
+func (s *authService) LoginUser(input models.LoginInput, meta models.SessionMeta) (*models.AuthTokens, string, error) {
 	user, err := s.userRepo.FindByIdentifier(input.Identifier)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", "", ErrInvalidCredentials
+			return nil, "", ErrInvalidCredentials
 		}
-		return "", "", err
+		return nil, "", err
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password))
 	if err != nil {
-		return "", "", ErrInvalidCredentials
+		return nil, "", ErrInvalidCredentials
+	}
+
+	refreshToken, refreshHash, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: refreshHash,
+		UserAgent:        truncate(meta.UserAgent, 255),
+		IPAddress:        truncate(meta.IPAddress, 64),
+		ExpiresAt:        now.Add(RefreshTokenTTL),
+		LastUsedAt:       now,
+	}
+	if err := s.sessionRepo.Create(&session); err != nil {
+		return nil, "", err
+	}
+
+	tokens, err := issueTokens(user, &session, refreshToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return tokens, user.Username, nil
+}
+
+func (s *authService) RefreshSession(refreshToken string, meta models.SessionMeta) (*models.AuthTokens, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	session, err := s.sessionRepo.FindActiveByRefreshTokenHash(hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repositories.ErrSessionNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindById(session.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	newToken, newHash, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
 	}
 
+	if err := s.sessionRepo.RotateRefreshToken(session, newHash, time.Now().Add(RefreshTokenTTL)); err != nil {
+		if errors.Is(err, repositories.ErrSessionNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	return issueTokens(user, session, newToken)
+}
+
+func (s *authService) Logout(sessionID uint, userID uint) error {
+	return s.sessionRepo.Revoke(sessionID, userID)
+}
+
+func (s *authService) GetSessions(userID uint, currentSessionID uint) ([]models.SessionResponse, error) {
+	sessions, err := s.sessionRepo.FindActiveByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]models.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		res = append(res, models.SessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+	return res, nil
+}
+
+func (s *authService) RevokeSession(sessionID uint, userID uint) error {
+	return s.sessionRepo.Revoke(sessionID, userID)
+}
+
+func (s *authService) VerifyEmail(token string) error {
+	userToken, err := s.tokenRepo.Consume(hashToken(token), models.TokenPurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+
+	return s.userRepo.MarkEmailVerified(userToken.UserID)
+}
+
+// ForgotPassword mails a reset link if the address belongs to an account. It
+// reports success either way so the endpoint cannot be used to probe emails.
+func (s *authService) ForgotPassword(email string) error {
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := s.tokenRepo.InvalidateForUser(user.ID, models.TokenPurposePasswordReset); err != nil {
+		return err
+	}
+
+	token, err := s.issueUserToken(user.ID, models.TokenPurposePasswordReset, PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	err = s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your GroAcademy password",
+		Body: fmt.Sprintf("Hi %s,\n\nWe received a request to reset your password. "+
+			"Open the link below within %d minutes to choose a new one:\n\n%sreset-password?token=%s\n\n"+
+			"If you did not request this, you can ignore this email.",
+			user.FirstName, int(PasswordResetTTL.Minutes()), os.Getenv("BASE_URL"), token),
+	})
+	// A send failure is only logged: returning it would answer differently
+	// for existing accounts than for unknown emails.
+	if err != nil {
+		log.Printf("ERROR: Failed to send password reset email to user %d: %v", user.ID, err)
+	}
+	return nil
+}
+
+func (s *authService) ResetPassword(input models.ResetPasswordInput) error {
+	userToken, err := s.tokenRepo.Consume(hashToken(input.Token), models.TokenPurposePasswordReset)
+	if err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), 10)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdatePassword(userToken.UserID, string(hash)); err != nil {
+		return err
+	}
+
+	return s.sessionRepo.RevokeAllForUser(userToken.UserID)
+}
+
+func (s *authService) sendEmailVerification(user *models.User) error {
+	token, err := s.issueUserToken(user.ID, models.TokenPurposeEmailVerification, EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your GroAcademy email",
+		Body: fmt.Sprintf("Hi %s,\n\nWelcome to GroAcademy! Please confirm your email address by opening the link below:\n\n%sverify-email?token=%s\n\n"+
+			"The link expires in %d hours.",
+			user.FirstName, os.Getenv("BASE_URL"), token, int(EmailVerificationTTL.Hours())),
+	})
+}
+
+func (s *authService) issueUserToken(userID uint, purpose string, ttl time.Duration) (string, error) {
+	token, hash, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.tokenRepo.Create(&models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func issueTokens(user *models.User, session *models.Session, refreshToken string) (*models.AuthTokens, error) {
+	expiresAt := time.Now().Add(AccessTokenTTL)
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"sub":  user.Username,
 		"role": user.Role,
-		"exp":  time.Now().Add(time.Hour).Unix(),
+		"sid":  session.ID,
+		"exp":  expiresAt.Unix(),
 	})
 
 	tokenString, err := token.SignedString([]byte(os.Getenv("SECRET")))
 	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthTokens{
+		AccessToken:      tokenString,
+		AccessExpiresAt:  expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+		SessionID:        session.ID,
+	}, nil
+}
+
+// newOpaqueToken returns a random token and the hash that is stored in place
+// of it.
+func newOpaqueToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
 		return "", "", err
 	}
+	token := hex.EncodeToString(b)
+	return token, hashToken(token), nil
+}
 
-	return tokenString, user.Username, nil
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// truncate shortens s to at most n characters without splitting a rune.
+// Invalid UTF-8, which Postgres would reject, is dropped first.
+func truncate(s string, n int) string {
+	s = strings.ToValidUTF8(s, "")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
 }
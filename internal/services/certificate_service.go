@@ -0,0 +1,1074 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"log"
+	"mime/multipart"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/rbac"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
+	"github.com/kin-ark/GroAcademy/internal/storage"
+	"github.com/kin-ark/GroAcademy/internal/utils"
+	"gorm.io/gorm"
+)
+
+const JobTypeRegenerateCertificates = "regenerate_certificates"
+
+// RegenerateCertificatesPayload re-renders every certificate of a course
+// with the template it currently uses.
+type RegenerateCertificatesPayload struct {
+	CourseID uint `json:"course_id"`
+}
+
+const JobTypeRecomputeCertificates = "recompute_certificates"
+
+// RecomputeCertificatesPayload applies a course's certificate policy to its
+// certificates after its published modules changed.
+type RecomputeCertificatesPayload struct {
+	CourseID uint `json:"course_id"`
+}
+
+// certificateOutdatedReason is shown on certificates revoked by the revoke
+// policy.
+const certificateOutdatedReason = "The course has new content the holder has not completed yet."
+
+var (
+	ErrInvalidCertificateTemplate = errors.New("invalid certificate template")
+	ErrCertificateTemplateExists  = errors.New("a certificate template with this name already exists")
+	ErrCertificateRevoked         = errors.New("certificate has been revoked")
+)
+
+type CertificateService interface {
+	VerifyCertificate(serial string) (*models.CertificateVerificationResponse, error)
+	RevokeCertificate(id uint, input models.CertificateRevocationInput, user models.User) (*models.CertificateVerificationResponse, error)
+	ReinstateCertificate(id uint) (*models.CertificateVerificationResponse, error)
+	IssueCertificate(user models.User, course models.Course) (*models.Certificate, error)
+	ListUserCertificates(user models.User, q models.PaginationQuery) ([]models.CertificateResponse, models.PaginationResponse, error)
+	OpenCertificate(id uint, user models.User, format string) (storage.Object, string, error)
+	RegenerateCertificate(id uint) (*models.CertificateResponse, error)
+	RecomputeCertificate(userID, courseID uint) (*models.Certificate, error)
+	ListTemplates() ([]models.CertificateTemplateResponse, error)
+	GetTemplate(id uint) (*models.CertificateTemplateResponse, error)
+	CreateTemplate(input models.CertificateTemplateFormInput) (*models.CertificateTemplateResponse, error)
+	UpdateTemplate(id uint, input models.CertificateTemplateFormInput) (*models.CertificateTemplateResponse, error)
+	DeleteTemplate(id uint) error
+	PreviewTemplate(id uint, format string) ([]byte, string, error)
+	SetCourseTemplate(courseID uint, input models.CourseCertificateTemplateInput, user models.User) (*models.Course, error)
+	HandleRegenerateJob(payload []byte) error
+	HandleRecomputeJob(payload []byte) error
+}
+
+type certificateService struct {
+	certificateRepo repositories.CertificateRepository
+	templateRepo    repositories.CertificateTemplateRepository
+	courseRepo      repositories.CourseRepository
+	jobRepo         repositories.JobRepository
+	store           storage.Store
+	signer          *utils.CertificateSigner
+}
+
+func NewCertificateService(cr repositories.CertificateRepository, tr repositories.CertificateTemplateRepository, courseRepo repositories.CourseRepository, jr repositories.JobRepository, st storage.Store, signer *utils.CertificateSigner) CertificateService {
+	return &certificateService{certificateRepo: cr, templateRepo: tr, courseRepo: courseRepo, jobRepo: jr, store: st, signer: signer}
+}
+
+// VerifyCertificate looks a certificate up by the serial printed on it and
+// checks its signature. Serials are matched regardless of case.
+func (s *certificateService) VerifyCertificate(serial string) (*models.CertificateVerificationResponse, error) {
+	cert, err := s.certificateRepo.FindBySerial(strings.ToUpper(strings.TrimSpace(serial)))
+	if err != nil {
+		return nil, err
+	}
+	return s.buildVerificationResponse(cert), nil
+}
+
+// RevokeCertificate marks a certificate as revoked; it stays verifiable so
+// the page can say it was revoked and why. Revoking again updates the reason.
+func (s *certificateService) RevokeCertificate(id uint, input models.CertificateRevocationInput, user models.User) (*models.CertificateVerificationResponse, error) {
+	cert, err := s.certificateRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if cert.RevokedAt == nil {
+		now := time.Now()
+		cert.RevokedAt = &now
+	}
+	cert.RevokedBy = &user.ID
+	cert.RevocationReason = strings.TrimSpace(input.Reason)
+
+	if err := s.certificateRepo.UpdateRevocation(cert); err != nil {
+		return nil, err
+	}
+	return s.buildVerificationResponse(cert), nil
+}
+
+func (s *certificateService) ReinstateCertificate(id uint) (*models.CertificateVerificationResponse, error) {
+	cert, err := s.certificateRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	cert.RevokedAt = nil
+	cert.RevokedBy = nil
+	cert.RevocationReason = ""
+
+	if err := s.certificateRepo.UpdateRevocation(cert); err != nil {
+		return nil, err
+	}
+	return s.buildVerificationResponse(cert), nil
+}
+
+func (s *certificateService) buildVerificationResponse(cert *models.Certificate) *models.CertificateVerificationResponse {
+	status := models.CertificateValid
+	switch {
+	case !s.signer.Verify(cert.Serial, cert.UserID, cert.CourseID, cert.IssuedAt, cert.Signature):
+		status = models.CertificateInvalid
+	case cert.RevokedAt != nil:
+		status = models.CertificateRevoked
+	case cert.OutdatedAt != nil:
+		status = models.CertificateNeedsUpdate
+	}
+
+	return &models.CertificateVerificationResponse{
+		ID:               cert.ID,
+		Serial:           cert.Serial,
+		Status:           status,
+		Valid:            status == models.CertificateValid || status == models.CertificateNeedsUpdate,
+		HolderName:       certificateHolderName(cert.User),
+		CourseID:         cert.CourseID,
+		CourseTitle:      cert.Course.Title,
+		Instructor:       cert.Course.Instructor,
+		IssuedAt:         cert.IssuedAt,
+		RevokedAt:        cert.RevokedAt,
+		RevocationReason: cert.RevocationReason,
+		OutdatedAt:       cert.OutdatedAt,
+	}
+}
+
+// certificateHolderName is the name printed on certificates and shown when
+// verifying them: the user's full name, or their username if they have none.
+func certificateHolderName(user models.User) string {
+	if name := strings.TrimSpace(user.FirstName + " " + user.LastName); name != "" {
+		return name
+	}
+	return user.Username
+}
+
+// IssueCertificate signs a new certificate for the user, renders it with the
+// course's template and saves it. A certificate revoked by the refund of an
+// earlier purchase is restored instead, keeping its serial.
+func (s *certificateService) IssueCertificate(user models.User, course models.Course) (*models.Certificate, error) {
+	existing, err := s.courseRepo.FindCourseCertificate(user.ID, course.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.RevokedByRefund() {
+		return s.restoreRefunded(existing, user, course)
+	}
+
+	serial, err := utils.NewCertificateSerial()
+	if err != nil {
+		return nil, err
+	}
+	issuedAt := time.Now().Truncate(time.Second)
+
+	cert := models.Certificate{
+		UserID:    user.ID,
+		CourseID:  course.ID,
+		Serial:    serial,
+		Signature: s.signer.Sign(serial, user.ID, course.ID, issuedAt),
+		IssuedAt:  issuedAt,
+	}
+
+	design, err := s.loadDesign(course)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.renderCertificate(design, &cert, course, certificateHolderName(user)); err != nil {
+		return nil, err
+	}
+
+	if err := s.courseRepo.CreateCourseCertificate(&cert); err != nil {
+		storage.Remove(s.store, cert.FileKey)
+		storage.Remove(s.store, cert.PDFKey)
+		// A concurrent completion issued the certificate first.
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return s.courseRepo.FindCourseCertificate(user.ID, course.ID)
+		}
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// restoreRefunded lifts the refund's revocation and renders the certificate
+// again, since its files were removed with the refund.
+func (s *certificateService) restoreRefunded(cert *models.Certificate, user models.User, course models.Course) (*models.Certificate, error) {
+	design, err := s.loadDesign(course)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.rerender(design, cert, course, certificateHolderName(user)); err != nil {
+		return nil, err
+	}
+
+	cert.RevokedAt = nil
+	cert.RevokedBy = nil
+	cert.RevocationReason = ""
+	cert.OutdatedAt = nil
+	if err := s.certificateRepo.UpdateRevocation(cert); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// ListUserCertificates returns the certificates the user has earned,
+// including revoked ones so the user can see why they are gone.
+func (s *certificateService) ListUserCertificates(user models.User, q models.PaginationQuery) ([]models.CertificateResponse, models.PaginationResponse, error) {
+	q.Normalize()
+
+	certs, totalItems, err := s.certificateRepo.FindByUser(user.ID, q)
+	if err != nil {
+		return nil, models.PaginationResponse{}, err
+	}
+
+	res := make([]models.CertificateResponse, 0, len(certs))
+	for i := range certs {
+		res = append(res, s.buildCertificateResponse(&certs[i]))
+	}
+
+	return res, q.Paginate(totalItems), nil
+}
+
+// OpenCertificate returns the certificate file in the given format (png or
+// pdf) with a download name. Holders can download their own certificates
+// until they are revoked; certificate managers can download any. Files that
+// are missing, such as PDFs of certificates issued before PDFs existed, are
+// rendered on the spot.
+func (s *certificateService) OpenCertificate(id uint, user models.User, format string) (storage.Object, string, error) {
+	cert, err := s.certificateRepo.FindByID(id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	manager := rbac.HasPermission(user.Role, rbac.PermCertificateManage)
+	if cert.UserID != user.ID && !manager {
+		return nil, "", gorm.ErrRecordNotFound
+	}
+	if cert.RevokedAt != nil && !manager {
+		return nil, "", ErrCertificateRevoked
+	}
+
+	if format != "png" {
+		format = "pdf"
+	}
+
+	obj, err := s.openCertificateFile(cert, format)
+	if errors.Is(err, storage.ErrNotFound) {
+		if err := s.regenerate(cert); err != nil {
+			return nil, "", err
+		}
+		obj, err = s.openCertificateFile(cert, format)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return obj, certificateFilename(cert, format), nil
+}
+
+func (s *certificateService) openCertificateFile(cert *models.Certificate, format string) (storage.Object, error) {
+	key := cert.FileKey
+	if format == "pdf" {
+		key = cert.PDFKey
+	}
+	if key == "" {
+		return nil, storage.ErrNotFound
+	}
+	return s.store.Open(key)
+}
+
+func certificateFilename(cert *models.Certificate, format string) string {
+	return fmt.Sprintf("certificate-%s.%s", cert.Serial, format)
+}
+
+// RegenerateCertificate re-renders one certificate with its course's current
+// template, e.g. after the holder changed their name.
+func (s *certificateService) RegenerateCertificate(id uint) (*models.CertificateResponse, error) {
+	cert, err := s.certificateRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.regenerate(cert); err != nil {
+		return nil, err
+	}
+
+	res := s.buildCertificateResponse(cert)
+	return &res, nil
+}
+
+// regenerate re-renders a certificate loaded with its user and course.
+func (s *certificateService) regenerate(cert *models.Certificate) error {
+	design, err := s.loadDesign(cert.Course)
+	if err != nil {
+		return err
+	}
+	return s.rerender(design, cert, cert.Course, certificateHolderName(cert.User))
+}
+
+func (s *certificateService) buildCertificateResponse(cert *models.Certificate) models.CertificateResponse {
+	res := models.CertificateResponse{
+		CertificateVerificationResponse: *s.buildVerificationResponse(cert),
+		VerifyURL:                       s.signer.VerifyURL(cert.Serial),
+		DownloadURL:                     fmt.Sprintf("/api/certificates/%d/download", cert.ID),
+	}
+	if cert.RevokedAt == nil {
+		res.ImageURL = storage.URL(s.store, cert.FileKey)
+		res.PDFURL = storage.URL(s.store, cert.PDFKey)
+	}
+	return res
+}
+
+// HandleRegenerateJob re-renders a course's certificates after its template
+// changed. Serials and signatures stay the same, so printed copies and QR
+// codes keep verifying.
+func (s *certificateService) HandleRegenerateJob(payload []byte) error {
+	var p RegenerateCertificatesPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return err
+	}
+
+	course, err := s.courseRepo.FindById(p.CourseID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	certs, err := s.certificateRepo.FindByCourse(course.ID)
+	if err != nil {
+		return err
+	}
+	if len(certs) == 0 {
+		return nil
+	}
+
+	design, err := s.loadDesign(*course)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range certs {
+		cert := &certs[i]
+		if err := s.rerender(design, cert, *course, certificateHolderName(cert.User)); err != nil {
+			errs = append(errs, fmt.Errorf("certificate %d: %w", cert.ID, err))
+		}
+	}
+
+	log.Printf("Regenerated %d of %d certificates for course %d", len(certs)-len(errs), len(certs), course.ID)
+	return errors.Join(errs...)
+}
+
+// queueCertificateRecompute schedules applying the course's certificate
+// policy after its published modules changed. If it cannot be queued the
+// certificates keep their state until the next module or policy change.
+func queueCertificateRecompute(jobRepo repositories.JobRepository, courseID uint) {
+	payload, err := json.Marshal(RecomputeCertificatesPayload{CourseID: courseID})
+	if err == nil {
+		err = jobRepo.Enqueue(&models.Job{Type: JobTypeRecomputeCertificates, Payload: string(payload)})
+	}
+	if err != nil {
+		log.Printf("ERROR: Failed to schedule certificate recompute for course %d: %v", courseID, err)
+	}
+}
+
+// applyCertificatePolicy brings a certificate in line with its course's
+// policy and whether the holder has completed the course, and reports
+// whether it changed. Revocations made by a person are kept.
+func applyCertificatePolicy(cert *models.Certificate, policy string, completed bool) bool {
+	outdated := !completed && (policy == models.CertificatePolicyRevoke || policy == models.CertificatePolicyNeedsUpdate)
+	revoked := !completed && policy == models.CertificatePolicyRevoke
+	now := time.Now()
+	changed := false
+
+	if outdated != (cert.OutdatedAt != nil) {
+		cert.OutdatedAt = nil
+		if outdated {
+			cert.OutdatedAt = &now
+		}
+		changed = true
+	}
+
+	manuallyRevoked := cert.RevokedAt != nil && cert.RevokedBy != nil
+	if !manuallyRevoked && revoked != (cert.RevokedAt != nil) {
+		cert.RevokedAt = nil
+		cert.RevocationReason = ""
+		if revoked {
+			cert.RevokedAt = &now
+			cert.RevocationReason = certificateOutdatedReason
+		}
+		changed = true
+	}
+
+	return changed
+}
+
+// RecomputeCertificate applies the course's certificate policy to the user's
+// certificate after their progress changed. It returns the certificate, or
+// nil if the user has none. Certificates revoked by a refund are left for
+// IssueCertificate to restore.
+func (s *certificateService) RecomputeCertificate(userID, courseID uint) (*models.Certificate, error) {
+	cert, err := s.courseRepo.FindCourseCertificate(userID, courseID)
+	if err != nil || cert == nil || cert.RevokedByRefund() {
+		return cert, err
+	}
+
+	course, err := s.courseRepo.FindById(courseID)
+	if err != nil {
+		return nil, err
+	}
+	progress, err := s.courseRepo.GetCourseProgress(courseID, models.User{ID: userID})
+	if err != nil {
+		return nil, err
+	}
+
+	completed := progress.CompletedModules == progress.TotalModules
+	if applyCertificatePolicy(cert, course.CertificatePolicy, completed) {
+		if err := s.certificateRepo.UpdateRevocation(cert); err != nil {
+			return nil, err
+		}
+	}
+	return cert, nil
+}
+
+// HandleRecomputeJob applies a course's certificate policy to every
+// certificate after modules were added, removed or published. Buyers who
+// completed the course in the meantime, for example because the module they
+// were missing was deleted, are issued their certificate.
+func (s *certificateService) HandleRecomputeJob(payload []byte) error {
+	var p RecomputeCertificatesPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return err
+	}
+
+	course, err := s.courseRepo.FindById(p.CourseID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	certs, err := s.certificateRepo.FindByCourse(course.ID)
+	if err != nil {
+		return err
+	}
+	completedIDs, err := s.courseRepo.FindCompletedUserIDs(course.ID)
+	if err != nil {
+		return err
+	}
+	// With no published modules left there is nothing to fall behind on.
+	progress, err := s.courseRepo.GetCourseProgress(course.ID, models.User{})
+	if err != nil {
+		return err
+	}
+
+	completed := make(map[uint]bool, len(completedIDs))
+	for _, id := range completedIDs {
+		completed[id] = true
+	}
+
+	var errs []error
+	updated := 0
+	certified := make(map[uint]bool, len(certs))
+	for i := range certs {
+		cert := &certs[i]
+		// Buyers who bought the course again after a refund and completed
+		// it are left pending, so their certificate is restored below.
+		if cert.RevokedByRefund() {
+			continue
+		}
+		certified[cert.UserID] = true
+		if !applyCertificatePolicy(cert, course.CertificatePolicy, progress.TotalModules == 0 || completed[cert.UserID]) {
+			continue
+		}
+		if err := s.certificateRepo.UpdateRevocation(cert); err != nil {
+			errs = append(errs, fmt.Errorf("certificate %d: %w", cert.ID, err))
+			continue
+		}
+		updated++
+	}
+
+	var pending []uint
+	for _, id := range completedIDs {
+		if !certified[id] {
+			pending = append(pending, id)
+		}
+	}
+	issued := 0
+	if len(pending) > 0 {
+		users, err := s.courseRepo.FindUsersByIDs(pending)
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		for _, user := range users {
+			if _, err := s.IssueCertificate(user, *course); err != nil {
+				errs = append(errs, fmt.Errorf("user %d: %w", user.ID, err))
+				continue
+			}
+			issued++
+		}
+	}
+
+	log.Printf("Recomputed certificates for course %d: %d updated, %d issued", course.ID, updated, issued)
+	return errors.Join(errs...)
+}
+
+// certificateDesign is a template together with its loaded assets.
+type certificateDesign struct {
+	template models.CertificateTemplate
+	assets   utils.CertificateAssets
+}
+
+// loadDesign resolves the template a course's certificates use: its own,
+// else the default template, else the built-in design.
+func (s *certificateService) loadDesign(course models.Course) (*certificateDesign, error) {
+	var tpl *models.CertificateTemplate
+	if course.CertificateTemplateID != nil {
+		found, err := s.templateRepo.FindByID(*course.CertificateTemplateID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		tpl = found
+	}
+	if tpl == nil {
+		found, err := s.templateRepo.FindDefault()
+		if err != nil {
+			return nil, err
+		}
+		tpl = found
+	}
+	if tpl == nil {
+		builtin := models.DefaultCertificateTemplate()
+		tpl = &builtin
+	}
+
+	return &certificateDesign{template: *tpl, assets: s.loadAssets(*tpl)}, nil
+}
+
+// loadAssets reads the template's files from storage. A file that cannot be
+// loaded is left out with a log line rather than blocking certificates.
+func (s *certificateService) loadAssets(tpl models.CertificateTemplate) utils.CertificateAssets {
+	return utils.CertificateAssets{
+		Background: s.loadAssetImage(tpl.BackgroundKey),
+		Logo:       s.loadAssetImage(tpl.LogoKey),
+		Signature:  s.loadAssetImage(tpl.SignatureKey),
+		TitleFont:  s.loadAssetFont(tpl.TitleFontKey),
+		BodyFont:   s.loadAssetFont(tpl.BodyFontKey),
+	}
+}
+
+func (s *certificateService) loadAsset(key string) []byte {
+	if key == "" {
+		return nil
+	}
+	r, err := s.store.Get(key)
+	if err != nil {
+		log.Printf("ERROR: Failed to load certificate asset %s: %v", key, err)
+		return nil
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		log.Printf("ERROR: Failed to load certificate asset %s: %v", key, err)
+		return nil
+	}
+	return data
+}
+
+func (s *certificateService) loadAssetImage(key string) image.Image {
+	data := s.loadAsset(key)
+	if data == nil {
+		return nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		log.Printf("ERROR: Failed to decode certificate asset %s: %v", key, err)
+		return nil
+	}
+	return img
+}
+
+func (s *certificateService) loadAssetFont(key string) []byte {
+	data := s.loadAsset(key)
+	if data == nil {
+		return nil
+	}
+	if err := utils.ParseCertificateFont(data); err != nil {
+		log.Printf("ERROR: Failed to parse certificate font %s: %v", key, err)
+		return nil
+	}
+	return data
+}
+
+// renderCertificate draws the certificate as PNG and PDF and stores both,
+// overwriting earlier renders of the same certificate.
+func (s *certificateService) renderCertificate(design *certificateDesign, cert *models.Certificate, course models.Course, holder string) error {
+	content := utils.CertificateContent{
+		Holder:      holder,
+		CourseTitle: course.Title,
+		Instructor:  course.Instructor,
+		Date:        cert.IssuedAt.Format("2006-01-02"),
+		Serial:      cert.Serial,
+		VerifyURL:   s.signer.VerifyURL(cert.Serial),
+	}
+
+	pngData, err := renderCertificateFile(design, content, "png")
+	if err != nil {
+		return err
+	}
+	pdfData, err := renderCertificateFile(design, content, "pdf")
+	if err != nil {
+		return err
+	}
+
+	// Keys follow the serial, so a render never overwrites the files of
+	// another certificate, such as one issued concurrently.
+	base := "certificates/" + cert.Serial
+	if err := s.store.Put(base+".png", bytes.NewReader(pngData), int64(len(pngData)), "image/png"); err != nil {
+		return err
+	}
+	if err := s.store.Put(base+".pdf", bytes.NewReader(pdfData), int64(len(pdfData)), "application/pdf"); err != nil {
+		return err
+	}
+
+	cert.FileKey = base + ".png"
+	cert.PDFKey = base + ".pdf"
+	return nil
+}
+
+// rerender renders a saved certificate again and stores its new file keys.
+// Files kept under other keys, as certificates rendered before keys followed
+// the serial were, are removed once the new keys are saved.
+func (s *certificateService) rerender(design *certificateDesign, cert *models.Certificate, course models.Course, holder string) error {
+	oldKeys := []string{cert.FileKey, cert.PDFKey}
+	if err := s.renderCertificate(design, cert, course, holder); err != nil {
+		return err
+	}
+	if err := s.certificateRepo.UpdateFiles(cert); err != nil {
+		return err
+	}
+
+	for _, key := range oldKeys {
+		if key != cert.FileKey && key != cert.PDFKey {
+			storage.Remove(s.store, key)
+		}
+	}
+	return nil
+}
+
+func renderCertificateFile(design *certificateDesign, content utils.CertificateContent, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	if format == "pdf" {
+		if err := utils.RenderCertificatePDF(&buf, design.template, design.assets, content); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	img, err := utils.RenderCertificatePNG(design.template, design.assets, content)
+	if err != nil {
+		return nil, err
+	}
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *certificateService) ListTemplates() ([]models.CertificateTemplateResponse, error) {
+	templates, err := s.templateRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]models.CertificateTemplateResponse, 0, len(templates))
+	for _, tpl := range templates {
+		res = append(res, s.buildTemplateResponse(tpl))
+	}
+	return res, nil
+}
+
+func (s *certificateService) GetTemplate(id uint) (*models.CertificateTemplateResponse, error) {
+	tpl, err := s.templateRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	res := s.buildTemplateResponse(*tpl)
+	return &res, nil
+}
+
+func (s *certificateService) buildTemplateResponse(tpl models.CertificateTemplate) models.CertificateTemplateResponse {
+	return models.CertificateTemplateResponse{
+		CertificateTemplate: tpl,
+		BackgroundURL:       storage.URL(s.store, tpl.BackgroundKey),
+		LogoURL:             storage.URL(s.store, tpl.LogoKey),
+		SignatureURL:        storage.URL(s.store, tpl.SignatureKey),
+	}
+}
+
+// CreateTemplate saves a new template starting from the built-in design.
+// Making it the default re-renders the certificates of courses without a
+// template of their own.
+func (s *certificateService) CreateTemplate(input models.CertificateTemplateFormInput) (*models.CertificateTemplateResponse, error) {
+	tpl := models.DefaultCertificateTemplate()
+	if err := s.applyTemplateInput(&tpl, input); err != nil {
+		return nil, err
+	}
+
+	replaced, err := s.saveTemplateAssets(&tpl, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.templateRepo.Create(&tpl); err != nil {
+		removeReplacedAssets(s.store, replaced, true)
+		return nil, err
+	}
+
+	if tpl.IsDefault {
+		s.regenerateTemplateCourses(nil)
+	}
+
+	res := s.buildTemplateResponse(tpl)
+	return &res, nil
+}
+
+// UpdateTemplate replaces the template's settings and re-renders every
+// certificate that uses it.
+func (s *certificateService) UpdateTemplate(id uint, input models.CertificateTemplateFormInput) (*models.CertificateTemplateResponse, error) {
+	tpl, err := s.templateRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	wasDefault := tpl.IsDefault
+
+	if err := s.applyTemplateInput(tpl, input); err != nil {
+		return nil, err
+	}
+
+	replaced, err := s.saveTemplateAssets(tpl, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.templateRepo.Update(tpl); err != nil {
+		removeReplacedAssets(s.store, replaced, true)
+		return nil, err
+	}
+	removeReplacedAssets(s.store, replaced, false)
+
+	s.regenerateTemplateCourses(&tpl.ID)
+	if wasDefault || tpl.IsDefault {
+		s.regenerateTemplateCourses(nil)
+	}
+
+	res := s.buildTemplateResponse(*tpl)
+	return &res, nil
+}
+
+// DeleteTemplate removes a template. Its courses fall back to the default
+// template and their certificates are re-rendered.
+func (s *certificateService) DeleteTemplate(id uint) error {
+	tpl, err := s.templateRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	courseIDs, err := s.templateRepo.FindCourseIDsWithCertificates(&tpl.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.templateRepo.Delete(tpl.ID); err != nil {
+		return err
+	}
+
+	for _, key := range []string{tpl.BackgroundKey, tpl.LogoKey, tpl.SignatureKey, tpl.TitleFontKey, tpl.BodyFontKey} {
+		storage.Remove(s.store, key)
+	}
+
+	if tpl.IsDefault {
+		s.regenerateTemplateCourses(nil)
+	} else {
+		s.enqueueRegeneration(courseIDs)
+	}
+	return nil
+}
+
+// PreviewTemplate renders the template with sample data, as a PNG or PDF.
+func (s *certificateService) PreviewTemplate(id uint, format string) ([]byte, string, error) {
+	tpl, err := s.templateRepo.FindByID(id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	serial := "PREV-IEW0-0000-0000-0000-0000"
+	content := utils.CertificateContent{
+		Holder:      "Jane Doe",
+		CourseTitle: "Sample Course Title",
+		Instructor:  "Instructor Name",
+		Date:        time.Now().Format("2006-01-02"),
+		Serial:      serial,
+		VerifyURL:   s.signer.VerifyURL(serial),
+	}
+
+	design := &certificateDesign{template: *tpl, assets: s.loadAssets(*tpl)}
+	data, err := renderCertificateFile(design, content, format)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if format == "pdf" {
+		return data, "application/pdf", nil
+	}
+	return data, "image/png", nil
+}
+
+// SetCourseTemplate picks the template for a course's certificates and
+// re-renders the ones already issued.
+func (s *certificateService) SetCourseTemplate(courseID uint, input models.CourseCertificateTemplateInput, user models.User) (*models.Course, error) {
+	course, err := s.courseRepo.FindById(courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeCourseManagement(s.courseRepo, user, course.ID); err != nil {
+		return nil, err
+	}
+
+	if input.TemplateID != nil {
+		if _, err := s.templateRepo.FindByID(*input.TemplateID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("%w: template %d does not exist", ErrInvalidCertificateTemplate, *input.TemplateID)
+			}
+			return nil, err
+		}
+	}
+
+	if err := s.courseRepo.SetCertificateTemplate(course.ID, input.TemplateID); err != nil {
+		return nil, err
+	}
+
+	unchanged := (course.CertificateTemplateID == nil && input.TemplateID == nil) ||
+		(course.CertificateTemplateID != nil && input.TemplateID != nil && *course.CertificateTemplateID == *input.TemplateID)
+	course.CertificateTemplateID = input.TemplateID
+	if !unchanged {
+		s.enqueueRegeneration([]uint{course.ID})
+	}
+	return course, nil
+}
+
+// applyTemplateInput copies the form onto tpl. Empty values keep what tpl
+// already has, and fields are merged over its current layout.
+func (s *certificateService) applyTemplateInput(tpl *models.CertificateTemplate, input models.CertificateTemplateFormInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCertificateTemplate)
+	}
+
+	existing, err := s.templateRepo.FindAll()
+	if err != nil {
+		return err
+	}
+	for _, other := range existing {
+		if other.ID != tpl.ID && strings.EqualFold(other.Name, name) {
+			return ErrCertificateTemplateExists
+		}
+	}
+
+	tpl.Name = name
+	tpl.IsDefault = input.IsDefault
+	if input.Width > 0 {
+		tpl.Width = input.Width
+	}
+	if input.Height > 0 {
+		tpl.Height = input.Height
+	}
+
+	colors := []struct {
+		name  string
+		value string
+		dst   *string
+	}{
+		{"text_color", input.TextColor, &tpl.TextColor},
+		{"accent_color", input.AccentColor, &tpl.AccentColor},
+	}
+	for _, c := range colors {
+		if strings.TrimSpace(c.value) == "" {
+			continue
+		}
+		rgb, err := utils.ParseHexColor(c.value)
+		if err != nil {
+			return fmt.Errorf("%w: %s must be a hex color such as #1a4f8b", ErrInvalidCertificateTemplate, c.name)
+		}
+		*c.dst = fmt.Sprintf("#%02x%02x%02x", rgb.R, rgb.G, rgb.B)
+	}
+
+	if tpl.Fields == nil {
+		tpl.Fields = models.DefaultCertificateFields()
+	}
+	if strings.TrimSpace(input.Fields) == "" {
+		return nil
+	}
+
+	var fields map[string]models.CertificateField
+	if err := json.Unmarshal([]byte(input.Fields), &fields); err != nil {
+		return fmt.Errorf("%w: fields must be a JSON object: %v", ErrInvalidCertificateTemplate, err)
+	}
+	for key, field := range fields {
+		if !slices.Contains(models.CertificateTextFields, key) && !slices.Contains(models.CertificateImageFields, key) {
+			return fmt.Errorf("%w: unknown field %q", ErrInvalidCertificateTemplate, key)
+		}
+		switch field.Align {
+		case "", "left", "center", "right":
+		default:
+			return fmt.Errorf("%w: %s.align must be left, center or right", ErrInvalidCertificateTemplate, key)
+		}
+		if field.Size < 0 || field.Width < 0 || field.Height < 0 {
+			return fmt.Errorf("%w: %s must not have negative sizes", ErrInvalidCertificateTemplate, key)
+		}
+		tpl.Fields[key] = field
+	}
+	return nil
+}
+
+// templateAsset is one file slot of a template.
+type templateAsset struct {
+	name string
+	kind storage.UploadKind
+	file *multipart.FileHeader
+	key  *string
+}
+
+// replacedAsset records a slot whose key changed, so the losing file can be
+// removed once the outcome is known.
+type replacedAsset struct {
+	oldKey string
+	newKey string
+}
+
+// saveTemplateAssets stores uploaded files and clears removed ones on tpl.
+// Nothing is deleted yet; the caller removes the old or new files through
+// removeReplacedAssets depending on whether saving the template succeeded.
+func (s *certificateService) saveTemplateAssets(tpl *models.CertificateTemplate, input models.CertificateTemplateFormInput) ([]replacedAsset, error) {
+	slots := []templateAsset{
+		{"background", storage.KindImage, input.Background, &tpl.BackgroundKey},
+		{"logo", storage.KindImage, input.Logo, &tpl.LogoKey},
+		{"signature", storage.KindImage, input.Signature, &tpl.SignatureKey},
+		{"title_font", storage.KindFont, input.TitleFont, &tpl.TitleFontKey},
+		{"body_font", storage.KindFont, input.BodyFont, &tpl.BodyFontKey},
+	}
+
+	var replaced []replacedAsset
+	for _, slot := range slots {
+		switch {
+		case slot.file != nil:
+			key, err := saveTemplateAsset(s.store, slot)
+			if err != nil {
+				removeReplacedAssets(s.store, replaced, true)
+				return nil, err
+			}
+			replaced = append(replaced, replacedAsset{oldKey: *slot.key, newKey: key})
+			*slot.key = key
+		case slices.Contains(input.Remove, slot.name) && *slot.key != "":
+			replaced = append(replaced, replacedAsset{oldKey: *slot.key})
+			*slot.key = ""
+		}
+	}
+	return replaced, nil
+}
+
+// saveTemplateAsset checks that the upload can actually be drawn before
+// storing it, since a broken file would only show up when certificates are
+// rendered.
+func saveTemplateAsset(store storage.Store, slot templateAsset) (string, error) {
+	upload, err := storage.ValidateUpload(slot.name, "certificate-templates", slot.kind, slot.file)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := slot.file.Open()
+	if err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return "", err
+	}
+
+	if slot.kind == storage.KindFont {
+		err = utils.ParseCertificateFont(data)
+	} else {
+		_, _, err = image.DecodeConfig(bytes.NewReader(data))
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: %s could not be read: %v", ErrInvalidCertificateTemplate, slot.name, err)
+	}
+
+	if err := upload.Save(store); err != nil {
+		return "", err
+	}
+	return upload.Key, nil
+}
+
+// removeReplacedAssets deletes the new files after a failed save, or the
+// old ones after a successful one.
+func removeReplacedAssets(store storage.Store, replaced []replacedAsset, failed bool) {
+	for _, r := range replaced {
+		if failed {
+			storage.Remove(store, r.newKey)
+		} else {
+			storage.Remove(store, r.oldKey)
+		}
+	}
+}
+
+// regenerateTemplateCourses queues re-rendering for the courses using the
+// template; nil means the courses that follow the default.
+func (s *certificateService) regenerateTemplateCourses(templateID *uint) {
+	courseIDs, err := s.templateRepo.FindCourseIDsWithCertificates(templateID)
+	if err != nil {
+		log.Printf("ERROR: Failed to find certificates to regenerate: %v", err)
+		return
+	}
+	s.enqueueRegeneration(courseIDs)
+}
+
+// enqueueRegeneration queues one job per course so a failing course is
+// retried on its own. A course that cannot be queued keeps its old images
+// until the template changes again or its certificates are regenerated.
+func (s *certificateService) enqueueRegeneration(courseIDs []uint) {
+	for _, id := range courseIDs {
+		payload, err := json.Marshal(RegenerateCertificatesPayload{CourseID: id})
+		if err == nil {
+			err = s.jobRepo.Enqueue(&models.Job{Type: JobTypeRegenerateCertificates, Payload: string(payload)})
+		}
+		if err != nil {
+			log.Printf("ERROR: Failed to schedule certificate regeneration for course %d: %v", id, err)
+		}
+	}
+}
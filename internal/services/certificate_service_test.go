@@ -0,0 +1,284 @@
+package services
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
+	"github.com/kin-ark/GroAcademy/internal/storage"
+	"github.com/kin-ark/GroAcademy/internal/utils"
+	"gorm.io/gorm"
+)
+
+func TestApplyCertificatePolicy(t *testing.T) {
+	earlier := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	admin := uint(1)
+
+	valid := func() *models.Certificate { return &models.Certificate{} }
+	outdated := func() *models.Certificate { return &models.Certificate{OutdatedAt: &earlier} }
+	autoRevoked := func() *models.Certificate {
+		return &models.Certificate{OutdatedAt: &earlier, RevokedAt: &earlier, RevocationReason: certificateOutdatedReason}
+	}
+	manuallyRevoked := func() *models.Certificate {
+		return &models.Certificate{RevokedAt: &earlier, RevokedBy: &admin, RevocationReason: "plagiarism"}
+	}
+
+	tests := []struct {
+		name      string
+		cert      func() *models.Certificate
+		policy    string
+		completed bool
+		changed   bool
+		outdated  bool
+		revoked   bool
+		reason    string
+	}{
+		{name: "grandfather keeps an incomplete holder's certificate", cert: valid, policy: models.CertificatePolicyGrandfather},
+		{name: "needs update flags an incomplete holder", cert: valid, policy: models.CertificatePolicyNeedsUpdate, changed: true, outdated: true},
+		{name: "revoke revokes an incomplete holder", cert: valid, policy: models.CertificatePolicyRevoke, changed: true, outdated: true, revoked: true, reason: certificateOutdatedReason},
+		{name: "complete holder stays valid", cert: valid, policy: models.CertificatePolicyRevoke, completed: true},
+		{name: "already outdated is unchanged", cert: outdated, policy: models.CertificatePolicyNeedsUpdate, outdated: true},
+		{name: "completing lifts the flag", cert: outdated, policy: models.CertificatePolicyNeedsUpdate, completed: true, changed: true},
+		{name: "completing lifts an automatic revocation", cert: autoRevoked, policy: models.CertificatePolicyRevoke, completed: true, changed: true},
+		{name: "switching to needs update lifts an automatic revocation", cert: autoRevoked, policy: models.CertificatePolicyNeedsUpdate, changed: true, outdated: true},
+		{name: "switching to grandfather lifts everything", cert: autoRevoked, policy: models.CertificatePolicyGrandfather, changed: true},
+		{name: "manual revocation is kept on completion", cert: manuallyRevoked, policy: models.CertificatePolicyRevoke, completed: true, revoked: true, reason: "plagiarism"},
+		{name: "manual revocation keeps its reason", cert: manuallyRevoked, policy: models.CertificatePolicyRevoke, changed: true, outdated: true, revoked: true, reason: "plagiarism"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert := tt.cert()
+
+			if changed := applyCertificatePolicy(cert, tt.policy, tt.completed); changed != tt.changed {
+				t.Errorf("changed = %v, want %v", changed, tt.changed)
+			}
+			if outdated := cert.OutdatedAt != nil; outdated != tt.outdated {
+				t.Errorf("outdated = %v, want %v", outdated, tt.outdated)
+			}
+			if revoked := cert.RevokedAt != nil; revoked != tt.revoked {
+				t.Errorf("revoked = %v, want %v", revoked, tt.revoked)
+			}
+			if cert.RevocationReason != tt.reason {
+				t.Errorf("RevocationReason = %q, want %q", cert.RevocationReason, tt.reason)
+			}
+		})
+	}
+}
+
+// certificateCourses holds the certificates of one course in memory, one per
+// user as the unique index keeps them. The next missed lookups find nothing,
+// as if a concurrent request saved its certificate right after them.
+type certificateCourses struct {
+	repositories.CourseRepository
+	certs  []*models.Certificate
+	missed int
+}
+
+func (r *certificateCourses) FindCourseCertificate(userID, courseID uint) (*models.Certificate, error) {
+	if r.missed > 0 {
+		r.missed--
+		return nil, nil
+	}
+	for _, c := range r.certs {
+		if c.UserID == userID && c.CourseID == courseID {
+			found := *c
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *certificateCourses) FindById(id uint) (*models.Course, error) {
+	return &models.Course{ID: id, Title: "Go", Instructor: "Grace"}, nil
+}
+
+func (r *certificateCourses) CreateCourseCertificate(cert *models.Certificate) error {
+	for _, c := range r.certs {
+		if c.UserID == cert.UserID && c.CourseID == cert.CourseID {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	cert.ID = uint(len(r.certs) + 1)
+	saved := *cert
+	r.certs = append(r.certs, &saved)
+	return nil
+}
+
+// certificateRows saves certificate updates into certificateCourses.
+type certificateRows struct {
+	repositories.CertificateRepository
+	courses *certificateCourses
+}
+
+func (r *certificateRows) saved(id uint) *models.Certificate {
+	return r.courses.certs[id-1]
+}
+
+func (r *certificateRows) UpdateFiles(cert *models.Certificate) error {
+	r.saved(cert.ID).FileKey, r.saved(cert.ID).PDFKey = cert.FileKey, cert.PDFKey
+	return nil
+}
+
+func (r *certificateRows) UpdateRevocation(cert *models.Certificate) error {
+	saved := r.saved(cert.ID)
+	saved.RevokedAt, saved.RevokedBy, saved.RevocationReason, saved.OutdatedAt = cert.RevokedAt, cert.RevokedBy, cert.RevocationReason, cert.OutdatedAt
+	return nil
+}
+
+type noCertificateTemplates struct {
+	repositories.CertificateTemplateRepository
+}
+
+func (noCertificateTemplates) FindDefault() (*models.CertificateTemplate, error) { return nil, nil }
+
+func newTestCertificateService(t *testing.T, certs ...*models.Certificate) (*certificateService, *certificateCourses) {
+	courses := &certificateCourses{certs: certs}
+	return &certificateService{
+		certificateRepo: &certificateRows{courses: courses},
+		templateRepo:    noCertificateTemplates{},
+		courseRepo:      courses,
+		store:           storage.NewLocalStore(t.TempDir(), nil),
+		signer:          utils.NewCertificateSigner("test", "http://localhost/"),
+	}, courses
+}
+
+func TestIssueCertificateRestoresRefundedOne(t *testing.T) {
+	revokedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	buyer := uint(1)
+	s, courses := newTestCertificateService(t, &models.Certificate{
+		ID: 1, UserID: 1, CourseID: 3, Serial: "K3VQ-7XWD", IssuedAt: revokedAt.Add(-time.Hour),
+		RevokedAt: &revokedAt, RevokedBy: &buyer, RevocationReason: models.CertificateRefundedReason,
+	})
+
+	cert, err := s.IssueCertificate(models.User{ID: 1, Username: "ada"}, models.Course{ID: 3, Title: "Go", Instructor: "Grace"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(courses.certs) != 1 {
+		t.Fatalf("got %d certificates, want the refunded one restored", len(courses.certs))
+	}
+	saved := courses.certs[0]
+	if cert.Serial != "K3VQ-7XWD" || saved.RevokedAt != nil || saved.RevokedBy != nil || saved.RevocationReason != "" {
+		t.Errorf("restored certificate = %+v, want serial kept and revocation lifted", saved)
+	}
+	for _, key := range []string{saved.FileKey, saved.PDFKey} {
+		if _, err := s.store.Open(key); err != nil {
+			t.Errorf("open %q: %v", key, err)
+		}
+	}
+}
+
+// A refund leaves a certificate an admin revoked as it was, so buying and
+// completing the course again does not restore it.
+func TestCompletingAfterRefundKeepsManualRevocation(t *testing.T) {
+	revokedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	admin := uint(4)
+	s, courses := newTestCertificateService(t, &models.Certificate{
+		ID: 1, UserID: 1, CourseID: 3, Serial: "K3VQ-7XWD", IssuedAt: revokedAt.Add(-time.Hour),
+		RevokedAt: &revokedAt, RevokedBy: &admin, RevocationReason: "plagiarised assignments",
+	})
+	modules := &moduleService{courseRepo: courses, store: s.store, certificateService: s}
+
+	url, err := modules.generateCertificateIfEligible(7, models.User{ID: 1, Username: "ada"}, 3, &models.CourseProgress{TotalModules: 2, CompletedModules: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if url != nil {
+		t.Errorf("got certificate %q, want none", *url)
+	}
+	if len(courses.certs) != 1 {
+		t.Fatalf("got %d certificates, want 1", len(courses.certs))
+	}
+	saved := courses.certs[0]
+	if saved.RevokedAt == nil || saved.RevokedBy == nil || *saved.RevokedBy != admin || saved.RevocationReason != "plagiarised assignments" {
+		t.Errorf("certificate = %+v, want the admin's revocation kept", saved)
+	}
+}
+
+func TestIssueCertificateConcurrently(t *testing.T) {
+	s, courses := newTestCertificateService(t)
+	user := models.User{ID: 1, Username: "ada"}
+	course := models.Course{ID: 3, Title: "Go", Instructor: "Grace"}
+
+	first, err := s.IssueCertificate(user, course)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second completion checked for a certificate before the first one
+	// was saved.
+	courses.missed = 1
+	second, err := s.IssueCertificate(user, course)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(courses.certs) != 1 || second.Serial != first.Serial {
+		t.Fatalf("got %d certificates, second serial %s; want only %s", len(courses.certs), second.Serial, first.Serial)
+	}
+	keys, err := s.store.List("certificates/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{first.FileKey, first.PDFKey}
+	slices.Sort(keys)
+	slices.Sort(want)
+	if !slices.Equal(keys, want) {
+		t.Errorf("stored files = %v, want only the first certificate's %v", keys, want)
+	}
+}
+
+func TestRegenerateMovesLegacyFiles(t *testing.T) {
+	legacy := "certificates/cert_user1_course3.png"
+	cert := &models.Certificate{
+		ID: 1, UserID: 1, CourseID: 3, Serial: "K3VQ-7XWD", FileKey: legacy,
+		User:   models.User{ID: 1, Username: "ada"},
+		Course: models.Course{ID: 3, Title: "Go", Instructor: "Grace"},
+	}
+	s, _ := newTestCertificateService(t, cert)
+	if err := s.store.Put(legacy, strings.NewReader("png"), 3, "image/png"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.regenerate(cert); err != nil {
+		t.Fatal(err)
+	}
+
+	if cert.FileKey != "certificates/K3VQ-7XWD.png" || cert.PDFKey != "certificates/K3VQ-7XWD.pdf" {
+		t.Errorf("keys = %s, %s; want them named after the serial", cert.FileKey, cert.PDFKey)
+	}
+	if _, err := s.store.Open(legacy); err == nil {
+		t.Errorf("legacy file %s was kept", legacy)
+	}
+}
+
+func TestCertificateHolderName(t *testing.T) {
+	tests := []struct {
+		name string
+		user models.User
+		want string
+	}{
+		{name: "full name", user: models.User{FirstName: "Ada", LastName: "Lovelace", Username: "ada"}, want: "Ada Lovelace"},
+		{name: "first name only", user: models.User{FirstName: "Ada", Username: "ada"}, want: "Ada"},
+		{name: "no name", user: models.User{Username: "ada"}, want: "ada"},
+	}
+
+	s, _ := newTestCertificateService(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := certificateHolderName(tt.user); got != tt.want {
+				t.Errorf("certificateHolderName = %q, want %q", got, tt.want)
+			}
+			// The verification page shows the name printed on the certificate.
+			if got := s.buildVerificationResponse(&models.Certificate{User: tt.user}).HolderName; got != tt.want {
+				t.Errorf("verified holder = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCoupon = errors.New("invalid coupon")
+	ErrCouponExists  = errors.New("a coupon with this code already exists")
+)
+
+// IsCouponError reports whether err means the coupon cannot be used, as
+// opposed to the purchase failing for another reason.
+func IsCouponError(err error) bool {
+	return errors.Is(err, repositories.ErrUnknownCoupon) || errors.Is(err, repositories.ErrCouponInactive) ||
+		errors.Is(err, repositories.ErrCouponNotApplicable) || errors.Is(err, repositories.ErrCouponUsedUp) ||
+		errors.Is(err, repositories.ErrCouponUserLimit)
+}
+
+type CouponService interface {
+	ListCoupons(q models.PaginationQuery) ([]models.CouponWithUses, models.PaginationResponse, error)
+	GetCoupon(id uint) (*models.CouponWithUses, error)
+	CreateCoupon(input models.CouponInput) (*models.CouponWithUses, error)
+	UpdateCoupon(id uint, input models.CouponInput) (*models.CouponWithUses, error)
+	DeleteCoupon(id uint) error
+}
+
+type couponService struct {
+	couponRepo repositories.CouponRepository
+	courseRepo repositories.CourseRepository
+}
+
+func NewCouponService(cpr repositories.CouponRepository, cr repositories.CourseRepository) CouponService {
+	return &couponService{couponRepo: cpr, courseRepo: cr}
+}
+
+func (s *couponService) ListCoupons(q models.PaginationQuery) ([]models.CouponWithUses, models.PaginationResponse, error) {
+	q.Normalize()
+
+	coupons, totalItems, err := s.couponRepo.FindAll(q)
+	if err != nil {
+		return nil, models.PaginationResponse{}, err
+	}
+
+	return coupons, q.Paginate(totalItems), nil
+}
+
+func (s *couponService) GetCoupon(id uint) (*models.CouponWithUses, error) {
+	return s.couponRepo.FindByID(id)
+}
+
+// CreateCoupon saves a new coupon, active unless the input says otherwise.
+func (s *couponService) CreateCoupon(input models.CouponInput) (*models.CouponWithUses, error) {
+	coupon := models.Coupon{Active: true}
+	if err := s.applyCouponInput(&coupon, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.couponRepo.Create(&coupon); err != nil {
+		return nil, err
+	}
+	return s.couponRepo.FindByID(coupon.ID)
+}
+
+// UpdateCoupon replaces the coupon's settings. Purchases already made keep
+// the discount they got.
+func (s *couponService) UpdateCoupon(id uint, input models.CouponInput) (*models.CouponWithUses, error) {
+	existing, err := s.couponRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	coupon := existing.Coupon
+	if err := s.applyCouponInput(&coupon, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.couponRepo.Update(&coupon); err != nil {
+		return nil, err
+	}
+	return s.couponRepo.FindByID(id)
+}
+
+func (s *couponService) DeleteCoupon(id uint) error {
+	return s.couponRepo.Delete(id)
+}
+
+func (s *couponService) applyCouponInput(coupon *models.Coupon, input models.CouponInput) error {
+	code := normalizeCouponCode(input.Code)
+	if code == "" || strings.IndexFunc(code, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_'
+	}) >= 0 {
+		return fmt.Errorf("%w: code may only contain letters, digits, '-' and '_'", ErrInvalidCoupon)
+	}
+
+	other, err := s.couponRepo.FindByCode(code)
+	if err != nil {
+		return err
+	}
+	if other != nil && other.ID != coupon.ID {
+		return ErrCouponExists
+	}
+
+	if input.Type == models.CouponTypePercent && input.Value > 100 {
+		return fmt.Errorf("%w: a percentage discount cannot exceed 100", ErrInvalidCoupon)
+	}
+	if input.StartsAt != nil && input.ExpiresAt != nil && !input.ExpiresAt.After(*input.StartsAt) {
+		return fmt.Errorf("%w: expires_at must be after starts_at", ErrInvalidCoupon)
+	}
+
+	if input.CourseID != nil {
+		if _, err := s.courseRepo.FindById(*input.CourseID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: course %d not found", ErrInvalidCoupon, *input.CourseID)
+			}
+			return err
+		}
+	}
+
+	coupon.Code = code
+	coupon.Type = input.Type
+	coupon.Value = input.Value
+	coupon.CourseID = input.CourseID
+	coupon.MaxUses = input.MaxUses
+	coupon.MaxUsesPerUser = input.MaxUsesPerUser
+	coupon.StartsAt = input.StartsAt
+	coupon.ExpiresAt = input.ExpiresAt
+	if input.Active != nil {
+		coupon.Active = *input.Active
+	}
+	return nil
+}
+
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// quoteCoursePrice prices the course at now: the sale price if one is
+// running, less the coupon's discount. Usage limits are only checked when
+// the course is bought, together with the coupon's validity again.
+func quoteCoursePrice(couponRepo repositories.CouponRepository, course *models.Course, code string, now time.Time) (*models.PriceQuote, error) {
+	price := course.PriceAt(now)
+	quote := models.PriceQuote{
+		CourseID:     course.ID,
+		ListPrice:    course.Price,
+		SaleDiscount: roundCents(course.Price - price),
+		Amount:       price,
+	}
+
+	code = normalizeCouponCode(code)
+	if code == "" {
+		return &quote, nil
+	}
+
+	coupon, err := couponRepo.FindByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if coupon == nil {
+		return nil, repositories.ErrUnknownCoupon
+	}
+	if !coupon.ValidAt(now) {
+		return nil, repositories.ErrCouponInactive
+	}
+	if !coupon.AppliesTo(course.ID) {
+		return nil, repositories.ErrCouponNotApplicable
+	}
+
+	quote.Coupon = coupon
+	quote.CouponCode = coupon.Code
+	quote.CouponDiscount = coupon.DiscountFor(price)
+	quote.Amount = roundCents(price - quote.CouponDiscount)
+	return &quote, nil
+}
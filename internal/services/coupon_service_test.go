@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
+)
+
+// fakeCouponRepository serves coupons by code; the other methods are not
+// used by quoteCoursePrice.
+type fakeCouponRepository struct {
+	repositories.CouponRepository
+	coupons map[string]models.Coupon
+	err     error
+}
+
+func (r fakeCouponRepository) FindByCode(code string) (*models.Coupon, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	coupon, ok := r.coupons[code]
+	if !ok {
+		return nil, nil
+	}
+	return &coupon, nil
+}
+
+func TestQuoteCoursePrice(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	later := now.Add(24 * time.Hour)
+	earlier := now.Add(-24 * time.Hour)
+	otherCourse := uint(9)
+	salePrice := 80.0
+	lookupErr := errors.New("lookup failed")
+
+	repo := fakeCouponRepository{coupons: map[string]models.Coupon{
+		"SAVE15":  {Code: "SAVE15", Type: models.CouponTypePercent, Value: 15, Active: true},
+		"MINUS30": {Code: "MINUS30", Type: models.CouponTypeFixed, Value: 30, Active: true},
+		"HUGE":    {Code: "HUGE", Type: models.CouponTypeFixed, Value: 500, Active: true},
+		"OFF":     {Code: "OFF", Type: models.CouponTypePercent, Value: 50},
+		"EXPIRED": {Code: "EXPIRED", Type: models.CouponTypePercent, Value: 50, Active: true, ExpiresAt: &earlier},
+		"OTHER":   {Code: "OTHER", Type: models.CouponTypePercent, Value: 50, Active: true, CourseID: &otherCourse},
+	}}
+
+	course := models.Course{ID: 3, Price: 100}
+	onSale := models.Course{ID: 3, Price: 100, SalePrice: &salePrice, SaleEndsAt: &later}
+	saleOver := models.Course{ID: 3, Price: 100, SalePrice: &salePrice, SaleEndsAt: &earlier}
+
+	tests := []struct {
+		name   string
+		repo   repositories.CouponRepository
+		course models.Course
+		code   string
+		want   models.PriceQuote
+		err    error
+	}{
+		{
+			name:   "list price",
+			course: course,
+			want:   models.PriceQuote{CourseID: 3, ListPrice: 100, Amount: 100},
+		},
+		{
+			name:   "running sale",
+			course: onSale,
+			want:   models.PriceQuote{CourseID: 3, ListPrice: 100, SaleDiscount: 20, Amount: 80},
+		},
+		{
+			name:   "ended sale",
+			course: saleOver,
+			want:   models.PriceQuote{CourseID: 3, ListPrice: 100, Amount: 100},
+		},
+		{
+			name:   "percent coupon",
+			course: course,
+			code:   "SAVE15",
+			want:   models.PriceQuote{CourseID: 3, ListPrice: 100, CouponCode: "SAVE15", CouponDiscount: 15, Amount: 85},
+		},
+		{
+			name:   "code is matched regardless of case",
+			course: course,
+			code:   "  save15 ",
+			want:   models.PriceQuote{CourseID: 3, ListPrice: 100, CouponCode: "SAVE15", CouponDiscount: 15, Amount: 85},
+		},
+		{
+			name:   "coupon applies to the sale price",
+			course: onSale,
+			code:   "SAVE15",
+			want:   models.PriceQuote{CourseID: 3, ListPrice: 100, SaleDiscount: 20, CouponCode: "SAVE15", CouponDiscount: 12, Amount: 68},
+		},
+		{
+			name:   "fixed coupon",
+			course: onSale,
+			code:   "MINUS30",
+			want:   models.PriceQuote{CourseID: 3, ListPrice: 100, SaleDiscount: 20, CouponCode: "MINUS30", CouponDiscount: 30, Amount: 50},
+		},
+		{
+			name:   "discount never exceeds the price",
+			course: course,
+			code:   "HUGE",
+			want:   models.PriceQuote{CourseID: 3, ListPrice: 100, CouponCode: "HUGE", CouponDiscount: 100, Amount: 0},
+		},
+		{name: "unknown coupon", course: course, code: "NOPE", err: repositories.ErrUnknownCoupon},
+		{name: "inactive coupon", course: course, code: "OFF", err: repositories.ErrCouponInactive},
+		{name: "expired coupon", course: course, code: "EXPIRED", err: repositories.ErrCouponInactive},
+		{name: "coupon for another course", course: course, code: "OTHER", err: repositories.ErrCouponNotApplicable},
+		{name: "lookup failure", repo: fakeCouponRepository{err: lookupErr}, course: course, code: "SAVE15", err: lookupErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.repo
+			if r == nil {
+				r = repo
+			}
+
+			quote, err := quoteCoursePrice(r, &tt.course, tt.code, now)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := *quote
+			if (got.Coupon != nil) != (tt.want.CouponCode != "") {
+				t.Errorf("Coupon = %v, want one only when a code applies", got.Coupon)
+			}
+			got.Coupon = nil
+			if got != tt.want {
+				t.Errorf("quote = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,76 +3,303 @@ package services
 import (
 	"errors"
 	"fmt"
-	"math"
-	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/rbac"
 	"github.com/kin-ark/GroAcademy/internal/repositories"
+	"github.com/kin-ark/GroAcademy/internal/storage"
+	"gorm.io/gorm"
 )
 
 type CourseService interface {
-	CreateCourse(*gin.Context, models.CourseFormInput) (*models.Course, error)
-	EditCourse(c *gin.Context, id uint, input models.CourseFormInput) (*models.Course, error)
-	GetAllCourses(query models.SearchQuery) ([]models.CourseWithModulesCount, models.PaginationResponse, error)
-	GetCourseByID(id uint) (*models.Course, error)
+	CreateCourse(c *gin.Context, input models.CourseFormInput, user models.User) (*models.Course, error)
+	EditCourse(c *gin.Context, id uint, input models.CourseFormInput, user models.User) (*models.Course, error)
+	GetAllCourses(query models.CourseSearchQuery, user models.User) ([]models.CourseWithModulesCount, models.PaginationResponse, *models.CourseFacets, error)
+	GetCourseByID(id uint, user models.User) (*models.Course, error)
 	BuildCourseResponses(courses []models.CourseWithModulesCount) []models.CourseResponse
 	GetModulesByCourse(id uint) ([]models.Module, int64, error)
 	DeleteCourseByID(id uint) error
-	BuyCourse(id uint, user *models.User) (*models.BuyCourseResponse, error)
+	QuoteCoursePrice(id uint, couponCode string) (*models.PriceQuote, error)
+	BuyCourse(id uint, user *models.User, couponCode string) (*models.BuyCourseResponse, error)
 	GetCoursesByUser(user *models.User, query models.SearchQuery) ([]models.MyCoursesResponse, models.PaginationResponse, error)
 	HasPurchasedCourse(uint, uint) (bool, error)
 	GetPurchaseStatusForCourses(courseIDs []uint, userID uint) (map[uint]bool, error)
+	GetRefundEligibility(courseID uint, user *models.User) (*models.RefundEligibility, error)
+	GetLatestRefund(courseID uint, userID uint) (*models.Refund, error)
+	RefundCourse(courseID uint, user *models.User, reason string) (*models.RefundResponse, error)
+	RefundPurchase(purchaseID uint, admin *models.User, reason string) (*models.RefundResponse, error)
+	GetCourseInstructors(courseID uint) ([]models.InstructorResponse, error)
+	GetInstructorDashboard(user models.User) ([]models.InstructorCourseStats, error)
+	GetPrerequisites(courseID uint, user models.User) ([]models.PrerequisiteResponse, error)
+	SetPrerequisites(courseID uint, courseIDs []uint, user models.User) ([]models.PrerequisiteResponse, error)
+	GetAssistants(courseID uint, user models.User) ([]models.InstructorResponse, error)
+	SetAssistants(courseID uint, userIDs []uint, user models.User) ([]models.InstructorResponse, error)
+	SetCourseStatus(id uint, input models.PublishStatusInput, user models.User) (*models.Course, error)
+	GetCourseRevisions(id uint, user models.User) ([]models.CourseRevisionResponse, error)
+	GetCourseRevision(id uint, number int, against int, user models.User) (*models.CourseRevisionDetail, error)
+	RestoreCourseRevision(id uint, number int, user models.User) (*models.Course, error)
+}
+
+var (
+	ErrNotCourseInstructor = errors.New("only instructors of this course can manage it")
+	ErrPrerequisitesNotMet = errors.New("complete the prerequisite courses first")
+	ErrInvalidPrerequisite = errors.New("invalid prerequisite")
+	ErrInvalidAssistant    = errors.New("invalid teaching assistant")
+	ErrCourseNotPublished  = errors.New("course is not available for purchase")
+	ErrInvalidPriceRange   = errors.New("max_price must not be below min_price")
+	ErrInvalidSale         = errors.New("invalid sale")
+)
+
+// authorizeCourseManagement allows users who may manage any course, and
+// otherwise only the course's own instructors.
+func authorizeCourseManagement(repo repositories.CourseRepository, user models.User, courseID uint) error {
+	if rbac.HasPermission(user.Role, rbac.PermCourseManageAny) {
+		return nil
+	}
+
+	isInstructor, err := repo.IsCourseInstructor(courseID, user.ID)
+	if err != nil {
+		return err
+	}
+	if !isInstructor {
+		return ErrNotCourseInstructor
+	}
+
+	return nil
+}
+
+// authorizeCourseStaff extends authorizeCourseManagement to the course's
+// teaching assistants, as long as their role grants perm.
+func authorizeCourseStaff(repo repositories.CourseRepository, user models.User, courseID uint, perm rbac.Permission) error {
+	err := authorizeCourseManagement(repo, user, courseID)
+	if !errors.Is(err, ErrNotCourseInstructor) || !rbac.HasPermission(user.Role, perm) {
+		return err
+	}
+
+	isAssistant, err := repo.IsCourseAssistant(courseID, user.ID)
+	if err != nil {
+		return err
+	}
+	if !isAssistant {
+		return ErrNotCourseInstructor
+	}
+
+	return nil
+}
+
+// canPreviewCourse reports whether the user may see the course's content and
+// unpublished modules without buying it, which only those who may manage the
+// course can.
+func canPreviewCourse(repo repositories.CourseRepository, user models.User, courseID uint) (bool, error) {
+	err := authorizeCourseManagement(repo, user, courseID)
+	if errors.Is(err, ErrNotCourseInstructor) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// requirePrerequisites fails with ErrPrerequisitesNotMet, naming the missing
+// courses, unless the user holds a certificate for every prerequisite.
+func requirePrerequisites(repo repositories.CourseRepository, courseID uint, userID uint) error {
+	prerequisites, err := repo.FindPrerequisites(courseID, userID)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, p := range prerequisites {
+		if !p.Completed {
+			missing = append(missing, p.Title)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrPrerequisitesNotMet, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// requireCourseVisible reports unpublished courses as not found, except to
+// users who can preview the course and to users who already bought it, so an
+// archived course stays open to its students.
+func requireCourseVisible(repo repositories.CourseRepository, course *models.Course, user models.User) error {
+	if course.Status == models.StatusPublished {
+		return nil
+	}
+
+	canPreview, err := canPreviewCourse(repo, user, course.ID)
+	if err != nil || canPreview {
+		return err
+	}
+
+	purchased, err := repo.HasPurchasedCourse(course.ID, user.ID)
+	if err != nil {
+		return err
+	}
+	if !purchased {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
 type courseService struct {
-	courseRepo repositories.CourseRepository
+	courseRepo   repositories.CourseRepository
+	jobRepo      repositories.JobRepository
+	revisionRepo repositories.RevisionRepository
+	couponRepo   repositories.CouponRepository
+	refundPolicy RefundPolicy
+	store        storage.Store
 }
 
-func NewCourseService(r repositories.CourseRepository) CourseService {
-	return &courseService{courseRepo: r}
+func NewCourseService(r repositories.CourseRepository, jr repositories.JobRepository, rr repositories.RevisionRepository, cpr repositories.CouponRepository, p RefundPolicy, st storage.Store) CourseService {
+	return &courseService{courseRepo: r, jobRepo: jr, revisionRepo: rr, couponRepo: cpr, refundPolicy: p, store: st}
 }
 
-func (s *courseService) CreateCourse(c *gin.Context, input models.CourseFormInput) (*models.Course, error) {
-	course := models.Course{Title: input.Title, Description: input.Description, Instructor: input.Instructor, Topics: input.Topics, Price: input.Price}
+// validateSale checks that a sale undercuts the list price and ends after it
+// starts.
+func validateSale(input models.CourseFormInput) error {
+	if input.SalePrice == nil {
+		if input.SaleStartsAt != nil || input.SaleEndsAt != nil {
+			return fmt.Errorf("%w: sale dates need a sale_price", ErrInvalidSale)
+		}
+		return nil
+	}
+	if *input.SalePrice >= input.Price {
+		return fmt.Errorf("%w: sale_price must be below price", ErrInvalidSale)
+	}
+	if input.SaleStartsAt != nil && input.SaleEndsAt != nil && !input.SaleEndsAt.After(*input.SaleStartsAt) {
+		return fmt.Errorf("%w: sale_ends_at must be after sale_starts_at", ErrInvalidSale)
+	}
+	return nil
+}
+
+func (s *courseService) CreateCourse(c *gin.Context, input models.CourseFormInput, user models.User) (*models.Course, error) {
+	instructors, err := s.resolveInstructors(input.InstructorIDs, user)
+	if err != nil {
+		return nil, err
+	}
+
+	instructorName := instructorDisplayName(input.Instructor, instructors)
+	if instructorName == "" {
+		return nil, errors.New("instructor or instructor_ids is required")
+	}
+
+	if err := validateSale(input); err != nil {
+		return nil, err
+	}
+
+	status := input.Status
+	if status == "" {
+		status = models.StatusDraft
+	}
+	publishAt, err := resolvePublishStatus(status, input.PublishAt)
+	if err != nil {
+		return nil, err
+	}
+
+	course := models.Course{Title: input.Title, Description: input.Description, Instructor: instructorName, Topics: input.Topics, Price: input.Price, SequentialModules: input.SequentialModules, CertificatePolicy: input.CertificatePolicy, Status: status, PublishAt: publishAt}
+	if course.CertificatePolicy == "" {
+		course.CertificatePolicy = models.CertificatePolicyGrandfather
+	}
+	course.SalePrice = input.SalePrice
+	course.SaleStartsAt = input.SaleStartsAt
+	course.SaleEndsAt = input.SaleEndsAt
 	if input.ThumbnailImage != nil {
-		path := "uploads/thumbnails/" + input.ThumbnailImage.Filename
-		err := c.SaveUploadedFile(input.ThumbnailImage, path)
+		key, err := storage.SaveUpload(s.store, "thumbnail_image", "thumbnails", storage.KindImage, input.ThumbnailImage)
 		if err != nil {
 			return nil, err
 		}
+		course.ThumbnailImage = key
+	}
+
+	if err := s.courseRepo.Create(&course, instructors); err != nil {
+		storage.Remove(s.store, course.ThumbnailImage)
+		return nil, err
+	}
+	schedulePublish(s.jobRepo, PublishKindCourse, course.ID, course.PublishAt)
+	s.recordCourseRevision(&course, &user.ID, "")
+
+	return s.withURLs(&course), nil
+}
+
+// withURLs swaps the stored thumbnail key for a URL clients can load.
+func (s *courseService) withURLs(course *models.Course) *models.Course {
+	course.ThumbnailImage = storage.URL(s.store, course.ThumbnailImage)
+	return course
+}
 
-		baseUrl := os.Getenv("BASE_URL")
-		course.ThumbnailImage = baseUrl + path
+// resolveInstructors loads the requested instructor accounts. Users who can
+// only manage their own courses are always kept on the list so they cannot
+// lock themselves out.
+func (s *courseService) resolveInstructors(ids []uint, actor models.User) ([]models.User, error) {
+	if !rbac.HasPermission(actor.Role, rbac.PermCourseManageAny) && !slices.Contains(ids, actor.ID) {
+		ids = append(ids, actor.ID)
 	}
 
-	if err := s.courseRepo.Create(&course); err != nil {
+	instructors, err := s.courseRepo.FindUsersByIDs(ids)
+	if err != nil {
 		return nil, err
 	}
 
-	return &course, nil
+	found := make(map[uint]bool, len(instructors))
+	for _, u := range instructors {
+		if !rbac.HasPermission(u.Role, rbac.PermCourseEdit) {
+			return nil, fmt.Errorf("user %d cannot be an instructor", u.ID)
+		}
+		found[u.ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return nil, fmt.Errorf("instructor not found: %d", id)
+		}
+	}
+
+	return instructors, nil
+}
+
+func instructorDisplayName(name string, instructors []models.User) string {
+	if strings.TrimSpace(name) != "" || len(instructors) == 0 {
+		return strings.TrimSpace(name)
+	}
+
+	names := make([]string, 0, len(instructors))
+	for _, u := range instructors {
+		names = append(names, strings.TrimSpace(u.FirstName+" "+u.LastName))
+	}
+	return strings.Join(names, ", ")
 }
 
-func (s *courseService) GetAllCourses(query models.SearchQuery) ([]models.CourseWithModulesCount, models.PaginationResponse, error) {
+// GetAllCourses searches the catalog and counts the facets of the matching
+// courses alongside the requested page.
+func (s *courseService) GetAllCourses(query models.CourseSearchQuery, user models.User) ([]models.CourseWithModulesCount, models.PaginationResponse, *models.CourseFacets, error) {
 	query.Normalize()
+	if !rbac.HasPermission(user.Role, rbac.PermCourseManageAny) {
+		query.TaughtBy = &user.ID
+	}
+	if query.MaxPrice > 0 && query.MaxPrice < query.MinPrice {
+		return nil, models.PaginationResponse{}, nil, ErrInvalidPriceRange
+	}
 
 	courses, totalItems, err := s.courseRepo.GetAllCourses(query)
 	if err != nil {
-		return nil, models.PaginationResponse{}, err
+		return nil, models.PaginationResponse{}, nil, err
 	}
 
-	totalPages := int(math.Ceil(float64(totalItems) / float64(query.Limit)))
-	if query.Page > totalPages && totalPages > 0 {
-		query.Page = totalPages
+	facets, err := s.courseRepo.GetCourseFacets(query)
+	if err != nil {
+		return nil, models.PaginationResponse{}, nil, err
 	}
 
-	pagination := models.PaginationResponse{
-		CurrentPage: query.Page,
-		TotalPages:  totalPages,
-		TotalItems:  int(totalItems),
+	for i := range courses {
+		s.withURLs(&courses[i].Course)
 	}
 
-	return courses, pagination, nil
+	pagination := query.Paginate(totalItems)
+
+	return courses, pagination, facets, nil
 }
 
 func (s *courseService) BuildCourseResponses(courses []models.CourseWithModulesCount) []models.CourseResponse {
@@ -90,58 +317,123 @@ func (s *courseService) BuildCourseResponses(courses []models.CourseWithModulesC
 			TotalModules:   int(c.ModulesCount),
 			CreatedAt:      c.Course.CreatedAt,
 			UpdatedAt:      c.Course.UpdatedAt,
+
+			SequentialModules: c.Course.SequentialModules,
+			SalePrice:         c.Course.SalePrice,
+			SaleStartsAt:      c.Course.SaleStartsAt,
+			SaleEndsAt:        c.Course.SaleEndsAt,
+			CurrentPrice:      c.Course.CurrentPrice(),
+			CertificatePolicy: c.Course.CertificatePolicy,
+			Status:            c.Course.Status,
+			PublishAt:         c.Course.PublishAt,
+			RatingAverage:     c.Course.RatingAverage,
+			RatingCount:       c.Course.RatingCount,
 		})
 	}
 
 	return responses
 }
 
-func (s *courseService) GetCourseByID(id uint) (*models.Course, error) {
-	return s.courseRepo.FindById(id)
+func (s *courseService) GetCourseByID(id uint, user models.User) (*models.Course, error) {
+	course, err := s.courseRepo.FindById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireCourseVisible(s.courseRepo, course, user); err != nil {
+		return nil, err
+	}
+
+	return s.withURLs(course), nil
 }
 
 func (s *courseService) GetModulesByCourse(id uint) ([]models.Module, int64, error) {
 	return s.courseRepo.FindModulesByCourseID(id)
 }
 
-func (s *courseService) EditCourse(c *gin.Context, id uint, input models.CourseFormInput) (*models.Course, error) {
+func (s *courseService) EditCourse(c *gin.Context, id uint, input models.CourseFormInput, user models.User) (*models.Course, error) {
 	existing, err := s.courseRepo.FindById(id)
 	if err != nil {
 		return nil, err
 	}
 
-	if existing.ThumbnailImage != "" {
-		_ = os.Remove(existing.ThumbnailImage)
+	if err := authorizeCourseManagement(s.courseRepo, user, id); err != nil {
+		return nil, err
+	}
+
+	var instructors []models.User
+	if len(input.InstructorIDs) > 0 {
+		instructors, err = s.resolveInstructors(input.InstructorIDs, user)
+	} else {
+		instructors, err = s.courseRepo.FindInstructors(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	instructorName := instructorDisplayName(input.Instructor, instructors)
+	if instructorName == "" {
+		return nil, errors.New("instructor or instructor_ids is required")
+	}
+
+	if err := validateSale(input); err != nil {
+		return nil, err
 	}
 
+	s.ensureCourseBaseline(existing)
+
+	oldThumbnail := existing.ThumbnailImage
+
 	if input.ThumbnailImage != nil {
-		path := "uploads/thumbnails/" + input.ThumbnailImage.Filename
-		if err := c.SaveUploadedFile(input.ThumbnailImage, path); err != nil {
+		key, err := storage.SaveUpload(s.store, "thumbnail_image", "thumbnails", storage.KindImage, input.ThumbnailImage)
+		if err != nil {
 			return nil, err
 		}
-
-		baseUrl := os.Getenv("BASE_URL")
-		existing.ThumbnailImage = baseUrl + path
+		existing.ThumbnailImage = key
 	} else {
 		existing.ThumbnailImage = ""
 	}
 
 	existing.Title = input.Title
 	existing.Description = input.Description
-	existing.Instructor = input.Instructor
+	existing.Instructor = instructorName
 	existing.Topics = input.Topics
 	existing.Price = input.Price
+	existing.SequentialModules = input.SequentialModules
+	existing.SalePrice = input.SalePrice
+	existing.SaleStartsAt = input.SaleStartsAt
+	existing.SaleEndsAt = input.SaleEndsAt
+	policyChanged := input.CertificatePolicy != "" && input.CertificatePolicy != existing.CertificatePolicy
+	if input.CertificatePolicy != "" {
+		existing.CertificatePolicy = input.CertificatePolicy
+	}
 
 	if err := s.courseRepo.Update(existing); err != nil {
+		if existing.ThumbnailImage != oldThumbnail {
+			storage.Remove(s.store, existing.ThumbnailImage)
+		}
 		return nil, err
 	}
 
+	// The replaced thumbnail stays in storage for the revision history and
+	// is removed together with the course.
+	s.recordCourseRevision(existing, &user.ID, "")
+	if policyChanged {
+		queueCertificateRecompute(s.jobRepo, id)
+	}
+
+	if len(input.InstructorIDs) > 0 {
+		if err := s.courseRepo.SetInstructors(existing, instructors); err != nil {
+			return nil, err
+		}
+	}
+
 	updated, err := s.courseRepo.FindById(id)
 	if err != nil {
 		return nil, err
 	}
 
-	return updated, nil
+	return s.withURLs(updated), nil
 }
 
 func (s *courseService) DeleteCourseByID(id uint) error {
@@ -150,49 +442,113 @@ func (s *courseService) DeleteCourseByID(id uint) error {
 		return err
 	}
 
-	thumbnailImagePath := existing.ThumbnailImage
+	// Modules and certificates are removed by the cascade, so collect their
+	// files before the rows disappear.
+	modules, _, err := s.courseRepo.FindModulesByCourseID(id)
+	if err != nil {
+		return err
+	}
+
+	certificates, err := s.courseRepo.FindCertificatesByCourse(id)
+	if err != nil {
+		return err
+	}
+
+	courseRevisions, err := s.revisionRepo.FindCourseRevisions(id)
+	if err != nil {
+		return err
+	}
+	moduleRevisions, err := s.revisionRepo.FindModuleRevisionsByCourse(id)
+	if err != nil {
+		return err
+	}
 
 	if err := s.courseRepo.Delete(existing); err != nil {
 		return err
 	}
 
-	if thumbnailImagePath != "" {
-		_ = os.Remove(existing.ThumbnailImage)
+	storage.Remove(s.store, existing.ThumbnailImage)
+	for _, m := range modules {
+		storage.Remove(s.store, m.PDFContent)
+		storage.Remove(s.store, m.VideoContent)
+		storage.RemovePrefix(s.store, hlsPrefix(m.VideoHLS))
+		storage.RemovePrefix(s.store, submissionPrefix(m.ID))
+	}
+	for _, cert := range certificates {
+		storage.Remove(s.store, cert.FileKey)
+		storage.Remove(s.store, cert.PDFKey)
+	}
+	for _, rev := range courseRevisions {
+		storage.Remove(s.store, rev.ThumbnailImage)
+	}
+	for _, rev := range moduleRevisions {
+		storage.Remove(s.store, rev.PDFContent)
+		storage.Remove(s.store, rev.VideoContent)
 	}
 
 	return nil
 }
 
-func (s *courseService) BuyCourse(id uint, user *models.User) (*models.BuyCourseResponse, error) {
-	purchased, err := s.courseRepo.HasPurchasedCourse(id, user.ID)
+// QuoteCoursePrice returns what buying the course with the coupon would cost.
+func (s *courseService) QuoteCoursePrice(id uint, couponCode string) (*models.PriceQuote, error) {
+	course, err := s.courseRepo.FindById(id)
 	if err != nil {
 		return nil, err
 	}
 
-	if purchased {
-		return nil, errors.New(user.Username + "already purchased course: " + fmt.Sprint(id))
-	} else {
-		course, err := s.courseRepo.FindById(id)
-		if err != nil {
-			return nil, err
-		}
+	if course.Status != models.StatusPublished {
+		return nil, ErrCourseNotPublished
+	}
 
-		if course.Price > user.Balance {
-			return nil, errors.New(user.Username + " balance is not enough to buy this course: " + fmt.Sprint(id))
-		}
+	return quoteCoursePrice(s.couponRepo, course, couponCode, time.Now())
+}
 
-		transaction, err := s.courseRepo.BuyCourse(user, course)
-		if err != nil {
-			return nil, err
-		}
+func (s *courseService) BuyCourse(id uint, user *models.User, couponCode string) (*models.BuyCourseResponse, error) {
+	course, err := s.courseRepo.FindById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if course.Status != models.StatusPublished {
+		return nil, ErrCourseNotPublished
+	}
+
+	if err := requirePrerequisites(s.courseRepo, id, user.ID); err != nil {
+		return nil, err
+	}
 
-		res := models.BuyCourseResponse{
-			TransactionID: transaction.ID,
-			CourseID:      id,
-			UserBalance:   user.Balance,
+	quote, err := quoteCoursePrice(s.couponRepo, course, couponCode, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, err := s.courseRepo.BuyCourse(user, course, *quote)
+	if err != nil {
+		if errors.Is(err, repositories.ErrAlreadyPurchased) {
+			return nil, errors.New(user.Username + " already purchased course: " + fmt.Sprint(id))
+		}
+		if errors.Is(err, repositories.ErrInsufficientBalance) {
+			return nil, errors.New(user.Username + " balance is not enough to buy this course: " + fmt.Sprint(id))
 		}
-		return &res, nil
+		return nil, err
 	}
+
+	// The purchase holds the price actually charged, which differs from the
+	// quote if the coupon changed in the meantime.
+	res := models.BuyCourseResponse{
+		TransactionID: transaction.ID,
+		CourseID:      id,
+		UserBalance:   user.Balance,
+		Price: models.PriceQuote{
+			CourseID:       id,
+			ListPrice:      transaction.ListPrice,
+			SaleDiscount:   transaction.SaleDiscount,
+			CouponCode:     transaction.CouponCode,
+			CouponDiscount: transaction.CouponDiscount,
+			Amount:         transaction.Amount,
+		},
+	}
+	return &res, nil
 }
 
 func (s *courseService) GetCoursesByUser(user *models.User, query models.SearchQuery) ([]models.MyCoursesResponse, models.PaginationResponse, error) {
@@ -203,19 +559,11 @@ func (s *courseService) GetCoursesByUser(user *models.User, query models.SearchQ
 		return nil, models.PaginationResponse{}, err
 	}
 
-	totalPages := int(math.Ceil(float64(totalItems) / float64(query.Limit)))
-	if totalPages == 0 {
-		totalPages = 1
-	}
-	if query.Page > totalPages {
-		query.Page = totalPages
+	for i := range courses {
+		s.withURLs(&courses[i].Course)
 	}
 
-	pagination := models.PaginationResponse{
-		CurrentPage: query.Page,
-		TotalPages:  totalPages,
-		TotalItems:  int(totalItems),
-	}
+	pagination := query.Paginate(totalItems)
 
 	return courses, pagination, nil
 }
@@ -240,3 +588,309 @@ func (s *courseService) GetPurchaseStatusForCourses(courseIDs []uint, userID uin
 
 	return statusMap, nil
 }
+
+func (s *courseService) GetRefundEligibility(courseID uint, user *models.User) (*models.RefundEligibility, error) {
+	purchase, err := s.courseRepo.FindPurchase(user.ID, courseID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrPurchaseNotFound) {
+			return &models.RefundEligibility{Eligible: false, Reason: "course has not been purchased"}, nil
+		}
+		return nil, err
+	}
+
+	return s.evaluateRefund(s.courseRepo, purchase)
+}
+
+// evaluateRefund checks the refund policy for the purchase, reading the
+// refund history and progress through repo.
+func (s *courseService) evaluateRefund(repo repositories.CourseRepository, purchase *models.Purchase) (*models.RefundEligibility, error) {
+	if purchase.RefundedAt != nil {
+		return &models.RefundEligibility{
+			Eligible: false,
+			Reason:   "purchase has already been refunded",
+			Deadline: purchase.CreatedAt.Add(s.refundPolicy.Window),
+		}, nil
+	}
+
+	// A refund resets the course progress, so without this a course could be
+	// bought, finished, refunded and bought again indefinitely.
+	previous, err := repo.FindLatestRefund(purchase.UserID, purchase.CourseID)
+	if err != nil {
+		return nil, err
+	}
+	if previous != nil {
+		return &models.RefundEligibility{
+			Eligible: false,
+			Reason:   "course has already been refunded once",
+			Deadline: purchase.CreatedAt.Add(s.refundPolicy.Window),
+		}, nil
+	}
+
+	progress, err := repo.GetCourseProgress(purchase.CourseID, models.User{ID: purchase.UserID})
+	if err != nil {
+		return nil, err
+	}
+
+	eligibility := s.refundPolicy.Evaluate(purchase, progress, time.Now())
+	return &eligibility, nil
+}
+
+func (s *courseService) GetLatestRefund(courseID uint, userID uint) (*models.Refund, error) {
+	return s.courseRepo.FindLatestRefund(userID, courseID)
+}
+
+func (s *courseService) RefundCourse(courseID uint, user *models.User, reason string) (*models.RefundResponse, error) {
+	purchase, err := s.courseRepo.FindPurchase(user.ID, courseID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrPurchaseNotFound) {
+			return nil, errors.New(user.Username + " has not bought this course!")
+		}
+		return nil, err
+	}
+
+	// Eligibility is checked again on the locked purchase, inside the
+	// refund's transaction, so a concurrent refund or a lapsing window cannot
+	// slip past it.
+	return s.refund(purchase, user, reason, func(repo repositories.CourseRepository, locked *models.Purchase) error {
+		eligibility, err := s.evaluateRefund(repo, locked)
+		if err != nil {
+			return err
+		}
+		if !eligibility.Eligible {
+			return errors.New("refund not allowed: " + eligibility.Reason)
+		}
+		return nil
+	})
+}
+
+func (s *courseService) RefundPurchase(purchaseID uint, admin *models.User, reason string) (*models.RefundResponse, error) {
+	purchase, err := s.courseRepo.FindPurchaseByID(purchaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.refund(purchase, admin, reason, nil)
+}
+
+func (s *courseService) refund(purchase *models.Purchase, actor *models.User, reason string, check func(repositories.CourseRepository, *models.Purchase) error) (*models.RefundResponse, error) {
+	refund := models.Refund{
+		Reason:     reason,
+		RefundedBy: actor.ID,
+	}
+
+	certificate, err := s.courseRepo.FindCourseCertificate(purchase.UserID, purchase.CourseID)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := s.courseRepo.RefundPurchase(purchase, &refund, check)
+	if err != nil {
+		return nil, err
+	}
+
+	// The revoked certificate is rendered again if it is ever restored.
+	if certificate != nil {
+		storage.Remove(s.store, certificate.FileKey)
+		storage.Remove(s.store, certificate.PDFKey)
+	}
+
+	if actor.ID == purchase.UserID {
+		actor.Balance = balance
+	}
+
+	res := models.RefundResponse{
+		RefundID:    refund.ID,
+		PurchaseID:  refund.PurchaseID,
+		CourseID:    refund.CourseID,
+		Amount:      refund.Amount,
+		UserBalance: balance,
+	}
+	return &res, nil
+}
+
+func (s *courseService) GetCourseInstructors(courseID uint) ([]models.InstructorResponse, error) {
+	instructors, err := s.courseRepo.FindInstructors(courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]models.InstructorResponse, 0, len(instructors))
+	for _, u := range instructors {
+		res = append(res, models.InstructorResponse{
+			ID:        u.ID,
+			Username:  u.Username,
+			FirstName: u.FirstName,
+			LastName:  u.LastName,
+		})
+	}
+	return res, nil
+}
+
+func (s *courseService) GetInstructorDashboard(user models.User) ([]models.InstructorCourseStats, error) {
+	return s.courseRepo.GetInstructorCourseStats(user.ID)
+}
+
+func (s *courseService) GetPrerequisites(courseID uint, user models.User) ([]models.PrerequisiteResponse, error) {
+	course, err := s.courseRepo.FindById(courseID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireCourseVisible(s.courseRepo, course, user); err != nil {
+		return nil, err
+	}
+
+	prerequisites, err := s.courseRepo.FindPrerequisites(courseID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if prerequisites == nil {
+		prerequisites = []models.PrerequisiteResponse{}
+	}
+	return prerequisites, nil
+}
+
+func (s *courseService) SetPrerequisites(courseID uint, courseIDs []uint, user models.User) ([]models.PrerequisiteResponse, error) {
+	course, err := s.courseRepo.FindById(courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeCourseManagement(s.courseRepo, user, courseID); err != nil {
+		return nil, err
+	}
+
+	slices.Sort(courseIDs)
+	courseIDs = slices.Compact(courseIDs)
+
+	prerequisites, err := s.courseRepo.FindCoursesByIDs(courseIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(prerequisites) != len(courseIDs) {
+		return nil, fmt.Errorf("%w: course not found", ErrInvalidPrerequisite)
+	}
+
+	if err := s.checkPrerequisiteCycle(courseID, courseIDs); err != nil {
+		return nil, err
+	}
+
+	if err := s.courseRepo.SetPrerequisites(course, prerequisites); err != nil {
+		return nil, err
+	}
+
+	return s.GetPrerequisites(courseID, user)
+}
+
+func (s *courseService) GetAssistants(courseID uint, user models.User) ([]models.InstructorResponse, error) {
+	if _, err := s.courseRepo.FindById(courseID); err != nil {
+		return nil, err
+	}
+
+	if err := authorizeCourseManagement(s.courseRepo, user, courseID); err != nil {
+		return nil, err
+	}
+
+	assistants, err := s.courseRepo.FindAssistants(courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]models.InstructorResponse, 0, len(assistants))
+	for _, u := range assistants {
+		res = append(res, models.InstructorResponse{
+			ID:        u.ID,
+			Username:  u.Username,
+			FirstName: u.FirstName,
+			LastName:  u.LastName,
+		})
+	}
+	return res, nil
+}
+
+// SetAssistants replaces the course's teaching assistants. Only users with
+// the teaching assistant role can be assigned.
+func (s *courseService) SetAssistants(courseID uint, userIDs []uint, user models.User) ([]models.InstructorResponse, error) {
+	course, err := s.courseRepo.FindById(courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeCourseManagement(s.courseRepo, user, courseID); err != nil {
+		return nil, err
+	}
+
+	slices.Sort(userIDs)
+	userIDs = slices.Compact(userIDs)
+
+	assistants, err := s.courseRepo.FindUsersByIDs(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(assistants) != len(userIDs) {
+		return nil, fmt.Errorf("%w: user not found", ErrInvalidAssistant)
+	}
+	for _, u := range assistants {
+		if u.Role != rbac.RoleTeachingAssistant {
+			return nil, fmt.Errorf("%w: user %d is not a teaching assistant", ErrInvalidAssistant, u.ID)
+		}
+	}
+
+	if err := s.courseRepo.SetAssistants(course, assistants); err != nil {
+		return nil, err
+	}
+
+	return s.GetAssistants(courseID, user)
+}
+
+// checkPrerequisiteCycle walks the prerequisite graph from the requested
+// courses and rejects the change if it leads back to courseID.
+func (s *courseService) checkPrerequisiteCycle(courseID uint, courseIDs []uint) error {
+	seen := make(map[uint]bool)
+	frontier := courseIDs
+	for len(frontier) > 0 {
+		var next []uint
+		for _, id := range frontier {
+			if id == courseID {
+				return fmt.Errorf("%w: a course cannot require itself", ErrInvalidPrerequisite)
+			}
+			if !seen[id] {
+				seen[id] = true
+				next = append(next, id)
+			}
+		}
+
+		var err error
+		frontier, err = s.courseRepo.FindPrerequisiteIDs(next)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetCourseStatus changes the course's publication state, scheduling the
+// publish job when a draft gets a publish time.
+func (s *courseService) SetCourseStatus(id uint, input models.PublishStatusInput, user models.User) (*models.Course, error) {
+	course, err := s.courseRepo.FindById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeCourseManagement(s.courseRepo, user, id); err != nil {
+		return nil, err
+	}
+
+	publishAt, err := resolvePublishStatus(input.Status, input.PublishAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.courseRepo.SetStatus(id, input.Status, publishAt); err != nil {
+		return nil, err
+	}
+	schedulePublish(s.jobRepo, PublishKindCourse, id, publishAt)
+
+	course.Status = input.Status
+	course.PublishAt = publishAt
+	return s.withURLs(course), nil
+}
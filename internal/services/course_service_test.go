@@ -0,0 +1,220 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/rbac"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// refundStore keeps purchases and refunds in memory for the refund flow.
+type refundStore struct {
+	repositories.CourseRepository
+	purchases []*models.Purchase
+	refunds   []models.Refund
+	balance   float64
+}
+
+func (r *refundStore) buy(userID, courseID uint, amount float64) {
+	r.purchases = append(r.purchases, &models.Purchase{
+		ID: uint(len(r.purchases) + 1), UserID: userID, CourseID: courseID, Amount: amount, CreatedAt: time.Now(),
+	})
+}
+
+func (r *refundStore) FindPurchase(userID, courseID uint) (*models.Purchase, error) {
+	for _, p := range r.purchases {
+		if p.UserID == userID && p.CourseID == courseID && p.RefundedAt == nil {
+			found := *p
+			return &found, nil
+		}
+	}
+	return nil, repositories.ErrPurchaseNotFound
+}
+
+func (r *refundStore) FindPurchaseByID(id uint) (*models.Purchase, error) {
+	found := *r.purchases[id-1]
+	return &found, nil
+}
+
+func (r *refundStore) FindLatestRefund(userID, courseID uint) (*models.Refund, error) {
+	for i := len(r.refunds) - 1; i >= 0; i-- {
+		if r.refunds[i].UserID == userID && r.refunds[i].CourseID == courseID {
+			return &r.refunds[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *refundStore) GetCourseProgress(courseID uint, user models.User) (*models.CourseProgress, error) {
+	// Refunds delete the progress, so every purchase starts from zero.
+	return &models.CourseProgress{}, nil
+}
+
+func (r *refundStore) FindCourseCertificate(userID, courseID uint) (*models.Certificate, error) {
+	return nil, nil
+}
+
+func (r *refundStore) RefundPurchase(purchase *models.Purchase, refund *models.Refund, check func(repositories.CourseRepository, *models.Purchase) error) (float64, error) {
+	locked := r.purchases[purchase.ID-1]
+	if locked.RefundedAt != nil {
+		return 0, repositories.ErrPurchaseRefunded
+	}
+	if check != nil {
+		if err := check(r, locked); err != nil {
+			return 0, err
+		}
+	}
+
+	now := time.Now()
+	locked.RefundedAt = &now
+	refund.ID = uint(len(r.refunds) + 1)
+	refund.UserID, refund.CourseID, refund.PurchaseID, refund.Amount = locked.UserID, locked.CourseID, locked.ID, locked.Amount
+	r.refunds = append(r.refunds, *refund)
+	r.balance += locked.Amount
+	return r.balance, nil
+}
+
+func TestRefundCourseOnlyOncePerCourse(t *testing.T) {
+	repo := &refundStore{}
+	s := &courseService{courseRepo: repo, refundPolicy: RefundPolicy{Window: 7 * 24 * time.Hour, MaxProgressPercentage: 30}}
+	user := &models.User{ID: 1, Username: "learner"}
+
+	repo.buy(user.ID, 5, 100)
+	if _, err := s.RefundCourse(5, user, "changed my mind"); err != nil {
+		t.Fatalf("first refund: %v", err)
+	}
+
+	repo.buy(user.ID, 5, 100)
+	eligibility, err := s.GetRefundEligibility(5, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eligibility.Eligible || eligibility.Reason != "course has already been refunded once" {
+		t.Errorf("eligibility after rebuying = %+v, want refused as already refunded", eligibility)
+	}
+
+	_, err = s.RefundCourse(5, user, "again")
+	if err == nil || !strings.Contains(err.Error(), "already been refunded once") {
+		t.Fatalf("second refund err = %v, want it refused", err)
+	}
+	if len(repo.refunds) != 1 || repo.balance != 100 {
+		t.Errorf("refunds = %d, balance = %v; want only the first refund paid out", len(repo.refunds), repo.balance)
+	}
+
+	// An admin refund is not bound by the policy.
+	if _, err := s.RefundPurchase(2, &models.User{ID: 99}, "support ticket"); err != nil {
+		t.Errorf("admin refund: %v", err)
+	}
+}
+
+// courseStaff links users to course 1 as instructors or assistants.
+type courseStaff struct {
+	repositories.CourseRepository
+	instructors map[uint]bool
+	assistants  map[uint]bool
+}
+
+func (r *courseStaff) IsCourseInstructor(courseID, userID uint) (bool, error) {
+	return courseID == 1 && r.instructors[userID], nil
+}
+
+func (r *courseStaff) IsCourseAssistant(courseID, userID uint) (bool, error) {
+	return courseID == 1 && r.assistants[userID], nil
+}
+
+func TestAuthorizeCourseStaff(t *testing.T) {
+	repo := &courseStaff{
+		instructors: map[uint]bool{1: true},
+		assistants:  map[uint]bool{2: true, 4: true},
+	}
+
+	tests := []struct {
+		name     string
+		user     models.User
+		courseID uint
+		perm     rbac.Permission
+		allowed  bool
+	}{
+		{"course instructor", models.User{ID: 1, Role: rbac.RoleInstructor}, 1, rbac.PermSubmissionGrade, true},
+		{"admin", models.User{ID: 9, Role: rbac.RoleAdmin}, 1, rbac.PermSubmissionGrade, true},
+		{"assistant grades", models.User{ID: 2, Role: rbac.RoleTeachingAssistant}, 1, rbac.PermSubmissionGrade, true},
+		{"assistant moderates", models.User{ID: 2, Role: rbac.RoleTeachingAssistant}, 1, rbac.PermDiscussionModerate, true},
+		{"assistant of another course", models.User{ID: 2, Role: rbac.RoleTeachingAssistant}, 2, rbac.PermSubmissionGrade, false},
+		{"unassigned assistant", models.User{ID: 3, Role: rbac.RoleTeachingAssistant}, 1, rbac.PermSubmissionGrade, false},
+		{"assistant without the permission", models.User{ID: 2, Role: rbac.RoleTeachingAssistant}, 1, rbac.PermModuleEdit, false},
+		{"assigned user demoted to student", models.User{ID: 4, Role: rbac.RoleStudent}, 1, rbac.PermSubmissionGrade, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := authorizeCourseStaff(repo, tt.user, tt.courseID, tt.perm)
+			if tt.allowed && err != nil {
+				t.Errorf("err = %v, want allowed", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrNotCourseInstructor) {
+				t.Errorf("err = %v, want ErrNotCourseInstructor", err)
+			}
+		})
+	}
+}
+
+// draftCourse is course 1, still a draft, taught by user 1 and bought by
+// user 2 before it was unpublished.
+type draftCourse struct {
+	courseStaff
+}
+
+func (r *draftCourse) FindById(id uint) (*models.Course, error) {
+	return &models.Course{ID: id, Status: models.StatusDraft}, nil
+}
+
+func (r *draftCourse) HasPurchasedCourse(courseID, userID uint) (bool, error) {
+	return courseID == 1 && userID == 2, nil
+}
+
+func (r *draftCourse) FindPrerequisites(courseID, userID uint) ([]models.PrerequisiteResponse, error) {
+	return nil, nil
+}
+
+type noSections struct {
+	repositories.SectionRepository
+}
+
+func (noSections) FindByCourse(courseID uint) ([]models.Section, error) { return nil, nil }
+
+func TestDraftCourseOutlineIsHidden(t *testing.T) {
+	repo := &draftCourse{courseStaff{instructors: map[uint]bool{1: true}}}
+	courses := &courseService{courseRepo: repo}
+	sections := &sectionService{sectionRepo: noSections{}, courseRepo: repo}
+
+	tests := []struct {
+		name    string
+		user    models.User
+		visible bool
+	}{
+		{"instructor", models.User{ID: 1, Role: rbac.RoleInstructor}, true},
+		{"buyer", models.User{ID: 2, Role: rbac.RoleStudent}, true},
+		{"admin", models.User{ID: 9, Role: rbac.RoleAdmin}, true},
+		{"other student", models.User{ID: 3, Role: rbac.RoleStudent}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, sectionsErr := sections.GetSections(1, tt.user)
+			_, prerequisitesErr := courses.GetPrerequisites(1, tt.user)
+			for _, err := range []error{sectionsErr, prerequisitesErr} {
+				if tt.visible && err != nil {
+					t.Errorf("err = %v, want visible", err)
+				}
+				if !tt.visible && !errors.Is(err, gorm.ErrRecordNotFound) {
+					t.Errorf("err = %v, want ErrRecordNotFound", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/rbac"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
+)
+
+var (
+	ErrInvalidDiscussionPost = errors.New("invalid discussion post")
+	ErrNotPostAuthor         = errors.New("only the author can edit this post")
+)
+
+type DiscussionService interface {
+	GetThreads(moduleID uint, q models.DiscussionQuery, user models.User) ([]models.DiscussionPostResponse, models.PaginationResponse, error)
+	GetThread(id uint, q models.PaginationQuery, user models.User) (*models.DiscussionThreadDetail, models.PaginationResponse, error)
+	CreateThread(moduleID uint, input models.DiscussionThreadInput, user models.User) (*models.DiscussionPostResponse, error)
+	Reply(threadID uint, input models.DiscussionReplyInput, user models.User) (*models.DiscussionPostResponse, error)
+	UpdatePost(id uint, input models.DiscussionPostUpdateInput, user models.User) (*models.DiscussionPostResponse, error)
+	DeletePost(id uint, user models.User) error
+	Upvote(id uint, user models.User) (*models.DiscussionPostResponse, error)
+	RemoveUpvote(id uint, user models.User) (*models.DiscussionPostResponse, error)
+	SetAnswer(id uint, isAnswer bool, user models.User) (*models.DiscussionPostResponse, error)
+}
+
+type discussionService struct {
+	discussionRepo repositories.DiscussionRepository
+	moduleRepo     repositories.ModuleRepository
+	courseRepo     repositories.CourseRepository
+	moduleService  ModuleService
+}
+
+func NewDiscussionService(dr repositories.DiscussionRepository, mr repositories.ModuleRepository, cr repositories.CourseRepository, ms ModuleService) DiscussionService {
+	return &discussionService{discussionRepo: dr, moduleRepo: mr, courseRepo: cr, moduleService: ms}
+}
+
+// requireDiscussionAccess opens a module's discussion to the same users as
+// the module itself, buyers once the module is unlocked and users who can
+// preview the course, and to the course's teaching assistants.
+func (s *discussionService) requireDiscussionAccess(moduleID uint, user models.User) (*models.Module, error) {
+	module, err := s.moduleRepo.FindById(moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = authorizeCourseStaff(s.courseRepo, user, module.CourseID, rbac.PermDiscussionModerate)
+	if err == nil {
+		return module, nil
+	}
+	if !errors.Is(err, ErrNotCourseInstructor) {
+		return nil, err
+	}
+
+	hasPurchased, err := s.courseRepo.HasPurchasedCourse(module.CourseID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !hasPurchased {
+		return nil, ErrNoContentAccess
+	}
+
+	if err := s.moduleService.CheckModuleUnlocked(module, user); err != nil {
+		return nil, err
+	}
+	return module, nil
+}
+
+// findAccessiblePost loads a post the user may see.
+func (s *discussionService) findAccessiblePost(id uint, user models.User) (*models.DiscussionPost, *models.Module, error) {
+	post, err := s.discussionRepo.FindPost(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	module, err := s.requireDiscussionAccess(post.ModuleID, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return post, module, nil
+}
+
+func (s *discussionService) GetThreads(moduleID uint, q models.DiscussionQuery, user models.User) ([]models.DiscussionPostResponse, models.PaginationResponse, error) {
+	if _, err := s.requireDiscussionAccess(moduleID, user); err != nil {
+		return nil, models.PaginationResponse{}, err
+	}
+
+	q.Normalize()
+	threads, totalItems, err := s.discussionRepo.ListThreads(moduleID, q, user.ID)
+	if err != nil {
+		return nil, models.PaginationResponse{}, err
+	}
+
+	return threads, q.Paginate(totalItems), nil
+}
+
+func (s *discussionService) GetThread(id uint, q models.PaginationQuery, user models.User) (*models.DiscussionThreadDetail, models.PaginationResponse, error) {
+	post, _, err := s.findAccessiblePost(id, user)
+	if err != nil {
+		return nil, models.PaginationResponse{}, err
+	}
+	if post.ParentID != nil {
+		return nil, models.PaginationResponse{}, fmt.Errorf("%w: post %d is a reply", ErrInvalidDiscussionPost, id)
+	}
+
+	thread, err := s.discussionRepo.FindPostResponse(id, user.ID)
+	if err != nil {
+		return nil, models.PaginationResponse{}, err
+	}
+
+	q.Normalize()
+	replies, totalItems, err := s.discussionRepo.ListReplies(id, q, user.ID)
+	if err != nil {
+		return nil, models.PaginationResponse{}, err
+	}
+	if replies == nil {
+		replies = []models.DiscussionPostResponse{}
+	}
+
+	return &models.DiscussionThreadDetail{
+		DiscussionPostResponse: *thread,
+		Replies:                replies,
+	}, q.Paginate(totalItems), nil
+}
+
+func (s *discussionService) CreateThread(moduleID uint, input models.DiscussionThreadInput, user models.User) (*models.DiscussionPostResponse, error) {
+	if _, err := s.requireDiscussionAccess(moduleID, user); err != nil {
+		return nil, err
+	}
+
+	title := strings.TrimSpace(input.Title)
+	body := strings.TrimSpace(input.Body)
+	if title == "" || body == "" {
+		return nil, fmt.Errorf("%w: title and body are required", ErrInvalidDiscussionPost)
+	}
+
+	post := models.DiscussionPost{
+		ModuleID: moduleID,
+		UserID:   user.ID,
+		Title:    title,
+		Body:     body,
+	}
+	if err := s.discussionRepo.CreateThread(&post); err != nil {
+		return nil, err
+	}
+	return s.discussionRepo.FindPostResponse(post.ID, user.ID)
+}
+
+// Reply answers a thread. Replying to a reply adds to the same thread.
+func (s *discussionService) Reply(threadID uint, input models.DiscussionReplyInput, user models.User) (*models.DiscussionPostResponse, error) {
+	post, _, err := s.findAccessiblePost(threadID, user)
+	if err != nil {
+		return nil, err
+	}
+	if post.ParentID != nil {
+		threadID = *post.ParentID
+	}
+
+	body := strings.TrimSpace(input.Body)
+	if body == "" {
+		return nil, fmt.Errorf("%w: body is required", ErrInvalidDiscussionPost)
+	}
+
+	reply := models.DiscussionPost{
+		ModuleID: post.ModuleID,
+		UserID:   user.ID,
+		ParentID: &threadID,
+		Body:     body,
+	}
+	if err := s.discussionRepo.CreateReply(&reply); err != nil {
+		return nil, err
+	}
+	return s.discussionRepo.FindPostResponse(reply.ID, user.ID)
+}
+
+func (s *discussionService) UpdatePost(id uint, input models.DiscussionPostUpdateInput, user models.User) (*models.DiscussionPostResponse, error) {
+	post, _, err := s.findAccessiblePost(id, user)
+	if err != nil {
+		return nil, err
+	}
+	if post.UserID != user.ID {
+		return nil, ErrNotPostAuthor
+	}
+
+	body := strings.TrimSpace(input.Body)
+	if body == "" {
+		return nil, fmt.Errorf("%w: body is required", ErrInvalidDiscussionPost)
+	}
+	if post.ParentID == nil {
+		if title := strings.TrimSpace(input.Title); title != "" {
+			post.Title = title
+		}
+	}
+
+	now := time.Now()
+	post.Body = body
+	post.EditedAt = &now
+	if err := s.discussionRepo.UpdatePost(post); err != nil {
+		return nil, err
+	}
+	return s.discussionRepo.FindPostResponse(id, user.ID)
+}
+
+// DeletePost lets authors remove their own posts and course instructors and
+// assistants remove any post in their course.
+func (s *discussionService) DeletePost(id uint, user models.User) error {
+	post, module, err := s.findAccessiblePost(id, user)
+	if err != nil {
+		return err
+	}
+
+	if post.UserID != user.ID {
+		if err := authorizeCourseStaff(s.courseRepo, user, module.CourseID, rbac.PermDiscussionModerate); err != nil {
+			return err
+		}
+	}
+
+	return s.discussionRepo.DeletePost(post)
+}
+
+func (s *discussionService) Upvote(id uint, user models.User) (*models.DiscussionPostResponse, error) {
+	post, _, err := s.findAccessiblePost(id, user)
+	if err != nil {
+		return nil, err
+	}
+	if post.UserID == user.ID {
+		return nil, fmt.Errorf("%w: you cannot upvote your own post", ErrInvalidDiscussionPost)
+	}
+
+	if err := s.discussionRepo.Upvote(id, user.ID); err != nil {
+		return nil, err
+	}
+	return s.discussionRepo.FindPostResponse(id, user.ID)
+}
+
+func (s *discussionService) RemoveUpvote(id uint, user models.User) (*models.DiscussionPostResponse, error) {
+	if _, _, err := s.findAccessiblePost(id, user); err != nil {
+		return nil, err
+	}
+
+	if err := s.discussionRepo.RemoveUpvote(id, user.ID); err != nil {
+		return nil, err
+	}
+	return s.discussionRepo.FindPostResponse(id, user.ID)
+}
+
+// SetAnswer marks or unmarks a reply as the answer to its thread. Only the
+// course's instructors and assistants can do so.
+func (s *discussionService) SetAnswer(id uint, isAnswer bool, user models.User) (*models.DiscussionPostResponse, error) {
+	post, module, err := s.findAccessiblePost(id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeCourseStaff(s.courseRepo, user, module.CourseID, rbac.PermDiscussionModerate); err != nil {
+		return nil, err
+	}
+	if post.ParentID == nil {
+		return nil, fmt.Errorf("%w: only replies can be marked as the answer", ErrInvalidDiscussionPost)
+	}
+
+	if err := s.discussionRepo.SetAnswer(post, isAnswer); err != nil {
+		return nil, err
+	}
+	return s.discussionRepo.FindPostResponse(id, user.ID)
+}
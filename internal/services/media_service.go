@@ -0,0 +1,92 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/kin-ark/GroAcademy/internal/storage"
+)
+
+type MediaService interface {
+	OpenSigned(key string, expires string, signature string) (storage.Object, error)
+	SignedPlaylist(key string, expires string, signature string) ([]byte, error)
+}
+
+type mediaService struct {
+	store  storage.Store
+	signer *storage.URLSigner
+}
+
+func NewMediaService(st storage.Store, signer *storage.URLSigner) MediaService {
+	return &mediaService{store: st, signer: signer}
+}
+
+const maxPlaylistSize = 1 << 20
+
+// OpenSigned opens the object behind a signed URL issued by the store. Stores
+// that sign their own URLs (such as S3) never route requests here.
+func (s *mediaService) OpenSigned(key string, expires string, signature string) (storage.Object, error) {
+	verifier, ok := s.store.(storage.SignedURLVerifier)
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	if err := verifier.VerifySignedURL(key, expires, signature); err != nil {
+		return nil, err
+	}
+
+	return s.store.Open(key)
+}
+
+// SignedPlaylist loads an HLS playlist and rewrites every URI in it into a
+// signed URL that expires together with the playlist URL. Segment URLs come
+// from the store, so they work whether files are local or on S3.
+func (s *mediaService) SignedPlaylist(key string, expires string, signature string) ([]byte, error) {
+	if path.Ext(key) != ".m3u8" {
+		return nil, storage.ErrNotFound
+	}
+
+	remaining, err := s.signer.Verify(storage.PlaylistRoute, key, expires, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := s.store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	body, err := io.ReadAll(io.LimitReader(rc, maxPlaylistSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxPlaylistSize {
+		return nil, errors.New("playlist too large")
+	}
+
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") && !storage.IsExternal(line) {
+			ref := path.Join(path.Dir(key), line)
+			if path.Ext(ref) == ".m3u8" {
+				line = s.signer.URL(storage.PlaylistRoute, ref, remaining)
+			} else if line, err = s.store.SignedURL(ref, remaining); err != nil {
+				return nil, err
+			}
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
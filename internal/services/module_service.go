@@ -3,120 +3,266 @@ package services
 import (
 	"errors"
 	"fmt"
-	"image/png"
-	"math"
-	"os"
-	"path/filepath"
+	"log"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kin-ark/GroAcademy/internal/models"
 	"github.com/kin-ark/GroAcademy/internal/repositories"
-	"github.com/kin-ark/GroAcademy/internal/utils"
+	"github.com/kin-ark/GroAcademy/internal/storage"
+	"gorm.io/gorm"
 )
 
 type ModuleService interface {
-	CreateModule(*gin.Context, models.ModuleFormInput, uint) (*models.Module, error)
-	EditModule(*gin.Context, models.ModuleFormInput, uint) (*models.Module, error)
-	DeleteModuleByID(uint) error
+	CreateModule(c *gin.Context, input models.ModuleFormInput, courseID uint, user models.User) (*models.Module, error)
+	EditModule(c *gin.Context, input models.ModuleFormInput, id uint, user models.User) (*models.Module, error)
+	DeleteModuleByID(id uint, user models.User) error
 	GetModules(user models.User, courseID uint, q models.PaginationQuery) ([]models.ModuleWithIsCompleted, models.PaginationResponse, error)
 	BuildModuleResponses(modules []models.ModuleWithIsCompleted) []models.ModuleResponse
 	GetModuleByID(id uint, user models.User) (*models.ModuleWithIsCompleted, error)
 	MarkModuleAsComplete(id uint, user models.User) (*models.MarkModuleResponse, error)
-	ReorderModules(req models.ReorderModulesRequest, courseID uint) error
+	ReorderModules(req models.ReorderModulesRequest, courseID uint, user models.User) error
 	GetCourseProgress(id uint, user models.User) (*models.CourseProgress, error)
 	ChangeModuleCompletion(moduleID uint, user models.User, completed bool) error
+	UpdateWatchProgress(id uint, user models.User, input models.WatchProgressInput) (*models.WatchProgressResponse, error)
 	GetCertificateURL(courseID, userID uint) (*string, error)
+	OpenModuleContent(id uint, user models.User, contentType string) (storage.Object, string, error)
+	CheckModuleUnlocked(module *models.Module, user models.User) error
+	SetModuleStatus(id uint, input models.PublishStatusInput, user models.User) (*models.Module, error)
+	GetModuleRevisions(id uint, user models.User) ([]models.ModuleRevisionResponse, error)
+	GetModuleRevision(id uint, number int, against int, user models.User) (*models.ModuleRevisionDetail, error)
+	RestoreModuleRevision(id uint, number int, user models.User) (*models.Module, error)
 }
 
+const (
+	ContentTypePDF   = "pdf"
+	ContentTypeVideo = "video"
+)
+
+var (
+	ErrContentNotFound     = errors.New("module has no content of this type")
+	ErrNoContentAccess     = errors.New("course has not been purchased")
+	ErrQuizNotPassed       = errors.New("the module quiz must be passed to complete this module")
+	ErrAssignmentNotPassed = errors.New("the module assignment must be passed to complete this module")
+	ErrModuleLocked        = errors.New("complete the previous module to unlock this one")
+)
+
 type moduleService struct {
-	moduleRepo repositories.ModuleRepository
-	courseRepo repositories.CourseRepository
+	moduleRepo         repositories.ModuleRepository
+	courseRepo         repositories.CourseRepository
+	jobRepo            repositories.JobRepository
+	quizRepo           repositories.QuizRepository
+	assignmentRepo     repositories.AssignmentRepository
+	sectionRepo        repositories.SectionRepository
+	revisionRepo       repositories.RevisionRepository
+	store              storage.Store
+	signer             *storage.URLSigner
+	certificateService CertificateService
+	watchPolicy        WatchPolicy
 }
 
-func NewModuleService(mr repositories.ModuleRepository, cr repositories.CourseRepository) ModuleService {
-	return &moduleService{moduleRepo: mr, courseRepo: cr}
+func NewModuleService(mr repositories.ModuleRepository, cr repositories.CourseRepository, jr repositories.JobRepository, qr repositories.QuizRepository, ar repositories.AssignmentRepository, sr repositories.SectionRepository, rr repositories.RevisionRepository, st storage.Store, signer *storage.URLSigner, cts CertificateService, wp WatchPolicy) ModuleService {
+	return &moduleService{moduleRepo: mr, courseRepo: cr, jobRepo: jr, quizRepo: qr, assignmentRepo: ar, sectionRepo: sr, revisionRepo: rr, store: st, signer: signer, certificateService: cts, watchPolicy: wp}
 }
 
-func (s *moduleService) CreateModule(c *gin.Context, input models.ModuleFormInput, courseId uint) (*models.Module, error) {
+func (s *moduleService) CreateModule(c *gin.Context, input models.ModuleFormInput, courseId uint, user models.User) (*models.Module, error) {
 	_, err := s.courseRepo.FindById(courseId)
 	if err != nil {
 		return nil, err
 	}
 
-	var pdfPath string
-	var videoPath string
-	baseUrl := os.Getenv("BASE_URL")
-	if input.PDFContent != nil {
-		pdfPath = "uploads/pdf_content/" + input.PDFContent.Filename
-		err := c.SaveUploadedFile(input.PDFContent, pdfPath)
-		if err != nil {
-			return nil, err
-		}
+	if err := authorizeCourseManagement(s.courseRepo, user, courseId); err != nil {
+		return nil, err
 	}
-	if input.VideoContent != nil {
-		videoPath = "uploads/video_content/" + input.VideoContent.Filename
-		err := c.SaveUploadedFile(input.VideoContent, videoPath)
-		if err != nil {
+
+	var sectionID *uint
+	if input.SectionID != nil && *input.SectionID != 0 {
+		if err := s.requireCourseSection(courseId, *input.SectionID); err != nil {
 			return nil, err
 		}
+		sectionID = input.SectionID
+	}
+
+	status := input.Status
+	if status == "" {
+		status = models.StatusPublished
+	}
+	publishAt, err := resolvePublishStatus(status, input.PublishAt)
+	if err != nil {
+		return nil, err
+	}
+
+	pdf, video, err := validateModuleUploads(input)
+	if err != nil {
+		return nil, err
 	}
 
 	module := models.Module{
 		Title:       input.Title,
 		Description: input.Description,
+		SectionID:   sectionID,
+		Status:      status,
+		PublishAt:   publishAt,
 	}
 
-	if pdfPath != "" {
-		module.PDFContent = baseUrl + pdfPath
+	if pdf != nil {
+		if err := pdf.Save(s.store); err != nil {
+			return nil, err
+		}
+		module.PDFContent = pdf.Key
 	}
-
-	if videoPath != "" {
-		module.VideoContent = baseUrl + videoPath
+	if video != nil {
+		if err := video.Save(s.store); err != nil {
+			storage.Remove(s.store, module.PDFContent)
+			return nil, err
+		}
+		module.VideoContent = video.Key
+		module.VideoStatus = models.VideoStatusPending
 	}
 
 	module.CourseID = courseId
 
 	if err := s.moduleRepo.Create(&module); err != nil {
+		storage.Remove(s.store, module.PDFContent)
+		storage.Remove(s.store, module.VideoContent)
 		return nil, err
 	}
 
-	return &module, nil
+	if video != nil {
+		s.queueTranscode(&module)
+	}
+	schedulePublish(s.jobRepo, PublishKindModule, module.ID, module.PublishAt)
+	s.recordModuleRevision(&module, &user.ID, "")
+	if module.Status == models.StatusPublished {
+		queueCertificateRecompute(s.jobRepo, courseId)
+	}
+
+	if sectionID != nil {
+		if err := s.moduleRepo.RenumberModules(courseId); err != nil {
+			return nil, err
+		}
+		created, err := s.moduleRepo.FindById(module.ID)
+		if err != nil {
+			return nil, err
+		}
+		module = *created
+	}
+
+	return s.withURLs(&module), nil
+}
+
+// requireCourseSection checks that the section exists and belongs to the
+// course.
+func (s *moduleService) requireCourseSection(courseID uint, sectionID uint) error {
+	section, err := s.sectionRepo.FindByID(sectionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && section.CourseID != courseID) {
+		return fmt.Errorf("%w: section %d does not belong to this course", ErrInvalidSection, sectionID)
+	}
+	return err
+}
+
+// queueTranscode schedules HLS transcoding for the module's video. The
+// upload itself already succeeded, so a queue failure only means the
+// original file keeps being served.
+func (s *moduleService) queueTranscode(module *models.Module) {
+	if err := enqueueTranscode(s.jobRepo, module.ID, module.VideoContent); err != nil {
+		log.Printf("ERROR: Failed to queue transcoding for module %d: %v", module.ID, err)
+		module.VideoStatus = models.VideoStatusFailed
+		_, _ = s.moduleRepo.SetVideoState(module.ID, module.VideoContent, map[string]any{
+			"video_status": models.VideoStatusFailed,
+			"video_error":  truncate(err.Error(), 500),
+		})
+	}
+}
+
+// validateModuleUploads checks both content files before anything is stored
+// so a bad video does not leave an orphaned PDF behind.
+func validateModuleUploads(input models.ModuleFormInput) (pdf *storage.Upload, video *storage.Upload, err error) {
+	if input.PDFContent != nil {
+		pdf, err = storage.ValidateUpload("pdf_content", "pdf_content", storage.KindPDF, input.PDFContent)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if input.VideoContent != nil {
+		video, err = storage.ValidateUpload("video_content", "video_content", storage.KindVideo, input.VideoContent)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return pdf, video, nil
+}
+
+// withURLs swaps the stored content keys for short-lived URLs clients can
+// embed.
+func (s *moduleService) withURLs(module *models.Module) *models.Module {
+	module.PDFContent = storage.URLWithExpiry(s.store, module.PDFContent, storage.ContentURLExpiry)
+	module.VideoContent = storage.URLWithExpiry(s.store, module.VideoContent, storage.ContentURLExpiry)
+	module.VideoPoster = storage.URLWithExpiry(s.store, module.VideoPoster, storage.ContentURLExpiry)
+	if module.VideoStatus == models.VideoStatusReady && module.VideoHLS != "" {
+		module.VideoHLS = s.signer.URL(storage.PlaylistRoute, module.VideoHLS, storage.ContentURLExpiry)
+	} else {
+		module.VideoHLS = ""
+	}
+	return module
 }
 
-func (s *moduleService) EditModule(c *gin.Context, input models.ModuleFormInput, id uint) (*models.Module, error) {
+func (s *moduleService) EditModule(c *gin.Context, input models.ModuleFormInput, id uint, user models.User) (*models.Module, error) {
 	existing, err := s.moduleRepo.FindById(id)
 	if err != nil {
 		return nil, err
 	}
 
-	if existing.PDFContent != "" {
-		_ = os.Remove(existing.PDFContent)
+	if err := authorizeCourseManagement(s.courseRepo, user, existing.CourseID); err != nil {
+		return nil, err
 	}
 
-	if existing.VideoContent != "" {
-		_ = os.Remove(existing.VideoContent)
+	sectionChanged := false
+	if input.SectionID != nil {
+		var current uint
+		if existing.SectionID != nil {
+			current = *existing.SectionID
+		}
+		if *input.SectionID != current {
+			if *input.SectionID != 0 {
+				if err := s.requireCourseSection(existing.CourseID, *input.SectionID); err != nil {
+					return nil, err
+				}
+				existing.SectionID = input.SectionID
+			} else {
+				existing.SectionID = nil
+			}
+			sectionChanged = true
+		}
 	}
 
-	if input.PDFContent != nil {
-		baseUrl := os.Getenv("BASE_URL")
-		path := "uploads/pdf_content/" + input.PDFContent.Filename
-		if err := c.SaveUploadedFile(input.PDFContent, path); err != nil {
+	pdf, video, err := validateModuleUploads(input)
+	if err != nil {
+		return nil, err
+	}
+
+	s.ensureModuleBaseline(existing)
+
+	oldVideo := existing.VideoContent
+
+	if pdf != nil {
+		if err := pdf.Save(s.store); err != nil {
 			return nil, err
 		}
-		existing.PDFContent = baseUrl + path
+		existing.PDFContent = pdf.Key
 	} else {
 		existing.PDFContent = ""
 	}
 
-	if input.VideoContent != nil {
-		baseUrl := os.Getenv("BASE_URL")
-		path := "uploads/video_content/" + input.VideoContent.Filename
-		if err := c.SaveUploadedFile(input.VideoContent, path); err != nil {
+	if video != nil {
+		if err := video.Save(s.store); err != nil {
+			if pdf != nil {
+				storage.Remove(s.store, pdf.Key)
+			}
 			return nil, err
 		}
-		existing.VideoContent = baseUrl + path
+		existing.VideoContent = video.Key
 	} else {
 		existing.VideoContent = ""
 	}
@@ -125,37 +271,126 @@ func (s *moduleService) EditModule(c *gin.Context, input models.ModuleFormInput,
 	existing.Description = input.Description
 
 	if err := s.moduleRepo.Update(existing); err != nil {
+		if pdf != nil {
+			storage.Remove(s.store, pdf.Key)
+		}
+		if video != nil {
+			storage.Remove(s.store, video.Key)
+		}
 		return nil, err
 	}
 
+	// Replaced files stay in storage for the revision history and are
+	// removed together with the module.
+	s.recordModuleRevision(existing, &user.ID, "")
+
+	if oldVideo != existing.VideoContent {
+		if err := s.resetVideo(existing); err != nil {
+			return nil, err
+		}
+	}
+
+	if sectionChanged {
+		if err := s.moduleRepo.RenumberModules(existing.CourseID); err != nil {
+			return nil, err
+		}
+	}
+
 	updated, err := s.moduleRepo.FindById(id)
 	if err != nil {
 		return nil, err
 	}
 
-	return updated, nil
+	return s.withURLs(updated), nil
+}
+
+// resetVideo discards the transcoding output of the module's previous video
+// and queues its current one for transcoding if it is a stored file.
+func (s *moduleService) resetVideo(module *models.Module) error {
+	storage.RemovePrefix(s.store, hlsPrefix(module.VideoHLS))
+
+	transcodable := module.VideoContent != "" && !storage.IsExternal(module.VideoContent)
+	status := models.VideoStatusNone
+	if transcodable {
+		status = models.VideoStatusPending
+	}
+	if _, err := s.moduleRepo.SetVideoState(module.ID, module.VideoContent, map[string]any{
+		"video_status":   status,
+		"video_hls":      "",
+		"video_poster":   "",
+		"video_duration": 0,
+		"video_error":    "",
+	}); err != nil {
+		return err
+	}
+	module.VideoStatus = status
+
+	if transcodable {
+		s.queueTranscode(module)
+	}
+	return nil
+}
+
+// SetModuleStatus changes the module's publication state, scheduling the
+// publish job when a draft gets a publish time.
+func (s *moduleService) SetModuleStatus(id uint, input models.PublishStatusInput, user models.User) (*models.Module, error) {
+	module, err := s.moduleRepo.FindById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeCourseManagement(s.courseRepo, user, module.CourseID); err != nil {
+		return nil, err
+	}
+
+	publishAt, err := resolvePublishStatus(input.Status, input.PublishAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.moduleRepo.SetStatus(id, input.Status, publishAt); err != nil {
+		return nil, err
+	}
+	schedulePublish(s.jobRepo, PublishKindModule, id, publishAt)
+	if (module.Status == models.StatusPublished) != (input.Status == models.StatusPublished) {
+		queueCertificateRecompute(s.jobRepo, module.CourseID)
+	}
+
+	module.Status = input.Status
+	module.PublishAt = publishAt
+	return s.withURLs(module), nil
 }
 
-func (s *moduleService) DeleteModuleByID(id uint) error {
+func (s *moduleService) DeleteModuleByID(id uint, user models.User) error {
 	existing, err := s.moduleRepo.FindById(id)
 	if err != nil {
 		return err
 	}
 
-	pdfContentPath := existing.PDFContent
-	videoContentPath := existing.VideoContent
+	if err := authorizeCourseManagement(s.courseRepo, user, existing.CourseID); err != nil {
+		return err
+	}
 
-	if err := s.moduleRepo.Delete(existing); err != nil {
+	revisions, err := s.revisionRepo.FindModuleRevisions(id)
+	if err != nil {
 		return err
 	}
 
-	if pdfContentPath != "" {
-		_ = os.Remove(existing.PDFContent)
+	if err := s.moduleRepo.Delete(existing); err != nil {
+		return err
+	}
+	if existing.Status == models.StatusPublished {
+		queueCertificateRecompute(s.jobRepo, existing.CourseID)
 	}
 
-	if videoContentPath != "" {
-		_ = os.Remove(existing.VideoContent)
+	storage.Remove(s.store, existing.PDFContent)
+	storage.Remove(s.store, existing.VideoContent)
+	for _, rev := range revisions {
+		storage.Remove(s.store, rev.PDFContent)
+		storage.Remove(s.store, rev.VideoContent)
 	}
+	storage.RemovePrefix(s.store, hlsPrefix(existing.VideoHLS))
+	storage.RemovePrefix(s.store, submissionPrefix(existing.ID))
 
 	return nil
 }
@@ -165,7 +400,7 @@ func (s *moduleService) GetModules(user models.User, courseID uint, q models.Pag
 	if err != nil {
 		return nil, models.PaginationResponse{}, err
 	}
-	
+
 	q.Normalize()
 	var res []models.ModuleWithIsCompleted
 
@@ -176,11 +411,16 @@ func (s *moduleService) GetModules(user models.User, courseID uint, q models.Pag
 
 	var totalItems int64
 
-	if !hasPurchased && user.Role != "admin" {
+	canPreview, err := canPreviewCourse(s.courseRepo, user, courseID)
+	if err != nil {
+		return nil, models.PaginationResponse{}, err
+	}
+
+	if !hasPurchased && !canPreview {
 		return nil, models.PaginationResponse{}, errors.New(user.Username + " has not bought this course!")
 	}
 
-	if !hasPurchased && user.Role == "admin" {
+	if !hasPurchased && canPreview {
 		modules, count, err := s.courseRepo.FindModulesByCourseIDPaginated(courseID, q)
 		if err != nil {
 			return nil, models.PaginationResponse{}, err
@@ -189,19 +429,32 @@ func (s *moduleService) GetModules(user models.User, courseID uint, q models.Pag
 
 		for _, module := range modules {
 			res = append(res, models.ModuleWithIsCompleted{
-				Module:      module,
+				Module:      *s.withURLs(&module),
 				IsCompleted: false,
 			})
 		}
 	} else {
-		modules, count, err := s.courseRepo.FindModulesWithProgressPaginated(courseID, user.ID, q)
+		modules, count, err := s.courseRepo.FindModulesWithProgressPaginated(courseID, user.ID, q, !canPreview)
 		if err != nil {
 			return nil, models.PaginationResponse{}, err
 		}
+		for i := range modules {
+			s.withURLs(&modules[i].Module)
+		}
 		res = modules
 		totalItems = count
 	}
 
+	if err := s.markAssessments(res); err != nil {
+		return nil, models.PaginationResponse{}, err
+	}
+
+	if hasPurchased {
+		if err := s.markLocks(courseID, user, res); err != nil {
+			return nil, models.PaginationResponse{}, err
+		}
+	}
+
 	if q.Limit <= 0 {
 		q.Limit = 10
 	}
@@ -209,21 +462,138 @@ func (s *moduleService) GetModules(user models.User, courseID uint, q models.Pag
 		q.Page = 1
 	}
 
-	totalPages := int(math.Ceil(float64(totalItems) / float64(q.Limit)))
-	if totalPages == 0 {
-		totalPages = 1
+	pagination := q.Paginate(totalItems)
+
+	return res, pagination, nil
+}
+
+func (s *moduleService) markAssessments(modules []models.ModuleWithIsCompleted) error {
+	ids := make([]uint, 0, len(modules))
+	for _, m := range modules {
+		ids = append(ids, m.ID)
 	}
-	if q.Page > totalPages {
-		q.Page = totalPages
+
+	withQuiz, err := s.quizRepo.ModulesWithQuiz(ids)
+	if err != nil {
+		return err
+	}
+	withAssignment, err := s.assignmentRepo.ModulesWithAssignment(ids)
+	if err != nil {
+		return err
 	}
 
-	pagination := models.PaginationResponse{
-		CurrentPage: q.Page,
-		TotalPages:  totalPages,
-		TotalItems:  int(totalItems),
+	for i := range modules {
+		modules[i].HasQuiz = withQuiz[modules[i].ID]
+		modules[i].HasAssignment = withAssignment[modules[i].ID]
 	}
+	return nil
+}
 
-	return res, pagination, nil
+// moduleLocks reports why modules of a course are still locked for the user.
+// Unmet course prerequisites lock every module, and in a sequential course a
+// module stays locked until the one before it is completed. Users who can
+// preview the course are never locked out.
+func (s *moduleService) moduleLocks(courseID uint, user models.User) (map[uint]error, error) {
+	locks := make(map[uint]error)
+	canPreview, err := canPreviewCourse(s.courseRepo, user, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if canPreview {
+		return locks, nil
+	}
+
+	course, err := s.courseRepo.FindById(courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	prerequisiteErr := requirePrerequisites(s.courseRepo, courseID, user.ID)
+	if prerequisiteErr != nil && !errors.Is(prerequisiteErr, ErrPrerequisitesNotMet) {
+		return nil, prerequisiteErr
+	}
+	if prerequisiteErr == nil && !course.SequentialModules {
+		return locks, nil
+	}
+
+	completions, err := s.moduleRepo.FindCompletionsByCourse(courseID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, m := range completions {
+		switch {
+		case prerequisiteErr != nil:
+			locks[m.ID] = prerequisiteErr
+		case i > 0 && !completions[i-1].IsCompleted:
+			locks[m.ID] = ErrModuleLocked
+		}
+	}
+	return locks, nil
+}
+
+// markLocks flags locked modules and withholds their content URLs.
+func (s *moduleService) markLocks(courseID uint, user models.User, modules []models.ModuleWithIsCompleted) error {
+	locks, err := s.moduleLocks(courseID, user)
+	if err != nil {
+		return err
+	}
+
+	for i := range modules {
+		lockErr, locked := locks[modules[i].ID]
+		if !locked {
+			continue
+		}
+		modules[i].IsLocked = true
+		modules[i].LockReason = lockErr.Error()
+		modules[i].PDFContent = ""
+		modules[i].VideoContent = ""
+		modules[i].VideoHLS = ""
+		modules[i].VideoPoster = ""
+	}
+	return nil
+}
+
+// CheckModuleUnlocked returns the reason the module is locked for the user,
+// or nil if they may study it. Unpublished modules are reported as not found
+// to users who cannot preview the course.
+func (s *moduleService) CheckModuleUnlocked(module *models.Module, user models.User) error {
+	if module.Status != models.StatusPublished {
+		canPreview, err := canPreviewCourse(s.courseRepo, user, module.CourseID)
+		if err != nil {
+			return err
+		}
+		if !canPreview {
+			return gorm.ErrRecordNotFound
+		}
+	}
+
+	locks, err := s.moduleLocks(module.CourseID, user)
+	if err != nil {
+		return err
+	}
+	return locks[module.ID]
+}
+
+// requireAssessmentsPassed keeps modules with a quiz or assignment from being
+// completed before the user passed them.
+func (s *moduleService) requireAssessmentsPassed(moduleID uint, userID uint) error {
+	passed, err := s.quizRepo.PassedModuleQuiz(moduleID, userID)
+	if err != nil {
+		return err
+	}
+	if !passed {
+		return ErrQuizNotPassed
+	}
+
+	passed, err = s.assignmentRepo.PassedModuleAssignment(moduleID, userID)
+	if err != nil {
+		return err
+	}
+	if !passed {
+		return ErrAssignmentNotPassed
+	}
+	return nil
 }
 
 func (s *moduleService) BuildModuleResponses(modules []models.ModuleWithIsCompleted) []models.ModuleResponse {
@@ -231,15 +601,28 @@ func (s *moduleService) BuildModuleResponses(modules []models.ModuleWithIsComple
 
 	for _, m := range modules {
 		responses = append(responses, models.ModuleResponse{
-			ID:           m.ID,
-			Title:        m.Title,
-			Description:  m.Description,
-			PDFContent:   m.PDFContent,
-			VideoContent: m.VideoContent,
-			Order:        m.Order,
-			IsCompleted:  m.IsCompleted,
-			CreatedAt:    m.CreatedAt,
-			UpdatedAt:    m.UpdatedAt,
+			ID:                m.ID,
+			Title:             m.Title,
+			Description:       m.Description,
+			PDFContent:        m.PDFContent,
+			VideoContent:      m.VideoContent,
+			VideoStatus:       m.VideoStatus,
+			VideoHLS:          m.VideoHLS,
+			VideoPoster:       m.VideoPoster,
+			VideoDuration:     m.VideoDuration,
+			Order:             m.Order,
+			SectionID:         m.SectionID,
+			Status:            m.Status,
+			PublishAt:         m.PublishAt,
+			IsCompleted:       m.IsCompleted,
+			HasQuiz:           m.HasQuiz,
+			HasAssignment:     m.HasAssignment,
+			PositionSeconds:   m.PositionSeconds,
+			WatchedPercentage: m.WatchedPercentage,
+			IsLocked:          m.IsLocked,
+			LockReason:        m.LockReason,
+			CreatedAt:         m.CreatedAt,
+			UpdatedAt:         m.UpdatedAt,
 		})
 	}
 
@@ -251,44 +634,59 @@ func (s *moduleService) GetModuleByID(id uint, user models.User) (*models.Module
 	if err != nil {
 		return nil, err
 	}
+	s.withURLs(module)
 
 	hasPurchased, err := s.courseRepo.HasPurchasedCourse(module.CourseID, user.ID)
 	if err != nil {
 		return nil, err
 	}
 
+	res := []models.ModuleWithIsCompleted{{Module: *module}}
+
 	if !hasPurchased {
-		if user.Role != "admin" {
+		canPreview, err := canPreviewCourse(s.courseRepo, user, module.CourseID)
+		if err != nil {
+			return nil, err
+		}
+		if !canPreview {
 			return nil, errors.New(user.Username + " has not bought this course!")
-		} else {
-			res := models.ModuleWithIsCompleted{
-				Module:      *module,
-				IsCompleted: false,
-			}
-			return &res, nil
 		}
 	} else {
-		isCompleted, err := s.moduleRepo.IsModuleCompleted(id, user.ID)
-		if err != nil {
+		if err := s.CheckModuleUnlocked(module, user); err != nil {
 			return nil, err
 		}
-		res := models.ModuleWithIsCompleted{
-			Module:      *module,
-			IsCompleted: isCompleted,
+
+		progress, err := s.moduleRepo.FindProgress(id, user.ID)
+		if err != nil {
+			return nil, err
 		}
-		return &res, nil
+		res[0].IsCompleted = progress.IsCompleted
+		res[0].PositionSeconds = progress.PositionSeconds
+		res[0].WatchedPercentage = progress.WatchedPercentage
 	}
+
+	if err := s.markAssessments(res); err != nil {
+		return nil, err
+	}
+	return &res[0], nil
 }
 
 func (s *moduleService) MarkModuleAsComplete(id uint, user models.User) (*models.MarkModuleResponse, error) {
-	err := s.moduleRepo.ChangeModuleCompletion(id, user.ID, true)
+	module, err := s.moduleRepo.FindById(id)
 	if err != nil {
 		return nil, err
 	}
 
-	module, err := s.moduleRepo.FindById(id)
+	if err := s.CheckModuleUnlocked(module, user); err != nil {
+		return nil, err
+	}
+
+	if err := s.requireAssessmentsPassed(id, user.ID); err != nil {
+		return nil, err
+	}
+
+	err = s.moduleRepo.ChangeModuleCompletion(id, user.ID, true)
 	if err != nil {
-		s.moduleRepo.ChangeModuleCompletion(id, user.ID, false)
 		return nil, err
 	}
 
@@ -318,12 +716,16 @@ func (s *moduleService) MarkModuleAsComplete(id uint, user models.User) (*models
 	return &res, nil
 }
 
-func (s *moduleService) ReorderModules(req models.ReorderModulesRequest, courseID uint) error {
+func (s *moduleService) ReorderModules(req models.ReorderModulesRequest, courseID uint, user models.User) error {
 	_, err := s.courseRepo.FindById(courseID)
 	if err != nil {
 		return err
 	}
 
+	if err := authorizeCourseManagement(s.courseRepo, user, courseID); err != nil {
+		return err
+	}
+
 	if len(req.ModuleOrder) == 0 {
 		return errors.New("module_order cannot be empty")
 	}
@@ -342,6 +744,15 @@ func (s *moduleService) ReorderModules(req models.ReorderModulesRequest, courseI
 		validModuleMap[id] = true
 	}
 
+	sections, err := s.sectionRepo.FindByCourse(courseID)
+	if err != nil {
+		return err
+	}
+	validSections := make(map[uint]bool)
+	for _, section := range sections {
+		validSections[section.ID] = true
+	}
+
 	orderSet := make(map[int]bool)
 	parsedOrders := make([]models.ModuleOrder, 0, len(req.ModuleOrder))
 
@@ -363,9 +774,14 @@ func (s *moduleService) ReorderModules(req models.ReorderModulesRequest, courseI
 		}
 		orderSet[m.Order] = true
 
+		if m.SectionID != nil && *m.SectionID != 0 && !validSections[*m.SectionID] {
+			return fmt.Errorf("%w: section %d does not belong to this course", ErrInvalidSection, *m.SectionID)
+		}
+
 		parsedOrders = append(parsedOrders, models.ModuleOrder{
-			ID:    moduleID,
-			Order: m.Order,
+			ID:        moduleID,
+			Order:     m.Order,
+			SectionID: m.SectionID,
 		})
 	}
 
@@ -373,7 +789,47 @@ func (s *moduleService) ReorderModules(req models.ReorderModulesRequest, courseI
 		return fmt.Errorf("all modules must be included in the reorder request")
 	}
 
-	return s.moduleRepo.ReorderModules(courseID, parsedOrders)
+	sectionOrders, err := parseSectionOrder(req, validSections)
+	if err != nil {
+		return err
+	}
+
+	return s.moduleRepo.ReorderModules(courseID, parsedOrders, sectionOrders)
+}
+
+// parseSectionOrder validates the optional section_order of a reorder
+// request. When present it must place every section of the course exactly
+// once.
+func parseSectionOrder(req models.ReorderModulesRequest, validSections map[uint]bool) ([]models.SectionOrder, error) {
+	if len(req.SectionOrder) == 0 {
+		return nil, nil
+	}
+	if len(req.SectionOrder) != len(validSections) {
+		return nil, fmt.Errorf("%w: all sections must be included in section_order", ErrInvalidSection)
+	}
+
+	seenIDs := make(map[uint]bool)
+	seenOrders := make(map[int]bool)
+	orders := make([]models.SectionOrder, 0, len(req.SectionOrder))
+	for _, so := range req.SectionOrder {
+		idUint, err := strconv.ParseUint(so.ID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid section id format: %s", ErrInvalidSection, so.ID)
+		}
+		sectionID := uint(idUint)
+
+		if !validSections[sectionID] || seenIDs[sectionID] {
+			return nil, fmt.Errorf("%w: invalid section id: %d", ErrInvalidSection, sectionID)
+		}
+		if so.Order < 1 || so.Order > len(validSections) || seenOrders[so.Order] {
+			return nil, fmt.Errorf("%w: invalid order %d for section %d", ErrInvalidSection, so.Order, sectionID)
+		}
+		seenIDs[sectionID] = true
+		seenOrders[so.Order] = true
+
+		orders = append(orders, models.SectionOrder{ID: sectionID, Order: so.Order})
+	}
+	return orders, nil
 }
 
 func (s *moduleService) GetCourseProgress(id uint, user models.User) (*models.CourseProgress, error) {
@@ -381,16 +837,33 @@ func (s *moduleService) GetCourseProgress(id uint, user models.User) (*models.Co
 }
 
 func (s *moduleService) ChangeModuleCompletion(moduleID uint, user models.User, completed bool) error {
-	if err := s.moduleRepo.ChangeModuleCompletion(moduleID, user.ID, completed); err != nil {
+	module, err := s.moduleRepo.FindById(moduleID)
+	if err != nil {
 		return err
 	}
 
-	module, err := s.moduleRepo.FindById(moduleID)
-	if err != nil {
+	if completed {
+		if err := s.CheckModuleUnlocked(module, user); err != nil {
+			return err
+		}
+		if err := s.requireAssessmentsPassed(moduleID, user.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.moduleRepo.ChangeModuleCompletion(moduleID, user.ID, completed); err != nil {
 		return err
 	}
+
 	courseId := module.CourseID
 
+	// Resetting a module may leave the course incomplete again; the course's
+	// certificate policy decides what happens to an issued certificate.
+	if !completed {
+		_, err := s.certificateService.RecomputeCertificate(user.ID, courseId)
+		return err
+	}
+
 	courseProgress, err := s.courseRepo.GetCourseProgress(courseId, user)
 	if err != nil {
 		return err
@@ -404,65 +877,121 @@ func (s *moduleService) ChangeModuleCompletion(moduleID uint, user models.User,
 	return nil
 }
 
-func (s *moduleService) generateCertificateIfEligible(id uint, user models.User, courseId uint, courseProgress *models.CourseProgress) (*string, error) {
-	if int64(courseProgress.TotalModules) == 0 || courseProgress.CompletedModules != courseProgress.TotalModules {
-		return nil, nil
-	}
-
-	course, err := s.courseRepo.FindById(courseId)
+// UpdateWatchProgress records a playback heartbeat for a purchased module's
+// video. When the heartbeat pushes the watched share past the watch policy's
+// threshold the module is completed, which may issue the course certificate.
+func (s *moduleService) UpdateWatchProgress(id uint, user models.User, input models.WatchProgressInput) (*models.WatchProgressResponse, error) {
+	module, err := s.moduleRepo.FindById(id)
 	if err != nil {
-		_ = s.moduleRepo.ChangeModuleCompletion(id, user.ID, false)
 		return nil, err
 	}
 
-	cert, err := s.courseRepo.FindCourseCertificate(user.ID, courseId)
-	if err == nil && cert != nil {
-		return &cert.FileURL, nil
+	if module.VideoContent == "" {
+		return nil, ErrContentNotFound
 	}
 
-	img, err := utils.GenerateCertificate(
-		user.Username,
-		course.Title,
-		course.Instructor,
-		time.Now().Format("2006-01-02"),
-	)
+	hasPurchased, err := s.courseRepo.HasPurchasedCourse(module.CourseID, user.ID)
 	if err != nil {
-		_ = s.moduleRepo.ChangeModuleCompletion(id, user.ID, false)
 		return nil, err
 	}
+	if !hasPurchased {
+		return nil, ErrNoContentAccess
+	}
 
-	fileName := fmt.Sprintf("cert_user%d_course%d.png", user.ID, courseId)
-	filePath := filepath.Join("uploads/certificates", fileName)
-	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
-		_ = s.moduleRepo.ChangeModuleCompletion(id, user.ID, false)
+	if err := s.CheckModuleUnlocked(module, user); err != nil {
 		return nil, err
 	}
 
-	f, err := os.Create(filePath)
+	// The probed duration is authoritative; the player's value is only used
+	// for videos that were not transcoded.
+	duration := module.VideoDuration
+	if duration <= 0 {
+		duration = input.DurationSeconds
+	}
+
+	// Watching is not enough while the module's quiz or assignment is still
+	// open.
+	gateErr := s.requireAssessmentsPassed(id, user.ID)
+	if gateErr != nil && !errors.Is(gateErr, ErrQuizNotPassed) && !errors.Is(gateErr, ErrAssignmentNotPassed) {
+		return nil, gateErr
+	}
+
+	autoCompleted := false
+	progress, err := s.moduleRepo.RecordWatchProgress(id, user.ID, func(p *models.ModuleProgress) error {
+		autoCompleted = s.watchPolicy.Apply(p, input, duration, time.Now())
+		if autoCompleted && gateErr != nil {
+			p.IsCompleted = false
+			autoCompleted = false
+		}
+		return nil
+	})
 	if err != nil {
-		_ = s.moduleRepo.ChangeModuleCompletion(id, user.ID, false)
 		return nil, err
 	}
-	defer f.Close()
 
-	if err := png.Encode(f, img); err != nil {
+	res := models.WatchProgressResponse{
+		ModuleID:          id,
+		PositionSeconds:   progress.PositionSeconds,
+		WatchedSeconds:    progress.WatchedSeconds,
+		WatchedPercentage: progress.WatchedPercentage,
+		IsCompleted:       progress.IsCompleted,
+		AutoCompleted:     autoCompleted,
+	}
+
+	if autoCompleted {
+		courseProgress, err := s.courseRepo.GetCourseProgress(module.CourseID, user)
+		if err != nil {
+			return nil, err
+		}
+
+		certificateURL, err := s.generateCertificateIfEligible(id, user, module.CourseID, courseProgress)
+		if err != nil {
+			return nil, err
+		}
+
+		res.CourseProgress = courseProgress
+		res.CertificateURL = certificateURL
+	}
+
+	return &res, nil
+}
+
+func (s *moduleService) generateCertificateIfEligible(id uint, user models.User, courseId uint, courseProgress *models.CourseProgress) (*string, error) {
+	if int64(courseProgress.TotalModules) == 0 || courseProgress.CompletedModules != courseProgress.TotalModules {
+		return nil, nil
+	}
+
+	course, err := s.courseRepo.FindById(courseId)
+	if err != nil {
 		_ = s.moduleRepo.ChangeModuleCompletion(id, user.ID, false)
 		return nil, err
 	}
 
-	publicURL := os.Getenv("BASE_URL") + "uploads/certificates/" + fileName
-
-	certificate := models.Certificate{
-		UserID:   user.ID,
-		CourseID: courseId,
-		FileURL:  publicURL,
+	cert, err := s.courseRepo.FindCourseCertificate(user.ID, courseId)
+	if err == nil && cert != nil && !cert.RevokedByRefund() {
+		// Completing the course again restores a certificate the policy
+		// marked as outdated or revoked.
+		if cert.OutdatedAt != nil {
+			cert, err = s.certificateService.RecomputeCertificate(user.ID, courseId)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if cert.RevokedAt != nil {
+			return nil, nil
+		}
+		url := storage.URL(s.store, cert.FileKey)
+		return &url, nil
 	}
-	if err := s.courseRepo.CreateCourseCertificate(&certificate); err != nil {
+
+	certificate, err := s.certificateService.IssueCertificate(user, *course)
+	if err != nil {
 		_ = s.moduleRepo.ChangeModuleCompletion(id, user.ID, false)
 		return nil, err
 	}
 
-	return &certificate.FileURL, nil
+	url := storage.URL(s.store, certificate.FileKey)
+	return &url, nil
 }
 
 func (s *moduleService) GetCertificateURL(courseID, userID uint) (*string, error) {
@@ -471,9 +1000,62 @@ func (s *moduleService) GetCertificateURL(courseID, userID uint) (*string, error
 		return nil, err
 	}
 
-	if cert != nil {
-		return &cert.FileURL, nil
+	if cert != nil && cert.RevokedAt == nil {
+		url := storage.URL(s.store, cert.FileKey)
+		return &url, nil
 	}
 
 	return nil, nil
 }
+
+// OpenModuleContent returns the requested content file of a module to users
+// who bought the course or may preview it. Content hosted elsewhere is
+// returned as a URL to redirect to instead.
+func (s *moduleService) OpenModuleContent(id uint, user models.User, contentType string) (storage.Object, string, error) {
+	module, err := s.moduleRepo.FindById(id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	hasPurchased, err := s.courseRepo.HasPurchasedCourse(module.CourseID, user.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !hasPurchased {
+		canPreview, err := canPreviewCourse(s.courseRepo, user, module.CourseID)
+		if err != nil {
+			return nil, "", err
+		}
+		if !canPreview {
+			return nil, "", ErrNoContentAccess
+		}
+	}
+
+	if err := s.CheckModuleUnlocked(module, user); err != nil {
+		return nil, "", err
+	}
+
+	var value string
+	switch contentType {
+	case ContentTypePDF:
+		value = module.PDFContent
+	case ContentTypeVideo:
+		value = module.VideoContent
+	}
+	if value == "" {
+		return nil, "", ErrContentNotFound
+	}
+
+	if storage.IsExternal(value) {
+		return nil, value, nil
+	}
+
+	obj, err := s.store.Open(value)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, "", ErrContentNotFound
+		}
+		return nil, "", err
+	}
+	return obj, "", nil
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
+	"gorm.io/gorm"
+)
+
+const JobTypePublishContent = "publish_content"
+
+const (
+	PublishKindCourse = "course"
+	PublishKindModule = "module"
+)
+
+var ErrInvalidStatus = errors.New("invalid status")
+
+type PublishContentPayload struct {
+	Kind      string    `json:"kind"`
+	ID        uint      `json:"id"`
+	PublishAt time.Time `json:"publish_at"`
+}
+
+type PublishService interface {
+	HandlePublishJob(payload []byte) error
+}
+
+type publishService struct {
+	courseRepo repositories.CourseRepository
+	moduleRepo repositories.ModuleRepository
+	jobRepo    repositories.JobRepository
+}
+
+func NewPublishService(cr repositories.CourseRepository, mr repositories.ModuleRepository, jr repositories.JobRepository) PublishService {
+	return &publishService{courseRepo: cr, moduleRepo: mr, jobRepo: jr}
+}
+
+// resolvePublishStatus validates a status change and returns the schedule to
+// store with it. Only drafts can be scheduled; the time is kept to whole
+// seconds so the stored value matches the one in the job payload.
+func resolvePublishStatus(status string, publishAt *time.Time) (*time.Time, error) {
+	switch status {
+	case models.StatusDraft, models.StatusPublished, models.StatusArchived:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidStatus, status)
+	}
+
+	if publishAt == nil {
+		return nil, nil
+	}
+	if status != models.StatusDraft {
+		return nil, fmt.Errorf("%w: publish_at can only be set on drafts", ErrInvalidStatus)
+	}
+	if !publishAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: publish_at must be in the future", ErrInvalidStatus)
+	}
+
+	at := publishAt.UTC().Truncate(time.Second)
+	return &at, nil
+}
+
+// schedulePublish queues the job that publishes a draft at its PublishAt. If
+// it cannot be queued the draft stays unpublished until it is rescheduled.
+func schedulePublish(jobRepo repositories.JobRepository, kind string, id uint, publishAt *time.Time) {
+	if publishAt == nil {
+		return
+	}
+
+	payload, err := json.Marshal(PublishContentPayload{Kind: kind, ID: id, PublishAt: *publishAt})
+	if err == nil {
+		err = jobRepo.Enqueue(&models.Job{Type: JobTypePublishContent, Payload: string(payload), RunAt: *publishAt})
+	}
+	if err != nil {
+		log.Printf("ERROR: Failed to schedule publishing of %s %d: %v", kind, id, err)
+	}
+}
+
+// HandlePublishJob publishes the scheduled draft. Jobs for content that was
+// rescheduled or changed status in the meantime are no-ops.
+func (s *publishService) HandlePublishJob(payload []byte) error {
+	var p PublishContentPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return err
+	}
+
+	var published bool
+	var err error
+	switch p.Kind {
+	case PublishKindCourse:
+		published, err = s.courseRepo.PublishScheduled(p.ID, p.PublishAt)
+	case PublishKindModule:
+		published, err = s.moduleRepo.PublishScheduled(p.ID, p.PublishAt)
+	default:
+		return fmt.Errorf("unknown publish kind %q", p.Kind)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !published {
+		return nil
+	}
+	log.Printf("Published %s %d as scheduled", p.Kind, p.ID)
+
+	if p.Kind == PublishKindModule {
+		module, err := s.moduleRepo.FindById(p.ID)
+		if err != nil {
+			return err
+		}
+		queueCertificateRecompute(s.jobRepo, module.CourseID)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+)
+
+func TestResolvePublishStatus(t *testing.T) {
+	future := time.Now().Add(48 * time.Hour).Truncate(time.Second).Add(750 * time.Millisecond)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		status    string
+		publishAt *time.Time
+		want      *time.Time
+		err       error
+	}{
+		{name: "draft", status: models.StatusDraft},
+		{name: "published", status: models.StatusPublished},
+		{name: "archived", status: models.StatusArchived},
+		{name: "unknown status", status: "hidden", err: ErrInvalidStatus},
+		{name: "empty status", status: "", err: ErrInvalidStatus},
+		{name: "scheduled draft", status: models.StatusDraft, publishAt: &future, want: &future},
+		{name: "scheduled published", status: models.StatusPublished, publishAt: &future, err: ErrInvalidStatus},
+		{name: "scheduled archived", status: models.StatusArchived, publishAt: &future, err: ErrInvalidStatus},
+		{name: "scheduled in the past", status: models.StatusDraft, publishAt: &past, err: ErrInvalidStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolvePublishStatus(tt.status, tt.publishAt)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("publishAt = %v, want nil", got)
+			case tt.want != nil && got == nil:
+				t.Errorf("publishAt = nil, want %v", tt.want)
+			case tt.want != nil:
+				want := tt.want.UTC().Truncate(time.Second)
+				if !got.Equal(want) || got.Location() != time.UTC || got.Nanosecond() != 0 {
+					t.Errorf("publishAt = %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,470 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidQuiz = errors.New("invalid quiz")
+
+type QuizService interface {
+	SaveQuiz(moduleID uint, input models.QuizInput, user models.User) (*models.Quiz, error)
+	DeleteQuiz(moduleID uint, user models.User) error
+	GetQuiz(moduleID uint, user models.User) (*models.QuizResponse, error)
+	StartAttempt(moduleID uint, user models.User) (*models.QuizAttemptResponse, error)
+	SubmitAttempt(attemptID uint, input models.QuizSubmission, user models.User) (*models.QuizAttemptResponse, error)
+}
+
+type quizService struct {
+	quizRepo      repositories.QuizRepository
+	moduleRepo    repositories.ModuleRepository
+	courseRepo    repositories.CourseRepository
+	moduleService ModuleService
+}
+
+func NewQuizService(qr repositories.QuizRepository, mr repositories.ModuleRepository, cr repositories.CourseRepository, ms ModuleService) QuizService {
+	return &quizService{quizRepo: qr, moduleRepo: mr, courseRepo: cr, moduleService: ms}
+}
+
+func (s *quizService) SaveQuiz(moduleID uint, input models.QuizInput, user models.User) (*models.Quiz, error) {
+	module, err := s.moduleRepo.FindById(moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeCourseManagement(s.courseRepo, user, module.CourseID); err != nil {
+		return nil, err
+	}
+
+	quiz, err := buildQuiz(moduleID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.quizRepo.Replace(quiz); err != nil {
+		return nil, err
+	}
+
+	return s.quizRepo.FindByModule(moduleID)
+}
+
+func buildQuiz(moduleID uint, input models.QuizInput) (*models.Quiz, error) {
+	if input.QuestionsPerAttempt > len(input.Questions) {
+		return nil, fmt.Errorf("%w: questions_per_attempt exceeds the number of questions", ErrInvalidQuiz)
+	}
+
+	quiz := &models.Quiz{
+		ModuleID:            moduleID,
+		Title:               input.Title,
+		PassPercentage:      70,
+		MaxAttempts:         input.MaxAttempts,
+		QuestionsPerAttempt: input.QuestionsPerAttempt,
+	}
+	if input.PassPercentage != nil {
+		quiz.PassPercentage = *input.PassPercentage
+	}
+
+	for i, q := range input.Questions {
+		question, err := buildQuestion(q)
+		if err != nil {
+			return nil, fmt.Errorf("%w: question %d: %s", ErrInvalidQuiz, i+1, err.Error())
+		}
+		question.Order = i + 1
+		quiz.Questions = append(quiz.Questions, question)
+	}
+
+	return quiz, nil
+}
+
+func buildQuestion(input models.QuizQuestionInput) (models.QuizQuestion, error) {
+	question := models.QuizQuestion{
+		ID:     input.ID,
+		Type:   input.Type,
+		Prompt: strings.TrimSpace(input.Prompt),
+		Points: input.Points,
+	}
+	if question.Points == 0 {
+		question.Points = 1
+	}
+
+	switch input.Type {
+	case models.QuestionSingleChoice, models.QuestionMultipleChoice:
+		if len(input.Options) < 2 {
+			return question, errors.New("needs at least two options")
+		}
+		correct := 0
+		for _, o := range input.Options {
+			if o.IsCorrect {
+				correct++
+			}
+		}
+		if input.Type == models.QuestionSingleChoice && correct != 1 {
+			return question, errors.New("needs exactly one correct option")
+		}
+		if correct == 0 {
+			return question, errors.New("needs at least one correct option")
+		}
+		for i, o := range input.Options {
+			question.Options = append(question.Options, models.QuizOption{ID: o.ID, Text: o.Text, IsCorrect: o.IsCorrect, Order: i + 1})
+		}
+
+	case models.QuestionTrueFalse:
+		if input.Answer == nil {
+			return question, errors.New("answer is required")
+		}
+		question.Options = []models.QuizOption{
+			{Text: "True", IsCorrect: *input.Answer, Order: 1},
+			{Text: "False", IsCorrect: !*input.Answer, Order: 2},
+		}
+
+	case models.QuestionShortAnswer:
+		for _, a := range input.AcceptedAnswers {
+			if a = strings.TrimSpace(a); a != "" {
+				question.Options = append(question.Options, models.QuizOption{Text: a, IsCorrect: true, Order: len(question.Options) + 1})
+			}
+		}
+		if len(question.Options) == 0 {
+			return question, errors.New("needs at least one accepted answer")
+		}
+	}
+
+	return question, nil
+}
+
+func (s *quizService) DeleteQuiz(moduleID uint, user models.User) error {
+	module, err := s.moduleRepo.FindById(moduleID)
+	if err != nil {
+		return err
+	}
+
+	if err := authorizeCourseManagement(s.courseRepo, user, module.CourseID); err != nil {
+		return err
+	}
+
+	return s.quizRepo.DeleteByModule(moduleID)
+}
+
+func (s *quizService) GetQuiz(moduleID uint, user models.User) (*models.QuizResponse, error) {
+	module, err := s.moduleRepo.FindById(moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	quiz, err := s.quizRepo.FindByModule(moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	isManager, err := s.canManage(user, module.CourseID)
+	if err != nil {
+		return nil, err
+	}
+	if !isManager {
+		if err := s.requireQuizAccess(module, user); err != nil {
+			return nil, err
+		}
+	}
+
+	attempts, err := s.quizRepo.FindAttempts(quiz.ID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := models.QuizResponse{
+		ID:                  quiz.ID,
+		ModuleID:            quiz.ModuleID,
+		Title:               quiz.Title,
+		PassPercentage:      quiz.PassPercentage,
+		MaxAttempts:         quiz.MaxAttempts,
+		QuestionsPerAttempt: quiz.QuestionsPerAttempt,
+		TotalQuestions:      len(quiz.Questions),
+		Attempts:            []models.QuizAttemptResponse{},
+	}
+
+	for i := range attempts {
+		attempt := &attempts[i]
+		if attempt.Status == models.AttemptInProgress {
+			res.ActiveAttempt = buildAttemptResponse(attempt, quiz)
+			continue
+		}
+		res.Passed = res.Passed || attempt.Passed
+		res.Attempts = append(res.Attempts, *buildAttemptResponse(attempt, nil))
+	}
+
+	if quiz.MaxAttempts > 0 {
+		remaining := max(quiz.MaxAttempts-len(attempts), 0)
+		res.AttemptsRemaining = &remaining
+	}
+
+	if isManager {
+		res.Questions = quiz.Questions
+	}
+
+	return &res, nil
+}
+
+func (s *quizService) canManage(user models.User, courseID uint) (bool, error) {
+	err := authorizeCourseManagement(s.courseRepo, user, courseID)
+	if errors.Is(err, ErrNotCourseInstructor) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// requireQuizAccess lets learners take a module's quiz while they own the
+// course and the module is published and unlocked for them.
+func (s *quizService) requireQuizAccess(module *models.Module, user models.User) error {
+	hasPurchased, err := s.courseRepo.HasPurchasedCourse(module.CourseID, user.ID)
+	if err != nil {
+		return err
+	}
+	if !hasPurchased {
+		return ErrNoContentAccess
+	}
+
+	return s.moduleService.CheckModuleUnlocked(module, user)
+}
+
+// StartAttempt returns the user's unfinished attempt or starts a new one,
+// drawing its questions from the quiz's question bank.
+func (s *quizService) StartAttempt(moduleID uint, user models.User) (*models.QuizAttemptResponse, error) {
+	module, err := s.moduleRepo.FindById(moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	quiz, err := s.quizRepo.FindByModule(moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.requireQuizAccess(module, user); err != nil {
+		return nil, err
+	}
+
+	active, err := s.quizRepo.FindActiveAttempt(quiz.ID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		return buildAttemptResponse(active, quiz), nil
+	}
+
+	attempt := models.QuizAttempt{
+		QuizID:      quiz.ID,
+		UserID:      user.ID,
+		QuestionIDs: drawQuestions(quiz),
+	}
+	if err := s.quizRepo.StartAttempt(&attempt, quiz.MaxAttempts); err != nil {
+		return nil, err
+	}
+
+	return buildAttemptResponse(&attempt, quiz), nil
+}
+
+// drawQuestions picks QuestionsPerAttempt random questions, kept in the
+// order they have in the bank.
+func drawQuestions(quiz *models.Quiz) []uint {
+	picked := make([]int, len(quiz.Questions))
+	for i := range picked {
+		picked[i] = i
+	}
+	if n := quiz.QuestionsPerAttempt; n > 0 && n < len(picked) {
+		rand.Shuffle(len(picked), func(i, j int) { picked[i], picked[j] = picked[j], picked[i] })
+		picked = picked[:n]
+		slices.Sort(picked)
+	}
+
+	ids := make([]uint, 0, len(picked))
+	for _, i := range picked {
+		ids = append(ids, quiz.Questions[i].ID)
+	}
+	return ids
+}
+
+// SubmitAttempt grades the attempt. Passing it completes the module, which
+// may issue the course certificate.
+func (s *quizService) SubmitAttempt(attemptID uint, input models.QuizSubmission, user models.User) (*models.QuizAttemptResponse, error) {
+	attempt, err := s.quizRepo.FindAttempt(attemptID)
+	if err != nil {
+		return nil, err
+	}
+	if attempt.UserID != user.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if attempt.Status != models.AttemptInProgress {
+		return nil, repositories.ErrAttemptSubmitted
+	}
+
+	quiz, err := s.quizRepo.FindByID(attempt.QuizID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The course may have been refunded or the module unpublished since the
+	// attempt started.
+	module, err := s.moduleRepo.FindById(quiz.ModuleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireQuizAccess(module, user); err != nil {
+		return nil, err
+	}
+
+	gradeAttempt(attempt, quiz, input)
+
+	if err := s.quizRepo.SubmitAttempt(attempt); err != nil {
+		return nil, err
+	}
+
+	res := buildAttemptResponse(attempt, quiz)
+	if attempt.Passed {
+		// The attempt is already recorded, so a failure here only delays
+		// completion until the learner marks the module again.
+		completion, err := s.moduleService.MarkModuleAsComplete(quiz.ModuleID, user)
+		if err != nil {
+			log.Printf("ERROR: Failed to complete module %d after quiz attempt %d: %v", quiz.ModuleID, attempt.ID, err)
+		}
+		res.Completion = completion
+	}
+
+	return res, nil
+}
+
+// gradeAttempt scores the submitted answers for the questions drawn for the
+// attempt. Questions removed from the bank since the attempt started are not
+// counted.
+func gradeAttempt(attempt *models.QuizAttempt, quiz *models.Quiz, input models.QuizSubmission) {
+	submitted := make(map[uint]models.QuizAnswerInput, len(input.Answers))
+	for _, a := range input.Answers {
+		submitted[a.QuestionID] = a
+	}
+
+	questions := make(map[uint]*models.QuizQuestion, len(quiz.Questions))
+	for i := range quiz.Questions {
+		questions[quiz.Questions[i].ID] = &quiz.Questions[i]
+	}
+
+	attempt.Answers = nil
+	attempt.Score = 0
+	attempt.MaxScore = 0
+
+	for _, id := range attempt.QuestionIDs {
+		question, ok := questions[id]
+		if !ok {
+			continue
+		}
+		attempt.MaxScore += question.Points
+
+		in := submitted[id]
+		answer := models.QuizAnswer{
+			QuestionID: id,
+			OptionIDs:  in.OptionIDs,
+			Text:       strings.TrimSpace(in.Text),
+			IsCorrect:  isCorrectAnswer(question, in),
+		}
+		if answer.IsCorrect {
+			answer.Points = question.Points
+			attempt.Score += question.Points
+		}
+		attempt.Answers = append(attempt.Answers, answer)
+	}
+
+	attempt.Percentage = 0
+	if attempt.MaxScore > 0 {
+		attempt.Percentage = math.Round(attempt.Score/attempt.MaxScore*10000) / 100
+	}
+	attempt.Passed = attempt.MaxScore > 0 && attempt.Percentage >= quiz.PassPercentage
+}
+
+func isCorrectAnswer(question *models.QuizQuestion, in models.QuizAnswerInput) bool {
+	if question.Type == models.QuestionShortAnswer {
+		given := normalizeAnswer(in.Text)
+		if given == "" {
+			return false
+		}
+		for _, o := range question.Options {
+			if normalizeAnswer(o.Text) == given {
+				return true
+			}
+		}
+		return false
+	}
+
+	selected := slices.Clone(in.OptionIDs)
+	slices.Sort(selected)
+	selected = slices.Compact(selected)
+
+	var correct []uint
+	for _, o := range question.Options {
+		if o.IsCorrect {
+			correct = append(correct, o.ID)
+		}
+	}
+	slices.Sort(correct)
+
+	// Multiple choice questions are all or nothing.
+	return len(selected) > 0 && slices.Equal(selected, correct)
+}
+
+func normalizeAnswer(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// buildAttemptResponse describes an attempt. When quiz is given the drawn
+// questions are included without their answer key.
+func buildAttemptResponse(attempt *models.QuizAttempt, quiz *models.Quiz) *models.QuizAttemptResponse {
+	res := &models.QuizAttemptResponse{
+		ID:          attempt.ID,
+		QuizID:      attempt.QuizID,
+		Status:      attempt.Status,
+		Score:       attempt.Score,
+		MaxScore:    attempt.MaxScore,
+		Percentage:  attempt.Percentage,
+		Passed:      attempt.Passed,
+		StartedAt:   attempt.CreatedAt,
+		SubmittedAt: attempt.SubmittedAt,
+		Answers:     attempt.Answers,
+	}
+
+	if quiz == nil {
+		return res
+	}
+
+	questions := make(map[uint]*models.QuizQuestion, len(quiz.Questions))
+	for i := range quiz.Questions {
+		questions[quiz.Questions[i].ID] = &quiz.Questions[i]
+	}
+
+	for _, id := range attempt.QuestionIDs {
+		question, ok := questions[id]
+		if !ok {
+			continue
+		}
+
+		q := models.QuizQuestionResponse{
+			ID:      question.ID,
+			Type:    question.Type,
+			Prompt:  question.Prompt,
+			Points:  question.Points,
+			Options: []models.QuizOptionResponse{},
+		}
+		if question.Type != models.QuestionShortAnswer {
+			for _, o := range question.Options {
+				q.Options = append(q.Options, models.QuizOptionResponse{ID: o.ID, Text: o.Text})
+			}
+		}
+		res.Questions = append(res.Questions, q)
+	}
+
+	return res
+}
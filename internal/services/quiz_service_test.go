@@ -0,0 +1,281 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
+	"gorm.io/gorm"
+)
+
+func TestIsCorrectAnswer(t *testing.T) {
+	single := &models.QuizQuestion{Type: models.QuestionSingleChoice, Options: []models.QuizOption{
+		{ID: 1, Text: "Go"}, {ID: 2, Text: "Rust", IsCorrect: true}, {ID: 3, Text: "Zig"},
+	}}
+	multiple := &models.QuizQuestion{Type: models.QuestionMultipleChoice, Options: []models.QuizOption{
+		{ID: 4, IsCorrect: true}, {ID: 5}, {ID: 6, IsCorrect: true},
+	}}
+	short := &models.QuizQuestion{Type: models.QuestionShortAnswer, Options: []models.QuizOption{
+		{ID: 7, Text: "Goroutine"}, {ID: 8, Text: "green  thread"},
+	}}
+
+	tests := []struct {
+		name     string
+		question *models.QuizQuestion
+		input    models.QuizAnswerInput
+		want     bool
+	}{
+		{name: "single correct", question: single, input: models.QuizAnswerInput{OptionIDs: []uint{2}}, want: true},
+		{name: "single wrong", question: single, input: models.QuizAnswerInput{OptionIDs: []uint{1}}},
+		{name: "single with an extra option", question: single, input: models.QuizAnswerInput{OptionIDs: []uint{2, 3}}},
+		{name: "single repeated option", question: single, input: models.QuizAnswerInput{OptionIDs: []uint{2, 2}}, want: true},
+		{name: "no option selected", question: single},
+		{name: "multiple all correct in any order", question: multiple, input: models.QuizAnswerInput{OptionIDs: []uint{6, 4}}, want: true},
+		{name: "multiple partially correct", question: multiple, input: models.QuizAnswerInput{OptionIDs: []uint{4}}},
+		{name: "multiple with a wrong option", question: multiple, input: models.QuizAnswerInput{OptionIDs: []uint{4, 5, 6}}},
+		{name: "short answer ignores case", question: short, input: models.QuizAnswerInput{Text: "goroutine"}, want: true},
+		{name: "short answer ignores spacing", question: short, input: models.QuizAnswerInput{Text: "  Green Thread "}, want: true},
+		{name: "short answer wrong", question: short, input: models.QuizAnswerInput{Text: "thread"}},
+		{name: "short answer blank", question: short, input: models.QuizAnswerInput{Text: "   "}},
+		{name: "short answer ignores options", question: short, input: models.QuizAnswerInput{OptionIDs: []uint{7}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isCorrectAnswer(tt.question, tt.input); got != tt.want {
+				t.Errorf("isCorrectAnswer = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGradeAttempt(t *testing.T) {
+	quiz := &models.Quiz{PassPercentage: 70, Questions: []models.QuizQuestion{
+		{ID: 1, Type: models.QuestionTrueFalse, Points: 1, Options: []models.QuizOption{{ID: 11, IsCorrect: true}, {ID: 12}}},
+		{ID: 2, Type: models.QuestionSingleChoice, Points: 2, Options: []models.QuizOption{{ID: 21}, {ID: 22, IsCorrect: true}}},
+		{ID: 3, Type: models.QuestionShortAnswer, Points: 3, Options: []models.QuizOption{{ID: 31, Text: "channel"}}},
+	}}
+
+	tests := []struct {
+		name        string
+		questionIDs []uint
+		answers     []models.QuizAnswerInput
+		score       float64
+		maxScore    float64
+		percentage  float64
+		passed      bool
+	}{
+		{
+			name:        "all correct",
+			questionIDs: []uint{1, 2, 3},
+			answers: []models.QuizAnswerInput{
+				{QuestionID: 1, OptionIDs: []uint{11}},
+				{QuestionID: 2, OptionIDs: []uint{22}},
+				{QuestionID: 3, Text: "Channel"},
+			},
+			score: 6, maxScore: 6, percentage: 100, passed: true,
+		},
+		{
+			name:        "weighted by points",
+			questionIDs: []uint{1, 2, 3},
+			answers: []models.QuizAnswerInput{
+				{QuestionID: 1, OptionIDs: []uint{11}},
+				{QuestionID: 3, Text: "channel"},
+			},
+			score: 4, maxScore: 6, percentage: 66.67,
+		},
+		{
+			name:        "below the pass mark",
+			questionIDs: []uint{2, 3},
+			answers: []models.QuizAnswerInput{
+				{QuestionID: 2, OptionIDs: []uint{21}},
+				{QuestionID: 3, Text: "channel"},
+			},
+			score: 3, maxScore: 5, percentage: 60,
+		},
+		{
+			name:        "unanswered questions count",
+			questionIDs: []uint{1, 2, 3},
+			score:       0, maxScore: 6, percentage: 0,
+		},
+		{
+			name:        "only drawn questions are graded",
+			questionIDs: []uint{1},
+			answers: []models.QuizAnswerInput{
+				{QuestionID: 1, OptionIDs: []uint{11}},
+				{QuestionID: 2, OptionIDs: []uint{22}},
+			},
+			score: 1, maxScore: 1, percentage: 100, passed: true,
+		},
+		{
+			name:        "removed questions are skipped",
+			questionIDs: []uint{1, 99},
+			answers:     []models.QuizAnswerInput{{QuestionID: 1, OptionIDs: []uint{11}}},
+			score:       1, maxScore: 1, percentage: 100, passed: true,
+		},
+		{
+			name:        "nothing to grade does not pass",
+			questionIDs: []uint{99},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempt := &models.QuizAttempt{QuestionIDs: tt.questionIDs, Score: 42}
+
+			gradeAttempt(attempt, quiz, models.QuizSubmission{Answers: tt.answers})
+
+			if attempt.Score != tt.score || attempt.MaxScore != tt.maxScore {
+				t.Errorf("score = %v/%v, want %v/%v", attempt.Score, attempt.MaxScore, tt.score, tt.maxScore)
+			}
+			if attempt.Percentage != tt.percentage {
+				t.Errorf("Percentage = %v, want %v", attempt.Percentage, tt.percentage)
+			}
+			if attempt.Passed != tt.passed {
+				t.Errorf("Passed = %v, want %v", attempt.Passed, tt.passed)
+			}
+
+			var graded []uint
+			for _, a := range attempt.Answers {
+				graded = append(graded, a.QuestionID)
+			}
+			want := slices.DeleteFunc(slices.Clone(tt.questionIDs), func(id uint) bool { return id == 99 })
+			if !slices.Equal(graded, want) {
+				t.Errorf("graded questions = %v, want %v", graded, want)
+			}
+		})
+	}
+}
+
+func TestDrawQuestions(t *testing.T) {
+	quiz := &models.Quiz{Questions: []models.QuizQuestion{{ID: 10}, {ID: 20}, {ID: 30}, {ID: 40}, {ID: 50}}}
+	all := []uint{10, 20, 30, 40, 50}
+
+	tests := []struct {
+		name    string
+		perDraw int
+		want    int
+	}{
+		{name: "whole bank by default", perDraw: 0, want: 5},
+		{name: "subset of the bank", perDraw: 3, want: 3},
+		{name: "bank smaller than the draw", perDraw: 8, want: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quiz.QuestionsPerAttempt = tt.perDraw
+
+			for range 20 {
+				ids := drawQuestions(quiz)
+				if len(ids) != tt.want {
+					t.Fatalf("drew %d questions, want %d", len(ids), tt.want)
+				}
+				if !slices.IsSorted(ids) || len(slices.Compact(slices.Clone(ids))) != len(ids) {
+					t.Fatalf("drew %v, want distinct questions in bank order", ids)
+				}
+				for _, id := range ids {
+					if !slices.Contains(all, id) {
+						t.Fatalf("drew %d, which is not in the bank", id)
+					}
+				}
+			}
+		})
+	}
+}
+
+// quizBank serves one quiz on module 2.
+type quizBank struct {
+	repositories.QuizRepository
+	submitted bool
+}
+
+func (r *quizBank) FindByModule(moduleID uint) (*models.Quiz, error) {
+	return r.FindByID(1)
+}
+
+func (r *quizBank) FindByID(id uint) (*models.Quiz, error) {
+	return &models.Quiz{ID: 1, ModuleID: 2, Questions: []models.QuizQuestion{{ID: 10}}}, nil
+}
+
+func (r *quizBank) FindAttempts(quizID, userID uint) ([]models.QuizAttempt, error) {
+	return nil, nil
+}
+
+func (r *quizBank) FindAttempt(id uint) (*models.QuizAttempt, error) {
+	return &models.QuizAttempt{ID: id, QuizID: 1, UserID: 1, Status: models.AttemptInProgress, QuestionIDs: []uint{10}}, nil
+}
+
+func (r *quizBank) SubmitAttempt(attempt *models.QuizAttempt) error {
+	r.submitted = true
+	return nil
+}
+
+// quizModule is module 2 of course 1.
+type quizModule struct {
+	repositories.ModuleRepository
+	module models.Module
+}
+
+func (r *quizModule) FindById(id uint) (*models.Module, error) {
+	module := r.module
+	return &module, nil
+}
+
+// quizLocks unlocks every module but hides unpublished ones, as the module
+// service does.
+type quizLocks struct{ ModuleService }
+
+func (quizLocks) CheckModuleUnlocked(module *models.Module, user models.User) error {
+	if module.Status != models.StatusPublished {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// quizBuyer is user 1, who bought course 1 unless it was refunded.
+type quizBuyer struct {
+	repositories.CourseRepository
+	refunded bool
+}
+
+func (r *quizBuyer) HasPurchasedCourse(courseID, userID uint) (bool, error) {
+	return userID == 1 && !r.refunded, nil
+}
+
+func (r *quizBuyer) IsCourseInstructor(courseID, userID uint) (bool, error) {
+	return false, nil
+}
+
+func TestQuizRequiresPurchaseAndPublishedModule(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		refunded bool
+		want     error
+	}{
+		{name: "bought and published", status: models.StatusPublished},
+		{name: "refunded", status: models.StatusPublished, refunded: true, want: ErrNoContentAccess},
+		{name: "module back in draft", status: models.StatusDraft, want: gorm.ErrRecordNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bank := &quizBank{}
+			module := &quizModule{module: models.Module{ID: 2, CourseID: 1, Status: tt.status}}
+			s := &quizService{quizRepo: bank, moduleRepo: module, courseRepo: &quizBuyer{refunded: tt.refunded}, moduleService: quizLocks{}}
+			user := models.User{ID: 1}
+
+			if _, err := s.GetQuiz(2, user); !errors.Is(err, tt.want) {
+				t.Errorf("GetQuiz err = %v, want %v", err, tt.want)
+			}
+			if _, err := s.SubmitAttempt(5, models.QuizSubmission{}, user); !errors.Is(err, tt.want) {
+				t.Errorf("SubmitAttempt err = %v, want %v", err, tt.want)
+			}
+			if bank.submitted != (tt.want == nil) {
+				t.Errorf("submitted = %v, want %v", bank.submitted, tt.want == nil)
+			}
+		})
+	}
+}
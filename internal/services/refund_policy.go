@@ -0,0 +1,63 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+)
+
+const (
+	defaultRefundWindowDays  = 7
+	defaultRefundMaxProgress = 30.0
+)
+
+// RefundPolicy decides whether a student may refund a purchase on their own.
+// Admin refunds are not subject to the policy.
+type RefundPolicy struct {
+	Window                time.Duration
+	MaxProgressPercentage float64
+}
+
+// LoadRefundPolicy reads REFUND_WINDOW_DAYS and REFUND_MAX_PROGRESS from the
+// environment, falling back to a 7 day window and 30% progress.
+func LoadRefundPolicy() RefundPolicy {
+	days := defaultRefundWindowDays
+	if v, err := strconv.Atoi(os.Getenv("REFUND_WINDOW_DAYS")); err == nil && v >= 0 {
+		days = v
+	}
+
+	maxProgress := defaultRefundMaxProgress
+	if v, err := strconv.ParseFloat(os.Getenv("REFUND_MAX_PROGRESS"), 64); err == nil && v >= 0 {
+		maxProgress = v
+	}
+
+	return RefundPolicy{
+		Window:                time.Duration(days) * 24 * time.Hour,
+		MaxProgressPercentage: maxProgress,
+	}
+}
+
+func (p RefundPolicy) Evaluate(purchase *models.Purchase, progress *models.CourseProgress, now time.Time) models.RefundEligibility {
+	deadline := purchase.CreatedAt.Add(p.Window)
+
+	if now.After(deadline) {
+		return models.RefundEligibility{
+			Eligible: false,
+			Reason:   "refund window has passed",
+			Deadline: deadline,
+		}
+	}
+
+	if progress != nil && progress.Percentage >= p.MaxProgressPercentage {
+		return models.RefundEligibility{
+			Eligible: false,
+			Reason:   fmt.Sprintf("course progress must be below %.0f%% to refund", p.MaxProgressPercentage),
+			Deadline: deadline,
+		}
+	}
+
+	return models.RefundEligibility{Eligible: true, Deadline: deadline}
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+)
+
+func TestRefundPolicyEvaluate(t *testing.T) {
+	policy := RefundPolicy{Window: 7 * 24 * time.Hour, MaxProgressPercentage: 30}
+	bought := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		progress *models.CourseProgress
+		eligible bool
+		reason   string
+	}{
+		{
+			name:     "within window without progress",
+			now:      bought.Add(24 * time.Hour),
+			eligible: true,
+		},
+		{
+			name:     "within window below progress limit",
+			now:      bought.Add(24 * time.Hour),
+			progress: &models.CourseProgress{Percentage: 29.9},
+			eligible: true,
+		},
+		{
+			name:     "at the deadline",
+			now:      bought.Add(7 * 24 * time.Hour),
+			eligible: true,
+		},
+		{
+			name:   "after the deadline",
+			now:    bought.Add(7*24*time.Hour + time.Second),
+			reason: "refund window has passed",
+		},
+		{
+			name:     "progress at the limit",
+			now:      bought.Add(time.Hour),
+			progress: &models.CourseProgress{Percentage: 30},
+			reason:   "course progress must be below 30% to refund",
+		},
+		{
+			name:     "window is checked before progress",
+			now:      bought.Add(30 * 24 * time.Hour),
+			progress: &models.CourseProgress{Percentage: 100},
+			reason:   "refund window has passed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policy.Evaluate(&models.Purchase{CreatedAt: bought}, tt.progress, tt.now)
+
+			if got.Eligible != tt.eligible {
+				t.Errorf("Eligible = %v, want %v", got.Eligible, tt.eligible)
+			}
+			if got.Reason != tt.reason {
+				t.Errorf("Reason = %q, want %q", got.Reason, tt.reason)
+			}
+			if want := bought.Add(policy.Window); !got.Deadline.Equal(want) {
+				t.Errorf("Deadline = %v, want %v", got.Deadline, want)
+			}
+		})
+	}
+}
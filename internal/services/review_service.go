@@ -0,0 +1,233 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/rbac"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrReviewExists     = errors.New("you have already reviewed this course")
+	ErrReviewNotAllowed = errors.New("only students who bought this course can review it")
+	ErrNotReviewAuthor  = errors.New("only the author can change this review")
+)
+
+type ReviewService interface {
+	GetCourseReviews(courseID uint, q models.ReviewQuery, user models.User) ([]models.ReviewResponse, models.PaginationResponse, error)
+	GetOwnReview(courseID uint, user models.User) (*models.ReviewResponse, error)
+	CreateReview(courseID uint, input models.ReviewInput, user models.User) (*models.ReviewResponse, error)
+	UpdateReview(id uint, input models.ReviewInput, user models.User) (*models.ReviewResponse, error)
+	DeleteReview(id uint, user models.User) error
+	ModerateReview(id uint, input models.ReviewModerationInput, user models.User) (*models.ReviewResponse, error)
+	ListReviews(q models.ReviewQuery, user models.User) ([]models.ReviewResponse, models.PaginationResponse, error)
+}
+
+type reviewService struct {
+	reviewRepo repositories.ReviewRepository
+	courseRepo repositories.CourseRepository
+}
+
+func NewReviewService(rr repositories.ReviewRepository, cr repositories.CourseRepository) ReviewService {
+	return &reviewService{reviewRepo: rr, courseRepo: cr}
+}
+
+// publicReviewStatuses are the reviews shown to everyone and counted in the
+// course rating.
+var publicReviewStatuses = []string{models.ReviewVisible, models.ReviewFlagged}
+
+// GetCourseReviews lists the course's public reviews. Moderators also see
+// hidden ones and can filter by status.
+func (s *reviewService) GetCourseReviews(courseID uint, q models.ReviewQuery, user models.User) ([]models.ReviewResponse, models.PaginationResponse, error) {
+	course, err := s.courseRepo.FindById(courseID)
+	if err != nil {
+		return nil, models.PaginationResponse{}, err
+	}
+
+	if err := requireCourseVisible(s.courseRepo, course, user); err != nil {
+		return nil, models.PaginationResponse{}, err
+	}
+
+	moderator := rbac.HasPermission(user.Role, rbac.PermReviewModerate)
+
+	statuses := publicReviewStatuses
+	if moderator {
+		statuses = []string{models.ReviewVisible, models.ReviewFlagged, models.ReviewHidden}
+	}
+	if q.Status != "" && (moderator || q.Status != models.ReviewHidden) {
+		statuses = []string{q.Status}
+	}
+
+	q.CourseID = courseID
+	return s.list(q, statuses, user)
+}
+
+func (s *reviewService) GetOwnReview(courseID uint, user models.User) (*models.ReviewResponse, error) {
+	review, err := s.reviewRepo.FindByCourseAndUser(courseID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return buildReviewResponse(review), nil
+}
+
+func (s *reviewService) CreateReview(courseID uint, input models.ReviewInput, user models.User) (*models.ReviewResponse, error) {
+	course, err := s.courseRepo.FindById(courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := requireCourseVisible(s.courseRepo, course, user); err != nil {
+		return nil, err
+	}
+
+	if err := s.requirePurchase(courseID, user.ID); err != nil {
+		return nil, err
+	}
+
+	_, err = s.reviewRepo.FindByCourseAndUser(courseID, user.ID)
+	if err == nil {
+		return nil, ErrReviewExists
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	review := models.Review{
+		CourseID: courseID,
+		UserID:   user.ID,
+		Rating:   input.Rating,
+		Body:     strings.TrimSpace(input.Body),
+		Status:   models.ReviewVisible,
+	}
+	if err := s.reviewRepo.Create(&review); err != nil {
+		// A concurrent request created the review after the check above.
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrReviewExists
+		}
+		return nil, err
+	}
+
+	review.User = user
+	return buildReviewResponse(&review), nil
+}
+
+// UpdateReview lets the author change their rating and text. The review
+// keeps its moderation status.
+func (s *reviewService) UpdateReview(id uint, input models.ReviewInput, user models.User) (*models.ReviewResponse, error) {
+	review, err := s.reviewRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if review.UserID != user.ID {
+		return nil, ErrNotReviewAuthor
+	}
+
+	if err := s.requirePurchase(review.CourseID, user.ID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	review.Rating = input.Rating
+	review.Body = strings.TrimSpace(input.Body)
+	review.EditedAt = &now
+
+	if err := s.reviewRepo.UpdateContent(review); err != nil {
+		return nil, err
+	}
+	return buildReviewResponse(review), nil
+}
+
+// DeleteReview removes a review for its author or a moderator.
+func (s *reviewService) DeleteReview(id uint, user models.User) error {
+	review, err := s.reviewRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	if review.UserID != user.ID && !rbac.HasPermission(user.Role, rbac.PermReviewModerate) {
+		return ErrNotReviewAuthor
+	}
+
+	return s.reviewRepo.Delete(review)
+}
+
+func (s *reviewService) ModerateReview(id uint, input models.ReviewModerationInput, user models.User) (*models.ReviewResponse, error) {
+	review, err := s.reviewRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	review.Status = input.Status
+	review.ModerationNote = strings.TrimSpace(input.Note)
+	review.ModeratedBy = &user.ID
+	review.ModeratedAt = &now
+
+	if err := s.reviewRepo.Moderate(review); err != nil {
+		return nil, err
+	}
+	return buildReviewResponse(review), nil
+}
+
+// ListReviews is the moderation queue across courses. It shows flagged
+// reviews unless another status is asked for.
+func (s *reviewService) ListReviews(q models.ReviewQuery, user models.User) ([]models.ReviewResponse, models.PaginationResponse, error) {
+	status := q.Status
+	if status == "" {
+		status = models.ReviewFlagged
+	}
+	return s.list(q, []string{status}, user)
+}
+
+func (s *reviewService) list(q models.ReviewQuery, statuses []string, user models.User) ([]models.ReviewResponse, models.PaginationResponse, error) {
+	q.Normalize()
+
+	reviews, totalItems, err := s.reviewRepo.List(q, statuses)
+	if err != nil {
+		return nil, models.PaginationResponse{}, err
+	}
+
+	// Moderation notes are between the moderators and the author.
+	if !rbac.HasPermission(user.Role, rbac.PermReviewModerate) {
+		for i := range reviews {
+			if reviews[i].UserID != user.ID {
+				reviews[i].ModerationNote = ""
+				reviews[i].ModeratedAt = nil
+			}
+		}
+	}
+
+	return reviews, q.Paginate(totalItems), nil
+}
+
+func (s *reviewService) requirePurchase(courseID uint, userID uint) error {
+	purchased, err := s.courseRepo.HasPurchasedCourse(courseID, userID)
+	if err != nil {
+		return err
+	}
+	if !purchased {
+		return ErrReviewNotAllowed
+	}
+	return nil
+}
+
+func buildReviewResponse(review *models.Review) *models.ReviewResponse {
+	return &models.ReviewResponse{
+		ID:             review.ID,
+		CourseID:       review.CourseID,
+		UserID:         review.UserID,
+		Username:       review.User.Username,
+		Rating:         review.Rating,
+		Body:           review.Body,
+		Status:         review.Status,
+		ModerationNote: review.ModerationNote,
+		ModeratedAt:    review.ModeratedAt,
+		EditedAt:       review.EditedAt,
+		CreatedAt:      review.CreatedAt,
+	}
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// racedReviews finds no review for the user, but by the time the review is
+// saved a concurrent request has already saved one.
+type racedReviews struct {
+	repositories.ReviewRepository
+}
+
+func (racedReviews) FindByCourseAndUser(courseID, userID uint) (*models.Review, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (racedReviews) Create(review *models.Review) error {
+	return gorm.ErrDuplicatedKey
+}
+
+// reviewedCourse is a published course bought by every user.
+type reviewedCourse struct {
+	repositories.CourseRepository
+}
+
+func (reviewedCourse) FindById(id uint) (*models.Course, error) {
+	return &models.Course{ID: id, Status: models.StatusPublished}, nil
+}
+
+func (reviewedCourse) HasPurchasedCourse(courseID, userID uint) (bool, error) {
+	return true, nil
+}
+
+func TestCreateReviewReportsConcurrentDuplicate(t *testing.T) {
+	s := NewReviewService(racedReviews{}, reviewedCourse{})
+
+	_, err := s.CreateReview(1, models.ReviewInput{Rating: 5}, models.User{ID: 2})
+	if !errors.Is(err, ErrReviewExists) {
+		t.Fatalf("err = %v, want ErrReviewExists", err)
+	}
+}
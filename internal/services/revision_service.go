@@ -0,0 +1,360 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/storage"
+	"github.com/kin-ark/GroAcademy/internal/utils"
+)
+
+// Revisions snapshot modules and courses after every change. Content that
+// predates revision history gets a baseline revision without an author the
+// first time it is edited, so the original version can still be restored.
+// Recording a revision never fails the edit it follows; errors are logged.
+
+const baselineRevisionNote = "version before revision history"
+
+// recordModuleRevision snapshots the module's stored content.
+func (s *moduleService) recordModuleRevision(module *models.Module, authorID *uint, note string) {
+	rev := models.ModuleRevision{
+		ModuleID:     module.ID,
+		AuthorID:     authorID,
+		Note:         note,
+		Title:        module.Title,
+		Description:  module.Description,
+		PDFContent:   module.PDFContent,
+		VideoContent: module.VideoContent,
+	}
+	if err := s.revisionRepo.CreateModuleRevision(&rev); err != nil {
+		log.Printf("ERROR: Failed to record revision of module %d: %v", module.ID, err)
+	}
+}
+
+func (s *moduleService) ensureModuleBaseline(module *models.Module) {
+	count, err := s.revisionRepo.CountModuleRevisions(module.ID)
+	if err != nil {
+		log.Printf("ERROR: Failed to count revisions of module %d: %v", module.ID, err)
+		return
+	}
+	if count == 0 {
+		s.recordModuleRevision(module, nil, baselineRevisionNote)
+	}
+}
+
+func (s *moduleService) GetModuleRevisions(id uint, user models.User) ([]models.ModuleRevisionResponse, error) {
+	module, err := s.moduleRepo.FindById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeCourseManagement(s.courseRepo, user, module.CourseID); err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.revisionRepo.FindModuleRevisions(id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]models.ModuleRevisionResponse, 0, len(revisions))
+	for i := range revisions {
+		var previous *models.ModuleRevision
+		if i+1 < len(revisions) {
+			previous = &revisions[i+1]
+		}
+		res = append(res, s.buildModuleRevisionResponse(&revisions[i], previous))
+	}
+	return res, nil
+}
+
+// GetModuleRevision returns the revision with a diff of its text fields
+// against revision against, or the one before it when against is 0.
+func (s *moduleService) GetModuleRevision(id uint, number int, against int, user models.User) (*models.ModuleRevisionDetail, error) {
+	module, err := s.moduleRepo.FindById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeCourseManagement(s.courseRepo, user, module.CourseID); err != nil {
+		return nil, err
+	}
+
+	rev, err := s.revisionRepo.FindModuleRevision(id, number)
+	if err != nil {
+		return nil, err
+	}
+
+	if against == 0 {
+		against = number - 1
+	}
+	var base *models.ModuleRevision
+	if against > 0 {
+		base, err = s.revisionRepo.FindModuleRevision(id, against)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var before models.ModuleRevision
+	if base != nil {
+		before = *base
+	}
+
+	return &models.ModuleRevisionDetail{
+		ModuleRevisionResponse: s.buildModuleRevisionResponse(rev, base),
+		Against:                against,
+		Diff: map[string][]models.DiffLine{
+			"title":       utils.DiffLines(before.Title, rev.Title),
+			"description": utils.DiffLines(before.Description, rev.Description),
+		},
+	}, nil
+}
+
+// RestoreModuleRevision brings back the revision's content as a new
+// revision, so the restore itself can be undone.
+func (s *moduleService) RestoreModuleRevision(id uint, number int, user models.User) (*models.Module, error) {
+	module, err := s.moduleRepo.FindById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeCourseManagement(s.courseRepo, user, module.CourseID); err != nil {
+		return nil, err
+	}
+
+	rev, err := s.revisionRepo.FindModuleRevision(id, number)
+	if err != nil {
+		return nil, err
+	}
+
+	oldVideo := module.VideoContent
+
+	module.Title = rev.Title
+	module.Description = rev.Description
+	module.PDFContent = rev.PDFContent
+	module.VideoContent = rev.VideoContent
+
+	if err := s.moduleRepo.Update(module); err != nil {
+		return nil, err
+	}
+	s.recordModuleRevision(module, &user.ID, fmt.Sprintf("restored from revision %d", number))
+
+	if oldVideo != module.VideoContent {
+		if err := s.resetVideo(module); err != nil {
+			return nil, err
+		}
+	}
+
+	restored, err := s.moduleRepo.FindById(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.withURLs(restored), nil
+}
+
+func (s *moduleService) buildModuleRevisionResponse(rev *models.ModuleRevision, previous *models.ModuleRevision) models.ModuleRevisionResponse {
+	changed := []string{}
+	if previous != nil {
+		if rev.Title != previous.Title {
+			changed = append(changed, "title")
+		}
+		if rev.Description != previous.Description {
+			changed = append(changed, "description")
+		}
+		if rev.PDFContent != previous.PDFContent {
+			changed = append(changed, "pdf_content")
+		}
+		if rev.VideoContent != previous.VideoContent {
+			changed = append(changed, "video_content")
+		}
+	}
+
+	return models.ModuleRevisionResponse{
+		Number:        rev.Number,
+		Author:        revisionAuthor(rev.Author),
+		Note:          rev.Note,
+		Title:         rev.Title,
+		Description:   rev.Description,
+		PDFContent:    storage.URLWithExpiry(s.store, rev.PDFContent, storage.ContentURLExpiry),
+		VideoContent:  storage.URLWithExpiry(s.store, rev.VideoContent, storage.ContentURLExpiry),
+		ChangedFields: changed,
+		CreatedAt:     rev.CreatedAt,
+	}
+}
+
+// recordCourseRevision snapshots the course's catalog details.
+func (s *courseService) recordCourseRevision(course *models.Course, authorID *uint, note string) {
+	rev := models.CourseRevision{
+		CourseID:       course.ID,
+		AuthorID:       authorID,
+		Note:           note,
+		Title:          course.Title,
+		Description:    course.Description,
+		Instructor:     course.Instructor,
+		Topics:         course.Topics,
+		Price:          course.Price,
+		ThumbnailImage: course.ThumbnailImage,
+	}
+	if err := s.revisionRepo.CreateCourseRevision(&rev); err != nil {
+		log.Printf("ERROR: Failed to record revision of course %d: %v", course.ID, err)
+	}
+}
+
+func (s *courseService) ensureCourseBaseline(course *models.Course) {
+	count, err := s.revisionRepo.CountCourseRevisions(course.ID)
+	if err != nil {
+		log.Printf("ERROR: Failed to count revisions of course %d: %v", course.ID, err)
+		return
+	}
+	if count == 0 {
+		s.recordCourseRevision(course, nil, baselineRevisionNote)
+	}
+}
+
+func (s *courseService) GetCourseRevisions(id uint, user models.User) ([]models.CourseRevisionResponse, error) {
+	if _, err := s.courseRepo.FindById(id); err != nil {
+		return nil, err
+	}
+
+	if err := authorizeCourseManagement(s.courseRepo, user, id); err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.revisionRepo.FindCourseRevisions(id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]models.CourseRevisionResponse, 0, len(revisions))
+	for i := range revisions {
+		var previous *models.CourseRevision
+		if i+1 < len(revisions) {
+			previous = &revisions[i+1]
+		}
+		res = append(res, s.buildCourseRevisionResponse(&revisions[i], previous))
+	}
+	return res, nil
+}
+
+// GetCourseRevision returns the revision with a diff of its text fields
+// against revision against, or the one before it when against is 0.
+func (s *courseService) GetCourseRevision(id uint, number int, against int, user models.User) (*models.CourseRevisionDetail, error) {
+	if _, err := s.courseRepo.FindById(id); err != nil {
+		return nil, err
+	}
+
+	if err := authorizeCourseManagement(s.courseRepo, user, id); err != nil {
+		return nil, err
+	}
+
+	rev, err := s.revisionRepo.FindCourseRevision(id, number)
+	if err != nil {
+		return nil, err
+	}
+
+	if against == 0 {
+		against = number - 1
+	}
+	var base *models.CourseRevision
+	if against > 0 {
+		base, err = s.revisionRepo.FindCourseRevision(id, against)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var before models.CourseRevision
+	if base != nil {
+		before = *base
+	}
+
+	return &models.CourseRevisionDetail{
+		CourseRevisionResponse: s.buildCourseRevisionResponse(rev, base),
+		Against:                against,
+		Diff: map[string][]models.DiffLine{
+			"title":       utils.DiffLines(before.Title, rev.Title),
+			"description": utils.DiffLines(before.Description, rev.Description),
+			"instructor":  utils.DiffLines(before.Instructor, rev.Instructor),
+		},
+	}, nil
+}
+
+// RestoreCourseRevision brings back the revision's catalog details as a new
+// revision. Instructors, status and other settings are left as they are.
+func (s *courseService) RestoreCourseRevision(id uint, number int, user models.User) (*models.Course, error) {
+	course, err := s.courseRepo.FindById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeCourseManagement(s.courseRepo, user, id); err != nil {
+		return nil, err
+	}
+
+	rev, err := s.revisionRepo.FindCourseRevision(id, number)
+	if err != nil {
+		return nil, err
+	}
+
+	course.Title = rev.Title
+	course.Description = rev.Description
+	course.Instructor = rev.Instructor
+	course.Topics = rev.Topics
+	course.Price = rev.Price
+	course.ThumbnailImage = rev.ThumbnailImage
+
+	if err := s.courseRepo.Update(course); err != nil {
+		return nil, err
+	}
+	s.recordCourseRevision(course, &user.ID, fmt.Sprintf("restored from revision %d", number))
+
+	return s.withURLs(course), nil
+}
+
+func (s *courseService) buildCourseRevisionResponse(rev *models.CourseRevision, previous *models.CourseRevision) models.CourseRevisionResponse {
+	changed := []string{}
+	if previous != nil {
+		if rev.Title != previous.Title {
+			changed = append(changed, "title")
+		}
+		if rev.Description != previous.Description {
+			changed = append(changed, "description")
+		}
+		if rev.Instructor != previous.Instructor {
+			changed = append(changed, "instructor")
+		}
+		if !slices.Equal(rev.Topics, previous.Topics) {
+			changed = append(changed, "topics")
+		}
+		if rev.Price != previous.Price {
+			changed = append(changed, "price")
+		}
+		if rev.ThumbnailImage != previous.ThumbnailImage {
+			changed = append(changed, "thumbnail_image")
+		}
+	}
+
+	return models.CourseRevisionResponse{
+		Number:         rev.Number,
+		Author:         revisionAuthor(rev.Author),
+		Note:           rev.Note,
+		Title:          rev.Title,
+		Description:    rev.Description,
+		Instructor:     rev.Instructor,
+		Topics:         rev.Topics,
+		Price:          rev.Price,
+		ThumbnailImage: storage.URL(s.store, rev.ThumbnailImage),
+		ChangedFields:  changed,
+		CreatedAt:      rev.CreatedAt,
+	}
+}
+
+func revisionAuthor(user *models.User) *models.RevisionAuthor {
+	if user == nil {
+		return nil
+	}
+	return &models.RevisionAuthor{ID: user.ID, Username: user.Username}
+}
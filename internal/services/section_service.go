@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
+)
+
+var ErrInvalidSection = errors.New("invalid section")
+
+type SectionService interface {
+	GetSections(courseID uint, user models.User) ([]models.SectionResponse, error)
+	CreateSection(courseID uint, input models.SectionInput, user models.User) (*models.SectionResponse, error)
+	UpdateSection(id uint, input models.SectionInput, user models.User) (*models.SectionResponse, error)
+	DeleteSection(id uint, user models.User) error
+}
+
+type sectionService struct {
+	sectionRepo repositories.SectionRepository
+	courseRepo  repositories.CourseRepository
+}
+
+func NewSectionService(sr repositories.SectionRepository, cr repositories.CourseRepository) SectionService {
+	return &sectionService{sectionRepo: sr, courseRepo: cr}
+}
+
+func (s *sectionService) GetSections(courseID uint, user models.User) ([]models.SectionResponse, error) {
+	course, err := s.courseRepo.FindById(courseID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireCourseVisible(s.courseRepo, course, user); err != nil {
+		return nil, err
+	}
+
+	sections, err := s.sectionRepo.FindByCourse(courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]models.SectionResponse, 0, len(sections))
+	for i := range sections {
+		res = append(res, buildSectionResponse(&sections[i]))
+	}
+	return res, nil
+}
+
+func (s *sectionService) CreateSection(courseID uint, input models.SectionInput, user models.User) (*models.SectionResponse, error) {
+	if _, err := s.courseRepo.FindById(courseID); err != nil {
+		return nil, err
+	}
+
+	if err := authorizeCourseManagement(s.courseRepo, user, courseID); err != nil {
+		return nil, err
+	}
+
+	section := models.Section{CourseID: courseID, Title: input.Title}
+	if err := s.sectionRepo.Create(&section); err != nil {
+		return nil, err
+	}
+
+	res := buildSectionResponse(&section)
+	return &res, nil
+}
+
+func (s *sectionService) UpdateSection(id uint, input models.SectionInput, user models.User) (*models.SectionResponse, error) {
+	section, err := s.sectionRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeCourseManagement(s.courseRepo, user, section.CourseID); err != nil {
+		return nil, err
+	}
+
+	section.Title = input.Title
+	if err := s.sectionRepo.UpdateTitle(section); err != nil {
+		return nil, err
+	}
+
+	res := buildSectionResponse(section)
+	return &res, nil
+}
+
+// DeleteSection removes the section but keeps its modules; they are left
+// without a section.
+func (s *sectionService) DeleteSection(id uint, user models.User) error {
+	section, err := s.sectionRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := authorizeCourseManagement(s.courseRepo, user, section.CourseID); err != nil {
+		return err
+	}
+
+	return s.sectionRepo.Delete(section)
+}
+
+func buildSectionResponse(section *models.Section) models.SectionResponse {
+	return models.SectionResponse{
+		ID:        section.ID,
+		CourseID:  section.CourseID,
+		Title:     section.Title,
+		Order:     section.Order,
+		CreatedAt: section.CreatedAt,
+		UpdatedAt: section.UpdatedAt,
+	}
+}
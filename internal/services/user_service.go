@@ -2,21 +2,27 @@ package services
 
 import (
 	"errors"
-	"math"
 	"strconv"
 
 	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/rbac"
 	"github.com/kin-ark/GroAcademy/internal/repositories"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var ErrUserHasTransactions = errors.New("user has balance transactions and cannot be deleted")
+
 type UserService interface {
 	GetUsers(query models.SearchQuery) ([]models.User, models.PaginationResponse, error)
 	EditUser(id uint, input models.PostUserRequest) (*models.User, error)
-    DeleteUser(id uint) error
+	DeleteUser(id uint) error
 	BuildUsersResponse(users []models.User) []models.UsersResponse
 	GetUserById(id uint) (*models.User, int, error)
 	AddUserBalance(id uint, increment float64) (*models.PostUserBalanceResponse, error)
+	GetBalanceTransactions(id uint, query models.PaginationQuery) ([]models.BalanceTransaction, models.PaginationResponse, error)
+	BuildBalanceTransactionsResponse(transactions []models.BalanceTransaction) []models.BalanceTransactionResponse
+	AssignRole(id uint, role string, actor models.User) (*models.User, error)
+	GetRoles() []models.RoleResponse
 }
 
 type userService struct {
@@ -35,16 +41,7 @@ func (s *userService) GetUsers(query models.SearchQuery) ([]models.User, models.
 		return nil, models.PaginationResponse{}, err
 	}
 
-	totalPages := int(math.Ceil(float64(totalItems) / float64(query.Limit)))
-	if query.Page > totalPages && totalPages > 0 {
-		query.Page = totalPages
-	}
-
-	pagination := models.PaginationResponse{
-		CurrentPage: query.Page,
-		TotalPages:  totalPages,
-		TotalItems:  int(totalItems),
-	}
+	pagination := query.Paginate(totalItems)
 
 	return users, pagination, nil
 }
@@ -58,6 +55,7 @@ func (s *userService) BuildUsersResponse(users []models.User) []models.UsersResp
 			LastName:  user.LastName,
 			Email:     user.Email,
 			Username:  user.Username,
+			Role:      user.Role,
 			Balance:   user.Balance,
 			ID:        stringId,
 		})
@@ -94,13 +92,46 @@ func (s *userService) AddUserBalance(id uint, increment float64) (*models.PostUs
 	return &res, nil
 }
 
+func (s *userService) GetBalanceTransactions(id uint, query models.PaginationQuery) ([]models.BalanceTransaction, models.PaginationResponse, error) {
+	query.Normalize()
+
+	if _, err := s.userRepo.FindById(id); err != nil {
+		return nil, models.PaginationResponse{}, err
+	}
+
+	transactions, totalItems, err := s.userRepo.GetBalanceTransactions(id, query)
+	if err != nil {
+		return nil, models.PaginationResponse{}, err
+	}
+
+	pagination := query.Paginate(totalItems)
+
+	return transactions, pagination, nil
+}
+
+func (s *userService) BuildBalanceTransactionsResponse(transactions []models.BalanceTransaction) []models.BalanceTransactionResponse {
+	res := make([]models.BalanceTransactionResponse, 0, len(transactions))
+	for _, t := range transactions {
+		res = append(res, models.BalanceTransactionResponse{
+			ID:           t.ID,
+			Type:         t.Type,
+			Amount:       t.Amount,
+			BalanceAfter: t.BalanceAfter,
+			PurchaseID:   t.PurchaseID,
+			Description:  t.Description,
+			CreatedAt:    t.CreatedAt,
+		})
+	}
+	return res
+}
+
 func (s *userService) EditUser(id uint, input models.PostUserRequest) (*models.User, error) {
 	user, err := s.userRepo.FindById(id)
 	if err != nil {
 		return nil, err
 	}
 
-	if user.Role == "admin" {
+	if user.Role == rbac.RoleAdmin {
 		return nil, errors.New("admin cannot be edited")
 	}
 
@@ -133,5 +164,52 @@ func (s *userService) DeleteUser(id uint) error {
 		return err
 	}
 
+	// Ledger entries are never deleted, so neither is their owner.
+	hasLedger, err := s.userRepo.HasBalanceTransactions(id)
+	if err != nil {
+		return err
+	}
+	if hasLedger {
+		return ErrUserHasTransactions
+	}
+
 	return s.userRepo.Delete(existing)
 }
+
+func (s *userService) AssignRole(id uint, role string, actor models.User) (*models.User, error) {
+	if !rbac.IsValidRole(role) {
+		return nil, errors.New("invalid role: " + role)
+	}
+
+	if id == actor.ID {
+		return nil, errors.New("you cannot change your own role")
+	}
+
+	user, err := s.userRepo.FindById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.UpdateRole(id, role); err != nil {
+		return nil, err
+	}
+
+	user.Role = role
+	return user, nil
+}
+
+func (s *userService) GetRoles() []models.RoleResponse {
+	roles := rbac.Roles()
+	res := make([]models.RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		permissions := make([]string, 0)
+		for _, p := range rbac.PermissionsFor(role) {
+			permissions = append(permissions, string(p))
+		}
+		res = append(res, models.RoleResponse{
+			Name:        role,
+			Permissions: permissions,
+		})
+	}
+	return res
+}
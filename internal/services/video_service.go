@@ -0,0 +1,191 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/kin-ark/GroAcademy/internal/repositories"
+	"github.com/kin-ark/GroAcademy/internal/storage"
+	"github.com/kin-ark/GroAcademy/internal/transcode"
+	"gorm.io/gorm"
+)
+
+const JobTypeTranscodeVideo = "transcode_video"
+
+type TranscodeVideoPayload struct {
+	ModuleID uint   `json:"module_id"`
+	Source   string `json:"source"`
+}
+
+type VideoService interface {
+	HandleTranscodeJob(payload []byte) error
+}
+
+type videoService struct {
+	moduleRepo repositories.ModuleRepository
+	store      storage.Store
+	transcoder *transcode.Transcoder
+}
+
+func NewVideoService(mr repositories.ModuleRepository, st storage.Store, t *transcode.Transcoder) VideoService {
+	return &videoService{moduleRepo: mr, store: st, transcoder: t}
+}
+
+func enqueueTranscode(jobRepo repositories.JobRepository, moduleID uint, source string) error {
+	payload, err := json.Marshal(TranscodeVideoPayload{ModuleID: moduleID, Source: source})
+	if err != nil {
+		return err
+	}
+
+	return jobRepo.Enqueue(&models.Job{Type: JobTypeTranscodeVideo, Payload: string(payload)})
+}
+
+// hlsPrefix is the storage prefix holding every file of a transcoded video.
+func hlsPrefix(masterKey string) string {
+	if masterKey == "" {
+		return ""
+	}
+	return path.Dir(masterKey)
+}
+
+func (s *videoService) HandleTranscodeJob(payload []byte) error {
+	var p TranscodeVideoPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return err
+	}
+
+	module, err := s.moduleRepo.FindById(p.ModuleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// The video was replaced or removed after the job was queued.
+	if module.VideoContent != p.Source || storage.IsExternal(p.Source) {
+		return nil
+	}
+
+	if _, err := s.moduleRepo.SetVideoState(module.ID, p.Source, map[string]any{
+		"video_status": models.VideoStatusProcessing,
+	}); err != nil {
+		return err
+	}
+
+	prefix := fmt.Sprintf("video_hls/%d/%d", module.ID, time.Now().UnixNano())
+	info, err := s.transcode(p.Source, prefix)
+	if err != nil {
+		storage.RemovePrefix(s.store, prefix)
+		_, _ = s.moduleRepo.SetVideoState(module.ID, p.Source, map[string]any{
+			"video_status": models.VideoStatusFailed,
+			"video_error":  truncate(err.Error(), 500),
+		})
+		return err
+	}
+
+	updated, err := s.moduleRepo.SetVideoState(module.ID, p.Source, map[string]any{
+		"video_status":   models.VideoStatusReady,
+		"video_hls":      path.Join(prefix, transcode.MasterPlaylist),
+		"video_poster":   path.Join(prefix, transcode.PosterFile),
+		"video_duration": info.Duration,
+		"video_error":    "",
+	})
+	if err != nil || !updated {
+		storage.RemovePrefix(s.store, prefix)
+	}
+	return err
+}
+
+// transcode downloads the source into a scratch directory, renders the HLS
+// renditions and poster there and uploads the result below prefix.
+func (s *videoService) transcode(source string, prefix string) (*transcode.Info, error) {
+	workDir, err := os.MkdirTemp("", "transcode-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	input := filepath.Join(workDir, "source"+path.Ext(source))
+	if err := s.download(source, input); err != nil {
+		return nil, err
+	}
+
+	info, err := s.transcoder.Probe(input)
+	if err != nil {
+		return nil, err
+	}
+
+	outDir := filepath.Join(workDir, "out")
+	if _, err := s.transcoder.HLS(input, info, outDir); err != nil {
+		return nil, err
+	}
+	if err := s.transcoder.Poster(input, info, filepath.Join(outDir, transcode.PosterFile)); err != nil {
+		return nil, err
+	}
+
+	if err := s.uploadDir(outDir, prefix); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (s *videoService) download(key string, dst string) error {
+	rc, err := s.store.Get(key)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, rc); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var hlsContentTypes = map[string]string{
+	".m3u8": "application/vnd.apple.mpegurl",
+	".ts":   "video/mp2t",
+	".jpg":  "image/jpeg",
+}
+
+func (s *videoService) uploadDir(dir string, prefix string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+
+		key := path.Join(prefix, filepath.ToSlash(rel))
+		return s.store.Put(key, f, info.Size(), hlsContentTypes[filepath.Ext(p)])
+	})
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+)
+
+const (
+	defaultAutoCompletePercentage = 90.0
+
+	// A heartbeat may credit at most maxHeartbeatWatch seconds, and never
+	// more than maxPlaybackRate times the time since the previous heartbeat.
+	maxHeartbeatWatch = 60.0
+	maxPlaybackRate   = 2.0
+	heartbeatSlack    = 5.0
+
+	// Watched ranges closer than this are merged, which keeps the list short.
+	rangeMergeGap = 1.0
+)
+
+// WatchPolicy turns playback heartbeats into module progress and decides
+// when a watched video completes its module.
+type WatchPolicy struct {
+	// AutoCompletePercentage is the share of the video that must be watched
+	// for the module to complete on its own. Zero disables auto-completion.
+	AutoCompletePercentage float64
+}
+
+// LoadWatchPolicy reads VIDEO_AUTO_COMPLETE_PERCENT from the environment,
+// falling back to 90%. A value of 0 disables auto-completion.
+func LoadWatchPolicy() WatchPolicy {
+	percentage := defaultAutoCompletePercentage
+	if v, err := strconv.ParseFloat(os.Getenv("VIDEO_AUTO_COMPLETE_PERCENT"), 64); err == nil && v >= 0 && v <= 100 {
+		percentage = v
+	}
+
+	return WatchPolicy{AutoCompletePercentage: percentage}
+}
+
+// Apply records a heartbeat on progress and reports whether it completed the
+// module. The heartbeat covers the span of video just played, ending at the
+// reported position; watched time is the length of all such spans, so
+// neither seeking to the end nor replaying the same part counts twice.
+// duration is the video length in seconds, or 0 when unknown.
+func (p WatchPolicy) Apply(progress *models.ModuleProgress, input models.WatchProgressInput, duration float64, now time.Time) bool {
+	watched := math.Min(input.WatchedSeconds, maxHeartbeatWatch)
+	if progress.LastWatchedAt != nil {
+		elapsed := now.Sub(*progress.LastWatchedAt).Seconds()
+		watched = math.Min(watched, math.Max(elapsed, 0)*maxPlaybackRate+heartbeatSlack)
+	}
+
+	position := input.PositionSeconds
+	if duration > 0 {
+		position = math.Min(position, duration)
+	}
+
+	ranges := progress.WatchedRanges
+	if len(ranges) == 0 && progress.WatchedSeconds > 0 {
+		// Progress saved before ranges were kept is counted from the start.
+		ranges = [][2]float64{{0, progress.WatchedSeconds}}
+	}
+	if watched > 0 {
+		ranges = addWatchedRange(ranges, math.Max(position-watched, 0), position)
+	}
+
+	var total float64
+	for _, r := range ranges {
+		total += r[1] - r[0]
+	}
+	if duration > 0 {
+		total = math.Min(total, duration)
+	}
+
+	previous := progress.WatchedPercentage
+	progress.PositionSeconds = position
+	progress.WatchedRanges = ranges
+	progress.WatchedSeconds = total
+	if duration > 0 {
+		progress.WatchedPercentage = math.Round(total/duration*1000) / 10
+	}
+	progress.LastWatchedAt = &now
+
+	// Only crossing the threshold completes the module, so a learner who
+	// unmarks a watched module is not overridden by the next heartbeat.
+	if p.AutoCompletePercentage <= 0 || progress.IsCompleted {
+		return false
+	}
+	if previous < p.AutoCompletePercentage && progress.WatchedPercentage >= p.AutoCompletePercentage {
+		progress.IsCompleted = true
+		return true
+	}
+	return false
+}
+
+// addWatchedRange adds [start, end] to the sorted, disjoint ranges, merging
+// it with any range it overlaps or nearly touches.
+func addWatchedRange(ranges [][2]float64, start, end float64) [][2]float64 {
+	merged := make([][2]float64, 0, len(ranges)+1)
+	added := false
+	for _, r := range ranges {
+		switch {
+		case r[1]+rangeMergeGap < start:
+			merged = append(merged, r)
+		case end+rangeMergeGap < r[0]:
+			if !added {
+				merged = append(merged, [2]float64{start, end})
+				added = true
+			}
+			merged = append(merged, r)
+		default:
+			start = math.Min(start, r[0])
+			end = math.Max(end, r[1])
+		}
+	}
+	if !added {
+		merged = append(merged, [2]float64{start, end})
+	}
+	return merged
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kin-ark/GroAcademy/internal/models"
+)
+
+func TestAddWatchedRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		ranges     [][2]float64
+		start, end float64
+		want       [][2]float64
+	}{
+		{name: "first range", start: 0, end: 10, want: [][2]float64{{0, 10}}},
+		{name: "replay inside a range", ranges: [][2]float64{{0, 30}}, start: 10, end: 20, want: [][2]float64{{0, 30}}},
+		{name: "extends a range", ranges: [][2]float64{{0, 30}}, start: 25, end: 40, want: [][2]float64{{0, 40}}},
+		{name: "merges a near gap", ranges: [][2]float64{{0, 30}}, start: 30.5, end: 40, want: [][2]float64{{0, 40}}},
+		{name: "after all ranges", ranges: [][2]float64{{0, 10}}, start: 50, end: 60, want: [][2]float64{{0, 10}, {50, 60}}},
+		{name: "before all ranges", ranges: [][2]float64{{50, 60}}, start: 0, end: 10, want: [][2]float64{{0, 10}, {50, 60}}},
+		{name: "between ranges", ranges: [][2]float64{{0, 10}, {50, 60}}, start: 20, end: 30, want: [][2]float64{{0, 10}, {20, 30}, {50, 60}}},
+		{name: "bridges ranges", ranges: [][2]float64{{0, 10}, {20, 30}, {50, 60}}, start: 5, end: 25, want: [][2]float64{{0, 30}, {50, 60}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addWatchedRange(tt.ranges, tt.start, tt.end); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("addWatchedRange = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchPolicyApply(t *testing.T) {
+	policy := WatchPolicy{AutoCompletePercentage: 90}
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	before := now.Add(-30 * time.Second)
+	recent := now.Add(-10 * time.Second)
+
+	tests := []struct {
+		name       string
+		disabled   bool
+		progress   models.ModuleProgress
+		input      models.WatchProgressInput
+		duration   float64
+		seconds    float64
+		percentage float64
+		position   float64
+		completed  bool
+		completes  bool
+	}{
+		{
+			name:       "heartbeat is capped at a minute",
+			progress:   models.ModuleProgress{},
+			input:      models.WatchProgressInput{WatchedSeconds: 300, PositionSeconds: 300},
+			duration:   600,
+			seconds:    60,
+			percentage: 10,
+			position:   300,
+		},
+		{
+			name:       "first heartbeat",
+			progress:   models.ModuleProgress{},
+			input:      models.WatchProgressInput{WatchedSeconds: 30, PositionSeconds: 30},
+			duration:   100,
+			seconds:    30,
+			percentage: 30,
+			position:   30,
+		},
+		{
+			name:       "replay does not add up",
+			progress:   models.ModuleProgress{WatchedRanges: [][2]float64{{0, 30}}, WatchedSeconds: 30, WatchedPercentage: 30, LastWatchedAt: &before},
+			input:      models.WatchProgressInput{WatchedSeconds: 30, PositionSeconds: 30},
+			duration:   100,
+			seconds:    30,
+			percentage: 30,
+			position:   30,
+		},
+		{
+			name:       "seeking to the end credits only the played span",
+			progress:   models.ModuleProgress{WatchedRanges: [][2]float64{{0, 30}}, WatchedSeconds: 30, WatchedPercentage: 30, LastWatchedAt: &before},
+			input:      models.WatchProgressInput{WatchedSeconds: 10, PositionSeconds: 100},
+			duration:   100,
+			seconds:    40,
+			percentage: 40,
+			position:   100,
+		},
+		{
+			name:       "heartbeat is capped by elapsed time",
+			progress:   models.ModuleProgress{LastWatchedAt: &recent},
+			input:      models.WatchProgressInput{WatchedSeconds: 60, PositionSeconds: 80},
+			duration:   100,
+			seconds:    25,
+			percentage: 25,
+			position:   80,
+		},
+		{
+			name:       "position is clamped to the duration",
+			progress:   models.ModuleProgress{},
+			input:      models.WatchProgressInput{WatchedSeconds: 20, PositionSeconds: 500},
+			duration:   100,
+			seconds:    20,
+			percentage: 20,
+			position:   100,
+		},
+		{
+			name:       "legacy progress counts from the start",
+			progress:   models.ModuleProgress{WatchedSeconds: 50, WatchedPercentage: 50, LastWatchedAt: &before},
+			input:      models.WatchProgressInput{WatchedSeconds: 10, PositionSeconds: 60},
+			duration:   100,
+			seconds:    60,
+			percentage: 60,
+			position:   60,
+		},
+		{
+			name:       "crossing the threshold completes",
+			progress:   models.ModuleProgress{WatchedRanges: [][2]float64{{0, 85}}, WatchedSeconds: 85, WatchedPercentage: 85, LastWatchedAt: &before},
+			input:      models.WatchProgressInput{WatchedSeconds: 10, PositionSeconds: 95},
+			duration:   100,
+			seconds:    95,
+			percentage: 95,
+			position:   95,
+			completed:  true,
+			completes:  true,
+		},
+		{
+			name:       "unmarked module is not completed again",
+			progress:   models.ModuleProgress{WatchedRanges: [][2]float64{{0, 95}}, WatchedSeconds: 95, WatchedPercentage: 95, LastWatchedAt: &before},
+			input:      models.WatchProgressInput{WatchedSeconds: 5, PositionSeconds: 100},
+			duration:   100,
+			seconds:    100,
+			percentage: 100,
+			position:   100,
+		},
+		{
+			name:       "already completed",
+			progress:   models.ModuleProgress{WatchedRanges: [][2]float64{{0, 85}}, WatchedSeconds: 85, WatchedPercentage: 85, IsCompleted: true, LastWatchedAt: &before},
+			input:      models.WatchProgressInput{WatchedSeconds: 10, PositionSeconds: 95},
+			duration:   100,
+			seconds:    95,
+			percentage: 95,
+			position:   95,
+			completed:  true,
+		},
+		{
+			name:       "auto-completion disabled",
+			disabled:   true,
+			progress:   models.ModuleProgress{WatchedRanges: [][2]float64{{0, 85}}, WatchedSeconds: 85, WatchedPercentage: 85, LastWatchedAt: &before},
+			input:      models.WatchProgressInput{WatchedSeconds: 10, PositionSeconds: 95},
+			duration:   100,
+			seconds:    95,
+			percentage: 95,
+			position:   95,
+		},
+		{
+			name:     "unknown duration",
+			progress: models.ModuleProgress{},
+			input:    models.WatchProgressInput{WatchedSeconds: 30, PositionSeconds: 30},
+			seconds:  30,
+			position: 30,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := policy
+			if tt.disabled {
+				p = WatchPolicy{}
+			}
+			progress := tt.progress
+
+			completes := p.Apply(&progress, tt.input, tt.duration, now)
+
+			if completes != tt.completes {
+				t.Errorf("Apply = %v, want %v", completes, tt.completes)
+			}
+			if progress.IsCompleted != tt.completed {
+				t.Errorf("IsCompleted = %v, want %v", progress.IsCompleted, tt.completed)
+			}
+			if progress.WatchedSeconds != tt.seconds {
+				t.Errorf("WatchedSeconds = %v, want %v", progress.WatchedSeconds, tt.seconds)
+			}
+			if progress.WatchedPercentage != tt.percentage {
+				t.Errorf("WatchedPercentage = %v, want %v", progress.WatchedPercentage, tt.percentage)
+			}
+			if progress.PositionSeconds != tt.position {
+				t.Errorf("PositionSeconds = %v, want %v", progress.PositionSeconds, tt.position)
+			}
+			if progress.LastWatchedAt == nil || !progress.LastWatchedAt.Equal(now) {
+				t.Errorf("LastWatchedAt = %v, want %v", progress.LastWatchedAt, now)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestURLSigner(t *testing.T) {
+	signer := NewURLSigner("secret", "http://localhost:8080/")
+	const route, key = "media/", "videos/intro lesson.mp4"
+
+	signed := signer.URL(route, key, time.Hour)
+	if want := "http://localhost:8080/media/videos/intro%20lesson.mp4?"; !strings.HasPrefix(signed, want) {
+		t.Fatalf("URL = %q, want prefix %q", signed, want)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires, signature := u.Query().Get("expires"), u.Query().Get("signature")
+	past := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		signer    *URLSigner
+		route     string
+		key       string
+		expires   string
+		signature string
+		valid     bool
+	}{
+		{name: "valid", route: route, key: key, expires: expires, signature: signature, valid: true},
+		{name: "other key", route: route, key: "videos/other.mp4", expires: expires, signature: signature},
+		{name: "other route", route: PlaylistRoute, key: key, expires: expires, signature: signature},
+		{name: "extended expiry", route: route, key: key, expires: expires + "0", signature: signature},
+		{name: "expired", route: route, key: key, expires: past, signature: signer.signature(route, key, past)},
+		{name: "malformed expiry", route: route, key: key, expires: "soon", signature: signature},
+		{name: "tampered signature", route: route, key: key, expires: expires, signature: strings.Repeat("0", len(signature))},
+		{name: "missing signature", route: route, key: key, expires: expires},
+		{name: "other secret", signer: NewURLSigner("other", "http://localhost:8080/"), route: route, key: key, expires: expires, signature: signature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := signer
+			if tt.signer != nil {
+				s = tt.signer
+			}
+
+			remaining, err := s.Verify(tt.route, tt.key, tt.expires, tt.signature)
+			if !tt.valid {
+				if !errors.Is(err, ErrInvalidSignature) {
+					t.Fatalf("err = %v, want %v", err, ErrInvalidSignature)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if remaining <= 0 || remaining > time.Hour {
+				t.Errorf("remaining = %v, want within (0, 1h]", remaining)
+			}
+		})
+	}
+}
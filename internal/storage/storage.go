@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound         = errors.New("object not found")
+	ErrInvalidKey       = errors.New("invalid object key")
+	ErrInvalidSignature = errors.New("invalid or expired signature")
+)
+
+const (
+	// DefaultURLExpiry is how long URLs handed out to clients stay valid.
+	DefaultURLExpiry = 2 * time.Hour
+	// ContentURLExpiry is used for paid module content, which should not
+	// stay shareable for long.
+	ContentURLExpiry = time.Hour
+)
+
+// Store persists uploaded files under slash-separated object keys such as
+// "thumbnails/abc.png". Only keys are stored in the database; URLs are
+// derived on demand through SignedURL.
+type Store interface {
+	Put(key string, r io.Reader, size int64, contentType string) error
+	Get(key string) (io.ReadCloser, error)
+	Open(key string) (Object, error)
+	List(prefix string) ([]string, error)
+	Delete(key string) error
+	SignedURL(key string, expiry time.Duration) (string, error)
+}
+
+// Object is a stored file opened for random access, which is what
+// http.ServeContent needs to answer Range requests.
+type Object interface {
+	io.ReadSeekCloser
+	Size() int64
+	ModTime() time.Time
+}
+
+// SignedURLVerifier is implemented by stores whose signed URLs are served by
+// this application rather than by the storage service itself.
+type SignedURLVerifier interface {
+	VerifySignedURL(key string, expires string, signature string) error
+}
+
+// NewFromEnv builds the store selected by STORAGE_DRIVER ("local" or "s3").
+func NewFromEnv() Store {
+	switch os.Getenv("STORAGE_DRIVER") {
+	case "s3":
+		store, err := NewS3Store(S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			UsePathStyle:    os.Getenv("S3_USE_PATH_STYLE") == "true",
+		})
+		if err != nil {
+			log.Fatal("Failed to configure S3 storage:", err)
+		}
+		return store
+	default:
+		return NewLocalStore("uploads", SignerFromEnv())
+	}
+}
+
+// IsExternal reports whether value is an absolute URL rather than an object
+// key, as used by seeded content hosted elsewhere.
+func IsExternal(value string) bool {
+	return strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://")
+}
+
+// URL resolves a stored value into something a client can fetch. External
+// URLs are passed through untouched.
+func URL(store Store, value string) string {
+	return URLWithExpiry(store, value, DefaultURLExpiry)
+}
+
+func URLWithExpiry(store Store, value string, expiry time.Duration) string {
+	if value == "" || IsExternal(value) {
+		return value
+	}
+
+	u, err := store.SignedURL(value, expiry)
+	if err != nil {
+		log.Printf("ERROR: Failed to build URL for %s: %v", value, err)
+		return ""
+	}
+	return u
+}
+
+// Remove deletes the object behind value, ignoring external URLs and empty
+// values. Failures are logged because callers have already committed the
+// database change that orphaned the file.
+func Remove(store Store, value string) {
+	if value == "" || IsExternal(value) {
+		return
+	}
+
+	if err := store.Delete(value); err != nil && !errors.Is(err, ErrNotFound) {
+		log.Printf("ERROR: Failed to delete %s: %v", value, err)
+	}
+}
+
+// RemovePrefix deletes every object below prefix, such as the files of an
+// HLS rendition set.
+func RemovePrefix(store Store, prefix string) {
+	if prefix == "" || IsExternal(prefix) {
+		return
+	}
+
+	keys, err := store.List(prefix)
+	if err != nil {
+		log.Printf("ERROR: Failed to list %s: %v", prefix, err)
+		return
+	}
+	for _, key := range keys {
+		Remove(store, key)
+	}
+}
+
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+
+	cleaned := path.Clean(key)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+)
+
+type UploadKind string
+
+const (
+	KindImage UploadKind = "image"
+	KindPDF   UploadKind = "pdf"
+	KindVideo UploadKind = "video"
+	// KindSubmission covers files students hand in for assignments.
+	KindSubmission UploadKind = "submission"
+	// KindFont covers TrueType fonts used by certificate templates.
+	KindFont UploadKind = "font"
+)
+
+var (
+	ErrFileTooLarge    = errors.New("file is too large")
+	ErrUnsupportedType = errors.New("unsupported file type")
+)
+
+// uploadRule lists the sniffed MIME types accepted for a kind together with
+// the extension used for stored objects.
+type uploadRule struct {
+	MaxSize int64
+	Types   map[string]string
+}
+
+const mb = 1 << 20
+
+// uploadRules is the single place upload limits are defined. Sizes can be
+// raised per deployment with UPLOAD_MAX_IMAGE_MB, UPLOAD_MAX_PDF_MB,
+// UPLOAD_MAX_VIDEO_MB, UPLOAD_MAX_SUBMISSION_MB and UPLOAD_MAX_FONT_MB.
+var uploadRules = map[UploadKind]uploadRule{
+	KindImage: {
+		MaxSize: envMegabytes("UPLOAD_MAX_IMAGE_MB", 5),
+		Types:   map[string]string{"image/png": ".png", "image/jpeg": ".jpg", "image/webp": ".webp"},
+	},
+	KindPDF: {
+		MaxSize: envMegabytes("UPLOAD_MAX_PDF_MB", 50),
+		Types:   map[string]string{"application/pdf": ".pdf"},
+	},
+	KindVideo: {
+		MaxSize: envMegabytes("UPLOAD_MAX_VIDEO_MB", 500),
+		Types:   map[string]string{"video/mp4": ".mp4", "video/webm": ".webm"},
+	},
+	KindSubmission: {
+		MaxSize: envMegabytes("UPLOAD_MAX_SUBMISSION_MB", 20),
+		Types: map[string]string{
+			"application/pdf":           ".pdf",
+			"application/zip":           ".zip",
+			"image/png":                 ".png",
+			"image/jpeg":                ".jpg",
+			"text/plain; charset=utf-8": ".txt",
+		},
+	},
+	KindFont: {
+		MaxSize: envMegabytes("UPLOAD_MAX_FONT_MB", 10),
+		Types:   map[string]string{"font/ttf": ".ttf"},
+	},
+}
+
+func envMegabytes(name string, fallback int64) int64 {
+	if v, err := strconv.ParseInt(os.Getenv(name), 10, 64); err == nil && v > 0 {
+		return v * mb
+	}
+	return fallback * mb
+}
+
+// MaxRequestSize bounds a whole multipart request: one file of every kind
+// plus some room for the other form fields.
+func MaxRequestSize() int64 {
+	var total int64 = mb
+	for _, rule := range uploadRules {
+		total += rule.MaxSize
+	}
+	return total
+}
+
+// Upload is a validated file that is ready to be stored.
+type Upload struct {
+	Key         string
+	ContentType string
+	fh          *multipart.FileHeader
+}
+
+// ValidateUpload checks the size of fh and sniffs its first bytes to make sure
+// it really is of the given kind. The returned upload gets a random object key
+// under dir so client-supplied names never reach the store.
+func ValidateUpload(field string, dir string, kind UploadKind, fh *multipart.FileHeader) (*Upload, error) {
+	rule, ok := uploadRules[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown upload kind %q", kind)
+	}
+
+	if fh.Size > rule.MaxSize {
+		return nil, fmt.Errorf("%s: %w (max %d MB)", field, ErrFileTooLarge, rule.MaxSize/mb)
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	contentType := http.DetectContentType(head[:n])
+	ext, ok := rule.Types[contentType]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w %s, expected %s", field, ErrUnsupportedType, contentType, kind)
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Upload{Key: path.Join(dir, name+ext), ContentType: contentType, fh: fh}, nil
+}
+
+// Save writes a validated upload to the store.
+func (u *Upload) Save(store Store) error {
+	f, err := u.fh.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return store.Put(u.Key, f, u.fh.Size, u.ContentType)
+}
+
+// SaveUpload validates fh and stores it, returning the new object key.
+func SaveUpload(store Store, field string, dir string, kind UploadKind, fh *multipart.FileHeader) (string, error) {
+	upload, err := ValidateUpload(field, dir, kind, fh)
+	if err != nil {
+		return "", err
+	}
+
+	if err := upload.Save(store); err != nil {
+		return "", err
+	}
+	return upload.Key, nil
+}
+
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
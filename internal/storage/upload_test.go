@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"path"
+	"strings"
+	"testing"
+)
+
+// fileHeader builds the header of a multipart file upload holding content.
+func fileHeader(t *testing.T, name string, content []byte) *multipart.FileHeader {
+	t.Helper()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := part.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	form, err := multipart.NewReader(&body, w.Boundary()).ReadForm(mb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+	return form.File["file"][0]
+}
+
+func TestValidateUpload(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	pdf := []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	mp4 := []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom")
+	ttf := []byte("\x00\x01\x00\x00\x00\x10\x01\x00\x00\x04")
+
+	tests := []struct {
+		name        string
+		kind        UploadKind
+		filename    string
+		content     []byte
+		size        int64
+		contentType string
+		ext         string
+		err         error
+	}{
+		{name: "png image", kind: KindImage, filename: "a.png", content: png, contentType: "image/png", ext: ".png"},
+		{name: "extension is not trusted", kind: KindImage, filename: "a.jpg", content: png, contentType: "image/png", ext: ".png"},
+		{name: "pdf", kind: KindPDF, filename: "notes.pdf", content: pdf, contentType: "application/pdf", ext: ".pdf"},
+		{name: "mp4 video", kind: KindVideo, filename: "v.mp4", content: mp4, contentType: "video/mp4", ext: ".mp4"},
+		{name: "text submission", kind: KindSubmission, filename: "a.txt", content: []byte("my answer"), contentType: "text/plain; charset=utf-8", ext: ".txt"},
+		{name: "truetype font", kind: KindFont, filename: "f.ttf", content: ttf, contentType: "font/ttf", ext: ".ttf"},
+		{name: "pdf is not an image", kind: KindImage, filename: "a.png", content: pdf, err: ErrUnsupportedType},
+		{name: "html renamed to pdf", kind: KindPDF, filename: "a.pdf", content: []byte("<html><script></script></html>"), err: ErrUnsupportedType},
+		{name: "empty file", kind: KindImage, filename: "a.png", content: nil, err: ErrUnsupportedType},
+		{name: "too large", kind: KindImage, filename: "a.png", content: png, size: uploadRules[KindImage].MaxSize + 1, err: ErrFileTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fh := fileHeader(t, tt.filename, tt.content)
+			if tt.size > 0 {
+				fh.Size = tt.size
+			}
+
+			upload, err := ValidateUpload("file", "uploads", tt.kind, fh)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				if !strings.HasPrefix(err.Error(), "file: ") {
+					t.Errorf("err = %q, want it to name the field", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if upload.ContentType != tt.contentType {
+				t.Errorf("ContentType = %q, want %q", upload.ContentType, tt.contentType)
+			}
+			if path.Dir(upload.Key) != "uploads" || path.Ext(upload.Key) != tt.ext {
+				t.Errorf("Key = %q, want uploads/<random>%s", upload.Key, tt.ext)
+			}
+			if stem := strings.TrimSuffix(path.Base(upload.Key), tt.ext); len(stem) != 32 || strings.Trim(stem, "0123456789abcdef") != "" {
+				t.Errorf("Key = %q, want a random hex name", upload.Key)
+			}
+		})
+	}
+}
+
+func TestValidateUploadUnknownKind(t *testing.T) {
+	if _, err := ValidateUpload("file", "uploads", UploadKind("exe"), fileHeader(t, "a", []byte("x"))); err == nil {
+		t.Fatal("expected an error for an unknown kind")
+	}
+}
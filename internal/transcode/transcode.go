@@ -0,0 +1,204 @@
+// Package transcode wraps the ffmpeg and ffprobe binaries to turn uploaded
+// videos into HLS renditions.
+package transcode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rendition is one quality level of the HLS output.
+type Rendition struct {
+	Name         string
+	Height       int
+	VideoBitrate int // kbit/s
+	AudioBitrate int // kbit/s
+}
+
+// DefaultRenditions are produced for every video, skipping those taller than
+// the source.
+var DefaultRenditions = []Rendition{
+	{Name: "360p", Height: 360, VideoBitrate: 800, AudioBitrate: 96},
+	{Name: "720p", Height: 720, VideoBitrate: 2800, AudioBitrate: 128},
+}
+
+const (
+	MasterPlaylist = "master.m3u8"
+	PosterFile     = "poster.jpg"
+	segmentSeconds = 6
+)
+
+type Info struct {
+	Duration float64
+	Width    int
+	Height   int
+}
+
+type Transcoder struct {
+	ffmpeg     string
+	ffprobe    string
+	renditions []Rendition
+	timeout    time.Duration
+}
+
+// NewFromEnv uses FFMPEG_PATH and FFPROBE_PATH, defaulting to the binaries on
+// PATH.
+func NewFromEnv() *Transcoder {
+	ffmpeg := os.Getenv("FFMPEG_PATH")
+	if ffmpeg == "" {
+		ffmpeg = "ffmpeg"
+	}
+	ffprobe := os.Getenv("FFPROBE_PATH")
+	if ffprobe == "" {
+		ffprobe = "ffprobe"
+	}
+
+	return &Transcoder{
+		ffmpeg:     ffmpeg,
+		ffprobe:    ffprobe,
+		renditions: DefaultRenditions,
+		timeout:    2 * time.Hour,
+	}
+}
+
+// Probe reads the duration and dimensions of the first video stream.
+func (t *Transcoder) Probe(input string) (*Info, error) {
+	out, err := t.run(t.ffprobe,
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=width,height:format=duration",
+		"-of", "json",
+		input,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var probe struct {
+		Streams []struct {
+			Width  int `json:"width"`
+			Height int `json:"height"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return nil, fmt.Errorf("parse ffprobe output: %w", err)
+	}
+	if len(probe.Streams) == 0 {
+		return nil, fmt.Errorf("no video stream found")
+	}
+
+	duration, _ := strconv.ParseFloat(probe.Format.Duration, 64)
+	return &Info{
+		Duration: duration,
+		Width:    probe.Streams[0].Width,
+		Height:   probe.Streams[0].Height,
+	}, nil
+}
+
+// Poster grabs a single frame near the start of the video as a JPEG.
+func (t *Transcoder) Poster(input string, info *Info, output string) error {
+	at := 1.0
+	if info.Duration > 0 && info.Duration < 2 {
+		at = info.Duration / 2
+	}
+
+	_, err := t.run(t.ffmpeg,
+		"-y",
+		"-ss", strconv.FormatFloat(at, 'f', 2, 64),
+		"-i", input,
+		"-frames:v", "1",
+		"-vf", "scale=-2:'min(720,ih)'",
+		"-q:v", "3",
+		output,
+	)
+	return err
+}
+
+// HLS writes one media playlist per rendition below outDir plus a master
+// playlist referencing them, and returns the renditions produced.
+func (t *Transcoder) HLS(input string, info *Info, outDir string) ([]Rendition, error) {
+	var produced []Rendition
+	var master strings.Builder
+	master.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+
+	for i, r := range t.renditions {
+		// Always keep the smallest rendition so tiny sources still play.
+		if i > 0 && r.Height > info.Height {
+			continue
+		}
+
+		dir := filepath.Join(outDir, r.Name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+
+		_, err := t.run(t.ffmpeg,
+			"-y",
+			"-i", input,
+			"-map", "0:v:0", "-map", "0:a:0?",
+			"-vf", fmt.Sprintf("scale=-2:%d", r.Height),
+			"-c:v", "libx264", "-preset", "veryfast", "-profile:v", "main",
+			"-b:v", fmt.Sprintf("%dk", r.VideoBitrate),
+			"-maxrate", fmt.Sprintf("%dk", r.VideoBitrate*107/100),
+			"-bufsize", fmt.Sprintf("%dk", r.VideoBitrate*3/2),
+			"-g", "48", "-keyint_min", "48", "-sc_threshold", "0",
+			"-c:a", "aac", "-b:a", fmt.Sprintf("%dk", r.AudioBitrate), "-ac", "2",
+			"-hls_time", strconv.Itoa(segmentSeconds),
+			"-hls_playlist_type", "vod",
+			"-hls_segment_filename", filepath.Join(dir, "seg_%04d.ts"),
+			filepath.Join(dir, "index.m3u8"),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("rendition %s: %w", r.Name, err)
+		}
+
+		fmt.Fprintf(&master, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d\n%s/index.m3u8\n",
+			(r.VideoBitrate+r.AudioBitrate)*1000, scaledWidth(info, r.Height), r.Height, r.Name)
+		produced = append(produced, r)
+	}
+
+	if err := os.WriteFile(filepath.Join(outDir, MasterPlaylist), []byte(master.String()), 0644); err != nil {
+		return nil, err
+	}
+	return produced, nil
+}
+
+// scaledWidth mirrors ffmpeg's scale=-2:h, which keeps the aspect ratio and
+// rounds to an even width.
+func scaledWidth(info *Info, height int) int {
+	if info.Height == 0 {
+		return height * 16 / 9
+	}
+	w := info.Width * height / info.Height
+	return w - w%2
+}
+
+func (t *Transcoder) run(name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > 500 {
+			msg = msg[len(msg)-500:]
+		}
+		return nil, fmt.Errorf("%s: %w: %s", filepath.Base(name), err, msg)
+	}
+	return stdout.Bytes(), nil
+}
@@ -1,40 +1,344 @@
 package utils
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"image"
 	"image/color"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"strconv"
 	"strings"
 
 	"github.com/fogleman/gg"
+	"github.com/golang/freetype/truetype"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/kin-ark/GroAcademy/internal/models"
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	_ "golang.org/x/image/webp"
 )
 
-func GenerateCertificate(username, courseTitle, instructorName, completionDate string) (image.Image, error) {
-	if strings.TrimSpace(username) == "" || strings.TrimSpace(courseTitle) == "" ||
-		strings.TrimSpace(instructorName) == "" || strings.TrimSpace(completionDate) == "" {
-		return nil, errors.New("all of username, courseTitle, instructorName, completionDate must be provided")
+// pdfScale converts template pixels (96 dpi) to PDF points.
+const pdfScale = 0.75
+
+// CertificateContent is what gets printed on one certificate.
+type CertificateContent struct {
+	Holder      string
+	CourseTitle string
+	Instructor  string
+	Date        string
+	Serial      string
+	VerifyURL   string
+}
+
+// CertificateAssets are the files a template refers to, already loaded from
+// storage. Missing images are left out and missing fonts fall back to the Go
+// fonts.
+type CertificateAssets struct {
+	Background image.Image
+	Logo       image.Image
+	Signature  image.Image
+	TitleFont  []byte
+	BodyFont   []byte
+}
+
+// ParseCertificateFont checks that data is a TrueType font both renderers
+// can use.
+func ParseCertificateFont(data []byte) error {
+	_, err := truetype.Parse(data)
+	return err
+}
+
+// ParseHexColor parses #rgb and #rrggbb colors.
+func ParseHexColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+// certificateText is one line of text placed by the template. Emphasized
+// lines use the title font and the accent color.
+type certificateText struct {
+	field      models.CertificateField
+	text       string
+	emphasized bool
+}
+
+// certificateLayout resolves the template against the content so both
+// renderers draw exactly the same things.
+type certificateLayout struct {
+	width, height float64
+	textColor     color.RGBA
+	accentColor   color.RGBA
+	texts         []certificateText
+	images        map[string]models.CertificateField
+	qr            *qrcode.QRCode
+	titleFont     []byte
+	bodyFont      []byte
+}
+
+func newCertificateLayout(tpl models.CertificateTemplate, assets CertificateAssets, content CertificateContent) (*certificateLayout, error) {
+	if strings.TrimSpace(content.Holder) == "" || strings.TrimSpace(content.CourseTitle) == "" ||
+		strings.TrimSpace(content.Instructor) == "" || strings.TrimSpace(content.Date) == "" ||
+		strings.TrimSpace(content.Serial) == "" || strings.TrimSpace(content.VerifyURL) == "" {
+		return nil, errors.New("all of holder, course title, instructor, date, serial and verify URL must be provided")
+	}
+	if tpl.Width <= 0 || tpl.Height <= 0 {
+		return nil, errors.New("certificate template must have a positive size")
+	}
+
+	textColor, err := ParseHexColor(tpl.TextColor)
+	if err != nil {
+		return nil, err
+	}
+	accentColor, err := ParseHexColor(tpl.AccentColor)
+	if err != nil {
+		return nil, err
+	}
+
+	qr, err := qrcode.New(content.VerifyURL, qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+	qr.DisableBorder = true
+
+	layout := &certificateLayout{
+		width:       float64(tpl.Width),
+		height:      float64(tpl.Height),
+		textColor:   textColor,
+		accentColor: accentColor,
+		images:      map[string]models.CertificateField{},
+		qr:          qr,
+		titleFont:   assets.TitleFont,
+		bodyFont:    assets.BodyFont,
+	}
+	if layout.titleFont == nil {
+		layout.titleFont = gobold.TTF
+	}
+	if layout.bodyFont == nil {
+		layout.bodyFont = goregular.TTF
+	}
+
+	texts := map[string]string{
+		models.CertFieldHeading:    "CERTIFICATE OF COMPLETION",
+		models.CertFieldIntro:      "This is to certify that",
+		models.CertFieldHolder:     content.Holder,
+		models.CertFieldCompletion: "has successfully completed the course",
+		models.CertFieldCourse:     content.CourseTitle,
+		models.CertFieldInstructor: fmt.Sprintf("Instructor: %s", content.Instructor),
+		models.CertFieldDate:       fmt.Sprintf("Date: %s", content.Date),
+		models.CertFieldSerial:     fmt.Sprintf("Certificate No. %s", content.Serial),
+		models.CertFieldVerifyURL:  fmt.Sprintf("Verify at %s", content.VerifyURL),
+	}
+	for _, key := range models.CertificateTextFields {
+		field, ok := tpl.Fields[key]
+		if !ok || field.Hidden {
+			continue
+		}
+		if field.Size <= 0 {
+			field.Size = 20
+		}
+		emphasis := key == models.CertFieldHeading || key == models.CertFieldHolder || key == models.CertFieldCourse
+		layout.texts = append(layout.texts, certificateText{field: field, text: texts[key], emphasized: emphasis})
+	}
+
+	for _, key := range models.CertificateImageFields {
+		field, ok := tpl.Fields[key]
+		if !ok || field.Hidden || field.Width <= 0 || field.Height <= 0 {
+			continue
+		}
+		layout.images[key] = field
+	}
+
+	return layout, nil
+}
+
+func alignFactor(align string) float64 {
+	switch align {
+	case "left":
+		return 0
+	case "right":
+		return 1
+	default:
+		return 0.5
 	}
+}
 
-	width, height := 1200, 800
-	dc := gg.NewContext(width, height)
+// RenderCertificatePNG draws the certificate as a raster image of the
+// template's size.
+func RenderCertificatePNG(tpl models.CertificateTemplate, assets CertificateAssets, content CertificateContent) (image.Image, error) {
+	layout, err := newCertificateLayout(tpl, assets, content)
+	if err != nil {
+		return nil, err
+	}
 
+	titleFont, err := truetype.Parse(layout.titleFont)
+	if err != nil {
+		return nil, fmt.Errorf("title font: %w", err)
+	}
+	bodyFont, err := truetype.Parse(layout.bodyFont)
+	if err != nil {
+		return nil, fmt.Errorf("body font: %w", err)
+	}
+
+	dc := gg.NewContext(tpl.Width, tpl.Height)
 	dc.SetColor(color.White)
 	dc.Clear()
 
-	dc.SetLineWidth(8)
-	dc.SetColor(color.Black)
-	dc.DrawRectangle(20, 20, float64(width-40), float64(height-40))
-	dc.Stroke()
+	if assets.Background != nil {
+		dc.DrawImage(scaleImage(assets.Background, tpl.Width, tpl.Height), 0, 0)
+	} else {
+		dc.SetLineWidth(8)
+		dc.SetColor(layout.accentColor)
+		dc.DrawRectangle(20, 20, layout.width-40, layout.height-40)
+		dc.Stroke()
+	}
 
-	dc.SetColor(color.Black)
-	dc.DrawStringAnchored("CERTIFICATE OF COMPLETION", float64(width)/2, 150, 0.5, 0.5)
-	dc.DrawStringAnchored("This is to certify that", float64(width)/2, 250, 0.5, 0.5)
-	dc.DrawStringAnchored(username, float64(width)/2, 320, 0.5, 0.5)
-	dc.DrawStringAnchored("has successfully completed the course", float64(width)/2, 400, 0.5, 0.5)
-	dc.DrawStringAnchored(courseTitle, float64(width)/2, 460, 0.5, 0.5)
-	dc.DrawStringAnchored(fmt.Sprintf("Instructor: %s", instructorName), float64(width)*0.25, 600, 0.5, 0.5)
-	dc.DrawStringAnchored(fmt.Sprintf("Date: %s", completionDate), float64(width)*0.75, 600, 0.5, 0.5)
+	placed := map[string]image.Image{models.CertFieldLogo: assets.Logo, models.CertFieldSignature: assets.Signature}
+	for _, key := range []string{models.CertFieldLogo, models.CertFieldSignature} {
+		img := placed[key]
+		field, ok := layout.images[key]
+		if !ok || img == nil {
+			continue
+		}
+		dc.DrawImage(scaleImage(img, int(field.Width), int(field.Height)), int(field.X), int(field.Y))
+	}
+
+	if field, ok := layout.images[models.CertFieldQR]; ok {
+		size := int(min(field.Width, field.Height))
+		dc.DrawImage(layout.qr.Image(size), int(field.X), int(field.Y))
+	}
+
+	for _, t := range layout.texts {
+		face, textColor := bodyFont, layout.textColor
+		if t.emphasized {
+			face, textColor = titleFont, layout.accentColor
+		}
+		dc.SetFontFace(truetype.NewFace(face, &truetype.Options{Size: t.field.Size}))
+		dc.SetColor(textColor)
+		dc.DrawStringAnchored(t.text, t.field.X, t.field.Y, alignFactor(t.field.Align), 0.5)
+	}
 
 	return dc.Image(), nil
 }
+
+// RenderCertificatePDF writes the certificate as a single-page vector PDF
+// with the same layout as the PNG. Text stays selectable and the QR code is
+// drawn as shapes so it prints sharply.
+func RenderCertificatePDF(w io.Writer, tpl models.CertificateTemplate, assets CertificateAssets, content CertificateContent) error {
+	layout, err := newCertificateLayout(tpl, assets, content)
+	if err != nil {
+		return err
+	}
+
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		OrientationStr: "P",
+		UnitStr:        "pt",
+		Size:           gofpdf.SizeType{Wd: layout.width * pdfScale, Ht: layout.height * pdfScale},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetCreator("GroAcademy", true)
+	pdf.SetTitle(fmt.Sprintf("Certificate %s", content.Serial), true)
+	pdf.AddUTF8FontFromBytes("title", "", layout.titleFont)
+	pdf.AddUTF8FontFromBytes("body", "", layout.bodyFont)
+	pdf.AddPage()
+
+	px := func(v float64) float64 { return v * pdfScale }
+	setColor := func(set func(r, g, b int), c color.RGBA) { set(int(c.R), int(c.G), int(c.B)) }
+
+	if assets.Background != nil {
+		if err := pdfImage(pdf, "background", assets.Background, 0, 0, px(layout.width), px(layout.height)); err != nil {
+			return err
+		}
+	} else {
+		pdf.SetLineWidth(px(8))
+		setColor(pdf.SetDrawColor, layout.accentColor)
+		pdf.Rect(px(20), px(20), px(layout.width-40), px(layout.height-40), "D")
+	}
+
+	placed := map[string]image.Image{models.CertFieldLogo: assets.Logo, models.CertFieldSignature: assets.Signature}
+	for _, key := range []string{models.CertFieldLogo, models.CertFieldSignature} {
+		img := placed[key]
+		field, ok := layout.images[key]
+		if !ok || img == nil {
+			continue
+		}
+		if err := pdfImage(pdf, key, img, px(field.X), px(field.Y), px(field.Width), px(field.Height)); err != nil {
+			return err
+		}
+	}
+
+	if field, ok := layout.images[models.CertFieldQR]; ok {
+		bitmap := layout.qr.Bitmap()
+		cell := px(min(field.Width, field.Height)) / float64(len(bitmap))
+		x0, y0 := px(field.X), px(field.Y)
+		// White backing like the PNG's, so the code scans on dark backgrounds.
+		pdf.SetFillColor(255, 255, 255)
+		pdf.Rect(x0, y0, cell*float64(len(bitmap)), cell*float64(len(bitmap)), "F")
+		pdf.SetFillColor(0, 0, 0)
+		for row, modules := range bitmap {
+			// Merge runs of dark modules into one rectangle each.
+			for col := 0; col < len(modules); col++ {
+				if !modules[col] {
+					continue
+				}
+				start := col
+				for col+1 < len(modules) && modules[col+1] {
+					col++
+				}
+				pdf.Rect(x0+float64(start)*cell, y0+float64(row)*cell, float64(col-start+1)*cell, cell, "F")
+			}
+		}
+	}
+
+	for _, t := range layout.texts {
+		family, textColor := "body", layout.textColor
+		if t.emphasized {
+			family, textColor = "title", layout.accentColor
+		}
+		pdf.SetFont(family, "", px(t.field.Size))
+		setColor(pdf.SetTextColor, textColor)
+		// Match gg's anchoring: the text box is as tall as the font size and
+		// is centered on Y.
+		x := px(t.field.X) - alignFactor(t.field.Align)*pdf.GetStringWidth(t.text)
+		pdf.Text(x, px(t.field.Y+t.field.Size/2), t.text)
+	}
+
+	return pdf.Output(w)
+}
+
+func scaleImage(src image.Image, width, height int) image.Image {
+	if b := src.Bounds(); b.Dx() == width && b.Dy() == height {
+		return src
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+	return dst
+}
+
+// pdfImage embeds img re-encoded as PNG, whatever format it was uploaded in.
+func pdfImage(pdf *gofpdf.Fpdf, name string, img image.Image, x, y, w, h float64) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	opts := gofpdf.ImageOptions{ImageType: "PNG"}
+	pdf.RegisterImageOptionsReader(name, opts, &buf)
+	pdf.ImageOptions(name, x, y, w, h, false, opts, 0, "")
+	return pdf.Error()
+}
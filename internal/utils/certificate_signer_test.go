@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestCertificateSignerVerify(t *testing.T) {
+	signer := NewCertificateSigner("secret", "https://groacademy.test/")
+	issuedAt := time.Date(2025, 3, 1, 12, 30, 15, 0, time.UTC)
+	const serial = "K3VQ-7XWD-ABCD-EFGH-2345-6789"
+	signature := signer.Sign(serial, 7, 3, issuedAt)
+
+	jakarta := time.FixedZone("WIB", 7*60*60)
+
+	tests := []struct {
+		name      string
+		signer    *CertificateSigner
+		serial    string
+		userID    uint
+		courseID  uint
+		issuedAt  time.Time
+		signature string
+		want      bool
+	}{
+		{name: "valid", serial: serial, userID: 7, courseID: 3, issuedAt: issuedAt, signature: signature, want: true},
+		{name: "same instant in another zone", serial: serial, userID: 7, courseID: 3, issuedAt: issuedAt.In(jakarta), signature: signature, want: true},
+		{name: "sub-second precision is ignored", serial: serial, userID: 7, courseID: 3, issuedAt: issuedAt.Add(400 * time.Millisecond), signature: signature, want: true},
+		{name: "other serial", serial: "AAAA-7XWD-ABCD-EFGH-2345-6789", userID: 7, courseID: 3, issuedAt: issuedAt, signature: signature},
+		{name: "other holder", serial: serial, userID: 8, courseID: 3, issuedAt: issuedAt, signature: signature},
+		{name: "other course", serial: serial, userID: 7, courseID: 4, issuedAt: issuedAt, signature: signature},
+		{name: "other issue date", serial: serial, userID: 7, courseID: 3, issuedAt: issuedAt.Add(time.Second), signature: signature},
+		{name: "holder and course swapped", serial: serial, userID: 3, courseID: 7, issuedAt: issuedAt, signature: signature},
+		{name: "empty signature", serial: serial, userID: 7, courseID: 3, issuedAt: issuedAt},
+		{name: "other secret", signer: NewCertificateSigner("other", ""), serial: serial, userID: 7, courseID: 3, issuedAt: issuedAt, signature: signature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := signer
+			if tt.signer != nil {
+				s = tt.signer
+			}
+			if got := s.Verify(tt.serial, tt.userID, tt.courseID, tt.issuedAt, tt.signature); got != tt.want {
+				t.Errorf("Verify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCertificateSignerVerifyURL(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{baseURL: "https://groacademy.test", want: "https://groacademy.test/verify/ABCD"},
+		{baseURL: "https://groacademy.test/", want: "https://groacademy.test/verify/ABCD"},
+		{baseURL: "", want: "/verify/ABCD"},
+	}
+
+	for _, tt := range tests {
+		if got := NewCertificateSigner("secret", tt.baseURL).VerifyURL("ABCD"); got != tt.want {
+			t.Errorf("VerifyURL with base %q = %q, want %q", tt.baseURL, got, tt.want)
+		}
+	}
+}
+
+func TestNewCertificateSerial(t *testing.T) {
+	format := regexp.MustCompile(`^[A-Z2-7]{4}(-[A-Z2-7]{4}){5}$`)
+	seen := make(map[string]bool)
+
+	for range 100 {
+		serial, err := NewCertificateSerial()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !format.MatchString(serial) {
+			t.Fatalf("serial %q is not six groups of four base32 characters", serial)
+		}
+		if seen[serial] {
+			t.Fatalf("serial %q was issued twice", serial)
+		}
+		seen[serial] = true
+	}
+}
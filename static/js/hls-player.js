@@ -0,0 +1,23 @@
+document.addEventListener('DOMContentLoaded', function() {
+    const video = document.getElementById('module-video');
+    if (!video || !video.dataset.hlsSrc) {
+        return;
+    }
+
+    const src = video.dataset.hlsSrc;
+
+    if (window.Hls && Hls.isSupported()) {
+        const hls = new Hls();
+        hls.on(Hls.Events.ERROR, function(event, data) {
+            // Fall back to the original upload if the stream cannot be played.
+            if (data.fatal) {
+                hls.destroy();
+                video.load();
+            }
+        });
+        hls.loadSource(src);
+        hls.attachMedia(video);
+    } else if (video.canPlayType('application/vnd.apple.mpegurl')) {
+        video.src = src;
+    }
+});
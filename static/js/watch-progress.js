@@ -0,0 +1,99 @@
+document.addEventListener('DOMContentLoaded', function() {
+    const video = document.getElementById('module-video');
+    if (!video || !video.dataset.progressUrl) {
+        return;
+    }
+
+    const url = video.dataset.progressUrl;
+    const resumeAt = parseFloat(video.dataset.resumePosition) || 0;
+    const heartbeatInterval = 15000;
+
+    let watched = 0;
+    let lastTime = null;
+    let enabled = true;
+
+    video.addEventListener('loadedmetadata', function() {
+        // Skip resuming when the learner stopped right at the start or end.
+        if (resumeAt > 5 && (!video.duration || resumeAt < video.duration - 5)) {
+            video.currentTime = resumeAt;
+        }
+    }, { once: true });
+
+    video.addEventListener('timeupdate', function() {
+        const now = video.currentTime;
+        if (lastTime !== null && !video.seeking) {
+            const delta = now - lastTime;
+            // Only count continuous playback; seeks show up as large jumps.
+            if (delta > 0 && delta < 2 * Math.max(video.playbackRate, 1)) {
+                watched += delta;
+            }
+        }
+        lastTime = now;
+    });
+
+    video.addEventListener('seeking', function() {
+        lastTime = null;
+    });
+
+    function payload() {
+        const body = {
+            position_seconds: video.currentTime,
+            watched_seconds: watched,
+            duration_seconds: isFinite(video.duration) ? video.duration : 0
+        };
+        watched = 0;
+        return JSON.stringify(body);
+    }
+
+    function markCompleted() {
+        const status = document.querySelector('.completion-status');
+        if (status) {
+            status.classList.remove('incomplete');
+            status.textContent = 'Module Completed';
+        }
+    }
+
+    function send() {
+        if (!enabled || (watched === 0 && video.paused)) {
+            return;
+        }
+        fetch(url, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            credentials: 'same-origin',
+            body: payload()
+        }).then(function(res) {
+            // Previews without a purchase have no progress to record.
+            if (res.status === 403 || res.status === 404) {
+                enabled = false;
+                return null;
+            }
+            return res.ok ? res.json() : null;
+        }).then(function(json) {
+            if (json && json.data && json.data.auto_completed) {
+                markCompleted();
+            }
+        }).catch(function() {});
+    }
+
+    function sendOnLeave() {
+        if (!enabled || !navigator.sendBeacon) {
+            return;
+        }
+        navigator.sendBeacon(url, new Blob([payload()], { type: 'application/json' }));
+    }
+
+    setInterval(function() {
+        if (!video.paused) {
+            send();
+        }
+    }, heartbeatInterval);
+
+    video.addEventListener('pause', send);
+    video.addEventListener('ended', send);
+    document.addEventListener('visibilitychange', function() {
+        if (document.visibilityState === 'hidden') {
+            sendOnLeave();
+        }
+    });
+});